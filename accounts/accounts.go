@@ -40,6 +40,7 @@ const (
 	MimetypeTypedData         = "data/typed"
 	MimetypeClique            = "application/x-clique-header"
 	MimetypeNpos              = "application/x-npos-header"
+	MimetypeNposAttestation   = "application/x-npos-attestation"
 	MimetypeTextPlain         = "text/plain"
 )
 
@@ -23,12 +23,14 @@ import (
 	"os"
 	"runtime"
 	"strconv"
+	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/ethereum/go-ethereum/cmd/utils"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
@@ -66,6 +68,16 @@ It expects the genesis file as argument.`,
 		Description: `
 The dumpgenesis command prints the genesis configuration of the network preset
 if one is set.  Otherwise it prints the genesis from the datadir.`,
+	}
+	sysUpgradesCommand = &cli.Command{
+		Action:    listSysUpgrades,
+		Name:      "sysupgrades",
+		Usage:     "Lists the system contract upgrades scheduled by a genesis",
+		ArgsUsage: "<genesisPath>",
+		Description: `
+The sysupgrades command prints the NPoS system contract upgrades enabled by the
+chain config of the genesis file, with their fork blocks and upgraded contracts.
+It fails if any of them can't be applied, e.g. its bytecode is not released.`,
 	}
 	importCommand = &cli.Command{
 		Action:    importChain,
@@ -245,6 +257,42 @@ func dumpGenesis(ctx *cli.Context) error {
 	return nil
 }
 
+func listSysUpgrades(ctx *cli.Context) error {
+	if ctx.Args().Len() != 1 {
+		utils.Fatalf("need genesis.json file as the only argument")
+	}
+	file, err := os.Open(ctx.Args().First())
+	if err != nil {
+		utils.Fatalf("Failed to read genesis file: %v", err)
+	}
+	defer file.Close()
+
+	genesis := new(core.Genesis)
+	if err := json.NewDecoder(file).Decode(genesis); err != nil {
+		utils.Fatalf("invalid genesis file: %v", err)
+	}
+	if genesis.Config == nil || genesis.Config.Npos == nil {
+		utils.Fatalf("not a NPoS genesis")
+	}
+	upgrades := systemcontract.ScheduledUpgrades(genesis.Config.Npos)
+	if len(upgrades) == 0 {
+		fmt.Println("No system contract upgrades scheduled")
+		return nil
+	}
+	var failed int
+	for _, upgrade := range upgrades {
+		fmt.Printf("v%d at block #%v: %s\n", upgrade.Version, upgrade.Block, strings.Join(upgrade.Contracts, ", "))
+		if upgrade.Err != nil {
+			fmt.Printf("  can't be applied: %v\n", upgrade.Err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d upgrades can't be applied", failed, len(upgrades))
+	}
+	return nil
+}
+
 func importChain(ctx *cli.Context) error {
 	if ctx.Args().Len() < 1 {
 		utils.Fatalf("This command requires an argument.")
@@ -69,6 +69,7 @@ Remove blockchain and state databases`,
 			dbExportCmd,
 			dbMetadataCmd,
 			dbCheckStateContentCmd,
+			dbBackfillInternalTxsCmd,
 		},
 	}
 	dbInspectCmd = &cli.Command{
@@ -193,6 +194,19 @@ WARNING: This is a low-level operation which may cause database corruption!`,
 		}, utils.NetworkFlags, utils.DatabasePathFlags),
 		Description: "Shows metadata about the chain status.",
 	}
+	dbBackfillInternalTxsCmd = &cli.Command{
+		Action:    backfillInternalTxs,
+		Name:      "backfill-internaltxs",
+		Usage:     "Regenerate the internal txs of a range of blocks by executing them again",
+		ArgsUsage: "<from> <to>",
+		Flags: flags.Merge([]cli.Flag{
+			utils.SyncModeFlag,
+			utils.InternalTxTraceAll,
+		}, utils.NetworkFlags, utils.DatabasePathFlags),
+		Description: `This command executes the canonical blocks from <from> to <to> again and stores
+the internal txs they produce, e.g. for the blocks imported while the internal txs trace
+was disabled or snap synced. The state of the block before <from> must be available.`,
+	}
 )
 
 func removeDB(ctx *cli.Context) error {
@@ -556,6 +570,30 @@ func freezerInspect(ctx *cli.Context) error {
 	return rawdb.InspectFreezerTable(ancient, freezer, table, start, end)
 }
 
+func backfillInternalTxs(ctx *cli.Context) error {
+	if ctx.NArg() != 2 {
+		return fmt.Errorf("required arguments: %v", ctx.Command.ArgsUsage)
+	}
+	from, err := strconv.ParseUint(ctx.Args().Get(0), 10, 64)
+	if err != nil {
+		log.Info("Could not read from-param", "err", err)
+		return err
+	}
+	to, err := strconv.ParseUint(ctx.Args().Get(1), 10, 64)
+	if err != nil {
+		log.Info("Could not read to-param", "err", err)
+		return err
+	}
+	stack, _ := makeConfigNode(ctx)
+	defer stack.Close()
+
+	chain, db := utils.MakeChain(ctx, stack, false)
+	defer db.Close()
+	defer chain.Stop()
+
+	return chain.BackfillInternalTxs(from, to)
+}
+
 func importLDBdata(ctx *cli.Context) error {
 	start := 0
 	switch ctx.NArg() {
@@ -86,6 +86,13 @@ var (
 		utils.TxPoolAccountQueueFlag,
 		utils.TxPoolGlobalQueueFlag,
 		utils.TxPoolLifetimeFlag,
+		utils.TxPoolStrictExValidationFlag,
+		utils.TxPoolCongestionPeriodFlag,
+		utils.TxPoolCongestionSecsFlag,
+		utils.TxPoolCongestionUnderPricedFactorFlag,
+		utils.TxPoolCongestionPendingFactorFlag,
+		utils.TxPoolCongestionMaxPendingSecsFlag,
+		utils.TxPoolCongestionHistoryFlag,
 		utils.SyncModeFlag,
 		utils.SyncTargetFlag,
 		utils.ExitWhenSyncedFlag,
@@ -131,6 +138,10 @@ var (
 		utils.MinerRecommitIntervalFlag,
 		utils.MinerNoVerifyFlag,
 		utils.MinerNewPayloadTimeout,
+		utils.MinerTxOrderingFlag,
+		utils.MinerSenderTxCapFlag,
+		utils.MinerReservedGasFlag,
+		utils.MinerPrioritySendersFlag,
 		utils.NATFlag,
 		utils.NoDiscoverFlag,
 		utils.DiscoveryV4Flag,
@@ -152,10 +163,14 @@ var (
 		utils.GpoPercentileFlag,
 		utils.GpoMaxGasPriceFlag,
 		utils.GpoIgnoreGasPriceFlag,
+		utils.GpoCongestionFlag,
+		utils.GpoMaxCongestionPremiumFlag,
 		utils.MinerNotifyFullFlag,
 		configFileFlag,
 		utils.InternalTxTraceDisabled,
 		utils.InternalTxTraceAll,
+		utils.InternalTxIndex,
+		utils.InternalTxHistory,
 	}, utils.NetworkFlags, utils.DatabasePathFlags)
 
 	rpcFlags = []cli.Flag{
@@ -223,6 +238,7 @@ func init() {
 		removedbCommand,
 		dumpCommand,
 		dumpGenesisCommand,
+		sysUpgradesCommand,
 		// See accountcmd.go:
 		accountCommand,
 		walletCommand,
@@ -436,6 +436,47 @@ var (
 		Value:    ethconfig.Defaults.TxPool.Lifetime,
 		Category: flags.TxPoolCategory,
 	}
+	TxPoolStrictExValidationFlag = &cli.BoolFlag{
+		Name:     "txpool.strictexvalidation",
+		Usage:    "Reject transactions while the consensus blacklist can not be evaluated",
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolCongestionPeriodFlag = &cli.IntFlag{
+		Name:     "txpool.congestion.period",
+		Usage:    "Seconds between two evaluations of the transaction congestion",
+		Value:    ethconfig.Defaults.TxPool.CongestionConfig.PeriodsSecs,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolCongestionSecsFlag = &cli.IntFlag{
+		Name:     "txpool.congestion.secs",
+		Usage:    "Seconds a transaction is pending from which it is congested",
+		Value:    ethconfig.Defaults.TxPool.CongestionConfig.CongestionSecs,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolCongestionUnderPricedFactorFlag = &cli.IntFlag{
+		Name:     "txpool.congestion.underpricedfactor",
+		Usage:    "Weight of the underpriced transactions in the congestion index",
+		Value:    ethconfig.Defaults.TxPool.CongestionConfig.UnderPricedFactor,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolCongestionPendingFactorFlag = &cli.IntFlag{
+		Name:     "txpool.congestion.pendingfactor",
+		Usage:    "Weight of the congested pending transactions in the congestion index",
+		Value:    ethconfig.Defaults.TxPool.CongestionConfig.PendingFactor,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolCongestionMaxPendingSecsFlag = &cli.IntFlag{
+		Name:     "txpool.congestion.maxpendingsecs",
+		Usage:    "Seconds a transaction is pending from which it is ignored by the congestion index",
+		Value:    ethconfig.Defaults.TxPool.CongestionConfig.MaxValidPendingSecs,
+		Category: flags.TxPoolCategory,
+	}
+	TxPoolCongestionHistoryFlag = &cli.IntFlag{
+		Name:     "txpool.congestion.history",
+		Usage:    "Number of evaluations of the transaction congestion to keep",
+		Value:    ethconfig.Defaults.TxPool.CongestionConfig.HistorySize,
+		Category: flags.TxPoolCategory,
+	}
 
 	// Performance tuning settings
 	CacheFlag = &cli.IntFlag{
@@ -557,6 +598,29 @@ var (
 		Value:    ethconfig.Defaults.Miner.NewPayloadTimeout,
 		Category: flags.MinerCategory,
 	}
+	MinerTxOrderingFlag = &cli.StringFlag{
+		Name:     "miner.ordering",
+		Usage:    `Transaction ordering policy of the mined blocks ("price" or "fifo")`,
+		Value:    ethconfig.Defaults.Miner.TxOrdering,
+		Category: flags.MinerCategory,
+	}
+	MinerSenderTxCapFlag = &cli.IntFlag{
+		Name:     "miner.sendertxcap",
+		Usage:    "Maximum number of transactions of a sender in a mined block (0 = no cap)",
+		Value:    ethconfig.Defaults.Miner.SenderTxCap,
+		Category: flags.MinerCategory,
+	}
+	MinerReservedGasFlag = &cli.Uint64Flag{
+		Name:     "miner.reservedgas",
+		Usage:    "Percentage of the block gas reserved for the local and priority senders",
+		Value:    ethconfig.Defaults.Miner.ReservedGas,
+		Category: flags.MinerCategory,
+	}
+	MinerPrioritySendersFlag = &cli.StringFlag{
+		Name:     "miner.prioritysenders",
+		Usage:    "Comma separated accounts sharing the reserved block space with the local accounts",
+		Category: flags.MinerCategory,
+	}
 
 	// Account settings
 	UnlockedAccountFlag = &cli.StringFlag{
@@ -911,6 +975,17 @@ var (
 		Value:    ethconfig.Defaults.GPO.IgnorePrice.Int64(),
 		Category: flags.GasPriceCategory,
 	}
+	GpoCongestionFlag = &cli.BoolFlag{
+		Name:     "gpo.congestion",
+		Usage:    "Raise the suggested gas prices on congestion of the transaction pool",
+		Category: flags.GasPriceCategory,
+	}
+	GpoMaxCongestionPremiumFlag = &cli.IntFlag{
+		Name:     "gpo.maxcongestionpremium",
+		Usage:    "Maximum percentage added to the suggested gas price on congestion (doubled for the fast suggestion)",
+		Value:    ethconfig.Defaults.GPO.MaxCongestionPremium,
+		Category: flags.GasPriceCategory,
+	}
 
 	// Metrics flags
 	MetricsEnabledFlag = &cli.BoolFlag{
@@ -1014,6 +1089,14 @@ Please note that --` + MetricsHTTPFlag.Name + ` must be set to start the server.
 		Name:  "internaltx.all",
 		Usage: "Trace and save all internal txs action, with input and output data. By default the node will only trace and save those with value greater then 0.",
 	}
+	InternalTxIndex = &cli.BoolFlag{
+		Name:  "internaltx.index",
+		Usage: "Index the internal txs with value greater then 0 by the addresses sending or receiving the value, to look them up by eth_getInternalTransactionsByAddress.",
+	}
+	InternalTxHistory = &cli.Uint64Flag{
+		Name:  "internaltx.history",
+		Usage: "Number of recent blocks to keep the internal txs of (default = keep all). Capped to the blocks not yet moved into the ancient store.",
+	}
 )
 
 var (
@@ -1600,6 +1683,12 @@ func setGPO(ctx *cli.Context, cfg *gasprice.Config, light bool) {
 	if ctx.IsSet(GpoIgnoreGasPriceFlag.Name) {
 		cfg.IgnorePrice = big.NewInt(ctx.Int64(GpoIgnoreGasPriceFlag.Name))
 	}
+	if ctx.IsSet(GpoCongestionFlag.Name) {
+		cfg.Congestion = ctx.Bool(GpoCongestionFlag.Name)
+	}
+	if ctx.IsSet(GpoMaxCongestionPremiumFlag.Name) {
+		cfg.MaxCongestionPremium = ctx.Int(GpoMaxCongestionPremiumFlag.Name)
+	}
 }
 
 func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
@@ -1643,6 +1732,27 @@ func setTxPool(ctx *cli.Context, cfg *txpool.Config) {
 	if ctx.IsSet(TxPoolLifetimeFlag.Name) {
 		cfg.Lifetime = ctx.Duration(TxPoolLifetimeFlag.Name)
 	}
+	if ctx.IsSet(TxPoolStrictExValidationFlag.Name) {
+		cfg.StrictExValidation = ctx.Bool(TxPoolStrictExValidationFlag.Name)
+	}
+	if ctx.IsSet(TxPoolCongestionPeriodFlag.Name) {
+		cfg.CongestionConfig.PeriodsSecs = ctx.Int(TxPoolCongestionPeriodFlag.Name)
+	}
+	if ctx.IsSet(TxPoolCongestionSecsFlag.Name) {
+		cfg.CongestionConfig.CongestionSecs = ctx.Int(TxPoolCongestionSecsFlag.Name)
+	}
+	if ctx.IsSet(TxPoolCongestionUnderPricedFactorFlag.Name) {
+		cfg.CongestionConfig.UnderPricedFactor = ctx.Int(TxPoolCongestionUnderPricedFactorFlag.Name)
+	}
+	if ctx.IsSet(TxPoolCongestionPendingFactorFlag.Name) {
+		cfg.CongestionConfig.PendingFactor = ctx.Int(TxPoolCongestionPendingFactorFlag.Name)
+	}
+	if ctx.IsSet(TxPoolCongestionMaxPendingSecsFlag.Name) {
+		cfg.CongestionConfig.MaxValidPendingSecs = ctx.Int(TxPoolCongestionMaxPendingSecsFlag.Name)
+	}
+	if ctx.IsSet(TxPoolCongestionHistoryFlag.Name) {
+		cfg.CongestionConfig.HistorySize = ctx.Int(TxPoolCongestionHistoryFlag.Name)
+	}
 }
 
 func setEthash(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1695,6 +1805,25 @@ func setMiner(ctx *cli.Context, cfg *miner.Config) {
 	if ctx.IsSet(MinerNewPayloadTimeout.Name) {
 		cfg.NewPayloadTimeout = ctx.Duration(MinerNewPayloadTimeout.Name)
 	}
+	if ctx.IsSet(MinerTxOrderingFlag.Name) {
+		cfg.TxOrdering = ctx.String(MinerTxOrderingFlag.Name)
+	}
+	if ctx.IsSet(MinerSenderTxCapFlag.Name) {
+		cfg.SenderTxCap = ctx.Int(MinerSenderTxCapFlag.Name)
+	}
+	if ctx.IsSet(MinerReservedGasFlag.Name) {
+		cfg.ReservedGas = ctx.Uint64(MinerReservedGasFlag.Name)
+	}
+	if ctx.IsSet(MinerPrioritySendersFlag.Name) {
+		senders := strings.Split(ctx.String(MinerPrioritySendersFlag.Name), ",")
+		for _, account := range senders {
+			if trimmed := strings.TrimSpace(account); !common.IsHexAddress(trimmed) {
+				Fatalf("Invalid account in --miner.prioritysenders: %s", trimmed)
+			} else {
+				cfg.PrioritySenders = append(cfg.PrioritySenders, common.HexToAddress(trimmed))
+			}
+		}
+	}
 }
 
 func setRequiredBlocks(ctx *cli.Context, cfg *ethconfig.Config) {
@@ -1785,6 +1914,12 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *ethconfig.Config) {
 	if ctx.IsSet(InternalTxTraceAll.Name) {
 		cfg.InternalTxTraceAll = ctx.Bool(InternalTxTraceAll.Name)
 	}
+	if ctx.IsSet(InternalTxIndex.Name) {
+		cfg.InternalTxIndex = ctx.Bool(InternalTxIndex.Name)
+	}
+	if ctx.IsSet(InternalTxHistory.Name) {
+		cfg.InternalTxHistory = ctx.Uint64(InternalTxHistory.Name)
+	}
 
 	setEtherbase(ctx, cfg)
 	setGPO(ctx, &cfg.GPO, ctx.String(SyncModeFlag.Name) == "light")
@@ -2336,7 +2471,11 @@ func MakeChain(ctx *cli.Context, stack *node.Node, readonly bool) (*core.BlockCh
 	if ctx.IsSet(CacheFlag.Name) || ctx.IsSet(CacheGCFlag.Name) {
 		cache.TrieDirtyLimit = ctx.Int(CacheFlag.Name) * ctx.Int(CacheGCFlag.Name) / 100
 	}
-	vmcfg := vm.Config{EnablePreimageRecording: ctx.Bool(VMEnableDebugFlag.Name)}
+	vmcfg := vm.Config{
+		EnablePreimageRecording: ctx.Bool(VMEnableDebugFlag.Name),
+		InternalTxTraceDisabled: ctx.Bool(InternalTxTraceDisabled.Name),
+		InternalTxTraceAll:      ctx.Bool(InternalTxTraceAll.Name),
+	}
 
 	// Disable transaction indexing/unindexing by default.
 	chain, err := core.NewBlockChain(chainDb, cache, gspec, nil, engine, vmcfg, nil, nil)
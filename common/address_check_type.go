@@ -5,6 +5,8 @@ const (
 	CheckFrom
 	CheckTo
 	CheckBothInAny
+	CheckCodeHash // the code hash of a called or deployed contract
+	CheckSelector // the function selector of a call to a contract
 )
 
 type AddressCheckType int
@@ -29,7 +29,7 @@ import (
 )
 
 var (
-	FeeRecoder = common.HexToAddress("0xffffffffffffffffffffffffffffffffffffffff")
+	FeeRecoder = params.NposFeeRecoder
 )
 
 // ChainHeaderReader defines a small collection of methods needed to access the local
@@ -150,8 +150,23 @@ type PoSA interface {
 	// ValidateTx do a consensus-related validation on the given transaction at the given header and state.
 	ValidateTx(sender common.Address, tx *types.Transaction, header *types.Header, parentState *state.StateDB) error
 
-	// CreateEvmExtraValidator returns a EvmExtraValidator if necessary.
-	CreateEvmExtraValidator(header *types.Header, parentState *state.StateDB) types.EvmExtraValidator
+	// FinalizeWithInternalTxs runs Finalize, and returns the internal txs of the
+	// value transfers and calls made by the engine, e.g. to distribute the block
+	// rewards. The actions made by the engine have the depth
+	// types.EngineActionDepth, the ones made out of any transaction belong to
+	// internal txs with an empty tx hash.
+	FinalizeWithInternalTxs(chain ChainHeaderReader, header *types.Header, state *state.StateDB, txs *[]*types.Transaction,
+		uncles []*types.Header, receipts *[]*types.Receipt, systemTxs []*types.Transaction, traceAll bool) (types.InternalTxs, error)
+
+	// FeeBreakdown returns the accounting of the fees of the block last finalized
+	// with the state root of the header, nil if it wasn't measured. It's stored
+	// with the block when the block is written.
+	FeeBreakdown(header *types.Header) *types.FeeBreakdown
+
+	// CreateEvmExtraValidator returns a EvmExtraValidator if necessary. It fails
+	// if the rules can't be read from the parent state, the block can't be
+	// processed then.
+	CreateEvmExtraValidator(header *types.Header, parentState *state.StateDB) (types.EvmExtraValidator, error)
 
 	//Methods for debug trace
 
@@ -160,6 +175,16 @@ type PoSA interface {
 	ApplySysTx(evm *vm.EVM, state *state.StateDB, txIndex int, sender common.Address, tx *types.Transaction) (ret []byte, vmerr error, err error)
 }
 
+// FinalityEngine is a consensus engine which is able to finalize blocks deterministically,
+// the blocks below the finalized one can never be reorged.
+type FinalityEngine interface {
+	Engine
+
+	// GetFinalizedHeader returns the latest finalized header on the chain of the given header,
+	// or nil if there isn't any.
+	GetFinalizedHeader(chain ChainHeaderReader, header *types.Header) *types.Header
+}
+
 type StateReader interface {
 	GetState(addr common.Address, hash common.Hash) common.Hash
 }
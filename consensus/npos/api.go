@@ -1,27 +1,22 @@
-// Copyright 2017 The go-ethereum Authors
-// This file is part of the go-ethereum library.
-//
-// The go-ethereum library is free software: you can redistribute it and/or modify
-// it under the terms of the GNU Lesser General Public License as published by
-// the Free Software Foundation, either version 3 of the License, or
-// (at your option) any later version.
-//
-// The go-ethereum library is distributed in the hope that it will be useful,
-// but WITHOUT ANY WARRANTY; without even the implied warranty of
-// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
-// GNU Lesser General Public License for more details.
-//
-// You should have received a copy of the GNU Lesser General Public License
-// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
-
 package npos
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -90,23 +85,37 @@ func (api *API) GetValidatorsAtHash(hash common.Hash) ([]common.Address, error)
 	return snap.validators(), nil
 }
 
+const (
+	defaultStatusBlocks = 64     // Number of blocks covered by Status if not specified
+	maxReportBlocks     = 100000 // Maximum number of blocks walked by a single status or performance request
+)
+
 type status struct {
 	InturnPercent float64                `json:"inturnPercent"`
 	SigningStatus map[common.Address]int `json:"sealerActivity"`
 	NumBlocks     uint64                 `json:"numBlocks"`
 }
 
-// Status returns the status of the last N blocks,
+// Status returns the status of the last N blocks (64 if not specified),
 // - the number of active validators,
 // - the number of validators,
 // - the percentage of in-turn blocks
-func (api *API) Status() (*status, error) {
+func (api *API) Status(blocks *hexutil.Uint64) (*status, error) {
 	var (
-		numBlocks = uint64(64)
+		numBlocks = uint64(defaultStatusBlocks)
 		header    = api.chain.CurrentHeader()
 		diff      = uint64(0)
 		optimals  = 0
 	)
+	if blocks != nil {
+		numBlocks = uint64(*blocks)
+	}
+	if numBlocks == 0 || numBlocks > maxReportBlocks {
+		return nil, fmt.Errorf("invalid number of blocks %d, must be in [1, %d]", numBlocks, maxReportBlocks)
+	}
+	if header.Number.Uint64() == 0 {
+		return nil, errUnknownBlock
+	}
 	snap, err := api.npos.snapshot(api.chain, header.Number.Uint64(), header.Hash(), nil)
 	if err != nil {
 		return nil, err
@@ -145,3 +154,746 @@ func (api *API) Status() (*status, error) {
 		NumBlocks:     numBlocks,
 	}, nil
 }
+
+// votePoolStateJail is the state of a vote pool whose validator is jailed.
+const votePoolStateJail = 3
+
+// errUnknownValidator is returned if the staking state of an address which never
+// registered as validator is requested.
+var errUnknownValidator = errors.New("unknown validator")
+
+// VotePool is the staking state of a validator, kept in its vote pool contract.
+type VotePool struct {
+	Address     common.Address `json:"address"`
+	Validator   common.Address `json:"validator"`
+	Manager     common.Address `json:"manager"`
+	Type        uint8          `json:"type"`
+	State       uint8          `json:"state"`
+	Margin      *hexutil.Big   `json:"margin"`
+	TotalVote   *hexutil.Big   `json:"totalVote"`
+	Percent     *hexutil.Big   `json:"percent"`
+	PunishBlock *hexutil.Big   `json:"punishBlock"`
+	ExitBlock   *hexutil.Big   `json:"exitBlock"`
+}
+
+// JailStatus tells whether a validator is jailed, and until which block.
+type JailStatus struct {
+	Jailed       bool         `json:"jailed"`
+	PunishBlock  *hexutil.Big `json:"punishBlock"`
+	ReleaseBlock *hexutil.Big `json:"releaseBlock,omitempty"`
+}
+
+// ValidatorInfo gathers the staking, reward and punishment state of a validator.
+type ValidatorInfo struct {
+	Active        bool           `json:"active"`
+	Backup        bool           `json:"backup"`
+	VotePool      *VotePool      `json:"votePool"`
+	PendingReward *hexutil.Big   `json:"pendingReward"`
+	MissedBlocks  hexutil.Uint64 `json:"missedBlocks"`
+	Jail          *JailStatus    `json:"jail"`
+}
+
+// headerAtBlock returns the header of the requested block, the current block if
+// none is requested.
+func (api *API) headerAtBlock(number *rpc.BlockNumber) (*types.Header, error) {
+	var header *types.Header
+	switch {
+	case number == nil || *number == rpc.LatestBlockNumber || *number == rpc.PendingBlockNumber:
+		header = api.chain.CurrentHeader()
+	case *number == rpc.FinalizedBlockNumber || *number == rpc.SafeBlockNumber:
+		header = api.npos.GetFinalizedHeader(api.chain, api.chain.CurrentHeader())
+	case *number >= 0:
+		header = api.chain.GetHeaderByNumber(uint64(number.Int64()))
+	}
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return header, nil
+}
+
+// stateAtBlock returns the header and state of the requested block, the current
+// block if none is requested.
+func (api *API) stateAtBlock(number *rpc.BlockNumber) (*types.Header, *state.StateDB, error) {
+	header, err := api.headerAtBlock(number)
+	if err != nil {
+		return nil, nil, err
+	}
+	statedb, err := api.npos.stateFn(header.Root)
+	if err != nil {
+		return nil, nil, err
+	}
+	return header, statedb, nil
+}
+
+func (api *API) callValidators(header *types.Header, statedb *state.StateDB, method string, args ...interface{}) (interface{}, error) {
+	ret, err := api.npos.commonCallContract(header, statedb, api.npos.abi[systemcontract.ValidatorsContractName], systemcontract.ValidatorsContractAddr, method, 1, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ret[0], nil
+}
+
+func (api *API) callVotePool(header *types.Header, statedb *state.StateDB, pool common.Address, method string, args ...interface{}) (interface{}, error) {
+	ret, err := api.npos.commonCallContract(header, statedb, api.npos.abi[systemcontract.VotePoolContractName], pool, method, 1, args...)
+	if err != nil {
+		return nil, err
+	}
+	return ret[0], nil
+}
+
+// votePoolAddress returns the address of the vote pool contract of a validator.
+func (api *API) votePoolAddress(header *types.Header, statedb *state.StateDB, validator common.Address) (common.Address, error) {
+	ret, err := api.callValidators(header, statedb, "votePools", validator)
+	if err != nil {
+		return common.Address{}, err
+	}
+	pool := ret.(common.Address)
+	if pool == (common.Address{}) {
+		return common.Address{}, errUnknownValidator
+	}
+	return pool, nil
+}
+
+func (api *API) votePool(header *types.Header, statedb *state.StateDB, validator common.Address) (*VotePool, error) {
+	addr, err := api.votePoolAddress(header, statedb, validator)
+	if err != nil {
+		return nil, err
+	}
+	pool := &VotePool{Address: addr, Validator: validator}
+	fields := []struct {
+		method string
+		set    func(interface{})
+	}{
+		{"manager", func(v interface{}) { pool.Manager = v.(common.Address) }},
+		{"validatorType", func(v interface{}) { pool.Type = v.(uint8) }},
+		{"state", func(v interface{}) { pool.State = v.(uint8) }},
+		{"margin", func(v interface{}) { pool.Margin = (*hexutil.Big)(v.(*big.Int)) }},
+		{"totalVote", func(v interface{}) { pool.TotalVote = (*hexutil.Big)(v.(*big.Int)) }},
+		{"percent", func(v interface{}) { pool.Percent = (*hexutil.Big)(v.(*big.Int)) }},
+		{"punishBlk", func(v interface{}) { pool.PunishBlock = (*hexutil.Big)(v.(*big.Int)) }},
+		{"exitBlk", func(v interface{}) { pool.ExitBlock = (*hexutil.Big)(v.(*big.Int)) }},
+	}
+	for _, field := range fields {
+		ret, err := api.callVotePool(header, statedb, addr, field.method)
+		if err != nil {
+			return nil, err
+		}
+		field.set(ret)
+	}
+	return pool, nil
+}
+
+func (api *API) pendingReward(header *types.Header, statedb *state.StateDB, validator common.Address, voter *common.Address) (*hexutil.Big, error) {
+	pool, err := api.votePoolAddress(header, statedb, validator)
+	if err != nil {
+		return nil, err
+	}
+	var ret interface{}
+	if voter == nil {
+		ret, err = api.callVotePool(header, statedb, pool, "getValidatorPendingReward")
+	} else {
+		ret, err = api.callVotePool(header, statedb, pool, "getPendingReward", *voter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return (*hexutil.Big)(ret.(*big.Int)), nil
+}
+
+func (api *API) missedBlocks(header *types.Header, statedb *state.StateDB, validator common.Address) (hexutil.Uint64, error) {
+	ret, err := api.npos.commonCallContract(header, statedb, api.npos.abi[systemcontract.PunishContractName], systemcontract.PunishContractAddr, "getPunishRecord", 1, validator)
+	if err != nil {
+		return 0, err
+	}
+	return hexutil.Uint64(ret[0].(*big.Int).Uint64()), nil
+}
+
+func (api *API) jailStatus(header *types.Header, statedb *state.StateDB, pool *VotePool) (*JailStatus, error) {
+	status := &JailStatus{
+		Jailed:      pool.State == votePoolStateJail,
+		PunishBlock: pool.PunishBlock,
+	}
+	if status.Jailed {
+		ret, err := api.callVotePool(header, statedb, pool.Address, "JailPeriod")
+		if err != nil {
+			return nil, err
+		}
+		status.ReleaseBlock = (*hexutil.Big)(new(big.Int).Add(pool.PunishBlock.ToInt(), ret.(*big.Int)))
+	}
+	return status, nil
+}
+
+// GetValidatorInfo returns the staking, reward and punishment state of a validator
+// at the specified block.
+func (api *API) GetValidatorInfo(validator common.Address, number *rpc.BlockNumber) (*ValidatorInfo, error) {
+	header, statedb, err := api.stateAtBlock(number)
+	if err != nil {
+		return nil, err
+	}
+	info := new(ValidatorInfo)
+	if info.VotePool, err = api.votePool(header, statedb, validator); err != nil {
+		return nil, err
+	}
+	for _, set := range []struct {
+		method string
+		member *bool
+	}{{"getActiveValidators", &info.Active}, {"getBackupValidators", &info.Backup}} {
+		ret, err := api.callValidators(header, statedb, set.method)
+		if err != nil {
+			return nil, err
+		}
+		for _, addr := range ret.([]common.Address) {
+			if addr == validator {
+				*set.member = true
+			}
+		}
+	}
+	if info.PendingReward, err = api.pendingReward(header, statedb, validator, nil); err != nil {
+		return nil, err
+	}
+	if info.MissedBlocks, err = api.missedBlocks(header, statedb, validator); err != nil {
+		return nil, err
+	}
+	if info.Jail, err = api.jailStatus(header, statedb, info.VotePool); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+// GetPendingReward returns the unclaimed reward of a voter of the validator at the
+// specified block, or the unclaimed commission of the validator itself if no voter
+// is given.
+func (api *API) GetPendingReward(validator common.Address, voter *common.Address, number *rpc.BlockNumber) (*hexutil.Big, error) {
+	header, statedb, err := api.stateAtBlock(number)
+	if err != nil {
+		return nil, err
+	}
+	return api.pendingReward(header, statedb, validator, voter)
+}
+
+// GetBackupValidators returns the backup validators at the specified block.
+func (api *API) GetBackupValidators(number *rpc.BlockNumber) ([]common.Address, error) {
+	header, statedb, err := api.stateAtBlock(number)
+	if err != nil {
+		return nil, err
+	}
+	ret, err := api.callValidators(header, statedb, "getBackupValidators")
+	if err != nil {
+		return nil, err
+	}
+	return ret.([]common.Address), nil
+}
+
+// GetMissedBlocks returns the number of blocks missed by a validator, as counted
+// by the punish contract at the specified block.
+func (api *API) GetMissedBlocks(validator common.Address, number *rpc.BlockNumber) (hexutil.Uint64, error) {
+	header, statedb, err := api.stateAtBlock(number)
+	if err != nil {
+		return 0, err
+	}
+	return api.missedBlocks(header, statedb, validator)
+}
+
+// GetJailStatus returns whether a validator is jailed at the specified block.
+func (api *API) GetJailStatus(validator common.Address, number *rpc.BlockNumber) (*JailStatus, error) {
+	header, statedb, err := api.stateAtBlock(number)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := api.votePool(header, statedb, validator)
+	if err != nil {
+		return nil, err
+	}
+	return api.jailStatus(header, statedb, pool)
+}
+
+// GetVotePool returns the vote pool state of a validator at the specified block.
+func (api *API) GetVotePool(validator common.Address, number *rpc.BlockNumber) (*VotePool, error) {
+	header, statedb, err := api.stateAtBlock(number)
+	if err != nil {
+		return nil, err
+	}
+	return api.votePool(header, statedb, validator)
+}
+
+// BlockRewardBreakdown is the accounting of the fees paid in a block: the base
+// fees burned from the fee burn fork on, and the shares the Validators contract
+// splits the rest in as the block reward.
+type BlockRewardBreakdown struct {
+	Fees          *hexutil.Big `json:"fees"`          // Fees paid by the transactions of the block
+	BaseFeeBurned *hexutil.Big `json:"baseFeeBurned"` // Base fees burned by the transactions
+	Reward        *hexutil.Big `json:"reward"`        // Fees collected as the block reward
+	Burned        *hexutil.Big `json:"burned"`        // Share of the reward burned by the Validators contract
+	Foundation    *hexutil.Big `json:"foundation"`    // Share of the reward credited to the foundation
+	Distributed   *hexutil.Big `json:"distributed"`   // Share of the reward distributed to the validators
+}
+
+// GetBlockRewardBreakdown returns the accounting of the fees paid in the specified
+// block, the current block if none is given. The breakdown is recorded when the
+// node imports or seals the block, so it is missing for the blocks it synced
+// without executing them.
+func (api *API) GetBlockRewardBreakdown(number *rpc.BlockNumber) (*BlockRewardBreakdown, error) {
+	header, err := api.headerAtBlock(number)
+	if err != nil {
+		return nil, err
+	}
+	fees := &api.npos.newFeeBreakdown(header, nil).FeeBreakdown
+	if header.TxHash != types.EmptyTxsHash {
+		if fees = rawdb.ReadFeeBreakdown(api.npos.db, header.Hash(), header.Number.Uint64()); fees == nil {
+			return nil, errMissingFeeBreakdown
+		}
+	}
+	return &BlockRewardBreakdown{
+		Fees:          (*hexutil.Big)(new(big.Int).Add(fees.BaseFeeBurned, fees.Reward)),
+		BaseFeeBurned: (*hexutil.Big)(fees.BaseFeeBurned),
+		Reward:        (*hexutil.Big)(fees.Reward),
+		Burned:        (*hexutil.Big)(fees.Burned),
+		Foundation:    (*hexutil.Big)(fees.Foundation),
+		Distributed:   (*hexutil.Big)(fees.Distributed),
+	}, nil
+}
+
+// ValidatorPerformance is the sealing record of a validator over a range of blocks.
+type ValidatorPerformance struct {
+	InTurnBlocks          hexutil.Uint64 `json:"inTurnBlocks"`          // Blocks sealed in turn
+	OutOfTurnBlocks       hexutil.Uint64 `json:"outOfTurnBlocks"`       // Blocks sealed out of turn
+	MissedBlocks          hexutil.Uint64 `json:"missedBlocks"`          // In-turn slots sealed by another validator
+	Punishments           hexutil.Uint64 `json:"punishments"`           // Missed blocks the validator was punished for
+	DoubleSignPunishments hexutil.Uint64 `json:"doubleSignPunishments"` // Double-sign evidences included against the validator
+	SealedBlockFees       *hexutil.Big   `json:"sealedBlockFees"`       // Fees collected in the sealed blocks, which the validators contract shares among all the validators
+}
+
+// PerformanceReport is the per-validator sealing record over a range of blocks.
+type PerformanceReport struct {
+	From          hexutil.Uint64                           `json:"from"`
+	To            hexutil.Uint64                           `json:"to"`
+	InturnPercent float64                                  `json:"inturnPercent"`
+	Validators    map[common.Address]*ValidatorPerformance `json:"validators"`
+}
+
+// blockReader is implemented by chains able to serve block bodies and receipts,
+// which the performance report needs for fees and double-sign evidences.
+type blockReader interface {
+	GetBlock(hash common.Hash, number uint64) *types.Block
+	GetReceiptsByHash(hash common.Hash) types.Receipts
+}
+
+// GetPerformance returns the sealing record of every validator between the two
+// blocks, inclusive. The end block defaults to the current block, an end block
+// past it is an error.
+//
+// The punish contract doesn't log into receipts, so the missed blocks and their
+// punishments are replayed against the validator snapshots rather than read
+// from the chain: a punishment is counted whenever an out-of-turn block marks
+// the in-turn validator as punished, the way the engine decides to call the
+// contract. A punish call that failed in the contract is still counted.
+func (api *API) GetPerformance(from rpc.BlockNumber, to *rpc.BlockNumber) (*PerformanceReport, error) {
+	end := api.chain.CurrentHeader().Number.Uint64()
+	if to != nil && *to >= 0 {
+		if uint64(*to) > end {
+			return nil, fmt.Errorf("end block %d past the current block %d", *to, end)
+		}
+		end = uint64(*to)
+	}
+	if from < 0 {
+		return nil, errUnknownBlock
+	}
+	return api.performance(uint64(from), end)
+}
+
+// GetEpochPerformance returns the sealing record of every validator from the
+// first to the last epoch, inclusive. The last epoch defaults to the first one.
+// Epoch n spans the blocks sealed by one validator set, (n*epoch, (n+1)*epoch].
+func (api *API) GetEpochPerformance(first hexutil.Uint64, last *hexutil.Uint64) (*PerformanceReport, error) {
+	if last == nil {
+		last = &first
+	}
+	if *last < first {
+		return nil, fmt.Errorf("last epoch %d before first epoch %d", *last, first)
+	}
+	epoch := api.npos.config.Epoch
+	return api.performance(uint64(first)*epoch+1, (uint64(*last)+1)*epoch)
+}
+
+func (api *API) performance(from, to uint64) (*PerformanceReport, error) {
+	if from == 0 {
+		from = 1
+	}
+	if from > to || to > api.chain.CurrentHeader().Number.Uint64() {
+		return nil, errUnknownBlock
+	}
+	if to-from+1 > maxReportBlocks {
+		return nil, fmt.Errorf("too many blocks requested, max %d", maxReportBlocks)
+	}
+	parent := api.chain.GetHeaderByNumber(from - 1)
+	if parent == nil {
+		return nil, errUnknownBlock
+	}
+	snap, err := api.npos.snapshot(api.chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	report := &PerformanceReport{
+		From:       hexutil.Uint64(from),
+		To:         hexutil.Uint64(to),
+		Validators: make(map[common.Address]*ValidatorPerformance),
+	}
+	record := func(validator common.Address) *ValidatorPerformance {
+		if report.Validators[validator] == nil {
+			report.Validators[validator] = &ValidatorPerformance{SealedBlockFees: new(hexutil.Big)}
+		}
+		return report.Validators[validator]
+	}
+	reader, _ := api.chain.(blockReader)
+	inturns := 0
+	for number := from; number <= to; number++ {
+		header := api.chain.GetHeaderByNumber(number)
+		if header == nil {
+			return nil, fmt.Errorf("missing block %d", number)
+		}
+		signer, err := api.npos.Author(header)
+		if err != nil {
+			return nil, err
+		}
+		perf := record(signer)
+		if header.Difficulty.Cmp(diffInTurn) == 0 {
+			perf.InTurnBlocks++
+			inturns++
+		} else {
+			perf.OutOfTurnBlocks++
+			// The punish contract doesn't log into receipts, so replay the
+			// punishment against the snapshot.
+			inturn, punished := snap.missedInTurn(number)
+			missed := record(inturn)
+			missed.MissedBlocks++
+			if punished {
+				missed.Punishments++
+			}
+		}
+		if reader != nil {
+			if err := api.blockFeesAndEvidences(reader, header, perf, record); err != nil {
+				return nil, err
+			}
+		}
+		if snap, err = snap.apply([]*types.Header{header}, api.chain, nil); err != nil {
+			return nil, err
+		}
+	}
+	report.InturnPercent = float64(100*inturns) / float64(to-from+1)
+	return report, nil
+}
+
+// blockFeesAndEvidences adds the fees collected in a block to the ones of its sealer,
+// and counts the double-sign evidences it includes against their offenders. The sealer
+// doesn't earn these fees, they are collected by the FeeRecoder and distributed by
+// the validators contract. From the fee burn fork on, only the tips are collected.
+func (api *API) blockFeesAndEvidences(reader blockReader, header *types.Header, sealer *ValidatorPerformance, record func(common.Address) *ValidatorPerformance) error {
+	block := reader.GetBlock(header.Hash(), header.Number.Uint64())
+	if block == nil {
+		return fmt.Errorf("missing block body %d", header.Number)
+	}
+	receipts := reader.GetReceiptsByHash(header.Hash())
+	if len(receipts) != len(block.Transactions()) {
+		return fmt.Errorf("missing receipts of block %d", header.Number)
+	}
+	fees := sealer.SealedBlockFees.ToInt()
+	burned := api.npos.config.IsFeeBurn(header.Number) && header.BaseFee != nil
+	for i, tx := range block.Transactions() {
+		receipt := receipts[i]
+		if price := receipt.EffectiveGasPrice; price != nil {
+			// system transactions are free, they pay no tip below the base fee
+			if burned {
+				if price = new(big.Int).Sub(price, header.BaseFee); price.Sign() < 0 {
+					continue
+				}
+			}
+			fees.Add(fees, new(big.Int).Mul(new(big.Int).SetUint64(receipt.GasUsed), price))
+		}
+		if tx.To() == nil || *tx.To() != systemcontract.DoubleSignEvidenceToAddr || receipt.Status != types.ReceiptStatusSuccessful {
+			continue
+		}
+		sender, err := types.Sender(api.npos.signer, tx)
+		if err != nil {
+			return err
+		}
+		if isSys, _ := api.npos.IsSysTransaction(sender, tx, header); !isSys {
+			continue
+		}
+		ev := new(DoubleSignEvidence)
+		if err := rlp.DecodeBytes(tx.Data(), ev); err != nil {
+			continue
+		}
+		if offender, err := recoverEvidence(ev, api.npos.signatures); err == nil {
+			record(offender).DoubleSignPunishments++
+		}
+	}
+	return nil
+}
+
+// ProposalArgs selects a proposal of the governance contract by id, or describes
+// a raw proposal if an action is given.
+type ProposalArgs struct {
+	Id     *hexutil.Big   `json:"id"`
+	Action *hexutil.Big   `json:"action"`
+	From   common.Address `json:"from"`
+	To     common.Address `json:"to"`
+	Value  *hexutil.Big   `json:"value"`
+	Data   hexutil.Bytes  `json:"data"`
+}
+
+// SimulateProposal executes a governance proposal on a copy of the latest state,
+// the same way it would be executed once passed, and returns its state diff,
+// logs and revert reason.
+func (api *API) SimulateProposal(args ProposalArgs) (*ProposalSimulation, error) {
+	header, statedb, err := api.stateAtBlock(nil)
+	if err != nil {
+		return nil, err
+	}
+	var prop *Proposal
+	if args.Action == nil {
+		if args.Id == nil {
+			return nil, errors.New("missing proposal id or action")
+		}
+		ctx := &systemcontract.CallContext{
+			Statedb:      statedb.Copy(),
+			Header:       header,
+			ChainContext: newChainContext(api.chain, api.npos),
+			ChainConfig:  api.npos.chainConfig,
+		}
+		if prop, err = api.npos.getProposalById(ctx, args.Id.ToInt()); err != nil {
+			return nil, err
+		}
+	} else {
+		prop = &Proposal{
+			Id:     new(big.Int),
+			Action: args.Action.ToInt(),
+			From:   args.From,
+			To:     args.To,
+			Value:  new(big.Int),
+			Data:   args.Data,
+		}
+		if args.Id != nil {
+			prop.Id = args.Id.ToInt()
+		}
+		if args.Value != nil {
+			prop.Value = args.Value.ToInt()
+		}
+	}
+	return api.npos.simulateProposal(api.chain, header, statedb, prop)
+}
+
+// blacklistAt returns the blacklist in effect after the given block.
+func (api *API) blacklistAt(number *rpc.BlockNumber) (map[common.Address]BannedDirection, error) {
+	header, statedb, err := api.stateAtBlock(number)
+	if err != nil {
+		return nil, err
+	}
+	// the blacklist is looked up for the transactions of the child block
+	child := &types.Header{
+		ParentHash: header.Hash(),
+		Number:     new(big.Int).Add(header.Number, common.Big1),
+		Time:       header.Time,
+		GasLimit:   header.GasLimit,
+		Difficulty: new(big.Int).Set(diffNoTurn),
+		BaseFee:    header.BaseFee,
+	}
+	// look up the index directly, a past state must not go through the caches
+	// of the block processing
+	return api.npos.lookupBlacklist(child, statedb)
+}
+
+// GetBlacklist returns the banned addresses and their directions after the given block.
+func (api *API) GetBlacklist(number *rpc.BlockNumber) (map[common.Address]BannedDirection, error) {
+	return api.blacklistAt(number)
+}
+
+// RebuildAddressListIndex indexes the blacklist and the event check rules in
+// effect after every canonical block between the two blocks, inclusive, in the
+// background. The end block defaults to the current block.
+func (api *API) RebuildAddressListIndex(from rpc.BlockNumber, to *rpc.BlockNumber) error {
+	end := api.chain.CurrentHeader().Number.Uint64()
+	if to != nil && *to >= 0 && uint64(*to) < end {
+		end = uint64(*to)
+	}
+	if from < 0 || uint64(from) > end {
+		return errUnknownBlock
+	}
+	return api.npos.RebuildAddressListIndex(uint64(from), end)
+}
+
+// BanStatus is the blacklist state of an address.
+type BanStatus struct {
+	Banned    bool             `json:"banned"`
+	Direction *BannedDirection `json:"direction,omitempty"`
+}
+
+// IsAddressBanned returns whether the address is on the blacklist after the given
+// block, and in which direction.
+func (api *API) IsAddressBanned(address common.Address, number *rpc.BlockNumber) (*BanStatus, error) {
+	blacklist, err := api.blacklistAt(number)
+	if err != nil {
+		return nil, err
+	}
+	d, banned := blacklist[address]
+	if !banned {
+		return &BanStatus{}, nil
+	}
+	return &BanStatus{Banned: true, Direction: &d}, nil
+}
+
+// BlacklistEvent is an update of the blacklist by the address list contract.
+type BlacklistEvent struct {
+	Address     common.Address  `json:"address"`
+	Direction   BannedDirection `json:"direction"`
+	Added       bool            `json:"added"` // Whether the address was added, or removed
+	BlockNumber hexutil.Uint64  `json:"blockNumber"`
+	BlockHash   common.Hash     `json:"blockHash"`
+	TxHash      common.Hash     `json:"txHash"`
+
+	// Removed is true if the block of the event was reorganised out of the chain,
+	// so the update must be reverted.
+	Removed bool `json:"removed,omitempty"`
+}
+
+// maxBlacklistCatchUp is the maximum number of blocks the blacklist subscription
+// looks back for events when several blocks were imported at once, or reorganised.
+const maxBlacklistCatchUp = 1024
+
+// chainHeadSubscriber is implemented by chains notifying the new heads, which
+// the blacklist subscription needs.
+type chainHeadSubscriber interface {
+	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
+}
+
+// BlacklistEvents subscribes to the blacklist updates of the new canonical blocks.
+// The updates of the blocks reorganised out of the chain are sent again, flagged
+// as removed, in reverse order.
+func (api *API) BlacklistEvents(ctx context.Context) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	subscriber, ok := api.chain.(chainHeadSubscriber)
+	if !ok {
+		return nil, errors.New("chain head events not available")
+	}
+	reader, ok := api.chain.(blockReader)
+	if !ok {
+		return nil, errors.New("receipts not available")
+	}
+	rpcSub := notifier.CreateSubscription()
+
+	heads := make(chan core.ChainHeadEvent, 16)
+	headSub := subscriber.SubscribeChainHeadEvent(heads)
+	last := api.chain.CurrentHeader()
+	go func() {
+		defer headSub.Unsubscribe()
+		for {
+			select {
+			case ev := <-heads:
+				head := ev.Block.Header()
+				for _, update := range api.blacklistUpdates(reader, last, head) {
+					notifier.Notify(rpcSub.ID, update)
+				}
+				last = head
+			case <-rpcSub.Err():
+				return
+			case <-notifier.Closed():
+				return
+			case <-headSub.Err():
+				return
+			}
+		}
+	}()
+	return rpcSub, nil
+}
+
+// blacklistUpdates returns the blacklist events from the old head to the new one:
+// the events of the old blocks down to the common ancestor flagged as removed, in
+// reverse order, then the events of the new blocks. Only the events of the new
+// head are returned if the ancestor is too far.
+func (api *API) blacklistUpdates(reader blockReader, oldHead, newHead *types.Header) []*BlacklistEvent {
+	var (
+		head           = newHead
+		dropped, added []*types.Header
+	)
+	for oldHead != nil && newHead != nil && oldHead.Hash() != newHead.Hash() {
+		if len(dropped)+len(added) >= maxBlacklistCatchUp {
+			oldHead = nil
+			break
+		}
+		oldNumber, newNumber := oldHead.Number.Uint64(), newHead.Number.Uint64()
+		if oldNumber >= newNumber {
+			dropped = append(dropped, oldHead)
+			oldHead = api.chain.GetHeader(oldHead.ParentHash, oldNumber-1)
+		}
+		if newNumber >= oldNumber {
+			added = append(added, newHead)
+			newHead = api.chain.GetHeader(newHead.ParentHash, newNumber-1)
+		}
+	}
+	if oldHead == nil || newHead == nil {
+		// no common ancestor in reach
+		dropped, added = nil, []*types.Header{head}
+	}
+	var updates []*BlacklistEvent
+	for _, header := range dropped {
+		block := reader.GetBlock(header.Hash(), header.Number.Uint64())
+		if block == nil {
+			continue
+		}
+		events := api.npos.blacklistEvents(block, reader.GetReceiptsByHash(block.Hash()))
+		for i := len(events) - 1; i >= 0; i-- {
+			events[i].Removed = true
+			updates = append(updates, events[i])
+		}
+	}
+	for i := len(added) - 1; i >= 0; i-- {
+		block := reader.GetBlock(added[i].Hash(), added[i].Number.Uint64())
+		if block == nil {
+			continue
+		}
+		updates = append(updates, api.npos.blacklistEvents(block, reader.GetReceiptsByHash(block.Hash()))...)
+	}
+	return updates
+}
+
+// blacklistEvents decodes the blacklist updates from the logs of a block.
+func (c *Npos) blacklistEvents(block *types.Block, receipts types.Receipts) []*BlacklistEvent {
+	alABI := c.abi[systemcontract.AddressListContractName]
+	added, removed := alABI.Events["BlackAddrAdded"], alABI.Events["BlackAddrRemoved"]
+
+	var updates []*BlacklistEvent
+	for _, receipt := range receipts {
+		for _, l := range receipt.Logs {
+			if l.Address != systemcontract.AddressListContractAddr || len(l.Topics) != 2 {
+				continue
+			}
+			if l.Topics[0] != added.ID && l.Topics[0] != removed.ID {
+				continue
+			}
+			ev := added
+			if l.Topics[0] == removed.ID {
+				ev = removed
+			}
+			ret, err := alABI.Unpack(ev.Name, l.Data)
+			if err != nil || len(ret) != 1 {
+				log.Warn("Invalid blacklist event", "block", block.Number(), "tx", l.TxHash, "err", err)
+				continue
+			}
+			d, ok := ret[0].(uint8)
+			if !ok {
+				continue
+			}
+			updates = append(updates, &BlacklistEvent{
+				Address:     common.BytesToAddress(l.Topics[1].Bytes()),
+				Direction:   BannedDirection(d),
+				Added:       l.Topics[0] == added.ID,
+				BlockNumber: hexutil.Uint64(block.NumberU64()),
+				BlockHash:   block.Hash(),
+				TxHash:      l.TxHash,
+			})
+		}
+	}
+	return updates
+}
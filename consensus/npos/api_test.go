@@ -0,0 +1,438 @@
+package npos
+
+import (
+	"context"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIStaking(t *testing.T) {
+	tc := newTestChain(t)
+	api := &API{chain: tc.chain, npos: tc.engine}
+	// the active validators are set at the first checkpoint
+	tc.extend(int(tc.config.Npos.Epoch)+2, nil)
+
+	// let the next block be signed out of turn, so the in-turn validator misses it
+	validators := tc.snapshot().validators()
+	number := tc.chain.CurrentBlock().Number.Uint64() + 1
+	missed := validators[number%uint64(len(validators))]
+	tc.signers[number] = validators[(number+1)%uint64(len(validators))]
+	tc.extend(1, nil)
+
+	info, err := api.GetValidatorInfo(missed, nil)
+	require.NoError(t, err)
+	require.True(t, info.Active)
+	require.False(t, info.Backup)
+	require.Equal(t, missed, info.VotePool.Validator)
+	require.Equal(t, common.HexToAddress("0x9E737Ee8bDc132c349dE7801Efbc9e12f4FE99e9"), info.VotePool.Manager)
+	require.Equal(t, uint64(1), uint64(info.MissedBlocks))
+	require.False(t, info.Jail.Jailed)
+
+	// state at earlier blocks is available too
+	before := rpc.BlockNumber(number - 1)
+	missedBefore, err := api.GetMissedBlocks(missed, &before)
+	require.NoError(t, err)
+	require.Zero(t, missedBefore)
+
+	pool, err := api.GetVotePool(missed, nil)
+	require.NoError(t, err)
+	require.Equal(t, info.VotePool, pool)
+
+	backups, err := api.GetBackupValidators(nil)
+	require.NoError(t, err)
+	require.Empty(t, backups)
+
+	_, err = api.GetPendingReward(missed, &testAdmin, nil)
+	require.NoError(t, err)
+
+	_, err = api.GetJailStatus(common.Address{0x1}, nil)
+	require.ErrorIs(t, err, errUnknownValidator)
+	unknown := rpc.BlockNumber(100)
+	_, err = api.GetVotePool(missed, &unknown)
+	require.ErrorIs(t, err, errUnknownBlock)
+}
+
+func TestAPIPerformance(t *testing.T) {
+	tc := newTestChain(t, func(config *params.NposConfig) {
+		config.FeeBurnBlock = big.NewInt(2)
+	})
+	api := &API{chain: tc.chain, npos: tc.engine}
+
+	to := common.Address{0xaa}
+	blocks := tc.extend(2, func(i int, b *core.BlockGen) {
+		tx, err := types.SignNewTx(testAdminKey, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+			Nonce:    b.TxNonce(testAdmin),
+			To:       &to,
+			Gas:      params.TxGas,
+			GasPrice: new(big.Int).Mul(b.BaseFee(), big.NewInt(2)),
+		})
+		require.NoError(t, err)
+		b.AddTx(tx)
+	})
+	// skip the in-turn validator of block 3, its successor is then out of turn too
+	validators := tc.snapshot().validators()
+	tc.signers[3] = validators[4%len(validators)]
+	blocks = append(blocks, tc.extend(3, nil)...)
+
+	four := rpc.BlockNumber(4)
+	report, err := api.GetPerformance(1, &four)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(1), report.From)
+	require.Equal(t, hexutil.Uint64(4), report.To)
+
+	var inturn, outOfTurn, missed uint64
+	for _, block := range blocks[:4] {
+		if block.Difficulty().Cmp(diffInTurn) == 0 {
+			inturn++
+		} else {
+			outOfTurn++
+		}
+	}
+	for validator, perf := range report.Validators {
+		missed += uint64(perf.MissedBlocks)
+		require.LessOrEqual(t, perf.Punishments, perf.MissedBlocks)
+
+		// punishments match the counter kept by the punish contract
+		counter, err := api.GetMissedBlocks(validator, &four)
+		require.NoError(t, err)
+		require.Equal(t, counter, perf.Punishments, "validator %x", validator)
+	}
+	require.Equal(t, uint64(2), inturn)
+	require.Equal(t, uint64(2), outOfTurn)
+	require.Equal(t, outOfTurn, missed)
+	require.Equal(t, hexutil.Uint64(1), report.Validators[validators[0]].Punishments)
+
+	// the fees of the transfers are reported for the sealers of the first blocks,
+	// only the tip once the base fee is burned
+	for i, block := range blocks[:2] {
+		fees := new(big.Int).Mul(new(big.Int).SetUint64(params.TxGas), block.BaseFee())
+		if i == 0 {
+			fees.Lsh(fees, 1)
+		}
+		require.Equal(t, fees, report.Validators[block.Coinbase()].SealedBlockFees.ToInt())
+	}
+
+	// the report doesn't stop silently at the current block
+	past := rpc.BlockNumber(len(blocks) + 1)
+	_, err = api.GetPerformance(1, &past)
+	require.Error(t, err)
+
+	report, err = api.GetEpochPerformance(0, nil)
+	require.NoError(t, err)
+	require.Equal(t, hexutil.Uint64(1), report.From)
+	require.Equal(t, hexutil.Uint64(tc.config.Npos.Epoch), report.To)
+	inturn = 0
+	for _, block := range blocks {
+		if block.Difficulty().Cmp(diffInTurn) == 0 {
+			inturn++
+		}
+	}
+	require.Equal(t, float64(100*inturn)/float64(len(blocks)), report.InturnPercent)
+
+	_, err = api.GetEpochPerformance(1, nil)
+	require.ErrorIs(t, err, errUnknownBlock)
+
+	blocksArg := hexutil.Uint64(3)
+	status, err := api.Status(&blocksArg)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), status.NumBlocks)
+}
+
+func TestAPIBlockRewardBreakdown(t *testing.T) {
+	equal := func(want, have *big.Int) {
+		t.Helper()
+		require.Zero(t, want.Cmp(have), "want %v, have %v", want, have)
+	}
+	for _, feeBurn := range []bool{false, true} {
+		tc := newTestChain(t, func(config *params.NposConfig) {
+			if feeBurn {
+				config.FeeBurnBlock = big.NewInt(3)
+			}
+		})
+		api := &API{chain: tc.chain, npos: tc.engine}
+
+		// burn 10% of the block reward and credit 20% to the foundation
+		foundation := common.Address{0xfd}
+		tc.extend(1, func(i int, b *core.BlockGen) {
+			b.AddTx(tc.adminTx(b, systemcontract.ValidatorsContractAddr, "updateFoundation", foundation))
+			b.AddTx(tc.adminTx(b, systemcontract.ValidatorsContractAddr, "updateRates", big.NewInt(1000), big.NewInt(2000)))
+		})
+		// a block without transactions has no fees
+		tc.extend(1, nil)
+		breakdown, err := api.GetBlockRewardBreakdown(nil)
+		require.NoError(t, err)
+		require.Zero(t, breakdown.Fees.ToInt().Sign())
+
+		burned := mustState(t, tc.chain).GetBalance(params.NposBurnAddress)
+		blocks := tc.extend(1, func(i int, b *core.BlockGen) {
+			tx, err := types.SignNewTx(testAdminKey, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+				Nonce:    b.TxNonce(testAdmin),
+				To:       &common.Address{0xaa},
+				Gas:      params.TxGas,
+				GasPrice: new(big.Int).Mul(b.BaseFee(), big.NewInt(3)),
+			})
+			require.NoError(t, err)
+			b.AddTx(tx)
+		})
+		baseFee := new(big.Int).Mul(blocks[0].BaseFee(), big.NewInt(int64(params.TxGas)))
+		fees := new(big.Int).Mul(baseFee, big.NewInt(3))
+		reward := new(big.Int).Set(fees)
+		if feeBurn {
+			reward.Sub(reward, baseFee)
+		}
+		number := rpc.BlockNumber(blocks[0].NumberU64())
+		breakdown, err = api.GetBlockRewardBreakdown(&number)
+		require.NoError(t, err)
+		equal(fees, breakdown.Fees.ToInt())
+		equal(new(big.Int).Sub(fees, reward), breakdown.BaseFeeBurned.ToInt())
+		equal(reward, breakdown.Reward.ToInt())
+		equal(new(big.Int).Div(new(big.Int).Mul(reward, big.NewInt(1000)), big.NewInt(10000)), breakdown.Burned.ToInt())
+		equal(new(big.Int).Div(new(big.Int).Mul(reward, big.NewInt(2000)), big.NewInt(10000)), breakdown.Foundation.ToInt())
+		distributed := new(big.Int).Sub(reward, breakdown.Burned.ToInt())
+		equal(distributed.Sub(distributed, breakdown.Foundation.ToInt()), breakdown.Distributed.ToInt())
+		equal(new(big.Int).Add(burned, breakdown.Burned.ToInt()), mustState(t, tc.chain).GetBalance(params.NposBurnAddress))
+
+		// the breakdown is written with the block, not when the block is executed again
+		rawdb.DeleteFeeBreakdown(tc.db, blocks[0].Hash(), blocks[0].NumberU64())
+		tc.traceAll(blocks[0])
+		_, err = api.GetBlockRewardBreakdown(&number)
+		require.ErrorIs(t, err, errMissingFeeBreakdown)
+	}
+}
+
+// Tests that the fee breakdown of a block sealed by the engine is recorded,
+// although the block isn't finalized again when it's written, and deleted with
+// the block.
+func TestAPIBlockRewardBreakdownSealed(t *testing.T) {
+	tc := newTestChain(t)
+	api := &API{chain: tc.chain, npos: tc.engine}
+	tc.extend(1, nil)
+
+	// assemble a block with a transfer as the miner does
+	parent := tc.chain.CurrentBlock()
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		GasLimit:   parent.GasLimit,
+		BaseFee:    misc.CalcBaseFee(tc.config, parent),
+	}
+	require.NoError(t, tc.Prepare(tc.chain, header))
+	statedb := mustState(t, tc.chain)
+	tx, err := types.SignNewTx(testAdminKey, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+		Nonce:    statedb.GetNonce(testAdmin),
+		To:       &common.Address{0xaa},
+		Gas:      params.TxGas,
+		GasPrice: header.BaseFee,
+	})
+	require.NoError(t, err)
+	statedb.SetTxContext(tx.Hash(), 0)
+	receipt, err := core.ApplyTransaction(tc.config, tc.chain, &header.Coinbase, new(core.GasPool).AddGas(header.GasLimit), statedb, header, tx, &header.GasUsed, vm.Config{}, nil)
+	require.NoError(t, err)
+	// the miner shares the engine of the chain, which the test chain doesn't
+	engine := tc.chain.Engine().(*Npos)
+	block, receipts, err := engine.FinalizeAndAssemble(tc.chain, header, statedb, []*types.Transaction{tx}, nil, []*types.Receipt{receipt})
+	require.NoError(t, err)
+
+	results := make(chan *types.Block, 1)
+	require.NoError(t, tc.engine.Seal(tc.chain, block, results, make(chan struct{})))
+	var sealed *types.Block
+	select {
+	case sealed = <-results:
+	case <-time.After(5 * time.Second):
+		t.Fatal("block not sealed")
+	}
+	_, err = tc.chain.WriteBlockAndSetHead(sealed, receipts, nil, statedb, false)
+	require.NoError(t, err)
+
+	number := rpc.BlockNumber(sealed.NumberU64())
+	breakdown, err := api.GetBlockRewardBreakdown(&number)
+	require.NoError(t, err)
+	fees := new(big.Int).Mul(header.BaseFee, new(big.Int).SetUint64(params.TxGas))
+	require.Zero(t, fees.Cmp(breakdown.Fees.ToInt()), "want %v, have %v", fees, breakdown.Fees)
+	require.Zero(t, fees.Cmp(breakdown.Distributed.ToInt()), "want %v, have %v", fees, breakdown.Distributed)
+
+	// and deleted with the block
+	require.NoError(t, tc.chain.SetHead(sealed.NumberU64()-1))
+	require.Nil(t, rawdb.ReadFeeBreakdown(tc.db, sealed.Hash(), sealed.NumberU64()))
+}
+
+func TestAPISimulateProposal(t *testing.T) {
+	tc := newTestChain(t)
+	api := &API{chain: tc.chain, npos: tc.engine}
+	tc.extend(1, nil)
+
+	to := common.Address{0xbb}
+	value := big.NewInt(params.Ether)
+	sim, err := api.SimulateProposal(ProposalArgs{
+		Action: (*hexutil.Big)(common.Big0),
+		From:   testAdmin,
+		To:     to,
+		Value:  (*hexutil.Big)(value),
+	})
+	require.NoError(t, err)
+	require.True(t, sim.Success)
+	require.Empty(t, sim.Error)
+	require.Equal(t, value, sim.StateDiff.Post[to].Balance.ToInt())
+	require.Zero(t, sim.StateDiff.Pre[to].Balance.ToInt().Sign())
+	balance := mustState(t, tc.chain).GetBalance(testAdmin)
+	require.Equal(t, balance, sim.StateDiff.Pre[testAdmin].Balance.ToInt())
+	require.Equal(t, new(big.Int).Sub(balance, value), sim.StateDiff.Post[testAdmin].Balance.ToInt())
+
+	// the chain state is left untouched
+	require.Zero(t, mustState(t, tc.chain).GetBalance(to).Sign())
+
+	// a transfer beyond the balance fails
+	sim, err = api.SimulateProposal(ProposalArgs{
+		Action: (*hexutil.Big)(common.Big0),
+		From:   to,
+		To:     testAdmin,
+		Value:  (*hexutil.Big)(value),
+	})
+	require.NoError(t, err)
+	require.False(t, sim.Success)
+	require.NotEmpty(t, sim.Error)
+	require.Empty(t, sim.StateDiff.Post)
+
+	// the failure cause of a call not reaching the evm is reported
+	require.Contains(t, sim.Error, "insufficient")
+
+	// an unsupported action reports the cause too
+	sim, err = api.SimulateProposal(ProposalArgs{
+		Action: (*hexutil.Big)(big.NewInt(1 << 20)),
+		From:   testAdmin,
+		To:     to,
+	})
+	require.NoError(t, err)
+	require.False(t, sim.Success)
+	require.Equal(t, errUnsupportedAction.Error(), sim.Error)
+
+	// erasing an account flags the removal of its storage
+	sim, err = api.SimulateProposal(ProposalArgs{
+		Action: (*hexutil.Big)(new(big.Int).SetUint64(ActionEraseCode)),
+		To:     systemcontract.AddressListContractAddr,
+	})
+	require.NoError(t, err)
+	require.True(t, sim.Success, sim.Error)
+	require.True(t, sim.StateDiff.Post[systemcontract.AddressListContractAddr].StorageCleared)
+	require.NotNil(t, sim.StateDiff.Post[systemcontract.AddressListContractAddr].Code)
+
+	_, err = api.SimulateProposal(ProposalArgs{Id: (*hexutil.Big)(big.NewInt(42))})
+	require.Error(t, err)
+	_, err = api.SimulateProposal(ProposalArgs{})
+	require.Error(t, err)
+}
+
+func TestAPIBlacklist(t *testing.T) {
+	tc := newTestChain(t)
+	api := &API{chain: tc.chain, npos: tc.engine}
+	tc.extend(1, nil)
+
+	server := rpc.NewServer()
+	t.Cleanup(server.Stop)
+	require.NoError(t, server.RegisterName("npos", api))
+	client := rpc.DialInProc(server)
+	t.Cleanup(client.Close)
+	events := make(chan *BlacklistEvent, 4)
+	sub, err := client.Subscribe(context.Background(), "npos", events, "blacklistEvents")
+	require.NoError(t, err)
+	defer sub.Unsubscribe()
+
+	from, both := common.Address{0x01}, common.Address{0x02}
+	blocks := tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(tc.adminTx(b, systemcontract.AddressListContractAddr, "addBlacklist", from, uint8(DirectionFrom)))
+		b.AddTx(tc.adminTx(b, systemcontract.AddressListContractAddr, "addBlacklist", both, uint8(DirectionBoth)))
+	})
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(tc.adminTx(b, systemcontract.AddressListContractAddr, "removeBlacklist", both, uint8(DirectionBoth)))
+	})
+
+	before, added := rpc.BlockNumber(1), rpc.BlockNumber(2)
+	blacklist, err := api.GetBlacklist(&before)
+	require.NoError(t, err)
+	require.Empty(t, blacklist)
+	blacklist, err = api.GetBlacklist(&added)
+	require.NoError(t, err)
+	require.Equal(t, map[common.Address]BannedDirection{from: DirectionFrom, both: DirectionBoth}, blacklist)
+
+	status, err := api.IsAddressBanned(both, &added)
+	require.NoError(t, err)
+	require.True(t, status.Banned)
+	require.Equal(t, DirectionBoth, *status.Direction)
+	status, err = api.IsAddressBanned(both, nil)
+	require.NoError(t, err)
+	require.False(t, status.Banned)
+	require.Nil(t, status.Direction)
+
+	// the directions are encoded by name
+	var decoded map[common.Address]BannedDirection
+	require.NoError(t, client.Call(&decoded, "npos_getBlacklist", "0x2"))
+	require.Equal(t, blacklist, decoded)
+
+	expected := []BlacklistEvent{
+		{Address: from, Direction: DirectionFrom, Added: true, BlockNumber: 2, BlockHash: blocks[0].Hash(), TxHash: blocks[0].Transactions()[0].Hash()},
+		{Address: both, Direction: DirectionBoth, Added: true, BlockNumber: 2, BlockHash: blocks[0].Hash(), TxHash: blocks[0].Transactions()[1].Hash()},
+		// the contract removes both directions one by one
+		{Address: both, Direction: DirectionFrom, Added: false, BlockNumber: 3},
+		{Address: both, Direction: DirectionTo, Added: false, BlockNumber: 3},
+	}
+	for i, want := range expected {
+		select {
+		case ev := <-events:
+			if i >= 2 {
+				want.BlockHash, want.TxHash = ev.BlockHash, ev.TxHash
+				require.Equal(t, tc.chain.CurrentBlock().Hash(), ev.BlockHash)
+			}
+			require.Equal(t, want, *ev)
+		case err := <-sub.Err():
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("missing event %d", i)
+		}
+	}
+
+	// a reorg reverts the events of the dropped block
+	other := common.Address{0x03}
+	fork, _ := core.GenerateSealedChain(tc.config, blocks[0], tc.engine, tc.db, 2, tc, func(i int, b *core.BlockGen) {
+		if i == 0 {
+			b.AddTx(tc.adminTx(b, systemcontract.AddressListContractAddr, "addBlacklist", other, uint8(DirectionTo)))
+		}
+	})
+	_, err = tc.chain.InsertChain(fork)
+	require.NoError(t, err)
+	require.Equal(t, fork[1].Hash(), tc.chain.CurrentBlock().Hash())
+	expected = []BlacklistEvent{
+		{Address: both, Direction: DirectionTo, Added: false, BlockNumber: 3, Removed: true},
+		{Address: both, Direction: DirectionFrom, Added: false, BlockNumber: 3, Removed: true},
+		{Address: other, Direction: DirectionTo, Added: true, BlockNumber: 3, BlockHash: fork[0].Hash(), TxHash: fork[0].Transactions()[0].Hash()},
+	}
+	for i, want := range expected {
+		select {
+		case ev := <-events:
+			if want.Removed {
+				want.BlockHash, want.TxHash = ev.BlockHash, ev.TxHash
+				require.NotEqual(t, fork[0].Hash(), ev.BlockHash)
+			}
+			require.Equal(t, want, *ev)
+		case err := <-sub.Err():
+			t.Fatal(err)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("missing reorg event %d", i)
+		}
+	}
+	blacklist, err = api.GetBlacklist(nil)
+	require.NoError(t, err)
+	require.Equal(t, map[common.Address]BannedDirection{from: DirectionFrom, both: DirectionBoth, other: DirectionTo}, blacklist)
+}
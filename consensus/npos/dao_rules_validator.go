@@ -6,14 +6,70 @@ import (
 	"github.com/ethereum/go-ethereum/log"
 )
 
+// DataCheckIdxBase is the first check index of an event check rule selecting a
+// word of the log data rather than a topic. The check index DataCheckIdxBase+n
+// selects the n-th 32-byte word of the ABI encoded data, which is checked from
+// the system contracts v2 fork on.
+const DataCheckIdxBase = 1 << 32
+
 type EventCheckRule struct {
-	EventSig common.Hash
-	Checks   map[int]common.AddressCheckType
+	EventSig   common.Hash
+	Checks     map[int]common.AddressCheckType // Checks of the topics, by topic index
+	DataChecks map[int]common.AddressCheckType // Checks of the data words, by word index
+}
+
+func newEventCheckRule(sig common.Hash) *EventCheckRule {
+	return &EventCheckRule{
+		EventSig:   sig,
+		Checks:     make(map[int]common.AddressCheckType),
+		DataChecks: make(map[int]common.AddressCheckType),
+	}
+}
+
+// setCheck sets the check of a check index as kept by the address list contract.
+func (r *EventCheckRule) setCheck(idx uint64, ct common.AddressCheckType) {
+	if idx >= DataCheckIdxBase {
+		r.DataChecks[int(idx-DataCheckIdxBase)] = ct
+	} else {
+		r.Checks[int(idx)] = ct
+	}
+}
+
+// CallRules are the calls banned by the AddressList contract v2.
+type CallRules struct {
+	CodeHashes map[common.Hash]struct{}                // Code hashes of the banned contracts
+	Selectors  map[common.Address]map[[4]byte]struct{} // Banned function selectors of every contract
+}
+
+// banned returns the type of the rule banning a call of the code at an address
+// with the given code hash and input, or CheckNone if it's allowed.
+func (r *CallRules) banned(address common.Address, codeHash common.Hash, input []byte) common.AddressCheckType {
+	if codeHash != (common.Hash{}) && codeHash != types.EmptyCodeHash {
+		if _, exist := r.CodeHashes[codeHash]; exist {
+			return common.CheckCodeHash
+		}
+	}
+	if len(input) >= 4 {
+		var selector [4]byte
+		copy(selector[:], input)
+		if _, exist := r.Selectors[address][selector]; exist {
+			return common.CheckSelector
+		}
+	}
+	return common.CheckNone
+}
+
+// empty returns whether the rules ban no call.
+func (r *CallRules) empty() bool {
+	return len(r.CodeHashes) == 0 && len(r.Selectors) == 0
 }
 
 type daoRulesValidator struct {
-	blacks map[common.Address]bannedDirection
-	rules  map[common.Hash]*EventCheckRule
+	blacks     map[common.Address]BannedDirection
+	rules      map[common.Hash]*EventCheckRule
+	developers map[common.Address]struct{} // nil if the developer verification is disabled
+	callRules  *CallRules                  // nil if the call rules are not enforced
+	dataChecks bool                        // whether the log data is checked by the event check rules
 }
 
 func (b *daoRulesValidator) IsAddressBanned(address common.Address, cType common.AddressCheckType) (hit bool) {
@@ -39,10 +95,14 @@ func (b *daoRulesValidator) IsAddressBanned(address common.Address, cType common
 }
 
 func (b *daoRulesValidator) IsAddressBannedFromLog(evLog *types.Log) bool {
-	if nil == evLog || len(evLog.Topics) <= 1 {
+	if nil == evLog || len(evLog.Topics) == 0 {
+		return false
+	}
+	rule, exist := b.rules[evLog.Topics[0]]
+	if !exist {
 		return false
 	}
-	if rule, exist := b.rules[evLog.Topics[0]]; exist {
+	if len(evLog.Topics) > 1 {
 		for idx, checkType := range rule.Checks {
 			// do a basic check
 			if idx >= len(evLog.Topics) {
@@ -55,5 +115,43 @@ func (b *daoRulesValidator) IsAddressBannedFromLog(evLog *types.Log) bool {
 			}
 		}
 	}
+	if b.dataChecks {
+		for idx, checkType := range rule.DataChecks {
+			if (idx+1)*common.HashLength > len(evLog.Data) {
+				log.Error("data word index in rule out of range", "sig", rule.EventSig.String(), "wordIdx", idx, "dataLen", len(evLog.Data))
+				continue
+			}
+			addr := common.BytesToAddress(evLog.Data[idx*common.HashLength : (idx+1)*common.HashLength])
+			if b.IsAddressBanned(addr, checkType) {
+				return true
+			}
+		}
+	}
 	return false
 }
+
+func (b *daoRulesValidator) CanCreate(address common.Address) bool {
+	if b.developers == nil {
+		return true
+	}
+	_, exist := b.developers[address]
+	if !exist {
+		log.Trace("Unauthorized developer", "addr", address.String())
+	}
+	return exist
+}
+
+func (b *daoRulesValidator) IsCallBanned(address common.Address, codeHash common.Hash, input []byte) common.AddressCheckType {
+	if b.callRules == nil {
+		return common.CheckNone
+	}
+	hit := b.callRules.banned(address, codeHash, input)
+	if hit != common.CheckNone {
+		log.Trace("Hit call rules", "addr", address.String(), "codeHash", codeHash, "checkType", hit)
+	}
+	return hit
+}
+
+func (b *daoRulesValidator) HasCallRules() bool {
+	return b.callRules != nil && !b.callRules.empty()
+}
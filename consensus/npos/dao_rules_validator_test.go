@@ -0,0 +1,188 @@
+package npos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeveloperVerification(t *testing.T) {
+	var (
+		developer = common.Address{0x01}
+		other     = common.Address{0x02}
+		header    = &types.Header{Number: big.NewInt(10), ParentHash: common.Hash{0xff}}
+		engine    = newTestNpos(&params.NposConfig{Epoch: 200, EnableDevVerification: true, SysContractV1Block: common.Big1, DevVerificationBlock: common.Big2})
+	)
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	require.NoError(t, err)
+	engine.blacklists.Add(header.ParentHash, map[common.Address]BannedDirection{})
+	engine.eventCheckRules.Add(header.ParentHash, map[common.Hash]*EventCheckRule{})
+	engine.developers.Add(header.ParentHash, map[common.Address]struct{}{developer: {}})
+
+	// contract creations are rejected by the pool
+	create := types.NewContractCreation(0, common.Big0, 100000, common.Big0, nil)
+	require.NoError(t, engine.ValidateTx(developer, create, header, statedb))
+	require.ErrorIs(t, engine.ValidateTx(other, create, header, statedb), types.ErrUnauthorizedDeveloper)
+	call := types.NewTransaction(0, developer, common.Big0, 100000, common.Big0, nil)
+	require.NoError(t, engine.ValidateTx(other, call, header, statedb))
+
+	// and by the evm, including the creations of contracts
+	validator, err := engine.CreateEvmExtraValidator(header, statedb)
+	require.NoError(t, err)
+	require.True(t, validator.CanCreate(developer))
+	require.False(t, validator.CanCreate(other))
+
+	// CREATE(0, 0, 0), then store the new address at slot 0
+	factory := common.FromHex("0x600060006000f060005500")
+	statedb.SetCode(developer, factory)
+	statedb.SetCode(other, factory)
+	evm := vm.NewEVM(vm.BlockContext{
+		CanTransfer:    core.CanTransfer,
+		Transfer:       core.Transfer,
+		BlockNumber:    header.Number,
+		ExtraValidator: validator,
+	}, vm.TxContext{}, statedb, params.AllEthashProtocolChanges, vm.Config{})
+	rules := params.AllEthashProtocolChanges.Rules(header.Number, false, 0)
+	statedb.Prepare(rules, developer, common.Address{}, nil, nil, nil)
+
+	_, _, _, err = evm.Create(vm.AccountRef(developer), []byte{0x00}, 100000, common.Big0)
+	require.NoError(t, err)
+	nonce := statedb.GetNonce(other)
+	_, _, _, err = evm.Create(vm.AccountRef(other), []byte{0x00}, 100000, common.Big0)
+	require.ErrorIs(t, err, types.ErrUnauthorizedDeveloper)
+	require.Equal(t, nonce+1, statedb.GetNonce(other))
+
+	statedb.Prepare(rules, developer, common.Address{}, &developer, nil, nil)
+	_, _, err = evm.Call(vm.AccountRef(developer), developer, nil, 100000, common.Big0)
+	require.NoError(t, err)
+	require.NotEqual(t, common.Hash{}, statedb.GetState(developer, common.Hash{}))
+	statedb.Prepare(rules, developer, common.Address{}, &other, nil, nil)
+	_, _, err = evm.Call(vm.AccountRef(developer), other, nil, 100000, common.Big0)
+	require.NoError(t, err)
+	require.Equal(t, common.Hash{}, statedb.GetState(other, common.Hash{}))
+
+	// the block isn't processed if the developers can't be read
+	failing := &types.Header{Number: header.Number, ParentHash: common.Hash{0xee}, Difficulty: common.Big1}
+	engine.blacklists.Add(failing.ParentHash, map[common.Address]BannedDirection{})
+	engine.eventCheckRules.Add(failing.ParentHash, map[common.Hash]*EventCheckRule{})
+	require.ErrorIs(t, engine.ValidateTx(developer, create, failing, statedb), types.ErrUnauthorizedDeveloper)
+	require.NoError(t, engine.ValidateTx(developer, call, failing, statedb))
+	_, err = engine.CreateEvmExtraValidator(failing, statedb)
+	require.Error(t, err)
+
+	// everyone can create contracts if the verification is disabled
+	engine.config.EnableDevVerification = false
+	require.NoError(t, engine.ValidateTx(other, create, header, statedb))
+	validator, err = engine.CreateEvmExtraValidator(header, statedb)
+	require.NoError(t, err)
+	require.True(t, validator.CanCreate(other))
+}
+
+func TestCallRules(t *testing.T) {
+	var (
+		target   = common.Address{0x01}
+		other    = common.Address{0x02}
+		exploit  = common.Hash{0xee}
+		selector = [4]byte{0x12, 0x34, 0x56, 0x78}
+		rules    = &CallRules{
+			CodeHashes: map[common.Hash]struct{}{exploit: {}},
+			Selectors:  map[common.Address]map[[4]byte]struct{}{target: {selector: {}}},
+		}
+		validator = &daoRulesValidator{callRules: rules}
+	)
+	for i, tt := range []struct {
+		address  common.Address
+		codeHash common.Hash
+		input    []byte
+		hit      common.AddressCheckType
+	}{
+		{target, common.Hash{0x01}, selector[:], common.CheckSelector},
+		{target, common.Hash{0x01}, append(selector[:], 0x01), common.CheckSelector},
+		{target, common.Hash{0x01}, selector[:3], common.CheckNone},
+		{target, common.Hash{0x01}, []byte{0x87, 0x65, 0x43, 0x21}, common.CheckNone},
+		{other, common.Hash{0x01}, selector[:], common.CheckNone},
+		{other, exploit, nil, common.CheckCodeHash},
+		{target, exploit, selector[:], common.CheckCodeHash},
+		{other, types.EmptyCodeHash, nil, common.CheckNone},
+	} {
+		require.Equal(t, tt.hit, validator.IsCallBanned(tt.address, tt.codeHash, tt.input), "case %d", i)
+	}
+	require.True(t, validator.HasCallRules())
+	// the rules are not enforced before the system contracts v2
+	require.Equal(t, common.CheckNone, (&daoRulesValidator{}).IsCallBanned(target, exploit, selector[:]))
+	require.False(t, (&daoRulesValidator{}).HasCallRules())
+	// the calls aren't looked up without any rule
+	require.False(t, (&daoRulesValidator{callRules: &CallRules{}}).HasCallRules())
+}
+
+func TestCallRulesReadFailure(t *testing.T) {
+	var (
+		contract = common.Address{0x01}
+		account  = common.Address{0x02}
+		header   = &types.Header{Number: big.NewInt(10), ParentHash: common.Hash{0xff}, Difficulty: common.Big1}
+		engine   = newTestNpos(&params.NposConfig{Epoch: 200, SysContractV1Block: common.Big1, SysContractV2Block: common.Big2})
+	)
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	require.NoError(t, err)
+	statedb.SetCode(contract, []byte{0x00})
+	engine.blacklists.Add(header.ParentHash, map[common.Address]BannedDirection{})
+	engine.eventCheckRules.Add(header.ParentHash, map[common.Hash]*EventCheckRule{})
+
+	// the call rules can't be read from the state without the AddressList contract,
+	// the contract calls are rejected and the block can't be processed
+	call := types.NewTransaction(0, contract, common.Big0, 100000, common.Big0, nil)
+	require.Error(t, engine.ValidateTx(account, call, header, statedb))
+	_, err = engine.CreateEvmExtraValidator(header, statedb)
+	require.Error(t, err)
+}
+
+func TestEventDataChecks(t *testing.T) {
+	var (
+		sig    = common.Hash{0xab}
+		banned = common.Address{0x01}
+		other  = common.Address{0x02}
+		rule   = newEventCheckRule(sig)
+	)
+	rule.setCheck(2, common.CheckTo)
+	rule.setCheck(DataCheckIdxBase+1, common.CheckTo)
+	require.Equal(t, map[int]common.AddressCheckType{2: common.CheckTo}, rule.Checks)
+	require.Equal(t, map[int]common.AddressCheckType{1: common.CheckTo}, rule.DataChecks)
+
+	validator := &daoRulesValidator{
+		blacks:     map[common.Address]BannedDirection{banned: DirectionTo},
+		rules:      map[common.Hash]*EventCheckRule{sig: rule},
+		dataChecks: true,
+	}
+	data := func(words ...common.Address) []byte {
+		var data []byte
+		for _, word := range words {
+			data = append(data, common.BytesToHash(word[:]).Bytes()...)
+		}
+		return data
+	}
+	for i, tt := range []struct {
+		log *types.Log
+		hit bool
+	}{
+		{&types.Log{Topics: []common.Hash{sig}, Data: data(other, banned)}, true},
+		{&types.Log{Topics: []common.Hash{sig, {}, common.BytesToHash(banned[:])}, Data: data(other, other)}, true},
+		{&types.Log{Topics: []common.Hash{sig, {}, common.BytesToHash(other[:])}, Data: data(other, banned)}, true},
+		{&types.Log{Topics: []common.Hash{sig}, Data: data(banned, other)}, false},
+		{&types.Log{Topics: []common.Hash{sig}, Data: data(banned)}, false},
+		{&types.Log{Topics: []common.Hash{{0xcd}}, Data: data(other, banned)}, false},
+		{&types.Log{Data: data(other, banned)}, false},
+	} {
+		require.Equal(t, tt.hit, validator.IsAddressBannedFromLog(tt.log), "case %d", i)
+	}
+	// the data is not checked before the system contracts v2
+	validator.dataChecks = false
+	require.False(t, validator.IsAddressBannedFromLog(&types.Log{Topics: []common.Hash{sig}, Data: data(other, banned)}))
+}
@@ -0,0 +1,118 @@
+package npos
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/params"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+// emptyChainReader is a chain without any blocks, so header verification stops
+// at the first check that needs the parent.
+type emptyChainReader struct {
+	config *params.ChainConfig
+}
+
+func (r *emptyChainReader) Config() *params.ChainConfig                             { return r.config }
+func (r *emptyChainReader) CurrentHeader() *types.Header                            { return nil }
+func (r *emptyChainReader) GetHeader(hash common.Hash, number uint64) *types.Header { return nil }
+func (r *emptyChainReader) GetHeaderByNumber(number uint64) *types.Header           { return nil }
+func (r *emptyChainReader) GetHeaderByHash(hash common.Hash) *types.Header          { return nil }
+func (r *emptyChainReader) GetTd(hash common.Hash, number uint64) *big.Int          { return nil }
+
+func addExtraSeeds(f *testing.F) {
+	f.Add(uint64(0), make([]byte, extraVanity+extraSeal))
+	f.Add(uint64(1), make([]byte, extraVanity+extraSeal))
+	f.Add(uint64(200), make([]byte, extraVanity+2*common.AddressLength+extraSeal))
+	f.Add(uint64(200), make([]byte, extraVanity+common.AddressLength+1+extraSeal))
+	f.Add(uint64(3), make([]byte, extraVanity))
+	f.Add(uint64(3), []byte{})
+}
+
+func FuzzParseValidators(f *testing.F) {
+	addExtraSeeds(f)
+	config := &params.NposConfig{Epoch: 200}
+	f.Fuzz(func(t *testing.T, number uint64, extra []byte) {
+		validators, err := parseValidators(config, &types.Header{Number: new(big.Int).SetUint64(number), Extra: extra})
+		if err != nil {
+			if len(extra) >= extraVanity+extraSeal && (len(extra)-extraVanity-extraSeal)%common.AddressLength == 0 {
+				t.Fatalf("valid extra-data rejected: %v", err)
+			}
+			return
+		}
+		encoded := make([]byte, 0, len(validators)*common.AddressLength)
+		for _, validator := range validators {
+			encoded = append(encoded, validator.Bytes()...)
+		}
+		if !bytes.Equal(encoded, extra[extraVanity:len(extra)-extraSeal]) {
+			t.Fatalf("validators mismatch: have %x, want %x", encoded, extra[extraVanity:len(extra)-extraSeal])
+		}
+	})
+}
+
+func FuzzVerifyHeaderExtra(f *testing.F) {
+	addExtraSeeds(f)
+	config := &params.ChainConfig{ChainID: big.NewInt(1), Npos: &params.NposConfig{Epoch: 200}}
+	chain := &emptyChainReader{config: config}
+	engine := newTestNpos(config.Npos)
+	sigcache, _ := lru.NewARC(inmemorySignatures)
+
+	f.Fuzz(func(t *testing.T, number uint64, extra []byte) {
+		header := &types.Header{
+			Number:     new(big.Int).SetUint64(number),
+			Difficulty: diffInTurn,
+			UncleHash:  types.EmptyUncleHash,
+			Extra:      extra,
+		}
+		// neither recovering the signer nor verifying the header may panic
+		ecrecover(header, sigcache)
+
+		err := engine.verifyHeader(chain, header, nil)
+		switch {
+		case errors.Is(err, errMissingVanity), errors.Is(err, errMissingSignature):
+			if len(extra) >= extraVanity+extraSeal {
+				t.Fatalf("extra-data of %d bytes rejected: %v", len(extra), err)
+			}
+		case errors.Is(err, errExtraValidators), errors.Is(err, errInvalidCheckpointValidators):
+			if _, perr := parseValidators(config.Npos, header); perr == nil && number%config.Npos.Epoch == 0 {
+				t.Fatalf("checkpoint validators rejected: %v", err)
+			}
+		case err == nil, errors.Is(err, consensus.ErrUnknownAncestor):
+			validators, perr := parseValidators(config.Npos, header)
+			if perr != nil {
+				t.Fatalf("malformed extra-data accepted: %v", perr)
+			}
+			if number%config.Npos.Epoch != 0 && len(validators) > 0 {
+				t.Fatalf("validators accepted on non-checkpoint block %d", number)
+			}
+		}
+	})
+}
+
+func FuzzParseAttestation(f *testing.F) {
+	addExtraSeeds(f)
+	f.Add(uint64(400), make([]byte, extraVanity+attestationLength+extraSeal))
+	f.Add(uint64(401), append(make([]byte, extraVanity), bytes.Repeat([]byte{0x01}, attestationLength+extraSeal)...))
+	config := &params.NposConfig{Epoch: 200, AttestationBlock: big.NewInt(400)}
+	f.Fuzz(func(t *testing.T, number uint64, extra []byte) {
+		header := &types.Header{Number: new(big.Int).SetUint64(number), Extra: extra}
+		attestation, err := parseAttestation(config, header)
+		if err != nil || attestation == nil {
+			return
+		}
+		// the attestation is the one embedded right before the seal
+		offset := len(extra) - extraSeal - attestationLength
+		if !bytes.Equal(attestation.Data.bytes(), extra[offset:offset+attestationDataLength]) {
+			t.Fatalf("attestation data mismatch: have %x, want %x", attestation.Data.bytes(), extra[offset:offset+attestationDataLength])
+		}
+		if !bytes.Equal(attestation.Signature, extra[offset+attestationDataLength:len(extra)-extraSeal]) {
+			t.Fatalf("attestation signature mismatch")
+		}
+	})
+}
@@ -0,0 +1,1028 @@
+package npos
+
+import (
+	"crypto/ecdsa"
+	"encoding/json"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+var (
+	// keys of the genesis validators in testdata/genesis.json
+	testValidatorKeys = []string{
+		"80ad06214c1be9cc3cd941d59f4a53ce0d2b7e24fc646fb48f81d8cf5f74a6bc",
+		"ed8da5520a704b8e417c0d3ab2511bb6eebc929d3812b90db7a4ac17132647f2",
+		"421d0fae37983caee801945267d9ab514fdefe51113389a380b8e1e2de968e4c",
+	}
+	// key of the staking and governance admin in testdata/genesis.json
+	testAdminKey, _ = crypto.HexToECDSA("ebaa2febee077847f41b9bd23b28ba7318f37d92658ccbe194a2df432a93810f")
+	testAdmin       = crypto.PubkeyToAddress(testAdminKey.PublicKey)
+)
+
+// testAdminABI contains the admin-only system contract methods used by the tests.
+const testAdminABI = `[
+	{"type":"function","name":"updateValidatorState","inputs":[{"name":"_validator","type":"address"},{"name":"pause","type":"bool"}],"outputs":[]},
+	{"type":"function","name":"addBlacklist","inputs":[{"name":"a","type":"address"},{"name":"d","type":"uint8"}],"outputs":[]},
+	{"type":"function","name":"removeBlacklist","inputs":[{"name":"a","type":"address"},{"name":"d","type":"uint8"}],"outputs":[]},
+	{"type":"function","name":"addDeveloper","inputs":[{"name":"a","type":"address"}],"outputs":[]},
+	{"type":"function","name":"removeDeveloper","inputs":[{"name":"a","type":"address"}],"outputs":[]},
+	{"type":"function","name":"addOrUpdateRule","inputs":[{"name":"sig","type":"bytes32"},{"name":"checkIdx","type":"uint128"},{"name":"tp","type":"uint8"}],"outputs":[]},
+	{"type":"function","name":"addSelectorRule","inputs":[{"name":"target","type":"address"},{"name":"selector","type":"bytes4"}],"outputs":[]},
+	{"type":"function","name":"removeSelectorRule","inputs":[{"name":"target","type":"address"},{"name":"selector","type":"bytes4"}],"outputs":[]},
+	{"type":"function","name":"addCodeHashRule","inputs":[{"name":"codeHash","type":"bytes32"}],"outputs":[]},
+	{"type":"function","name":"removeCodeHashRule","inputs":[{"name":"codeHash","type":"bytes32"}],"outputs":[]},
+	{"type":"function","name":"updateFoundation","inputs":[{"name":"_foundation","type":"address"}],"outputs":[]},
+	{"type":"function","name":"updateRates","inputs":[{"name":"_burnRate","type":"uint256"},{"name":"_foundationRate","type":"uint256"}],"outputs":[]},
+	{"type":"function","name":"commitProposal","inputs":[{"name":"action","type":"uint256"},{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"value","type":"uint256"},{"name":"input","type":"bytes"}],"outputs":[]}
+]`
+
+// testChain produces sealed NPoS blocks with core.GenerateSealedChain and
+// imports them into a real blockchain, which re-verifies the headers and
+// replays every block through Finalize.
+type testChain struct {
+	t       *testing.T
+	config  *params.ChainConfig
+	db      ethdb.Database
+	engine  *Npos // engine producing blocks
+	chain   *core.BlockChain
+	keys    map[common.Address]*ecdsa.PrivateKey
+	signers map[uint64]common.Address // signer overrides by block number
+	abi     abi.ABI
+}
+
+// newTestChain creates a chain of testdata/genesis.json, with the NPoS config
+// modified by the given functions.
+func newTestChain(t *testing.T, configs ...func(*params.NposConfig)) *testChain {
+	t.Helper()
+	blob, err := os.ReadFile("testdata/genesis.json")
+	require.NoError(t, err)
+	genesis := new(core.Genesis)
+	require.NoError(t, json.Unmarshal(blob, genesis))
+	for _, config := range configs {
+		config(genesis.Config.Npos)
+	}
+
+	db := rawdb.NewMemoryDatabase()
+	verifier := New(genesis.Config, db)
+	chain, err := core.NewBlockChain(db, nil, genesis, nil, verifier, vm.Config{}, nil, nil)
+	require.NoError(t, err)
+	t.Cleanup(chain.Stop)
+	verifier.SetStateFn(chain.StateAt)
+	verifier.SetChain(chain)
+
+	engine := New(genesis.Config, db)
+	engine.SetStateFn(chain.StateAt)
+	engine.SetChain(chain)
+
+	keys := make(map[common.Address]*ecdsa.PrivateKey)
+	for _, hex := range testValidatorKeys {
+		key, err := crypto.HexToECDSA(hex)
+		require.NoError(t, err)
+		keys[crypto.PubkeyToAddress(key.PublicKey)] = key
+	}
+	parsed, err := abi.JSON(strings.NewReader(testAdminABI))
+	require.NoError(t, err)
+
+	return &testChain{
+		t:       t,
+		config:  genesis.Config,
+		db:      db,
+		engine:  engine,
+		chain:   chain,
+		keys:    keys,
+		signers: make(map[uint64]common.Address),
+		abi:     parsed,
+	}
+}
+
+// Prepare implements core.ChainSealer, signing the block in turn unless
+// another signer has been scheduled, or the in-turn validator signed recently.
+func (tc *testChain) Prepare(chain consensus.ChainHeaderReader, header *types.Header) error {
+	number := header.Number.Uint64()
+	snap, err := tc.engine.snapshot(chain, number-1, header.ParentHash, nil)
+	if err != nil {
+		return err
+	}
+	signer, ok := tc.signers[number]
+	if !ok {
+		validators := snap.validators()
+		for i := range validators {
+			signer = validators[(number+uint64(i))%uint64(len(validators))]
+			if !tc.signedRecently(snap, number, signer) {
+				break
+			}
+		}
+	}
+	key := tc.keys[signer]
+	signFn := func(account accounts.Account, mimeType string, message []byte) ([]byte, error) {
+		return crypto.Sign(crypto.Keccak256(message), key)
+	}
+	tc.engine.Authorize(signer, signFn, func(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error) {
+		return types.SignTx(tx, types.LatestSignerForChainID(chainID), key)
+	})
+	if err := tc.engine.Prepare(chain, header); err != nil {
+		return err
+	}
+	if err := tc.engine.signAttestation(snap, header, signer, signFn); err != nil {
+		return err
+	}
+	// Prepare never produces blocks in the past, but the test chain starts at 0
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	header.Time = parent.Time + snap.Period.at(number)
+	return nil
+}
+
+func (tc *testChain) signedRecently(snap *Snapshot, number uint64, signer common.Address) bool {
+	for seen, recent := range snap.Recents {
+		if recent == signer {
+			if limit := uint64(len(snap.Validators)/2 + 1); number < limit || seen > number-limit {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Seal implements core.ChainSealer.
+func (tc *testChain) Seal(block *types.Block) (*types.Block, error) {
+	header := block.Header()
+	sig, err := crypto.Sign(SealHash(header).Bytes(), tc.keys[header.Coinbase])
+	if err != nil {
+		return nil, err
+	}
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+	return block.WithSeal(header), nil
+}
+
+// extend generates n blocks on top of the current head and imports them.
+func (tc *testChain) extend(n int, gen func(int, *core.BlockGen)) []*types.Block {
+	tc.t.Helper()
+	head := tc.chain.CurrentBlock()
+	parent := tc.chain.GetBlock(head.Hash(), head.Number.Uint64())
+	blocks, _ := core.GenerateSealedChain(tc.config, parent, tc.engine, tc.db, n, tc, gen)
+	_, err := tc.chain.InsertChain(blocks)
+	require.NoError(tc.t, err)
+	return blocks
+}
+
+// adminTx returns a transaction calling a system contract method from the admin account.
+func (tc *testChain) adminTx(b *core.BlockGen, to common.Address, method string, args ...interface{}) *types.Transaction {
+	tc.t.Helper()
+	data, err := tc.abi.Pack(method, args...)
+	require.NoError(tc.t, err)
+	tx, err := types.SignNewTx(testAdminKey, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+		Nonce:    b.TxNonce(testAdmin),
+		To:       &to,
+		Gas:      5_000_000,
+		GasPrice: b.BaseFee(),
+		Data:     data,
+	})
+	require.NoError(tc.t, err)
+	return tx
+}
+
+// call executes a read-only system contract method on the state of the current head.
+func (tc *testChain) call(contractABI abi.ABI, to common.Address, method string, args ...interface{}) []interface{} {
+	tc.t.Helper()
+	head := tc.chain.CurrentBlock()
+	statedb, err := tc.chain.StateAt(head.Root)
+	require.NoError(tc.t, err)
+	data, err := contractABI.Pack(method, args...)
+	require.NoError(tc.t, err)
+	ctx := &systemcontract.CallContext{
+		Statedb:      statedb,
+		Header:       head,
+		ChainContext: newChainContext(tc.chain, tc.engine),
+		ChainConfig:  tc.config,
+	}
+	result, err := systemcontract.VmCall(ctx, to, data)
+	require.NoError(tc.t, err)
+	ret, err := contractABI.Unpack(method, result)
+	require.NoError(tc.t, err)
+	return ret
+}
+
+// internalTx returns the internal tx of the transaction with the given hash in
+// the block, or of the engine out of any transaction for an empty hash.
+func internalTx(t *testing.T, itxs types.InternalTxs, hash common.Hash) *types.InternalTx {
+	t.Helper()
+	for _, itx := range itxs {
+		if itx.TxHash == hash {
+			return itx
+		}
+	}
+	require.Failf(t, "no internal tx", "tx %x", hash)
+	return nil
+}
+
+// traceAll replays the block on its parent state recording all the calls as
+// internal txs, the chain only records the value transfers.
+func (tc *testChain) traceAll(block *types.Block) types.InternalTxs {
+	tc.t.Helper()
+	parent := tc.chain.GetHeader(block.ParentHash(), block.NumberU64()-1)
+	statedb, err := tc.chain.StateAt(parent.Root)
+	require.NoError(tc.t, err)
+	_, _, itxs, _, err := tc.chain.Processor().Process(block, statedb, vm.Config{InternalTxTraceAll: true})
+	require.NoError(tc.t, err)
+	return itxs
+}
+
+func (tc *testChain) snapshot() *Snapshot {
+	tc.t.Helper()
+	head := tc.chain.CurrentBlock()
+	snap, err := tc.engine.snapshot(tc.chain, head.Number.Uint64(), head.Hash(), nil)
+	require.NoError(tc.t, err)
+	return snap
+}
+
+// finalized waits for the chain to finalize the block of the given number, as
+// the finality is updated in the background.
+func (tc *testChain) finalized(number uint64) *types.Header {
+	tc.t.Helper()
+	require.Eventually(tc.t, func() bool {
+		finalized := tc.chain.CurrentFinalBlock()
+		return finalized != nil && finalized.Number.Uint64() == number
+	}, 5*time.Second, time.Millisecond, "block %d not finalized", number)
+	return tc.chain.CurrentFinalBlock()
+}
+
+func TestChainEpochs(t *testing.T) {
+	tc := newTestChain(t)
+	epoch := tc.config.Npos.Epoch
+
+	// transfers in every block exercise the block reward distribution
+	to := common.Address{0xaa}
+	blocks := tc.extend(int(3*epoch), func(i int, b *core.BlockGen) {
+		tx, err := types.SignNewTx(testAdminKey, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+			Nonce:    b.TxNonce(testAdmin),
+			To:       &to,
+			Value:    big.NewInt(1),
+			Gas:      params.TxGas,
+			GasPrice: b.BaseFee(),
+		})
+		require.NoError(t, err)
+		b.AddTx(tx)
+	})
+	for _, block := range blocks {
+		require.Equal(t, diffInTurn, block.Difficulty(), "block %d", block.NumberU64())
+		validators, err := parseValidators(tc.config.Npos, block.Header())
+		require.NoError(t, err)
+		if block.NumberU64()%epoch == 0 {
+			require.Len(t, validators, len(testValidatorKeys))
+		} else {
+			require.Empty(t, validators)
+		}
+	}
+	require.Equal(t, big.NewInt(int64(len(blocks))), mustState(t, tc.chain).GetBalance(to))
+}
+
+func TestChainValidatorRotation(t *testing.T) {
+	tc := newTestChain(t)
+	epoch := tc.config.Npos.Epoch
+	paused := tc.snapshot().validators()[0]
+
+	// pause a validator in the first epoch; it is left out of the top
+	// validators at the next checkpoint
+	tc.extend(int(epoch), func(i int, b *core.BlockGen) {
+		if i == 1 {
+			b.AddTx(tc.adminTx(b, systemcontract.ValidatorsContractAddr, "updateValidatorState", paused, true))
+		}
+	})
+	checkpoint := tc.chain.CurrentBlock()
+	validators, err := parseValidators(tc.config.Npos, checkpoint)
+	require.NoError(t, err)
+	require.Len(t, validators, len(testValidatorKeys)-1)
+	require.NotContains(t, validators, paused)
+
+	// the new set only takes effect one epoch after the checkpoint
+	tc.extend(int(epoch)-1, nil)
+	require.Contains(t, tc.snapshot().Validators, paused)
+	tc.extend(1, nil)
+	snap := tc.snapshot()
+	require.Equal(t, validators, snap.validators())
+	require.LessOrEqual(t, len(snap.Recents), len(validators)/2+1)
+
+	// the shrunk set keeps producing blocks in turn
+	for _, block := range tc.extend(int(epoch), nil) {
+		require.NotEqual(t, paused, block.Coinbase())
+		require.Equal(t, diffInTurn, block.Difficulty(), "block %d", block.NumberU64())
+	}
+}
+
+func TestChainPunishOutOfTurn(t *testing.T) {
+	tc := newTestChain(t)
+	tc.extend(2, nil)
+
+	// let the validator after the in-turn one sign block 3
+	validators := tc.snapshot().validators()
+	missed := validators[3%len(validators)]
+	tc.signers[3] = validators[4%len(validators)]
+
+	punished := func() uint64 {
+		return tc.call(tc.engine.abi[systemcontract.PunishContractName], systemcontract.PunishContractAddr, "getPunishRecord", missed)[0].(*big.Int).Uint64()
+	}
+	require.Zero(t, punished())
+	blocks := tc.extend(1, nil)
+	require.Equal(t, diffNoTurn, blocks[0].Difficulty())
+	require.Equal(t, uint64(1), punished())
+
+	// the punishment is a call of the engine, which moves no value
+	require.Empty(t, rawdb.ReadInternalTxs(tc.db, blocks[0].Hash(), blocks[0].NumberU64()))
+	punish := internalTx(t, tc.traceAll(blocks[0]), common.Hash{}).Actions[0]
+	require.Equal(t, systemcontract.EngineCaller, punish.From)
+	require.Equal(t, systemcontract.PunishContractAddr, punish.To)
+	require.Equal(t, types.EngineActionDepth, punish.Depth)
+	require.True(t, punish.Success)
+}
+
+func TestChainGovernanceProposal(t *testing.T) {
+	tc := newTestChain(t)
+	tc.extend(1, nil)
+
+	to := common.Address{0xbb}
+	value := big.NewInt(params.Ether)
+	blocks := tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(tc.adminTx(b, systemcontract.SysGovContractAddr, "commitProposal", common.Big0, testAdmin, to, value, []byte{}))
+	})
+
+	// the passed proposal is executed in the same block by a system transaction
+	txs := blocks[0].Transactions()
+	require.Len(t, txs, 2)
+	require.Equal(t, systemcontract.SysGovToAddr, *txs[1].To())
+	receipts := tc.chain.GetReceiptsByHash(blocks[0].Hash())
+	require.Len(t, receipts, 2)
+	require.Equal(t, types.ReceiptStatusSuccessful, receipts[1].Status)
+	// and it pays no fee, unlike the proposal
+	require.Equal(t, consensus.FeeRecoder, *receipts[0].FeeRecipient)
+	require.Nil(t, receipts[1].Fee)
+	require.Nil(t, receipts[1].FeeRecipient)
+
+	require.Equal(t, value, mustState(t, tc.chain).GetBalance(to))
+
+	// and it is finished, so the next block doesn't execute it again
+	blocks = tc.extend(1, nil)
+	require.Empty(t, blocks[0].Transactions())
+	require.Equal(t, value, mustState(t, tc.chain).GetBalance(to))
+}
+
+func TestChainInternalTxs(t *testing.T) {
+	tc := newTestChain(t)
+	tc.extend(1, nil)
+
+	to := common.Address{0xbb}
+	value := big.NewInt(params.Ether)
+	blocks := tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(tc.adminTx(b, systemcontract.SysGovContractAddr, "commitProposal", new(big.Int).SetUint64(ActionEvmCall), testAdmin, to, value, []byte{}))
+	})
+	txs := blocks[0].Transactions()
+	require.Len(t, txs, 2)
+	receipts := tc.chain.GetReceiptsByHash(blocks[0].Hash())
+	fee := new(big.Int).Mul(new(big.Int).SetUint64(receipts[0].GasUsed), txs[0].GasPrice())
+
+	// the internal txs of the transaction, the block reward and the proposal, in execution order
+	itxs := rawdb.ReadInternalTxs(tc.db, blocks[0].Hash(), blocks[0].NumberU64())
+	require.Len(t, itxs, 3)
+	require.Equal(t, txs[0].Hash(), itxs[0].TxHash)
+
+	require.Equal(t, common.Hash{}, itxs[1].TxHash)
+	require.GreaterOrEqual(t, len(itxs[1].Actions), 2)
+	sweep, reward := itxs[1].Actions[0], itxs[1].Actions[1]
+	require.Equal(t, types.FeeSweepOpCode, sweep.OpCode)
+	require.Equal(t, consensus.FeeRecoder, sweep.From)
+	require.Equal(t, systemcontract.EngineCaller, sweep.To)
+	require.Equal(t, fee, sweep.Value)
+	require.Equal(t, "CALL", reward.OpCode)
+	require.Equal(t, systemcontract.EngineCaller, reward.From)
+	require.Equal(t, systemcontract.ValidatorsContractAddr, reward.To)
+	require.Equal(t, fee, reward.Value)
+	require.True(t, reward.Success)
+	// the actions of the engine are value transfers of their own
+	for _, action := range []*types.Action{sweep, reward, itxs[2].Actions[0]} {
+		require.Equal(t, types.EngineActionDepth, action.Depth)
+		require.True(t, core.IsValueTransferAction(action))
+	}
+
+	require.Equal(t, txs[1].Hash(), itxs[2].TxHash)
+	require.Len(t, itxs[2].Actions, 1)
+	require.Equal(t, testAdmin, itxs[2].Actions[0].From)
+	require.Equal(t, to, itxs[2].Actions[0].To)
+	require.Equal(t, value, itxs[2].Actions[0].Value)
+	require.True(t, itxs[2].Actions[0].Success)
+
+	// a block without transactions has no reward to distribute
+	blocks = tc.extend(1, nil)
+	require.Empty(t, rawdb.ReadInternalTxs(tc.db, blocks[0].Hash(), blocks[0].NumberU64()))
+}
+
+func TestChainSysContractV1(t *testing.T) {
+	tc := newTestChain(t, func(config *params.NposConfig) {
+		config.SysContractV1Block = big.NewInt(3)
+	})
+	addrList := systemcontract.AddressListContractAddr
+	alABI := tc.engine.abi[systemcontract.AddressListContractName]
+	v0 := mustState(t, tc.chain).GetCode(addrList)
+	banned := common.Address{0xaa}
+	tc.extend(3, func(i int, b *core.BlockGen) {
+		if i == 0 {
+			b.AddTx(tc.adminTx(b, addrList, "addBlacklist", banned, uint8(DirectionTo)))
+		}
+	})
+
+	// the version 0 code is kept for the delegation
+	statedb := mustState(t, tc.chain)
+	for _, name := range []string{systemcontract.PunishContractName, systemcontract.AddressListContractName} {
+		code, err := systemcontract.Bytecode(systemcontract.SysContractV1, name)
+		require.NoError(t, err)
+		require.Contains(t, [][]byte{statedb.GetCode(systemcontract.PunishContractAddr), statedb.GetCode(addrList)}, code, name)
+	}
+	require.Equal(t, v0, statedb.GetCode(systemcontract.AddressListV0ContractAddr))
+	require.NotEmpty(t, statedb.GetCode(systemcontract.PunishV0ContractAddr))
+
+	// the migration adds the admin to the developers, and the version 0
+	// state is still there
+	require.Equal(t, []common.Address{testAdmin}, tc.call(alABI, addrList, "getDevelopers")[0])
+	require.Equal(t, []common.Address{banned}, tc.call(alABI, addrList, "getBlacksTo")[0])
+	require.Zero(t, tc.call(tc.engine.abi[systemcontract.PunishContractName], systemcontract.PunishContractAddr, "getPunishRecord", banned)[0].(*big.Int).Sign())
+
+	developer := common.Address{0xbb}
+	key, err := crypto.HexToECDSA(testValidatorKeys[0])
+	require.NoError(t, err)
+	blocks := tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(tc.adminTx(b, addrList, "addDeveloper", developer))
+		b.AddTx(tc.adminTx(b, addrList, "removeDeveloper", testAdmin))
+		b.AddTx(tc.adminTx(b, addrList, "removeBlacklist", banned, uint8(DirectionTo)))
+		// only the admin can update the developers
+		data, err := alABI.Pack("addDeveloper", common.Address{0xcc})
+		require.NoError(t, err)
+		sender := crypto.PubkeyToAddress(key.PublicKey)
+		tx, err := types.SignNewTx(key, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+			Nonce:    b.TxNonce(sender),
+			To:       &addrList,
+			Gas:      1_000_000,
+			GasPrice: b.BaseFee(),
+			Data:     data,
+		})
+		require.NoError(t, err)
+		b.AddTx(tx)
+	})
+	receipts := tc.chain.GetReceiptsByHash(blocks[0].Hash())
+	for i, receipt := range receipts {
+		status := types.ReceiptStatusSuccessful
+		if i == 3 {
+			status = types.ReceiptStatusFailed
+		}
+		require.Equal(t, status, receipt.Status, "tx %d", i)
+	}
+	require.Equal(t, []common.Address{developer}, tc.call(alABI, addrList, "getDevelopers")[0])
+	require.Equal(t, true, tc.call(alABI, addrList, "isDeveloper", developer)[0])
+	require.Equal(t, false, tc.call(alABI, addrList, "isDeveloper", testAdmin)[0])
+	require.Equal(t, blocks[0].Number(), tc.call(alABI, addrList, "developersLastUpdatedNumber")[0])
+	require.Empty(t, tc.call(alABI, addrList, "getBlacksTo")[0])
+}
+
+func TestChainDevVerification(t *testing.T) {
+	tc := newTestChain(t, func(config *params.NposConfig) {
+		config.SysContractV1Block = big.NewInt(2)
+		config.EnableDevVerification = true
+		config.DevVerificationBlock = big.NewInt(3)
+	})
+	key, err := crypto.HexToECDSA(testValidatorKeys[0])
+	require.NoError(t, err)
+	other := crypto.PubkeyToAddress(key.PublicKey)
+	// the factory runtime code runs CREATE(0, 0, 0), then stores the new address at slot 0
+	factoryInit := common.FromHex("0x6a600060006000f060005500600052600b6015f3")
+	create := func(b *core.BlockGen, key *ecdsa.PrivateKey) *types.Transaction {
+		tx, err := types.SignNewTx(key, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+			Nonce:    b.TxNonce(crypto.PubkeyToAddress(key.PublicKey)),
+			Gas:      1_000_000,
+			GasPrice: b.BaseFee(),
+			Data:     factoryInit,
+		})
+		require.NoError(t, err)
+		return tx
+	}
+	call := func(b *core.BlockGen, to common.Address) *types.Transaction {
+		tx, err := types.SignNewTx(testAdminKey, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+			Nonce:    b.TxNonce(testAdmin),
+			To:       &to,
+			Gas:      1_000_000,
+			GasPrice: b.BaseFee(),
+		})
+		require.NoError(t, err)
+		return tx
+	}
+
+	// anyone creates contracts before the fork
+	factory := crypto.CreateAddress(other, 0)
+	tc.extend(2, func(i int, b *core.BlockGen) {
+		if i == 0 {
+			b.AddTx(create(b, key))
+		}
+	})
+	require.NotEmpty(t, mustState(t, tc.chain).GetCode(factory))
+
+	// then only the developers of the address list contract, initially the
+	// admin, the factory isn't one so the admin can't create through it
+	adminFactory := crypto.CreateAddress(testAdmin, mustState(t, tc.chain).GetNonce(testAdmin))
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(create(b, testAdminKey))
+		b.AddTx(call(b, factory))
+	})
+	statedb := mustState(t, tc.chain)
+	require.NotEmpty(t, statedb.GetCode(adminFactory))
+	require.Equal(t, common.Hash{}, statedb.GetState(factory, common.Hash{}))
+
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(tc.adminTx(b, systemcontract.AddressListContractAddr, "addDeveloper", factory))
+	})
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(call(b, factory))
+	})
+	created := mustState(t, tc.chain).GetState(factory, common.Hash{})
+	require.NotEqual(t, common.Hash{}, created)
+
+	// an allowed factory creates contracts for anyone, as the creator is the
+	// factory, not the origin of the transaction
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		tx, err := types.SignNewTx(key, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+			Nonce:    b.TxNonce(other),
+			To:       &factory,
+			Gas:      1_000_000,
+			GasPrice: b.BaseFee(),
+		})
+		require.NoError(t, err)
+		b.AddTx(tx)
+	})
+	require.NotEqual(t, created, mustState(t, tc.chain).GetState(factory, common.Hash{}))
+
+	// the pool and the block validation reject other creators
+	head := tc.chain.CurrentBlock()
+	next := &types.Header{ParentHash: head.Hash(), Number: new(big.Int).Add(head.Number, common.Big1), Difficulty: diffInTurn}
+	tx := types.NewContractCreation(mustState(t, tc.chain).GetNonce(other), common.Big0, 1_000_000, head.BaseFee, factoryInit)
+	require.ErrorIs(t, tc.engine.ValidateTx(other, tx, next, mustState(t, tc.chain)), types.ErrUnauthorizedDeveloper)
+	blocks, _ := core.GenerateSealedChain(tc.config, tc.chain.GetBlock(head.Hash(), head.Number.Uint64()), tc.engine, tc.db, 1, tc, func(i int, b *core.BlockGen) {
+		b.AddTx(create(b, key))
+	})
+	_, err = tc.chain.InsertChain(blocks)
+	require.ErrorIs(t, err, types.ErrUnauthorizedDeveloper)
+}
+
+func TestChainCallRules(t *testing.T) {
+	tc := newTestChain(t, func(config *params.NposConfig) {
+		config.SysContractV1Block = big.NewInt(2)
+		config.SysContractV2Block = big.NewInt(3)
+	})
+	addrList := systemcontract.AddressListContractAddr
+	alABI := tc.engine.abi[systemcontract.AddressListContractName]
+	// the target runtime code stops, and the proxy runtime code calls the
+	// target with its input, then stores the success at the first input word
+	target := crypto.CreateAddress(testAdmin, 0)
+	proxy := crypto.CreateAddress(testAdmin, 1)
+	targetCode := []byte{0x00}
+	proxyCode := append(append(common.FromHex("0x366000600037600060003660006000"), append([]byte{0x73}, target[:]...)...), common.FromHex("0x5af160003555")...)
+	deploy := func(code []byte) []byte {
+		return append([]byte{0x60, byte(len(code)), 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, byte(len(code)), 0x60, 0x00, 0xf3}, code...)
+	}
+	send := func(b *core.BlockGen, to *common.Address, data []byte) *types.Transaction {
+		tx, err := types.SignNewTx(testAdminKey, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+			Nonce:    b.TxNonce(testAdmin),
+			To:       to,
+			Gas:      1_000_000,
+			GasPrice: b.BaseFee(),
+			Data:     data,
+		})
+		require.NoError(t, err)
+		return tx
+	}
+	banned, allowed := [4]byte{0x12, 0x34, 0x56, 0x78}, [4]byte{0x87, 0x65, 0x43, 0x21}
+	input := func(selector [4]byte) []byte {
+		return append(selector[:], make([]byte, 28)...)
+	}
+	succeeded := func(selector [4]byte) bool {
+		word := common.BytesToHash(input(selector))
+		return mustState(t, tc.chain).GetState(proxy, word) == common.BytesToHash([]byte{1})
+	}
+	tc.extend(3, func(i int, b *core.BlockGen) {
+		if i == 0 {
+			b.AddTx(send(b, nil, deploy(targetCode)))
+			b.AddTx(send(b, nil, deploy(proxyCode)))
+		}
+	})
+	code, err := systemcontract.Bytecode(systemcontract.SysContractV2, systemcontract.AddressListContractName)
+	require.NoError(t, err)
+	statedb := mustState(t, tc.chain)
+	require.Equal(t, code, statedb.GetCode(addrList))
+	require.Equal(t, targetCode, statedb.GetCode(target))
+	require.NotEmpty(t, statedb.GetCode(proxy))
+	// the version 1 methods and state are kept
+	require.Equal(t, []common.Address{testAdmin}, tc.call(alABI, addrList, "getDevelopers")[0])
+
+	// a selector rule bans the calls of the selector on the target, from a
+	// transaction or a contract
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(tc.adminTx(b, addrList, "addSelectorRule", target, banned))
+	})
+	key := [32]byte{}
+	copy(key[:], target[:])
+	copy(key[common.AddressLength:], banned[:])
+	require.Equal(t, [][32]byte{key}, tc.call(alABI, addrList, "getSelectorRules")[0])
+	require.Equal(t, tc.chain.CurrentBlock().Number, tc.call(alABI, addrList, "callRulesLastUpdatedNumber")[0])
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(send(b, &proxy, input(banned)))
+		b.AddTx(send(b, &proxy, input(allowed)))
+	})
+	require.False(t, succeeded(banned))
+	require.True(t, succeeded(allowed))
+
+	head := tc.chain.CurrentBlock()
+	next := &types.Header{ParentHash: head.Hash(), Number: new(big.Int).Add(head.Number, common.Big1), Difficulty: diffInTurn}
+	tx := types.NewTransaction(mustState(t, tc.chain).GetNonce(testAdmin), target, common.Big0, 1_000_000, head.BaseFee, input(banned))
+	require.ErrorIs(t, tc.engine.ValidateTx(testAdmin, tx, next, mustState(t, tc.chain)), types.ErrCallBanned)
+	tx = types.NewTransaction(mustState(t, tc.chain).GetNonce(testAdmin), target, common.Big0, 1_000_000, head.BaseFee, input(allowed))
+	require.NoError(t, tc.engine.ValidateTx(testAdmin, tx, next, mustState(t, tc.chain)))
+	blocks, _ := core.GenerateSealedChain(tc.config, tc.chain.GetBlock(head.Hash(), head.Number.Uint64()), tc.engine, tc.db, 1, tc, func(i int, b *core.BlockGen) {
+		b.AddTx(send(b, &target, input(banned)))
+	})
+	_, err = tc.chain.InsertChain(blocks)
+	require.ErrorIs(t, err, types.ErrCallBanned)
+
+	// a code hash rule bans any call to the contracts with the code, and their deployment
+	codeHash := crypto.Keccak256Hash(targetCode)
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(tc.adminTx(b, addrList, "removeSelectorRule", target, banned))
+		b.AddTx(tc.adminTx(b, addrList, "addCodeHashRule", codeHash))
+	})
+	require.Empty(t, tc.call(alABI, addrList, "getSelectorRules")[0])
+	require.Equal(t, [][32]byte{codeHash}, tc.call(alABI, addrList, "getCodeHashRules")[0])
+	clone := crypto.CreateAddress(testAdmin, mustState(t, tc.chain).GetNonce(testAdmin)+1)
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(send(b, &proxy, input(banned)))
+		b.AddTx(send(b, nil, deploy(targetCode)))
+	})
+	require.False(t, succeeded(banned))
+	require.Empty(t, mustState(t, tc.chain).GetCode(clone))
+
+	// the calls are allowed again once the rule is removed
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(tc.adminTx(b, addrList, "removeCodeHashRule", codeHash))
+	})
+	require.Empty(t, tc.call(alABI, addrList, "getCodeHashRules")[0])
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(send(b, &proxy, input(banned)))
+	})
+	require.True(t, succeeded(banned))
+}
+
+func TestChainFeeRecoderProtection(t *testing.T) {
+	tc := newTestChain(t, func(config *params.NposConfig) {
+		config.FeeRecoderProtectionBlock = big.NewInt(2)
+	})
+	send := func(b *core.BlockGen, value *big.Int) *types.Transaction {
+		tx, err := types.SignNewTx(testAdminKey, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+			Nonce:    b.TxNonce(testAdmin),
+			To:       &consensus.FeeRecoder,
+			Value:    value,
+			Gas:      params.TxGas,
+			GasPrice: new(big.Int).Mul(b.BaseFee(), big.NewInt(2)),
+		})
+		require.NoError(t, err)
+		return tx
+	}
+	// the transfers to the FeeRecoder fail from the protection block on, and
+	// the receipts attribute the fees to the FeeRecoder
+	balance := mustState(t, tc.chain).GetBalance(testAdmin)
+	blocks := tc.extend(2, func(i int, b *core.BlockGen) {
+		b.AddTx(send(b, big.NewInt(params.Ether)))
+	})
+	spent := new(big.Int).SetUint64(params.Ether)
+	for i, block := range blocks {
+		receipts := tc.chain.GetReceiptsByHash(block.Hash())
+		require.Len(t, receipts, 1)
+		want := types.ReceiptStatusSuccessful
+		if i == 1 {
+			want = types.ReceiptStatusFailed
+		}
+		require.Equal(t, want, receipts[0].Status)
+		fee := new(big.Int).Mul(new(big.Int).SetUint64(receipts[0].GasUsed), block.Transactions()[0].GasPrice())
+		require.Zero(t, fee.Cmp(receipts[0].Fee))
+		require.Equal(t, consensus.FeeRecoder, *receipts[0].FeeRecipient)
+		spent.Add(spent, fee)
+	}
+	require.Zero(t, spent.Cmp(balance.Sub(balance, mustState(t, tc.chain).GetBalance(testAdmin))))
+
+	// a zero value call is still allowed
+	blocks = tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(send(b, common.Big0))
+	})
+	require.Equal(t, types.ReceiptStatusSuccessful, tc.chain.GetReceiptsByHash(blocks[0].Hash())[0].Status)
+
+	// a self-destruct to the FeeRecoder fails without destroying the contract
+	// nor crediting its balance: PUSH20 <FeeRecoder> SELFDESTRUCT
+	runtime := append(append([]byte{byte(vm.PUSH20)}, consensus.FeeRecoder.Bytes()...), byte(vm.SELFDESTRUCT))
+	initCode := append(common.FromHex("0x6016600c60003960166000f3"), runtime...)
+	var contract common.Address
+	blocks = tc.extend(2, func(i int, b *core.BlockGen) {
+		tx := &types.LegacyTx{Nonce: b.TxNonce(testAdmin), Gas: 200000, GasPrice: new(big.Int).Mul(b.BaseFee(), big.NewInt(2))}
+		if i == 0 {
+			contract = crypto.CreateAddress(testAdmin, tx.Nonce)
+			tx.Value, tx.Data = big.NewInt(params.Ether), initCode
+		} else {
+			tx.To = &contract
+		}
+		signed, err := types.SignNewTx(testAdminKey, types.LatestSignerForChainID(tc.config.ChainID), tx)
+		require.NoError(t, err)
+		b.AddTx(signed)
+	})
+	require.Equal(t, types.ReceiptStatusSuccessful, tc.chain.GetReceiptsByHash(blocks[0].Hash())[0].Status)
+	require.Equal(t, types.ReceiptStatusFailed, tc.chain.GetReceiptsByHash(blocks[1].Hash())[0].Status)
+	call := rawdb.ReadInternalTxs(tc.db, blocks[1].Hash(), blocks[1].NumberU64())[0].Actions[0]
+	require.Equal(t, vm.ErrFeeRecoderTransfer.Error(), call.Error)
+	statedb := mustState(t, tc.chain)
+	require.Equal(t, runtime, statedb.GetCode(contract))
+	require.Zero(t, big.NewInt(params.Ether).Cmp(statedb.GetBalance(contract)))
+}
+
+func TestChainEventDataChecks(t *testing.T) {
+	tc := newTestChain(t, func(config *params.NposConfig) {
+		config.SysContractV1Block = big.NewInt(2)
+		config.SysContractV2Block = big.NewInt(4)
+	})
+	var (
+		addrList = systemcontract.AddressListContractAddr
+		banned   = common.Address{0x01}
+		sig      = common.Hash{0xab}
+		emitter  = crypto.CreateAddress(testAdmin, 0)
+	)
+	// the emitter runtime code logs its first input word as the data of an
+	// event with a single topic
+	emitterCode := append(append(common.FromHex("0x6000356000527f"), sig[:]...), common.FromHex("0x60206000a100")...)
+	deploy := append([]byte{0x60, byte(len(emitterCode)), 0x60, 0x0c, 0x60, 0x00, 0x39, 0x60, byte(len(emitterCode)), 0x60, 0x00, 0xf3}, emitterCode...)
+	send := func(b *core.BlockGen, to *common.Address, data []byte) *types.Transaction {
+		tx, err := types.SignNewTx(testAdminKey, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+			Nonce:    b.TxNonce(testAdmin),
+			To:       to,
+			Gas:      1_000_000,
+			GasPrice: b.BaseFee(),
+			Data:     data,
+		})
+		require.NoError(t, err)
+		return tx
+	}
+	emit := func(b *core.BlockGen) {
+		b.AddTx(send(b, &emitter, common.BytesToHash(banned[:]).Bytes()))
+	}
+	status := func(n int, gen func(*core.BlockGen)) uint64 {
+		blocks := tc.extend(n, func(i int, b *core.BlockGen) {
+			if i == n-1 {
+				gen(b)
+			}
+		})
+		receipts := tc.chain.GetReceiptsByHash(blocks[n-1].Hash())
+		return receipts[len(receipts)-1].Status
+	}
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(send(b, nil, deploy))
+		b.AddTx(tc.adminTx(b, addrList, "addBlacklist", banned, uint8(DirectionTo)))
+		b.AddTx(tc.adminTx(b, addrList, "addOrUpdateRule", sig, new(big.Int).SetUint64(DataCheckIdxBase), uint8(common.CheckTo)))
+	})
+	rules, err := tc.engine.readEventCheckRules(tc.chain.CurrentHeader(), mustState(t, tc.chain))
+	require.NoError(t, err)
+	require.Equal(t, map[int]common.AddressCheckType{0: common.CheckTo}, rules[sig].DataChecks)
+
+	// the log data is checked from the system contracts v2 on
+	require.Equal(t, types.ReceiptStatusSuccessful, status(1, emit))
+	require.Equal(t, types.ReceiptStatusFailed, status(3, emit))
+}
+
+func TestChainDoubleSign(t *testing.T) {
+	tc := newTestChain(t, func(config *params.NposConfig) {
+		config.SysContractV1Block = big.NewInt(2)
+		config.DoubleSignBlock = big.NewInt(2)
+	})
+	blocks := tc.extend(3, nil)
+
+	// let the signer of block 3 seal a conflicting block
+	offender := blocks[2].Coinbase()
+	fork, _ := core.GenerateSealedChain(tc.config, blocks[1], tc.engine, tc.db, 1, tc, func(i int, b *core.BlockGen) {
+		to := common.Address{0xbb}
+		tx, err := types.SignNewTx(testAdminKey, types.LatestSignerForChainID(tc.config.ChainID), &types.LegacyTx{
+			Nonce:    b.TxNonce(testAdmin),
+			To:       &to,
+			Gas:      params.TxGas,
+			GasPrice: b.BaseFee(),
+			Value:    common.Big1,
+		})
+		require.NoError(t, err)
+		b.AddTx(tx)
+	})
+	require.NotEqual(t, blocks[2].Hash(), fork[0].Hash())
+	require.Equal(t, offender, fork[0].Coinbase())
+
+	// the evidence is collected by the header verification
+	for _, header := range []*types.Header{blocks[2].Header(), fork[0].Header()} {
+		require.NoError(t, tc.engine.VerifyHeader(tc.chain, header, true))
+	}
+	evs := tc.engine.PendingEvidences()
+	require.Len(t, evs, 1)
+
+	// and submitted by the next block, which the chain replays
+	blocks = tc.extend(1, nil)
+	txs := blocks[0].Transactions()
+	require.Len(t, txs, 1)
+	require.Equal(t, systemcontract.DoubleSignEvidenceToAddr, *txs[0].To())
+	receipts := tc.chain.GetReceiptsByHash(blocks[0].Hash())
+	require.Equal(t, types.ReceiptStatusSuccessful, receipts[0].Status)
+	require.Empty(t, tc.engine.PendingEvidences())
+
+	// the evidence is recorded as a call of the engine in the evidence transaction
+	var punished bool
+	for _, action := range internalTx(t, tc.traceAll(blocks[0]), txs[0].Hash()).Actions {
+		if action.Depth == types.EngineActionDepth && action.To == systemcontract.PunishContractAddr {
+			require.Equal(t, systemcontract.EngineCaller, action.From)
+			require.True(t, action.Success)
+			punished = true
+		}
+	}
+	require.True(t, punished)
+
+	punishABI := tc.engine.abi[systemcontract.PunishContractName]
+	require.Equal(t, true, tc.call(punishABI, systemcontract.PunishContractAddr, "isDoubleSignPunished", offenceHash(offender, 3))[0])
+	pool := tc.call(tc.engine.abi[systemcontract.ValidatorsContractName], systemcontract.ValidatorsContractAddr, "votePools", offender)[0].(common.Address)
+	poolABI := tc.engine.abi[systemcontract.VotePoolContractName]
+	require.Equal(t, uint8(votePoolStateJail), tc.call(poolABI, pool, "state")[0])
+	require.Equal(t, blocks[0].Number(), tc.call(poolABI, pool, "punishBlk")[0])
+}
+
+func TestChainAttestations(t *testing.T) {
+	tc := newTestChain(t, func(config *params.NposConfig) {
+		config.SysContractV1Block = big.NewInt(2)
+		config.AttestationBlock = big.NewInt(5)
+	})
+	blocks := tc.extend(13, nil)
+
+	// the first checkpoint of the fork is the root, the validators attest the
+	// link to the next one, which finalizes the root once justified
+	for _, block := range blocks {
+		attestation, err := parseAttestation(tc.config.Npos, block.Header())
+		require.NoError(t, err)
+		if number := block.NumberU64(); number > 10 {
+			require.NotNil(t, attestation, "block %d", number)
+			require.Equal(t, uint64(5), attestation.Data.Source.Number)
+			require.Equal(t, tc.chain.GetHeaderByNumber(10).Hash(), attestation.Data.Target.Hash)
+		} else {
+			require.Nil(t, attestation, "block %d", number)
+		}
+	}
+	require.Equal(t, uint64(10), tc.snapshot().Justified.Number)
+	require.Equal(t, tc.chain.GetHeaderByNumber(5).Hash(), tc.finalized(5).Hash())
+
+	tc.extend(5, nil)
+	require.Equal(t, uint64(15), tc.snapshot().Justified.Number)
+	require.Equal(t, tc.chain.GetHeaderByNumber(10).Hash(), tc.finalized(10).Hash())
+
+	// a heavier branch conflicting with the finalized checkpoint is refused
+	head := tc.chain.CurrentBlock().Hash()
+	fork, _ := core.GenerateSealedChain(tc.config, tc.chain.GetBlockByNumber(8), tc.engine, tc.db, 12, tc, func(i int, b *core.BlockGen) {
+		if i == 0 {
+			b.AddTx(tc.adminTx(b, systemcontract.AddressListContractAddr, "addDeveloper", common.Address{0xbb}))
+		}
+	})
+	_, err := tc.chain.InsertChain(fork)
+	require.NoError(t, err)
+	require.Greater(t, tc.chain.GetTd(fork[11].Hash(), 20).Cmp(tc.chain.GetTd(head, 18)), 0)
+	require.Equal(t, head, tc.chain.CurrentBlock().Hash())
+}
+
+// prunedChain is a header reader of a chain which misses the headers below a
+// checkpoint, like a light client synced from a CHT.
+type prunedChain struct {
+	*core.BlockChain
+	tail uint64
+}
+
+func (c *prunedChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if number < c.tail {
+		return nil
+	}
+	return c.BlockChain.GetHeader(hash, number)
+}
+
+func (c *prunedChain) GetHeaderByNumber(number uint64) *types.Header {
+	if number < c.tail {
+		return nil
+	}
+	return c.BlockChain.GetHeaderByNumber(number)
+}
+
+func TestChainCheckpointSnapshot(t *testing.T) {
+	tc := newTestChain(t, func(config *params.NposConfig) {
+		config.SysContractV1Block = big.NewInt(2)
+		config.AttestationBlock = big.NewInt(5)
+	})
+	blocks := tc.extend(18, nil)
+
+	// the epoch headers carry the checkpoints of their parent snapshot
+	justified, finalized, ok, err := parseCheckpoints(tc.config.Npos, blocks[14].Header())
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, BlockRef{Number: 10, Hash: blocks[9].Hash()}, justified)
+	require.Equal(t, BlockRef{Number: 5, Hash: blocks[4].Hash()}, finalized)
+
+	// an engine without the headers before the checkpoint 15 rebuilds its
+	// snapshot from the header, and accepts the attestations built on it
+	engine := New(tc.config, rawdb.NewMemoryDatabase())
+	engine.SetStateFn(tc.chain.StateAt)
+	engine.SetChain(tc.chain)
+	chain := &prunedChain{BlockChain: tc.chain, tail: 15}
+	for _, block := range blocks[15:] {
+		attestation, err := parseAttestation(tc.config.Npos, block.Header())
+		require.NoError(t, err)
+		require.NotNil(t, attestation)
+		require.NoError(t, engine.VerifyHeader(chain, block.Header(), true), "block %d", block.NumberU64())
+	}
+	snap, err := engine.snapshot(chain, 18, blocks[17].Hash(), nil)
+	require.NoError(t, err)
+	want := tc.snapshot()
+	require.Equal(t, want.Justified, snap.Justified)
+	require.Equal(t, want.Finalized, snap.Finalized)
+	require.Equal(t, uint64(15), snap.Justified.Number)
+
+	// an epoch header carrying other checkpoints is refused
+	header := types.CopyHeader(blocks[14].Header())
+	end := len(header.Extra) - extraSuffix(tc.config.Npos, header.Number)
+	header.Extra[end-1]++
+	require.ErrorIs(t, tc.engine.verifyCascadingFields(tc.chain, header, nil), errInvalidCheckpoints)
+}
+
+func TestChainConflictingAttestations(t *testing.T) {
+	tc := newTestChain(t, func(config *params.NposConfig) {
+		config.SysContractV1Block = big.NewInt(2)
+		config.AttestationBlock = big.NewInt(5)
+	})
+	blocks := tc.extend(13, nil)
+
+	// the validators attested the checkpoint 10, let one of them attest a
+	// conflicting checkpoint on another branch with an engine which doesn't
+	// know its attestations
+	engine := tc.engine
+	tc.engine = New(tc.config, tc.db)
+	tc.engine.SetStateFn(tc.chain.StateAt)
+	tc.engine.SetChain(tc.chain)
+	fork, _ := core.GenerateSealedChain(tc.config, blocks[8], tc.engine, tc.db, 2, tc, func(i int, b *core.BlockGen) {
+		if i == 0 {
+			b.AddTx(tc.adminTx(b, systemcontract.AddressListContractAddr, "addDeveloper", common.Address{0xbb}))
+		}
+	})
+	tc.engine = engine
+	offender := fork[1].Coinbase()
+	attestation, err := parseAttestation(tc.config.Npos, fork[1].Header())
+	require.NoError(t, err)
+	require.Equal(t, fork[0].Hash(), attestation.Data.Target.Hash)
+
+	// the conflict is collected by the header verification
+	_, results := tc.engine.VerifyHeaders(tc.chain, []*types.Header{fork[0].Header(), fork[1].Header()}, []bool{true, true})
+	for range fork {
+		require.NoError(t, <-results)
+	}
+	evs := tc.engine.PendingAttestationEvidences()
+	require.Len(t, evs, 1)
+
+	// and submitted by the next block, which the chain replays
+	blocks = tc.extend(1, nil)
+	txs := blocks[0].Transactions()
+	require.Len(t, txs, 1)
+	require.Equal(t, systemcontract.AttestationEvidenceToAddr, *txs[0].To())
+	receipts := tc.chain.GetReceiptsByHash(blocks[0].Hash())
+	require.Equal(t, types.ReceiptStatusSuccessful, receipts[0].Status)
+	require.Empty(t, tc.engine.PendingAttestationEvidences())
+
+	punishABI := tc.engine.abi[systemcontract.PunishContractName]
+	require.Equal(t, true, tc.call(punishABI, systemcontract.PunishContractAddr, "isDoubleSignPunished", attestationOffenceHash(offender, 10))[0])
+	pool := tc.call(tc.engine.abi[systemcontract.ValidatorsContractName], systemcontract.ValidatorsContractAddr, "votePools", offender)[0].(common.Address)
+	require.Equal(t, uint8(votePoolStateJail), tc.call(tc.engine.abi[systemcontract.VotePoolContractName], pool, "state")[0])
+}
+
+func mustState(t *testing.T, chain *core.BlockChain) *state.StateDB {
+	t.Helper()
+	statedb, err := chain.State()
+	require.NoError(t, err)
+	return statedb
+}
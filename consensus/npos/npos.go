@@ -55,16 +55,47 @@ const (
 	maxValidators = 21                     // Max validators allowed to seal.
 
 	inmemoryBlacklist = 21 // Number of recent blacklist snapshots to keep in memory
+	inmemoryFees      = 16 // Number of recently finalized blocks to keep the fee breakdowns of, until they're written
 )
 
-type bannedDirection uint
+// BannedDirection is the direction of the transfers an address of the blacklist
+// is banned from, as defined by the address list contract.
+type BannedDirection uint
 
 const (
-	DirectionFrom bannedDirection = iota
+	DirectionFrom BannedDirection = iota
 	DirectionTo
 	DirectionBoth
 )
 
+var bannedDirectionNames = []string{"from", "to", "both"}
+
+func (d BannedDirection) String() string {
+	if int(d) < len(bannedDirectionNames) {
+		return bannedDirectionNames[d]
+	}
+	return fmt.Sprintf("unknown(%d)", uint(d))
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d BannedDirection) MarshalText() ([]byte, error) {
+	if int(d) >= len(bannedDirectionNames) {
+		return nil, fmt.Errorf("unknown banned direction %d", uint(d))
+	}
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *BannedDirection) UnmarshalText(input []byte) error {
+	for i, name := range bannedDirectionNames {
+		if name == string(input) {
+			*d = BannedDirection(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown banned direction %q", input)
+}
+
 // NPoS proof-of-stake-authority protocol constants.
 var (
 	epochLength = uint64(200) // Default number of blocks after which to checkpoint and ranking the current votes
@@ -95,6 +126,10 @@ var (
 	// to contain a 65 byte secp256k1 signature.
 	errMissingSignature = errors.New("extra-data 65 byte signature suffix missing")
 
+	// errMissingAttestation is returned if a block's extra-data section doesn't
+	// contain the attestation of the signer since the attestation fork.
+	errMissingAttestation = errors.New("extra-data attestation missing")
+
 	// errExtraValidators is returned if non-checkpoint block contain validator data in
 	// their extra-data fields.
 	errExtraValidators = errors.New("non-checkpoint block contains extra validator list")
@@ -154,6 +189,24 @@ type StateFn func(hash common.Hash) (*state.StateDB, error)
 type ValidatorFn func(validator accounts.Account, mimeType string, message []byte) ([]byte, error)
 type SignTxFn func(account accounts.Account, tx *types.Transaction, chainID *big.Int) (*types.Transaction, error)
 
+// parseValidators extracts the validator list from the extra-data of a
+// checkpoint header.
+func parseValidators(config *params.NposConfig, header *types.Header) ([]common.Address, error) {
+	suffix := extraSuffix(config, header.Number) + extraScheduleLength(config, header.Number) + extraCheckpointsLength(config, header.Number)
+	if len(header.Extra) < extraVanity+suffix {
+		return nil, errMissingSignature
+	}
+	validatorsBytes := header.Extra[extraVanity : len(header.Extra)-suffix]
+	if len(validatorsBytes)%common.AddressLength != 0 {
+		return nil, errInvalidCheckpointValidators
+	}
+	validators := make([]common.Address, len(validatorsBytes)/common.AddressLength)
+	for i := 0; i < len(validators); i++ {
+		copy(validators[i][:], validatorsBytes[i*common.AddressLength:])
+	}
+	return validators, nil
+}
+
 // ecrecover extracts the Ethereum account address from a signed header.
 func ecrecover(header *types.Header, sigcache *lru.ARCCache) (common.Address, error) {
 	// If the signature's already cached, return that
@@ -193,9 +246,19 @@ type Npos struct {
 	blLock          sync.Mutex // Make sure only get blacklist once for each block
 	eventCheckRules *lru.Cache // eventCheckRules caches recent EventCheckRules to speed up log validation
 	rulesLock       sync.Mutex // Make sure only get eventCheckRules once for each block
+	developers      *lru.Cache // developers caches recent developer lists to speed up contract creation validation
+	devLock         sync.Mutex // Make sure only get developers once for each block
+	callRules       *lru.Cache // callRules caches recent call rules to speed up call validation
+	callRulesLock   sync.Mutex // Make sure only get call rules once for each block
+	indexRebuilding int32      // Whether the address list index is being rebuilt, accessed atomically
+
+	fees *lru.Cache // Fee breakdowns of the recently finalized blocks, by state root
 
 	proposals map[common.Address]bool // Current list of proposals we are pushing
 
+	evidences    *evidencePool    // Double-sign evidences collected during header verification
+	attestations *attestationPool // Attestations and their conflicts collected during header verification
+
 	signer types.Signer // the signer instance to recover tx sender
 
 	validator common.Address // Ethereum address of the signing key
@@ -221,11 +284,17 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database) *Npos {
 	if conf.Epoch == 0 {
 		conf.Epoch = epochLength
 	}
+	if err := validateConfig(chainConfig, &conf); err != nil {
+		log.Crit("Invalid NPoS config", "err", err)
+	}
 	// Allocate the snapshot caches and create the engine
 	recents, _ := lru.NewARC(inmemorySnapshots)
 	signatures, _ := lru.NewARC(inmemorySignatures)
 	blacklists, _ := lru.New(inmemoryBlacklist)
 	rules, _ := lru.New(inmemoryBlacklist)
+	developers, _ := lru.New(inmemoryBlacklist)
+	callRules, _ := lru.New(inmemoryBlacklist)
+	fees, _ := lru.New(inmemoryFees)
 
 	abi := systemcontract.GetInteractiveABI()
 
@@ -237,12 +306,51 @@ func New(chainConfig *params.ChainConfig, db ethdb.Database) *Npos {
 		signatures:      signatures,
 		blacklists:      blacklists,
 		eventCheckRules: rules,
+		developers:      developers,
+		callRules:       callRules,
+		fees:            fees,
 		proposals:       make(map[common.Address]bool),
+		evidences:       newEvidencePool(),
+		attestations:    newAttestationPool(),
 		abi:             abi,
 		signer:          types.LatestSignerForChainID(chainConfig.ChainID),
 	}
 }
 
+// validateConfig checks the forks of the config can be applied, so that a node
+// refuses to start rather than halting the chain at a fork.
+func validateConfig(chainConfig *params.ChainConfig, config *params.NposConfig) error {
+	if err := systemcontract.ValidateUpgrades(config); err != nil {
+		return err
+	}
+	// the double-sign slashing needs the Punish contract v1
+	if config.DoubleSignBlock != nil && (config.SysContractV1Block == nil || config.DoubleSignBlock.Cmp(config.SysContractV1Block) < 0) {
+		return fmt.Errorf("double-sign slashing at block %v before the system contracts v1", config.DoubleSignBlock)
+	}
+	// the conflicting attestations are punished by the Punish contract v1, and
+	// the first checkpoint of the fork is the root of the justified checkpoints
+	if config.AttestationBlock != nil {
+		if config.SysContractV1Block == nil || config.AttestationBlock.Cmp(config.SysContractV1Block) < 0 {
+			return fmt.Errorf("attestations at block %v before the system contracts v1", config.AttestationBlock)
+		}
+		if config.AttestationBlock.Uint64()%config.Epoch != 0 {
+			return fmt.Errorf("attestations at block %v not at an epoch", config.AttestationBlock)
+		}
+	}
+	if config.SysContractV2Block != nil && (config.SysContractV1Block == nil || config.SysContractV2Block.Cmp(config.SysContractV1Block) < 0) {
+		return fmt.Errorf("system contracts v2 at block %v before the system contracts v1", config.SysContractV2Block)
+	}
+	// the developers are read from the AddressList contract v1 in the parent state
+	if config.DevVerificationBlock != nil && (config.SysContractV1Block == nil || config.DevVerificationBlock.Cmp(config.SysContractV1Block) <= 0) {
+		return fmt.Errorf("developer verification at block %v not after the system contracts v1", config.DevVerificationBlock)
+	}
+	// only the blocks with a base fee can burn it
+	if config.FeeBurnBlock != nil && !chainConfig.IsLondon(config.FeeBurnBlock) {
+		return fmt.Errorf("base fee burn at block %v before london", config.FeeBurnBlock)
+	}
+	return nil
+}
+
 func (c *Npos) SetChain(chain consensus.ChainHeaderReader) {
 	c.chain = chain
 }
@@ -253,10 +361,37 @@ func (c *Npos) SetStateFn(fn StateFn) {
 }
 
 // Author implements consensus.Engine, returning the Ethereum address recovered
-// from the signature in the header's extra-data section.
+// from the signature in the header's extra-data section. A header which is not
+// sealed yet has no author, its coinbase is passed explicitly to the EVM instead.
 func (c *Npos) Author(header *types.Header) (common.Address, error) {
-	return header.Coinbase, nil
-	// return ecrecover(header, c.signatures)
+	return ecrecover(header, c.signatures)
+}
+
+// verifyCoinbase recovers the signer of a header, and checks it's the same as the coinbase.
+func (c *Npos) verifyCoinbase(header *types.Header) (common.Address, error) {
+	signer, err := ecrecover(header, c.signatures)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if signer != header.Coinbase {
+		return signer, errInvalidCoinbase
+	}
+	return signer, nil
+}
+
+// GetFinalizedHeader implements consensus.FinalityEngine, returning the latest checkpoint
+// finalized by the attestations of the validators on the chain of the given header.
+// Nothing is finalized before the attestation fork.
+func (c *Npos) GetFinalizedHeader(chain consensus.ChainHeaderReader, header *types.Header) *types.Header {
+	snap, err := c.snapshot(chain, header.Number.Uint64(), header.Hash(), nil)
+	if err != nil {
+		log.Debug("Failed to get snapshot for finality", "number", header.Number, "hash", header.Hash(), "err", err)
+		return nil
+	}
+	if snap.Finalized.Hash == (common.Hash{}) {
+		return nil
+	}
+	return chain.GetHeader(snap.Finalized.Hash, snap.Finalized.Number)
 }
 
 // VerifyHeader checks whether a header conforms to the consensus rules.
@@ -306,11 +441,15 @@ func (c *Npos) verifyHeader(chain consensus.ChainHeaderReader, header *types.Hea
 	if len(header.Extra) < extraVanity+extraSeal {
 		return errMissingSignature
 	}
+	suffix := extraSuffix(c.config, header.Number)
+	if len(header.Extra) < extraVanity+suffix {
+		return errMissingAttestation
+	}
 	// check extra data
 	isEpoch := number%c.config.Epoch == 0
 
 	// Ensure that the extra-data contains a validator list on checkpoint, but none otherwise
-	validatorsBytes := len(header.Extra) - extraVanity - extraSeal
+	validatorsBytes := len(header.Extra) - extraVanity - suffix - extraScheduleLength(c.config, header.Number) - extraCheckpointsLength(c.config, header.Number)
 	if !isEpoch && validatorsBytes != 0 {
 		return errExtraValidators
 	}
@@ -362,8 +501,40 @@ func (c *Npos) verifyCascadingFields(chain consensus.ChainHeaderReader, header *
 		return consensus.ErrUnknownAncestor
 	}
 
-	if parent.Time+c.config.Period > header.Time {
-		return ErrInvalidTimestamp
+	// Once the period can be updated by governance, it's announced by the epoch headers
+	if !c.config.IsGovActions(header.Number) {
+		if parent.Time+c.config.Period > header.Time {
+			return ErrInvalidTimestamp
+		}
+	} else {
+		snap, err := c.snapshot(chain, number-1, header.ParentHash, parents)
+		if err != nil {
+			return err
+		}
+		if parent.Time+snap.Period.at(number) > header.Time {
+			return ErrInvalidTimestamp
+		}
+		schedule, err := parsePeriodSchedule(c.config, header)
+		if err != nil {
+			return err
+		}
+		if schedule != nil && schedule.Period != snap.Period.at(number) {
+			return errInvalidPeriodSchedule
+		}
+	}
+	// The epoch headers carry the checkpoints of the parent snapshot
+	if extraCheckpointsLength(c.config, header.Number) > 0 {
+		snap, err := c.snapshot(chain, number-1, header.ParentHash, parents)
+		if err != nil {
+			return err
+		}
+		justified, finalized, _, err := parseCheckpoints(c.config, header)
+		if err != nil {
+			return err
+		}
+		if justified != snap.Justified || finalized != snap.Finalized {
+			return errInvalidCheckpoints
+		}
 	}
 
 	// Verify that the gasUsed is <= gasLimit
@@ -417,11 +588,28 @@ func (c *Npos) snapshot(chain consensus.ChainHeaderReader, number uint64, hash c
 			if checkpoint != nil {
 				hash := checkpoint.Hash()
 
-				validators := make([]common.Address, (len(checkpoint.Extra)-extraVanity-extraSeal)/common.AddressLength)
-				for i := 0; i < len(validators); i++ {
-					copy(validators[i][:], checkpoint.Extra[extraVanity+i*common.AddressLength:])
+				validators, err := parseValidators(c.config, checkpoint)
+				if err != nil {
+					return nil, err
 				}
 				snap = newSnapshot(c.config, c.signatures, number, hash, validators)
+				schedule, err := parsePeriodSchedule(c.config, checkpoint)
+				if err != nil {
+					return nil, err
+				}
+				if schedule != nil {
+					snap.Period = *schedule
+				}
+				justified, finalized, ok, err := parseCheckpoints(c.config, checkpoint)
+				if err != nil {
+					return nil, err
+				}
+				if ok {
+					if justified != (BlockRef{}) {
+						snap.Justified = justified
+					}
+					snap.Finalized = finalized
+				}
 				if err := snap.store(c.db); err != nil {
 					return nil, err
 				}
@@ -500,13 +688,10 @@ func (c *Npos) verifySeal(chain consensus.ChainHeaderReader, header *types.Heade
 	}
 
 	// Resolve the authorization key and check against validators
-	signer, err := ecrecover(header, c.signatures)
+	signer, err := c.verifyCoinbase(header)
 	if err != nil {
 		return err
 	}
-	if signer != header.Coinbase {
-		return errInvalidCoinbase
-	}
 
 	if _, ok := snap.Validators[signer]; !ok {
 		return errUnauthorizedValidator
@@ -531,6 +716,12 @@ func (c *Npos) verifySeal(chain consensus.ChainHeaderReader, header *types.Heade
 			return errWrongDifficulty
 		}
 	}
+	// The attestation must link the checkpoints of the parent snapshot
+	if err := c.verifyAttestation(snap, header, signer); err != nil {
+		return err
+	}
+	// Keep track of the sealed header to detect double signs
+	c.evidences.add(header, signer)
 
 	return nil
 }
@@ -542,6 +733,12 @@ func (c *Npos) PreHandle(chain consensus.ChainHeaderReader, header *types.Header
 			panic(err)
 		}
 	}
+	// Upgrade the system contracts at the forks of their versions.
+	for _, version := range systemcontract.UpgradesAt(c.config, header.Number) {
+		if err := systemcontract.ApplySystemContractUpgrade(version, state, header, newChainContext(chain, c), c.chainConfig); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -581,8 +778,19 @@ func (c *Npos) Prepare(chain consensus.ChainHeaderReader, header *types.Header)
 		for _, validator := range newSortedValidators {
 			header.Extra = append(header.Extra, validator.Bytes()...)
 		}
+		if extraScheduleLength(c.config, header.Number) > 0 {
+			schedule, err := c.periodSchedule(chain, snap, header)
+			if err != nil {
+				return err
+			}
+			header.Extra = append(header.Extra, schedule.bytes()...)
+		}
+		if extraCheckpointsLength(c.config, header.Number) > 0 {
+			header.Extra = append(header.Extra, snap.checkpointsBytes()...)
+		}
 	}
-	header.Extra = append(header.Extra, make([]byte, extraSeal)...)
+	// The attestation is signed along with the seal, it's abstained until then
+	header.Extra = append(header.Extra, make([]byte, extraSuffix(c.config, header.Number))...)
 
 	// Mix digest is reserved for now, set to empty
 	header.MixDigest = common.Hash{}
@@ -592,7 +800,7 @@ func (c *Npos) Prepare(chain consensus.ChainHeaderReader, header *types.Header)
 	if parent == nil {
 		return consensus.ErrUnknownAncestor
 	}
-	header.Time = parent.Time + c.config.Period
+	header.Time = parent.Time + snap.Period.at(number)
 	if header.Time < uint64(time.Now().Unix()) {
 		header.Time = uint64(time.Now().Unix())
 	}
@@ -602,16 +810,42 @@ func (c *Npos) Prepare(chain consensus.ChainHeaderReader, header *types.Header)
 // Finalize implements consensus.Engine, ensuring no uncles are set, nor block
 // rewards given.
 func (c *Npos) Finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs *[]*types.Transaction, uncles []*types.Header, receipts *[]*types.Receipt, systemTxs []*types.Transaction) error {
-	ctx := &systemcontract.CallContext{
+	return c.finalize(chain, header, state, txs, receipts, systemTxs, nil)
+}
+
+// FinalizeWithInternalTxs implements consensus.PoSA, finalizing the block as
+// Finalize does and returning the internal txs of the block reward distribution
+// and of the system governance transactions.
+func (c *Npos) FinalizeWithInternalTxs(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs *[]*types.Transaction, uncles []*types.Header, receipts *[]*types.Receipt, systemTxs []*types.Transaction, traceAll bool) (types.InternalTxs, error) {
+	recorder := newInternalTxRecorder(traceAll)
+	if err := c.finalize(chain, header, state, txs, receipts, systemTxs, recorder); err != nil {
+		return nil, err
+	}
+	return recorder.internalTxs, nil
+}
+
+func (c *Npos) finalize(chain consensus.ChainHeaderReader, header *types.Header, state *state.StateDB, txs *[]*types.Transaction, receipts *[]*types.Receipt, systemTxs []*types.Transaction, recorder *internalTxRecorder) error {
+	ctx := recorder.traced(&systemcontract.CallContext{
 		Statedb:      state,
 		Header:       header,
 		ChainContext: newChainContext(chain, c),
 		ChainConfig:  c.chainConfig,
+	})
+	// The transactions can't schedule config updates, so the state holds the ones of the parent.
+	if extraScheduleLength(c.config, header.Number) > 0 {
+		schedule, err := parsePeriodSchedule(c.config, header)
+		if err != nil {
+			return err
+		}
+		if value, block := scheduledConfig(state, "period", header.Number.Uint64()); schedule.Pending != value || schedule.Block != block {
+			return errInvalidPeriodSchedule
+		}
 	}
 	if header.Difficulty.Cmp(diffInTurn) != 0 {
 		if err := c.tryPunishValidator(ctx, chain); err != nil {
 			return err
 		}
+		recorder.record(common.Hash{}, false)
 	}
 
 	// avoid nil pointer
@@ -625,8 +859,10 @@ func (c *Npos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 	}
 
 	// execute block reward tx.
+	var fees *feeBreakdown
 	if len(*txs) > 0 {
-		if err := c.trySendBlockReward(ctx); err != nil {
+		fees = c.newFeeBreakdown(header, *receipts)
+		if err := c.trySendBlockReward(ctx, recorder, fees); err != nil {
 			return err
 		}
 	}
@@ -637,14 +873,15 @@ func (c *Npos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 		if err != nil {
 			return err
 		}
+		recorder.record(common.Hash{}, false)
 
 		validatorsBytes := make([]byte, len(newValidators)*common.AddressLength)
 		for i, validator := range newValidators {
 			copy(validatorsBytes[i*common.AddressLength:], validator.Bytes())
 		}
 
-		extraSuffix := len(header.Extra) - extraSeal
-		if !bytes.Equal(header.Extra[extraVanity:extraSuffix], validatorsBytes) {
+		suffix := len(header.Extra) - extraSuffix(c.config, header.Number) - extraScheduleLength(c.config, header.Number) - extraCheckpointsLength(c.config, header.Number)
+		if !bytes.Equal(header.Extra[extraVanity:suffix], validatorsBytes) {
 			return errMismatchingCheckpointValidators
 		}
 	}
@@ -654,9 +891,24 @@ func (c *Npos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 	if err != nil {
 		return err
 	}
-	if proposalCount != uint32(len(systemTxs)) {
+	// System governance transactions come first, followed by the evidence transactions.
+	if proposalCount > uint32(len(systemTxs)) {
 		return errInvalidSysGovCount
 	}
+	for _, tx := range systemTxs[:proposalCount] {
+		if *tx.To() != systemcontract.SysGovToAddr {
+			return errInvalidSysGovCount
+		}
+	}
+	evidenceTxs := systemTxs[proposalCount:]
+	if len(evidenceTxs) > maxEvidencesPerBlock {
+		return errInvalidEvidence
+	}
+	for _, tx := range evidenceTxs {
+		if *tx.To() != systemcontract.DoubleSignEvidenceToAddr && *tx.To() != systemcontract.AttestationEvidenceToAddr {
+			return errInvalidSysGovCount
+		}
+	}
 	// Due to the logics of the finish operation of contract `governance`, when finishing a proposal which
 	// is not the last passed proposal, it will change the sequence. So in here we must first executes all
 	// passed proposals, and then finish then all.
@@ -672,6 +924,7 @@ func (c *Npos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 		if err != nil {
 			return err
 		}
+		recorder.record(tx.Hash(), receipt.Status == types.ReceiptStatusFailed)
 		*txs = append(*txs, tx)
 		*receipts = append(*receipts, receipt)
 		// set
@@ -684,11 +937,29 @@ func (c *Npos) Finalize(chain consensus.ChainHeaderReader, header *types.Header,
 			return err
 		}
 	}
+	recorder.record(common.Hash{}, false)
+
+	// handle double-sign and conflicting attestations evidences
+	for _, tx := range evidenceTxs {
+		var receipt *types.Receipt
+		if *tx.To() == systemcontract.AttestationEvidenceToAddr {
+			receipt, err = c.replayAttestationEvidence(ctx, chain, len(*txs), tx)
+		} else {
+			receipt, err = c.replayEvidence(ctx, chain, len(*txs), tx)
+		}
+		if err != nil {
+			return err
+		}
+		recorder.record(tx.Hash(), receipt.Status == types.ReceiptStatusFailed)
+		*txs = append(*txs, tx)
+		*receipts = append(*receipts, receipt)
+	}
 
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
 
+	c.keepFeeBreakdown(header, fees)
 	return nil
 }
 
@@ -716,8 +987,10 @@ func (c *Npos) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *ty
 	}
 
 	// deposit block reward if any tx exists.
+	var fees *feeBreakdown
 	if len(txs) > 0 {
-		if err := c.trySendBlockReward(ctx); err != nil {
+		fees = c.newFeeBreakdown(header, receipts)
+		if err := c.trySendBlockReward(ctx, nil, fees); err != nil {
 			panic(err)
 		}
 	}
@@ -767,12 +1040,38 @@ func (c *Npos) FinalizeAndAssemble(chain consensus.ChainHeaderReader, header *ty
 				return nil, nil, err
 			}
 		}
+
+		// submit double-sign evidences
+		evidences := 0
+		if c.config.IsDoubleSign(header.Number) {
+			for _, ev := range c.tryPackEvidences(ctx, chain) {
+				tx, receipt, err := c.executeEvidence(ctx, ev, len(txs))
+				if err != nil {
+					return nil, nil, err
+				}
+				txs = append(txs, tx)
+				receipts = append(receipts, receipt)
+				evidences++
+			}
+		}
+		// submit conflicting attestations evidences
+		if c.config.IsAttestation(header.Number) {
+			for _, ev := range c.tryPackAttestationEvidences(ctx, chain, maxEvidencesPerBlock-evidences) {
+				tx, receipt, err := c.executeAttestationEvidence(ctx, ev, len(txs))
+				if err != nil {
+					return nil, nil, err
+				}
+				txs = append(txs, tx)
+				receipts = append(receipts, receipt)
+			}
+		}
 	}
 
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
 	header.Root = state.IntermediateRoot(chain.Config().IsEIP158(header.Number))
 	header.UncleHash = types.CalcUncleHash(nil)
 
+	c.keepFeeBreakdown(header, fees)
 	// Assemble and return the final block for sealing
 	return types.NewBlock(header, txs, nil, receipts, new(trie.Trie)), receipts, nil
 }
@@ -837,6 +1136,9 @@ func (c *Npos) Seal(chain consensus.ChainHeaderReader, block *types.Block, resul
 		log.Trace("Out-of-turn signing requested", "wiggle", common.PrettyDuration(wiggle))
 	}
 	// Sign all the things!
+	if err := c.signAttestation(snap, header, val, signFn); err != nil {
+		return err
+	}
 	sighash, err := signFn(accounts.Account{Address: val}, accounts.MimetypeNpos, NposRLP(header))
 	if err != nil {
 		return err
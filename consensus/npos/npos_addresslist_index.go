@@ -0,0 +1,259 @@
+package npos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The blacklist and the event check rules read from the address list contract
+// are indexed on disk, so looking them up for any block is a database read
+// rather than contract calls.
+//
+// An entry is keyed by the last updated number of the list kept by the
+// contract, with the hash of the contract code and storage telling apart the
+// lists updated at the same height on different branches.
+var (
+	blacklistIndexPrefix = []byte("npos-blacklist-") // blacklistIndexPrefix + num (uint64 big endian) + state hash -> blacklist
+	rulesIndexPrefix     = []byte("npos-rules-")     // rulesIndexPrefix + num (uint64 big endian) + state hash -> event check rules
+)
+
+var (
+	blacklistCacheHitMeter  = metrics.NewRegisteredMeter("congress/blacklist/cache/hit", nil)
+	blacklistIndexHitMeter  = metrics.NewRegisteredMeter("congress/blacklist/index/hit", nil)
+	blacklistIndexMissMeter = metrics.NewRegisteredMeter("congress/blacklist/index/miss", nil)
+	rulesCacheHitMeter      = metrics.NewRegisteredMeter("congress/eventcheckrules/cache/hit", nil)
+	rulesIndexHitMeter      = metrics.NewRegisteredMeter("congress/eventcheckrules/index/hit", nil)
+	rulesIndexMissMeter     = metrics.NewRegisteredMeter("congress/eventcheckrules/index/miss", nil)
+
+	indexRebuildGauge = metrics.NewRegisteredGauge("congress/addresslist/index/rebuild/number", nil)
+	indexRebuildTimer = metrics.NewRegisteredTimer("congress/addresslist/index/rebuild", nil)
+)
+
+var errIndexRebuilding = errors.New("address list index rebuild already running")
+
+func addressListIndexKey(prefix []byte, number uint64, hash common.Hash) []byte {
+	key := make([]byte, len(prefix)+8+common.HashLength)
+	copy(key, prefix)
+	binary.BigEndian.PutUint64(key[len(prefix):], number)
+	copy(key[len(prefix)+8:], hash[:])
+	return key
+}
+
+// addressListHash returns the hash of the code and the storage root of the
+// address list contract, which determine the lists it returns.
+func addressListHash(statedb *state.StateDB) common.Hash {
+	root := types.EmptyRootHash
+	if tr := statedb.StorageTrie(systemcontract.AddressListContractAddr); tr != nil {
+		root = tr.Hash()
+	}
+	return crypto.Keccak256Hash(statedb.GetCodeHash(systemcontract.AddressListContractAddr).Bytes(), root.Bytes())
+}
+
+// blacklistEntry is the RLP encoding of a blacklisted address.
+type blacklistEntry struct {
+	Address   common.Address
+	Direction uint8
+}
+
+// ruleEntry is the RLP encoding of a check of an event check rule, with the
+// check index kept by the address list contract.
+type ruleEntry struct {
+	EventSig common.Hash
+	Index    uint64
+	Check    uint8
+}
+
+func (c *Npos) readBlacklistIndex(number uint64, hash common.Hash) (map[common.Address]BannedDirection, bool) {
+	blob, err := c.db.Get(addressListIndexKey(blacklistIndexPrefix, number, hash))
+	if err != nil || len(blob) == 0 {
+		return nil, false
+	}
+	var entries []blacklistEntry
+	if err := rlp.DecodeBytes(blob, &entries); err != nil {
+		log.Warn("Invalid blacklist index entry", "number", number, "hash", hash, "err", err)
+		return nil, false
+	}
+	m := make(map[common.Address]BannedDirection, len(entries))
+	for _, entry := range entries {
+		m[entry.Address] = BannedDirection(entry.Direction)
+	}
+	return m, true
+}
+
+func (c *Npos) writeBlacklistIndex(number uint64, hash common.Hash, m map[common.Address]BannedDirection) {
+	entries := make([]blacklistEntry, 0, len(m))
+	for addr, d := range m {
+		entries = append(entries, blacklistEntry{Address: addr, Direction: uint8(d)})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Address[:], entries[j].Address[:]) < 0
+	})
+	blob, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		log.Crit("Failed to encode blacklist index entry", "err", err)
+	}
+	if err := c.db.Put(addressListIndexKey(blacklistIndexPrefix, number, hash), blob); err != nil {
+		log.Error("Failed to store blacklist index entry", "number", number, "hash", hash, "err", err)
+	}
+}
+
+func (c *Npos) readRulesIndex(number uint64, hash common.Hash) (map[common.Hash]*EventCheckRule, bool) {
+	blob, err := c.db.Get(addressListIndexKey(rulesIndexPrefix, number, hash))
+	if err != nil || len(blob) == 0 {
+		return nil, false
+	}
+	var entries []ruleEntry
+	if err := rlp.DecodeBytes(blob, &entries); err != nil {
+		log.Warn("Invalid event check rules index entry", "number", number, "hash", hash, "err", err)
+		return nil, false
+	}
+	rules := make(map[common.Hash]*EventCheckRule)
+	for _, entry := range entries {
+		rule, exist := rules[entry.EventSig]
+		if !exist {
+			rule = newEventCheckRule(entry.EventSig)
+			rules[entry.EventSig] = rule
+		}
+		rule.setCheck(entry.Index, common.AddressCheckType(entry.Check))
+	}
+	return rules, true
+}
+
+func (c *Npos) writeRulesIndex(number uint64, hash common.Hash, rules map[common.Hash]*EventCheckRule) {
+	var entries []ruleEntry
+	for sig, rule := range rules {
+		for idx, check := range rule.Checks {
+			entries = append(entries, ruleEntry{EventSig: sig, Index: uint64(idx), Check: uint8(check)})
+		}
+		for idx, check := range rule.DataChecks {
+			entries = append(entries, ruleEntry{EventSig: sig, Index: DataCheckIdxBase + uint64(idx), Check: uint8(check)})
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].EventSig != entries[j].EventSig {
+			return bytes.Compare(entries[i].EventSig[:], entries[j].EventSig[:]) < 0
+		}
+		return entries[i].Index < entries[j].Index
+	})
+	blob, err := rlp.EncodeToBytes(entries)
+	if err != nil {
+		log.Crit("Failed to encode event check rules index entry", "err", err)
+	}
+	if err := c.db.Put(addressListIndexKey(rulesIndexPrefix, number, hash), blob); err != nil {
+		log.Error("Failed to store event check rules index entry", "number", number, "hash", hash, "err", err)
+	}
+}
+
+// lookupBlacklist returns the blacklist in the given state from the index, or
+// reads it from the contract and indexes it.
+func (c *Npos) lookupBlacklist(header *types.Header, statedb *state.StateDB) (map[common.Address]BannedDirection, error) {
+	number, hash := lastBlacklistUpdatedNumber(statedb), addressListHash(statedb)
+	if m, ok := c.readBlacklistIndex(number, hash); ok {
+		blacklistIndexHitMeter.Mark(1)
+		return m, nil
+	}
+	blacklistIndexMissMeter.Mark(1)
+	m, err := c.readBlacklist(header, statedb)
+	if err != nil {
+		return nil, err
+	}
+	c.writeBlacklistIndex(number, hash, m)
+	return m, nil
+}
+
+// lookupEventCheckRules returns the event check rules in the given state from
+// the index, or reads them from the contract and indexes them.
+func (c *Npos) lookupEventCheckRules(header *types.Header, statedb *state.StateDB) (map[common.Hash]*EventCheckRule, error) {
+	number, hash := lastRulesUpdatedNumber(statedb), addressListHash(statedb)
+	if rules, ok := c.readRulesIndex(number, hash); ok {
+		rulesIndexHitMeter.Mark(1)
+		return rules, nil
+	}
+	rulesIndexMissMeter.Mark(1)
+	rules, err := c.readEventCheckRules(header, statedb)
+	if err != nil {
+		return nil, err
+	}
+	c.writeRulesIndex(number, hash, rules)
+	return rules, nil
+}
+
+// RebuildAddressListIndex indexes the blacklist and the event check rules in
+// effect after every canonical block in the range in the background. Blocks
+// whose state is missing are skipped. Only one rebuild runs at a time.
+func (c *Npos) RebuildAddressListIndex(from, to uint64) error {
+	if c.chain == nil || c.stateFn == nil {
+		return errors.New("chain not available")
+	}
+	if !atomic.CompareAndSwapInt32(&c.indexRebuilding, 0, 1) {
+		return errIndexRebuilding
+	}
+	go func() {
+		defer atomic.StoreInt32(&c.indexRebuilding, 0)
+		c.rebuildAddressListIndex(from, to)
+	}()
+	return nil
+}
+
+// rebuildAddressListIndex indexes the address list contract state after the
+// canonical blocks in the range, and returns the number of blocks indexed.
+func (c *Npos) rebuildAddressListIndex(from, to uint64) int {
+	var (
+		start   = time.Now()
+		logged  = time.Now()
+		indexed int
+		missing int
+	)
+	defer indexRebuildTimer.UpdateSince(start)
+
+	for number := from; number <= to; number++ {
+		header := c.chain.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		statedb, err := c.stateFn(header.Root)
+		if err != nil {
+			missing++
+			continue
+		}
+		// the lists in effect after the block are read for its child
+		child := &types.Header{
+			ParentHash: header.Hash(),
+			Number:     new(big.Int).Add(header.Number, common.Big1),
+			Time:       header.Time,
+			GasLimit:   header.GasLimit,
+			Difficulty: new(big.Int).Set(diffNoTurn),
+			BaseFee:    header.BaseFee,
+		}
+		if _, err := c.lookupBlacklist(child, statedb); err != nil {
+			log.Warn("Failed to index blacklist", "number", number, "err", err)
+			continue
+		}
+		if _, err := c.lookupEventCheckRules(child, statedb); err != nil {
+			log.Warn("Failed to index event check rules", "number", number, "err", err)
+			continue
+		}
+		indexed++
+		indexRebuildGauge.Update(int64(number))
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Rebuilding address list index", "number", number, "to", to, "missing", missing, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	log.Info("Rebuilt address list index", "from", from, "to", to, "indexed", indexed, "missing", missing, "elapsed", common.PrettyDuration(time.Since(start)))
+	return indexed
+}
@@ -0,0 +1,73 @@
+package npos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/stretchr/testify/require"
+)
+
+func countIndexEntries(t *testing.T, db ethdb.Database, prefix []byte) int {
+	t.Helper()
+	it := db.NewIterator(prefix, nil)
+	defer it.Release()
+	count := 0
+	for it.Next() {
+		count++
+	}
+	require.NoError(t, it.Error())
+	return count
+}
+
+func TestAddressListIndex(t *testing.T) {
+	tc := newTestChain(t)
+	banned := common.Address{0x01}
+	tc.extend(2, func(i int, b *core.BlockGen) {
+		if i == 1 {
+			b.AddTx(tc.adminTx(b, systemcontract.AddressListContractAddr, "addBlacklist", banned, uint8(DirectionTo)))
+		}
+	})
+	tc.extend(2, nil)
+
+	// the lists are indexed once per contract state while the blocks are processed
+	require.Equal(t, 2, countIndexEntries(t, tc.db, blacklistIndexPrefix))
+	require.Equal(t, 2, countIndexEntries(t, tc.db, rulesIndexPrefix))
+
+	// a restarted engine finds the lists in the index
+	engine := New(tc.config, tc.db)
+	engine.SetChain(tc.chain)
+	engine.SetStateFn(tc.chain.StateAt)
+	head := tc.chain.CurrentHeader()
+	statedb := mustState(t, tc.chain)
+	number, hash := lastBlacklistUpdatedNumber(statedb), addressListHash(statedb)
+	require.Equal(t, uint64(2), number)
+	m, ok := engine.readBlacklistIndex(number, hash)
+	require.True(t, ok)
+	require.Equal(t, map[common.Address]BannedDirection{banned: DirectionTo}, m)
+
+	child := &types.Header{ParentHash: head.Hash(), Number: new(big.Int).Add(head.Number, common.Big1), Difficulty: diffNoTurn, GasLimit: head.GasLimit}
+	m, err := engine.getBlacklist(child, statedb)
+	require.NoError(t, err)
+	require.Equal(t, map[common.Address]BannedDirection{banned: DirectionTo}, m)
+
+	// a rebuild fills an empty index, the genesis state included
+	engine = New(tc.config, rawdb.NewMemoryDatabase())
+	engine.SetChain(tc.chain)
+	engine.SetStateFn(tc.chain.StateAt)
+	require.Equal(t, 5, engine.rebuildAddressListIndex(0, head.Number.Uint64()))
+	require.Equal(t, 3, countIndexEntries(t, engine.db, blacklistIndexPrefix))
+	require.Equal(t, 3, countIndexEntries(t, engine.db, rulesIndexPrefix))
+	m, ok = engine.readBlacklistIndex(number, hash)
+	require.True(t, ok)
+	require.Equal(t, map[common.Address]BannedDirection{banned: DirectionTo}, m)
+
+	// only one rebuild runs at a time
+	engine.indexRebuilding = 1
+	require.ErrorIs(t, engine.RebuildAddressListIndex(0, 0), errIndexRebuilding)
+}
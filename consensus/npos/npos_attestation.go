@@ -0,0 +1,519 @@
+package npos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	attestationDataLength = 2 * (8 + common.HashLength)                    // Source and target checkpoint numbers and hashes
+	attestationLength     = attestationDataLength + crypto.SignatureLength // Fixed number of extra-data bytes reserved for the attestation of the signer
+
+	inmemoryValidatorAttestations = 256 // Number of validators whose recent attestations are kept for the accountability checks
+	attestationsPerValidator      = 16  // Number of recent attestations of a validator to check the new ones against
+)
+
+var (
+	// errInvalidAttestation is returned if the attestation in a header isn't the
+	// one expected from its signer.
+	errInvalidAttestation = errors.New("invalid attestation")
+
+	// errInvalidCheckpoints is returned if an epoch header doesn't carry the
+	// checkpoints of its parent snapshot.
+	errInvalidCheckpoints = errors.New("invalid checkpoints")
+
+	// errInvalidAttestationEvidence is returned if an evidence can not prove that
+	// a validator signed conflicting attestations.
+	errInvalidAttestationEvidence = errors.New("invalid attestation evidence")
+
+	// attestationPrefix domain-separates the signed attestations from any other signed data.
+	attestationPrefix = []byte("npos attestation")
+)
+
+// AttestationData is the vote of a validator for the link from the latest
+// justified checkpoint to the latest checkpoint of its chain. A checkpoint is
+// justified by the links of more than 2/3 of the validators, and its source
+// is finalized if it's the previous checkpoint.
+type AttestationData struct {
+	Source BlockRef
+	Target BlockRef
+}
+
+// Attestation is an attestation data signed by a validator. The signer of a
+// block embeds its attestation in the header extra-data, right before the seal,
+// so the descendants of a checkpoint aggregate the votes to justify it.
+type Attestation struct {
+	Data      AttestationData
+	Signature []byte
+}
+
+// bytes returns the fixed-length encoding of the attestation data.
+func (d *AttestationData) bytes() []byte {
+	b := make([]byte, attestationDataLength)
+	binary.BigEndian.PutUint64(b, d.Source.Number)
+	copy(b[8:], d.Source.Hash[:])
+	binary.BigEndian.PutUint64(b[8+common.HashLength:], d.Target.Number)
+	copy(b[16+common.HashLength:], d.Target.Hash[:])
+	return b
+}
+
+// signingData returns the data signed by the validators, which is bound to
+// the chain to avoid replays.
+func (d *AttestationData) signingData(chainID *big.Int) []byte {
+	return bytes.Join([][]byte{attestationPrefix, common.BigToHash(chainID).Bytes(), d.bytes()}, nil)
+}
+
+// conflicts tells whether two attestations of a validator break the accountability
+// rule: a validator must never sign two different attestations with the same target
+// height, nor an attestation whose link surrounds the link of another one. Two
+// conflicting checkpoints can only be finalized if more than 1/3 of the validators
+// break the rule, which can be proven by the signed attestations.
+func (d *AttestationData) conflicts(o *AttestationData) bool {
+	if *d == *o {
+		return false
+	}
+	if d.Target.Number == o.Target.Number {
+		return true
+	}
+	return (d.Source.Number < o.Source.Number && o.Target.Number < d.Target.Number) ||
+		(o.Source.Number < d.Source.Number && d.Target.Number < o.Target.Number)
+}
+
+// recover returns the validator who signed the attestation.
+func (a *Attestation) recover(chainID *big.Int) (common.Address, error) {
+	if len(a.Signature) != crypto.SignatureLength {
+		return common.Address{}, errInvalidAttestation
+	}
+	pubkey, err := crypto.Ecrecover(crypto.Keccak256(a.Data.signingData(chainID)), a.Signature)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var validator common.Address
+	copy(validator[:], crypto.Keccak256(pubkey[1:])[12:])
+	return validator, nil
+}
+
+// extraSuffix returns the number of extra-data bytes following the validators,
+// which are the attestation and the seal since the attestation fork.
+func extraSuffix(config *params.NposConfig, number *big.Int) int {
+	if config.IsAttestation(number) {
+		return attestationLength + extraSeal
+	}
+	return extraSeal
+}
+
+// checkpointsLength is the size of the justified and finalized checkpoints in
+// the header extra-data.
+const checkpointsLength = 2 * (8 + common.HashLength)
+
+// extraCheckpointsLength returns the size of the checkpoints in the header extra-data.
+// Since the attestation fork, every epoch header carries the justified and finalized
+// checkpoints of its parent snapshot after the period schedule, so a snapshot
+// can be rebuilt from a trusted checkpoint without the votes before it.
+func extraCheckpointsLength(config *params.NposConfig, number *big.Int) int {
+	if config.IsAttestation(number) && number.Sign() > 0 && number.Uint64()%config.Epoch == 0 {
+		return checkpointsLength
+	}
+	return 0
+}
+
+// checkpointsBytes returns the encoding of the justified and finalized checkpoints.
+func (s *Snapshot) checkpointsBytes() []byte {
+	b := make([]byte, checkpointsLength)
+	binary.BigEndian.PutUint64(b, s.Justified.Number)
+	copy(b[8:], s.Justified.Hash[:])
+	binary.BigEndian.PutUint64(b[8+common.HashLength:], s.Finalized.Number)
+	copy(b[16+common.HashLength:], s.Finalized.Hash[:])
+	return b
+}
+
+// parseCheckpoints extracts the justified and finalized checkpoints from the
+// extra-data of an epoch header, ok is false if the header doesn't carry any.
+func parseCheckpoints(config *params.NposConfig, header *types.Header) (justified BlockRef, finalized BlockRef, ok bool, err error) {
+	if extraCheckpointsLength(config, header.Number) == 0 {
+		return BlockRef{}, BlockRef{}, false, nil
+	}
+	end := len(header.Extra) - extraSuffix(config, header.Number)
+	if end-checkpointsLength < extraVanity {
+		return BlockRef{}, BlockRef{}, false, errInvalidCheckpoints
+	}
+	b := header.Extra[end-checkpointsLength : end]
+	justified = BlockRef{Number: binary.BigEndian.Uint64(b), Hash: common.BytesToHash(b[8 : 8+common.HashLength])}
+	finalized = BlockRef{Number: binary.BigEndian.Uint64(b[8+common.HashLength:]), Hash: common.BytesToHash(b[16+common.HashLength:])}
+	return justified, finalized, true, nil
+}
+
+// parseAttestation extracts the attestation from the extra-data of a header,
+// it returns nil if the signer abstained, which is encoded as zero bytes.
+func parseAttestation(config *params.NposConfig, header *types.Header) (*Attestation, error) {
+	if !config.IsAttestation(header.Number) {
+		return nil, nil
+	}
+	if len(header.Extra) < extraVanity+attestationLength+extraSeal {
+		return nil, errMissingAttestation
+	}
+	b := header.Extra[len(header.Extra)-extraSeal-attestationLength : len(header.Extra)-extraSeal]
+	if bytes.Equal(b, make([]byte, attestationLength)) {
+		return nil, nil
+	}
+	attestation := &Attestation{Signature: common.CopyBytes(b[attestationDataLength:])}
+	attestation.Data.Source.Number = binary.BigEndian.Uint64(b)
+	attestation.Data.Source.Hash = common.BytesToHash(b[8 : 8+common.HashLength])
+	attestation.Data.Target.Number = binary.BigEndian.Uint64(b[8+common.HashLength:])
+	attestation.Data.Target.Hash = common.BytesToHash(b[16+common.HashLength : attestationDataLength])
+	return attestation, nil
+}
+
+// verifyAttestation checks that the attestation in a header is the one expected on
+// top of the parent snapshot, and that it's signed by the signer of the header.
+func (c *Npos) verifyAttestation(snap *Snapshot, header *types.Header, signer common.Address) error {
+	attestation, err := parseAttestation(c.config, header)
+	if err != nil || attestation == nil {
+		return err
+	}
+	if data, ok := snap.attestation(); !ok || data != attestation.Data {
+		return errInvalidAttestation
+	}
+	validator, err := attestation.recover(c.chainConfig.ChainID)
+	if err != nil {
+		return err
+	}
+	if validator != signer {
+		return errInvalidAttestation
+	}
+	c.attestations.add(attestation, validator)
+	return nil
+}
+
+// signAttestation fills the attestation of the local validator in the header
+// extra-data. The validator abstains if there is nothing to attest, or if the
+// attestation would conflict with one it signed before, e.g. after a reorg.
+func (c *Npos) signAttestation(snap *Snapshot, header *types.Header, val common.Address, signFn ValidatorFn) error {
+	if !c.config.IsAttestation(header.Number) {
+		return nil
+	}
+	data, ok := snap.attestation()
+	if !ok {
+		return nil
+	}
+	if c.attestations.conflicting(val, &data) {
+		log.Warn("Abstain from conflicting attestation", "number", header.Number, "source", data.Source.Number, "target", data.Target.Number)
+		return nil
+	}
+	sig, err := signFn(accounts.Account{Address: val}, accounts.MimetypeNposAttestation, data.signingData(c.chainConfig.ChainID))
+	if err != nil {
+		return err
+	}
+	if len(header.Extra) < extraVanity+attestationLength+extraSeal {
+		return errMissingAttestation
+	}
+	offset := len(header.Extra) - extraSeal - attestationLength
+	copy(header.Extra[offset:], data.bytes())
+	copy(header.Extra[offset+attestationDataLength:], sig)
+
+	c.attestations.add(&Attestation{Data: data, Signature: sig}, val)
+	return nil
+}
+
+// AttestationEvidence is the proof that a validator signed two conflicting attestations.
+type AttestationEvidence struct {
+	AttestationA *Attestation
+	AttestationB *Attestation
+}
+
+// Number returns the highest target of the conflicting attestations.
+func (e *AttestationEvidence) Number() uint64 {
+	if e.AttestationA.Data.Target.Number > e.AttestationB.Data.Target.Number {
+		return e.AttestationA.Data.Target.Number
+	}
+	return e.AttestationB.Data.Target.Number
+}
+
+// attestationOffenceHash identifies the conflicting attestations of a validator by the
+// highest target, it's distinct from the double-sign offence at the same height.
+func attestationOffenceHash(validator common.Address, number uint64) common.Hash {
+	return crypto.Keccak256Hash(attestationPrefix, offenceHash(validator, number).Bytes())
+}
+
+// recoverAttestationEvidence checks the self-contained parts of an evidence, and
+// returns the validator who signed both attestations.
+func recoverAttestationEvidence(e *AttestationEvidence, chainID *big.Int) (common.Address, error) {
+	if e == nil || e.AttestationA == nil || e.AttestationB == nil || !e.AttestationA.Data.conflicts(&e.AttestationB.Data) {
+		return common.Address{}, errInvalidAttestationEvidence
+	}
+	signerA, err := e.AttestationA.recover(chainID)
+	if err != nil {
+		return common.Address{}, err
+	}
+	signerB, err := e.AttestationB.recover(chainID)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if signerA != signerB {
+		return common.Address{}, errInvalidAttestationEvidence
+	}
+	return signerA, nil
+}
+
+// attestationPool keeps the recent attestations of every validator seen during
+// header verification, and the evidences of the conflicting ones.
+type attestationPool struct {
+	signed  *lru.Cache                           // recent attestations of each validator
+	pending map[common.Hash]*AttestationEvidence // evidences waiting to be submitted, keyed by offence hash
+	lock    sync.Mutex
+}
+
+func newAttestationPool() *attestationPool {
+	signed, _ := lru.New(inmemoryValidatorAttestations)
+	return &attestationPool{
+		signed:  signed,
+		pending: make(map[common.Hash]*AttestationEvidence),
+	}
+}
+
+// conflicting tells whether an attestation data conflicts with one of the recent
+// attestations of the validator.
+func (p *attestationPool) conflicting(validator common.Address, data *AttestationData) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if v, ok := p.signed.Get(validator); ok {
+		for _, prev := range v.([]*Attestation) {
+			if prev.Data.conflicts(data) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// add records an attestation signed by the given validator, and turns it into
+// an evidence if it conflicts with one of its recent attestations.
+func (p *attestationPool) add(attestation *Attestation, validator common.Address) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	var recents []*Attestation
+	if v, ok := p.signed.Get(validator); ok {
+		recents = v.([]*Attestation)
+	}
+	for _, prev := range recents {
+		if prev.Data == attestation.Data {
+			return
+		}
+	}
+	for _, prev := range recents {
+		if !prev.Data.conflicts(&attestation.Data) {
+			continue
+		}
+		ev := &AttestationEvidence{AttestationA: prev, AttestationB: attestation}
+		id := attestationOffenceHash(validator, ev.Number())
+		if _, exist := p.pending[id]; !exist && len(p.pending) < maxPendingEvidences {
+			p.pending[id] = ev
+			log.Warn("Conflicting attestations detected", "validator", validator,
+				"source", attestation.Data.Source.Number, "target", attestation.Data.Target.Number,
+				"prevSource", prev.Data.Source.Number, "prevTarget", prev.Data.Target.Number)
+		}
+		break
+	}
+	recents = append(recents, attestation)
+	if len(recents) > attestationsPerValidator {
+		recents = recents[len(recents)-attestationsPerValidator:]
+	}
+	p.signed.Add(validator, recents)
+}
+
+// list returns all pending evidences in ascending order of target number.
+func (p *attestationPool) list() []*AttestationEvidence {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	evs := make([]*AttestationEvidence, 0, len(p.pending))
+	for _, ev := range p.pending {
+		evs = append(evs, ev)
+	}
+	sort.Slice(evs, func(i, j int) bool {
+		return evs[i].Number() < evs[j].Number()
+	})
+	return evs
+}
+
+// remove drops an evidence from the pending set.
+func (p *attestationPool) remove(validator common.Address, number uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.pending, attestationOffenceHash(validator, number))
+}
+
+// PendingAttestationEvidences returns the conflicting attestation evidences that
+// have been detected but not yet submitted.
+func (c *Npos) PendingAttestationEvidences() []*AttestationEvidence {
+	return c.attestations.list()
+}
+
+// verifyAttestationEvidence checks whether an evidence can be submitted in the given
+// block, and returns the offending validator. An evidence is only valid if it is
+// not older than two epochs, and the signer was an authorized validator at its
+// height on the chain that the given header builds on.
+func (c *Npos) verifyAttestationEvidence(chain consensus.ChainHeaderReader, header *types.Header, ev *AttestationEvidence) (common.Address, error) {
+	validator, err := recoverAttestationEvidence(ev, c.chainConfig.ChainID)
+	if err != nil {
+		return common.Address{}, err
+	}
+	number, evNumber := header.Number.Uint64(), ev.Number()
+	if evNumber >= number {
+		return common.Address{}, errInvalidAttestationEvidence
+	}
+	if number-evNumber > 2*c.config.Epoch {
+		return common.Address{}, errEvidenceTooOld
+	}
+	ancestor := header
+	for ancestor.Number.Uint64() > evNumber {
+		ancestor = chain.GetHeader(ancestor.ParentHash, ancestor.Number.Uint64()-1)
+		if ancestor == nil {
+			return common.Address{}, consensus.ErrUnknownAncestor
+		}
+	}
+	snap, err := c.snapshot(chain, ancestor.Number.Uint64(), ancestor.Hash(), nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if _, ok := snap.Validators[validator]; !ok {
+		return common.Address{}, errUnauthorizedValidator
+	}
+	return validator, nil
+}
+
+// tryPackAttestationEvidences verifies and filters the pending evidences that can be
+// submitted in the given block, the invalid or already punished ones are dropped.
+func (c *Npos) tryPackAttestationEvidences(ctx *systemcontract.CallContext, chain consensus.ChainHeaderReader, limit int) []*AttestationEvidence {
+	evs := make([]*AttestationEvidence, 0)
+	for _, ev := range c.attestations.list() {
+		if len(evs) >= limit {
+			break
+		}
+		validator, err := c.verifyAttestationEvidence(chain, ctx.Header, ev)
+		if err == nil {
+			var punished bool
+			if punished, err = c.isDoubleSignPunished(ctx, attestationOffenceHash(validator, ev.Number())); err == nil && punished {
+				err = errEvidencePunished
+			}
+		}
+		if err != nil {
+			log.Debug("Drop attestation evidence", "number", ev.Number(), "validator", validator, "err", err)
+			c.attestations.remove(validator, ev.Number())
+			continue
+		}
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+// executeAttestationEvidence makes and executes a system transaction which submits
+// a conflicting attestations evidence.
+func (c *Npos) executeAttestationEvidence(ctx *systemcontract.CallContext, ev *AttestationEvidence, totalTxIndex int) (*types.Transaction, *types.Receipt, error) {
+	if c.signTxFn == nil {
+		return nil, nil, errors.New("signTxFn not set")
+	}
+
+	evRLP, err := rlp.EncodeToBytes(ev)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := ctx.Statedb.GetNonce(c.validator)
+
+	tx := types.NewTransaction(nonce, systemcontract.AttestationEvidenceToAddr, new(big.Int), ctx.Header.GasLimit, new(big.Int), evRLP)
+	tx, err = c.signTxFn(accounts.Account{Address: c.validator}, tx, c.chainConfig.ChainID)
+	if err != nil {
+		return nil, nil, err
+	}
+	//add nonce for validator
+	ctx.Statedb.SetNonce(c.validator, nonce+1)
+
+	validator, err := recoverAttestationEvidence(ev, c.chainConfig.ChainID)
+	if err != nil {
+		return nil, nil, err
+	}
+	receipt, err := c.executeEvidenceMsg(ctx, validator, ev.Number(), attestationOffenceHash(validator, ev.Number()), totalTxIndex, tx.Hash(), ctx.Header.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+	c.attestations.remove(validator, ev.Number())
+
+	return tx, receipt, nil
+}
+
+// replayAttestationEvidence verifies and re-executes a conflicting attestations
+// evidence transaction from a block.
+func (c *Npos) replayAttestationEvidence(ctx *systemcontract.CallContext, chain consensus.ChainHeaderReader, totalTxIndex int, tx *types.Transaction) (*types.Receipt, error) {
+	sender, err := types.Sender(c.signer, tx)
+	if err != nil {
+		return nil, err
+	}
+	if sender != ctx.Header.Coinbase {
+		return nil, errors.New("invalid sender for attestation evidence transaction")
+	}
+	ev := new(AttestationEvidence)
+	if err := rlp.DecodeBytes(tx.Data(), ev); err != nil {
+		return nil, err
+	}
+	validator, err := c.verifyAttestationEvidence(chain, ctx.Header, ev)
+	if err != nil {
+		return nil, err
+	}
+	id := attestationOffenceHash(validator, ev.Number())
+	punished, err := c.isDoubleSignPunished(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if punished {
+		return nil, errEvidencePunished
+	}
+	nonce := ctx.Statedb.GetNonce(sender)
+	//add nonce for validator
+	ctx.Statedb.SetNonce(sender, nonce+1)
+
+	receipt, err := c.executeEvidenceMsg(ctx, validator, ev.Number(), id, totalTxIndex, tx.Hash(), ctx.Header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	c.attestations.remove(validator, ev.Number())
+
+	return receipt, nil
+}
+
+// applyAttestationEvidenceTx applies a conflicting attestations evidence transaction
+// using a given evm, it's the counterpart of `ApplySysTx` for tracing.
+func (c *Npos) applyAttestationEvidenceTx(evm *vm.EVM, state *state.StateDB, txIndex int, sender common.Address, tx *types.Transaction) (ret []byte, vmerr error, err error) {
+	ev := new(AttestationEvidence)
+	if err = rlp.DecodeBytes(tx.Data(), ev); err != nil {
+		return
+	}
+	validator, err := recoverAttestationEvidence(ev, c.chainConfig.ChainID)
+	if err != nil {
+		return
+	}
+	data, err := c.packPunishDoubleSign(validator, ev.Number(), attestationOffenceHash(validator, ev.Number()))
+	if err != nil {
+		return
+	}
+	return c.applyPunishTx(evm, state, txIndex, sender, tx, data)
+}
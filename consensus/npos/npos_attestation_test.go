@@ -0,0 +1,98 @@
+package npos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func signedTestAttestation(t *testing.T, source, target uint64, key []byte) *Attestation {
+	t.Helper()
+	k, err := crypto.ToECDSA(key)
+	require.NoError(t, err)
+	data := AttestationData{
+		Source: BlockRef{Number: source, Hash: common.Hash{byte(source)}},
+		Target: BlockRef{Number: target, Hash: common.Hash{byte(target)}},
+	}
+	sig, err := crypto.Sign(crypto.Keccak256(data.signingData(big.NewInt(1))), k)
+	require.NoError(t, err)
+	return &Attestation{Data: data, Signature: sig}
+}
+
+func TestAttestationConflicts(t *testing.T) {
+	link := func(source, target uint64) *AttestationData {
+		return &AttestationData{Source: BlockRef{Number: source}, Target: BlockRef{Number: target}}
+	}
+	for i, tt := range []struct {
+		a, b     *AttestationData
+		conflict bool
+	}{
+		{link(0, 10), link(0, 10), false},
+		{link(0, 10), link(10, 20), false},
+		{link(0, 10), link(0, 20), false},
+		// double vote
+		{link(0, 10), &AttestationData{Target: BlockRef{Number: 10, Hash: common.Hash{0x1}}}, true},
+		{link(0, 20), link(10, 20), true},
+		// surround vote
+		{link(0, 30), link(10, 20), true},
+		{link(10, 20), link(0, 30), true},
+		{link(0, 20), link(10, 30), false},
+	} {
+		require.Equal(t, tt.conflict, tt.a.conflicts(tt.b), "case %d", i)
+	}
+}
+
+func TestRecoverAttestationEvidence(t *testing.T) {
+	var (
+		keyA    = common.Hex2Bytes("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		keyB    = common.Hex2Bytes("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		chainID = big.NewInt(1)
+	)
+	kA, _ := crypto.ToECDSA(keyA)
+	a := signedTestAttestation(t, 0, 30, keyA)
+
+	signer, err := recoverAttestationEvidence(&AttestationEvidence{AttestationA: a, AttestationB: signedTestAttestation(t, 10, 20, keyA)}, chainID)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(kA.PublicKey), signer)
+
+	// the same attestation twice
+	_, err = recoverAttestationEvidence(&AttestationEvidence{AttestationA: a, AttestationB: a}, chainID)
+	require.ErrorIs(t, err, errInvalidAttestationEvidence)
+	// different signers
+	_, err = recoverAttestationEvidence(&AttestationEvidence{AttestationA: a, AttestationB: signedTestAttestation(t, 10, 20, keyB)}, chainID)
+	require.ErrorIs(t, err, errInvalidAttestationEvidence)
+	// no conflict
+	_, err = recoverAttestationEvidence(&AttestationEvidence{AttestationA: a, AttestationB: signedTestAttestation(t, 30, 40, keyA)}, chainID)
+	require.ErrorIs(t, err, errInvalidAttestationEvidence)
+	// signed for another chain
+	signer, err = recoverAttestationEvidence(&AttestationEvidence{AttestationA: a, AttestationB: signedTestAttestation(t, 10, 20, keyA)}, big.NewInt(2))
+	if err == nil {
+		require.NotEqual(t, crypto.PubkeyToAddress(kA.PublicKey), signer)
+	}
+}
+
+func TestAttestationPool(t *testing.T) {
+	key := common.Hex2Bytes("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	k, _ := crypto.ToECDSA(key)
+	validator := crypto.PubkeyToAddress(k.PublicKey)
+	pool := newAttestationPool()
+
+	pool.add(signedTestAttestation(t, 0, 10, key), validator)
+	pool.add(signedTestAttestation(t, 0, 10, key), validator)
+	pool.add(signedTestAttestation(t, 10, 20, key), validator)
+	require.Empty(t, pool.list())
+	require.False(t, pool.conflicting(validator, &AttestationData{Source: BlockRef{Number: 20, Hash: common.Hash{20}}, Target: BlockRef{Number: 30, Hash: common.Hash{30}}}))
+	require.True(t, pool.conflicting(validator, &AttestationData{Source: BlockRef{Number: 0}, Target: BlockRef{Number: 30}}))
+
+	// a surround vote is an offence
+	pool.add(signedTestAttestation(t, 0, 30, key), validator)
+	evs := pool.list()
+	require.Len(t, evs, 1)
+	require.Equal(t, uint64(30), evs[0].Number())
+
+	pool.remove(validator, 30)
+	require.Empty(t, pool.list())
+}
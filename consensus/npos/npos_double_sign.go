@@ -0,0 +1,361 @@
+package npos
+
+import (
+	"errors"
+	"math/big"
+	"sort"
+	"sync"
+
+	"github.com/ethereum/go-ethereum/accounts"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+	lru "github.com/hashicorp/golang-lru"
+)
+
+const (
+	inmemorySealedHeaders = 1024 // Number of recent (validator, number) pairs to keep for double-sign detection
+	maxPendingEvidences   = 64   // Max number of double-sign evidences waiting to be submitted
+	maxEvidencesPerBlock  = 4    // Max number of double-sign evidences a block can submit
+)
+
+var (
+	// errInvalidEvidence is returned if a double-sign evidence can not prove an equivocation.
+	errInvalidEvidence = errors.New("invalid double-sign evidence")
+
+	// errEvidenceTooOld is returned if a double-sign evidence is older than one epoch.
+	errEvidenceTooOld = errors.New("double-sign evidence too old")
+
+	// errEvidencePunished is returned if the offence of an evidence has already been punished.
+	errEvidencePunished = errors.New("double-sign offence already punished")
+)
+
+// DoubleSignEvidence is the proof that a validator sealed two different blocks
+// at the same height. The parents of the blocks don't matter: a validator never
+// seals a second block at a height, even after a reorg to another branch.
+type DoubleSignEvidence struct {
+	HeaderA *types.Header
+	HeaderB *types.Header
+}
+
+// Number returns the block number that the validator double signed at.
+func (e *DoubleSignEvidence) Number() uint64 {
+	return e.HeaderA.Number.Uint64()
+}
+
+// offenceHash identifies a double-sign offence by the validator and the height,
+// so that an offence is only punished once, no matter how many conflicting header
+// pairs are submitted.
+func offenceHash(validator common.Address, number uint64) common.Hash {
+	return crypto.Keccak256Hash(validator.Bytes(), common.BigToHash(new(big.Int).SetUint64(number)).Bytes())
+}
+
+// recoverEvidence checks the self-contained parts of an evidence, and returns the
+// validator who signed both headers.
+func recoverEvidence(e *DoubleSignEvidence, sigcache *lru.ARCCache) (common.Address, error) {
+	if e == nil || e.HeaderA == nil || e.HeaderB == nil || e.HeaderA.Number == nil || e.HeaderB.Number == nil {
+		return common.Address{}, errInvalidEvidence
+	}
+	if e.HeaderA.Number.Cmp(e.HeaderB.Number) != 0 || e.HeaderA.Number.Sign() <= 0 {
+		return common.Address{}, errInvalidEvidence
+	}
+	if e.HeaderA.Hash() == e.HeaderB.Hash() {
+		return common.Address{}, errInvalidEvidence
+	}
+	signerA, err := ecrecover(e.HeaderA, sigcache)
+	if err != nil {
+		return common.Address{}, err
+	}
+	signerB, err := ecrecover(e.HeaderB, sigcache)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if signerA != signerB {
+		return common.Address{}, errInvalidEvidence
+	}
+	return signerA, nil
+}
+
+// evidencePool collects the conflicting headers seen during header verification.
+type evidencePool struct {
+	sealed  *lru.Cache                          // first seen header for each (validator, number) pair
+	pending map[common.Hash]*DoubleSignEvidence // evidences waiting to be submitted, keyed by offence hash
+	lock    sync.Mutex
+}
+
+func newEvidencePool() *evidencePool {
+	sealed, _ := lru.New(inmemorySealedHeaders)
+	return &evidencePool{
+		sealed:  sealed,
+		pending: make(map[common.Hash]*DoubleSignEvidence),
+	}
+}
+
+// add records a verified header sealed by the given validator, and turns it
+// into a double-sign evidence if the validator has already sealed a different
+// header at the same height.
+func (p *evidencePool) add(header *types.Header, validator common.Address) {
+	key := offenceHash(validator, header.Number.Uint64())
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	v, ok := p.sealed.Get(key)
+	if !ok {
+		p.sealed.Add(key, types.CopyHeader(header))
+		return
+	}
+	first := v.(*types.Header)
+	if first.Hash() == header.Hash() {
+		return
+	}
+	number := header.Number.Uint64()
+	id := offenceHash(validator, number)
+	if _, exist := p.pending[id]; exist || len(p.pending) >= maxPendingEvidences {
+		return
+	}
+	p.pending[id] = &DoubleSignEvidence{HeaderA: first, HeaderB: types.CopyHeader(header)}
+	log.Warn("Double sign detected", "validator", validator, "number", number, "hashA", first.Hash(), "hashB", header.Hash())
+}
+
+// list returns all pending evidences in ascending order of block number.
+func (p *evidencePool) list() []*DoubleSignEvidence {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	evs := make([]*DoubleSignEvidence, 0, len(p.pending))
+	for _, ev := range p.pending {
+		evs = append(evs, ev)
+	}
+	sort.Slice(evs, func(i, j int) bool {
+		return evs[i].Number() < evs[j].Number()
+	})
+	return evs
+}
+
+// remove drops an evidence from the pending set.
+func (p *evidencePool) remove(validator common.Address, number uint64) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	delete(p.pending, offenceHash(validator, number))
+}
+
+// PendingEvidences returns the double-sign evidences that have been detected
+// but not yet submitted.
+func (c *Npos) PendingEvidences() []*DoubleSignEvidence {
+	return c.evidences.list()
+}
+
+// verifyEvidence checks whether an evidence can be submitted in the given block,
+// and returns the offending validator. An evidence is only valid if it is not
+// older than one epoch, and the signer was an authorized validator at its height
+// on the chain that the given header builds on.
+func (c *Npos) verifyEvidence(chain consensus.ChainHeaderReader, header *types.Header, ev *DoubleSignEvidence) (common.Address, error) {
+	validator, err := recoverEvidence(ev, c.signatures)
+	if err != nil {
+		return common.Address{}, err
+	}
+	number, evNumber := header.Number.Uint64(), ev.Number()
+	if evNumber >= number {
+		return common.Address{}, errInvalidEvidence
+	}
+	if number-evNumber > c.config.Epoch {
+		return common.Address{}, errEvidenceTooOld
+	}
+	// Walk back along the chain to the height before the conflicting headers
+	ancestor := header
+	for ancestor.Number.Uint64() > evNumber-1 {
+		ancestor = chain.GetHeader(ancestor.ParentHash, ancestor.Number.Uint64()-1)
+		if ancestor == nil {
+			return common.Address{}, consensus.ErrUnknownAncestor
+		}
+	}
+	snap, err := c.snapshot(chain, ancestor.Number.Uint64(), ancestor.Hash(), nil)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if _, ok := snap.Validators[validator]; !ok {
+		return common.Address{}, errUnauthorizedValidator
+	}
+	return validator, nil
+}
+
+func (c *Npos) isDoubleSignPunished(ctx *systemcontract.CallContext, id common.Hash) (bool, error) {
+	ret, err := c.commonCallContract(ctx.Header, ctx.Statedb, c.abi[systemcontract.PunishContractName], systemcontract.PunishContractAddr, "isDoubleSignPunished", 1, id)
+	if err != nil {
+		return false, err
+	}
+	punished, ok := ret[0].(bool)
+	if !ok {
+		return false, errors.New("invalid punished format")
+	}
+	return punished, nil
+}
+
+func (c *Npos) packPunishDoubleSign(validator common.Address, number uint64, id common.Hash) ([]byte, error) {
+	method := "punishDoubleSign"
+	data, err := c.abi[systemcontract.PunishContractName].Pack(method, validator, new(big.Int).SetUint64(number), id)
+	if err != nil {
+		log.Error("Can't pack data for punishDoubleSign", "error", err)
+		return nil, err
+	}
+	return data, nil
+}
+
+// tryPackEvidences verifies and filters the pending evidences that can be submitted
+// in the given block, the invalid or already punished ones are dropped from the pool.
+func (c *Npos) tryPackEvidences(ctx *systemcontract.CallContext, chain consensus.ChainHeaderReader) []*DoubleSignEvidence {
+	evs := make([]*DoubleSignEvidence, 0)
+	for _, ev := range c.evidences.list() {
+		validator, err := c.verifyEvidence(chain, ctx.Header, ev)
+		if err == nil {
+			var punished bool
+			if punished, err = c.isDoubleSignPunished(ctx, offenceHash(validator, ev.Number())); err == nil && punished {
+				err = errEvidencePunished
+			}
+		}
+		if err != nil {
+			log.Debug("Drop double-sign evidence", "number", ev.Number(), "validator", validator, "err", err)
+			c.evidences.remove(validator, ev.Number())
+			continue
+		}
+		evs = append(evs, ev)
+		if len(evs) >= maxEvidencesPerBlock {
+			break
+		}
+	}
+	return evs
+}
+
+// executeEvidence makes and executes a system transaction which submits a double-sign evidence.
+func (c *Npos) executeEvidence(ctx *systemcontract.CallContext, ev *DoubleSignEvidence, totalTxIndex int) (*types.Transaction, *types.Receipt, error) {
+	if c.signTxFn == nil {
+		return nil, nil, errors.New("signTxFn not set")
+	}
+
+	evRLP, err := rlp.EncodeToBytes(ev)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce := ctx.Statedb.GetNonce(c.validator)
+
+	tx := types.NewTransaction(nonce, systemcontract.DoubleSignEvidenceToAddr, new(big.Int), ctx.Header.GasLimit, new(big.Int), evRLP)
+	tx, err = c.signTxFn(accounts.Account{Address: c.validator}, tx, c.chainConfig.ChainID)
+	if err != nil {
+		return nil, nil, err
+	}
+	//add nonce for validator
+	ctx.Statedb.SetNonce(c.validator, nonce+1)
+
+	validator, err := recoverEvidence(ev, c.signatures)
+	if err != nil {
+		return nil, nil, err
+	}
+	receipt, err := c.executeEvidenceMsg(ctx, validator, ev.Number(), offenceHash(validator, ev.Number()), totalTxIndex, tx.Hash(), ctx.Header.Hash())
+	if err != nil {
+		return nil, nil, err
+	}
+	c.evidences.remove(validator, ev.Number())
+
+	return tx, receipt, nil
+}
+
+// replayEvidence verifies and re-executes a double-sign evidence transaction from a block.
+func (c *Npos) replayEvidence(ctx *systemcontract.CallContext, chain consensus.ChainHeaderReader, totalTxIndex int, tx *types.Transaction) (*types.Receipt, error) {
+	sender, err := types.Sender(c.signer, tx)
+	if err != nil {
+		return nil, err
+	}
+	if sender != ctx.Header.Coinbase {
+		return nil, errors.New("invalid sender for double-sign evidence transaction")
+	}
+	ev := new(DoubleSignEvidence)
+	if err := rlp.DecodeBytes(tx.Data(), ev); err != nil {
+		return nil, err
+	}
+	validator, err := c.verifyEvidence(chain, ctx.Header, ev)
+	if err != nil {
+		return nil, err
+	}
+	punished, err := c.isDoubleSignPunished(ctx, offenceHash(validator, ev.Number()))
+	if err != nil {
+		return nil, err
+	}
+	if punished {
+		return nil, errEvidencePunished
+	}
+	nonce := ctx.Statedb.GetNonce(sender)
+	//add nonce for validator
+	ctx.Statedb.SetNonce(sender, nonce+1)
+
+	receipt, err := c.executeEvidenceMsg(ctx, validator, ev.Number(), offenceHash(validator, ev.Number()), totalTxIndex, tx.Hash(), ctx.Header.Hash())
+	if err != nil {
+		return nil, err
+	}
+	c.evidences.remove(validator, ev.Number())
+
+	return receipt, nil
+}
+
+func (c *Npos) executeEvidenceMsg(ctx *systemcontract.CallContext, validator common.Address, number uint64, id common.Hash, totalTxIndex int, txHash, bHash common.Hash) (*types.Receipt, error) {
+	data, err := c.packPunishDoubleSign(validator, number, id)
+	if err != nil {
+		return nil, err
+	}
+	ctx.Statedb.SetTxContext(txHash, totalTxIndex)
+	_, err = systemcontract.VmCall(ctx, systemcontract.PunishContractAddr, data)
+	// evidence message will not actually consumes gas
+	receipt := types.NewReceipt([]byte{}, err != nil, ctx.Header.GasUsed)
+	receipt.Logs = ctx.Statedb.GetLogs(txHash, ctx.Header.Number.Uint64(), bHash)
+	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	receipt.TxHash = txHash
+	receipt.BlockHash = bHash
+	receipt.BlockNumber = ctx.Header.Number
+	receipt.TransactionIndex = uint(ctx.Statedb.TxIndex())
+
+	log.Info("executeEvidenceMsg", "validator", validator, "number", number, "txHash", txHash.String(), "err", err)
+
+	return receipt, nil
+}
+
+// applyEvidenceTx applies a double-sign evidence transaction using a given evm,
+// it's the counterpart of `ApplySysTx` for tracing.
+func (c *Npos) applyEvidenceTx(evm *vm.EVM, state *state.StateDB, txIndex int, sender common.Address, tx *types.Transaction) (ret []byte, vmerr error, err error) {
+	ev := new(DoubleSignEvidence)
+	if err = rlp.DecodeBytes(tx.Data(), ev); err != nil {
+		return
+	}
+	validator, err := recoverEvidence(ev, c.signatures)
+	if err != nil {
+		return
+	}
+	data, err := c.packPunishDoubleSign(validator, ev.Number(), offenceHash(validator, ev.Number()))
+	if err != nil {
+		return
+	}
+	return c.applyPunishTx(evm, state, txIndex, sender, tx, data)
+}
+
+// applyPunishTx runs the punishment of an evidence transaction from the engine.
+func (c *Npos) applyPunishTx(evm *vm.EVM, state *state.StateDB, txIndex int, sender common.Address, tx *types.Transaction, data []byte) (ret []byte, vmerr error, err error) {
+	evm.Context.ExtraValidator = nil
+	nonce := evm.StateDB.GetNonce(sender)
+	//add nonce for validator
+	evm.StateDB.SetNonce(sender, nonce+1)
+
+	state.SetTxContext(tx.Hash(), txIndex)
+	evm.TxContext = vm.TxContext{
+		Origin:   systemcontract.EngineCaller,
+		GasPrice: new(big.Int),
+	}
+	ret, _, vmerr = evm.Call(vm.AccountRef(systemcontract.EngineCaller), systemcontract.PunishContractAddr, data, tx.Gas(), new(big.Int))
+	state.Finalise(true)
+	return
+}
@@ -0,0 +1,123 @@
+package npos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/stretchr/testify/require"
+)
+
+func signedTestHeader(t *testing.T, number int64, parent common.Hash, time uint64, key []byte) *types.Header {
+	t.Helper()
+	k, err := crypto.ToECDSA(key)
+	require.NoError(t, err)
+	header := &types.Header{
+		ParentHash: parent,
+		Number:     big.NewInt(number),
+		Difficulty: diffNoTurn,
+		Time:       time,
+		Extra:      make([]byte, extraVanity+extraSeal),
+		Coinbase:   crypto.PubkeyToAddress(k.PublicKey),
+	}
+	sealTestHeader(t, header, key)
+	return header
+}
+
+func sealTestHeader(t *testing.T, header *types.Header, key []byte) {
+	t.Helper()
+	k, err := crypto.ToECDSA(key)
+	require.NoError(t, err)
+	sig, err := crypto.Sign(SealHash(header).Bytes(), k)
+	require.NoError(t, err)
+	copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+}
+
+func TestRecoverEvidence(t *testing.T) {
+	var (
+		keyA        = common.Hex2Bytes("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		keyB        = common.Hex2Bytes("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+		parent      = common.HexToHash("0x01")
+		sigcache, _ = lru.NewARC(inmemorySignatures)
+	)
+	a := signedTestHeader(t, 10, parent, 100, keyA)
+	b := signedTestHeader(t, 10, parent, 101, keyA)
+
+	signer, err := recoverEvidence(&DoubleSignEvidence{HeaderA: a, HeaderB: b}, sigcache)
+	require.NoError(t, err)
+	require.Equal(t, a.Coinbase, signer)
+
+	// same header twice
+	_, err = recoverEvidence(&DoubleSignEvidence{HeaderA: a, HeaderB: a}, sigcache)
+	require.ErrorIs(t, err, errInvalidEvidence)
+	// different signers
+	_, err = recoverEvidence(&DoubleSignEvidence{HeaderA: a, HeaderB: signedTestHeader(t, 10, parent, 101, keyB)}, sigcache)
+	require.ErrorIs(t, err, errInvalidEvidence)
+	// different heights
+	_, err = recoverEvidence(&DoubleSignEvidence{HeaderA: a, HeaderB: signedTestHeader(t, 11, parent, 101, keyA)}, sigcache)
+	require.ErrorIs(t, err, errInvalidEvidence)
+	// different parents are still an equivocation
+	signer, err = recoverEvidence(&DoubleSignEvidence{HeaderA: a, HeaderB: signedTestHeader(t, 10, common.HexToHash("0x02"), 100, keyA)}, sigcache)
+	require.NoError(t, err)
+	require.Equal(t, a.Coinbase, signer)
+}
+
+func TestEvidencePool(t *testing.T) {
+	var (
+		key    = common.Hex2Bytes("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		parent = common.HexToHash("0x01")
+		pool   = newEvidencePool()
+	)
+	a := signedTestHeader(t, 10, parent, 100, key)
+	pool.add(a, a.Coinbase)
+	pool.add(a, a.Coinbase)
+	require.Empty(t, pool.list())
+
+	b := signedTestHeader(t, 10, parent, 101, key)
+	pool.add(b, b.Coinbase)
+	evs := pool.list()
+	require.Len(t, evs, 1)
+	require.Equal(t, a.Hash(), evs[0].HeaderA.Hash())
+	require.Equal(t, b.Hash(), evs[0].HeaderB.Hash())
+
+	// a third conflicting header doesn't produce a new offence
+	pool.add(signedTestHeader(t, 10, parent, 102, key), a.Coinbase)
+	require.Len(t, pool.list(), 1)
+
+	pool.remove(a.Coinbase, 10)
+	require.Empty(t, pool.list())
+
+	// sealing at the same height on another branch is an offence too
+	c := signedTestHeader(t, 11, parent, 100, key)
+	pool.add(c, c.Coinbase)
+	d := signedTestHeader(t, 11, common.HexToHash("0x02"), 100, key)
+	pool.add(d, d.Coinbase)
+	evs = pool.list()
+	require.Len(t, evs, 1)
+	require.Equal(t, c.Hash(), evs[0].HeaderA.Hash())
+	require.Equal(t, d.Hash(), evs[0].HeaderB.Hash())
+}
+
+// Tests that the double-sign slashing can't be scheduled before the Punish
+// contract v1, which implements the methods the evidences call.
+func TestDoubleSignForkNeedsPunishV1(t *testing.T) {
+	chainConfig := &params.ChainConfig{LondonBlock: big.NewInt(0)}
+	for _, tt := range []struct {
+		v1, doubleSign *big.Int
+		ok             bool
+	}{
+		{nil, big.NewInt(5), false},
+		{big.NewInt(10), big.NewInt(5), false},
+		{big.NewInt(10), big.NewInt(10), true},
+		{big.NewInt(10), big.NewInt(20), true},
+		{nil, nil, true},
+	} {
+		config := &params.NposConfig{Epoch: 10, SysContractV1Block: tt.v1, DoubleSignBlock: tt.doubleSign}
+		err := validateConfig(chainConfig, config)
+		require.Equal(t, tt.ok, err == nil, "v1 %v, double sign %v: %v", tt.v1, tt.doubleSign, err)
+	}
+}
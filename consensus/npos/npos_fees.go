@@ -0,0 +1,114 @@
+package npos
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// The Validators contract splits the block reward by its burn and foundation
+// rates: it burns a share by sending it to params.NposBurnAddress, credits
+// another to the foundation reward, and distributes the rest to the validators.
+// The engine measures the shares while finalizing or assembling a block, and
+// keeps them with the base fees burned as the fee breakdown of the block, which
+// the chain stores with the block.
+
+var errMissingFeeBreakdown = errors.New("fee breakdown of the block not recorded")
+
+// feeBreakdown is the accounting of the fees paid in a block being finalized. A
+// nil breakdown measures nothing.
+type feeBreakdown struct {
+	types.FeeBreakdown
+
+	burnedBefore     *big.Int // balance of the burn address before the distribution
+	foundationBefore *big.Int // foundation reward before the distribution
+	failed           bool     // whether the shares couldn't be measured
+}
+
+// newFeeBreakdown returns the breakdown of the fees paid by the transactions
+// with the given receipts, whose reward is still to be distributed.
+func (c *Npos) newFeeBreakdown(header *types.Header, receipts []*types.Receipt) *feeBreakdown {
+	fees := &feeBreakdown{FeeBreakdown: types.FeeBreakdown{
+		BaseFeeBurned: new(big.Int),
+		Reward:        new(big.Int),
+		Burned:        new(big.Int),
+		Foundation:    new(big.Int),
+		Distributed:   new(big.Int),
+	}}
+	if c.config.IsFeeBurn(header.Number) && header.BaseFee != nil {
+		for _, receipt := range receipts {
+			fee := new(big.Int).SetUint64(receipt.GasUsed)
+			fees.BaseFeeBurned.Add(fees.BaseFeeBurned, fee.Mul(fee, header.BaseFee))
+		}
+	}
+	return fees
+}
+
+// foundationReward reads the foundation reward accumulated by the Validators contract.
+func (c *Npos) foundationReward(ctx *systemcontract.CallContext) (*big.Int, error) {
+	ret, err := c.commonCallContract(ctx.Header, ctx.Statedb, c.abi[systemcontract.ValidatorsContractName], systemcontract.ValidatorsContractAddr, "foundationReward", 1)
+	if err != nil {
+		return nil, err
+	}
+	reward, ok := ret[0].(*big.Int)
+	if !ok {
+		return nil, errors.New("invalid foundation reward format")
+	}
+	return reward, nil
+}
+
+// beforeReward records the state the shares of the reward are measured from.
+// The accounting never fails the block, the breakdown is dropped instead.
+func (fees *feeBreakdown) beforeReward(c *Npos, ctx *systemcontract.CallContext, reward *big.Int) {
+	if fees == nil {
+		return
+	}
+	fees.Reward = new(big.Int).Set(reward)
+	fees.burnedBefore = new(big.Int).Set(ctx.Statedb.GetBalance(params.NposBurnAddress))
+	foundation, err := c.foundationReward(ctx)
+	if err != nil {
+		log.Warn("Failed to read the foundation reward", "number", ctx.Header.Number, "err", err)
+		fees.failed = true
+		return
+	}
+	fees.foundationBefore = foundation
+}
+
+// afterReward measures the shares of the distributed reward.
+func (fees *feeBreakdown) afterReward(c *Npos, ctx *systemcontract.CallContext) {
+	if fees == nil || fees.failed {
+		return
+	}
+	foundation, err := c.foundationReward(ctx)
+	if err != nil {
+		log.Warn("Failed to read the foundation reward", "number", ctx.Header.Number, "err", err)
+		fees.failed = true
+		return
+	}
+	fees.Burned = new(big.Int).Sub(ctx.Statedb.GetBalance(params.NposBurnAddress), fees.burnedBefore)
+	fees.Foundation = new(big.Int).Sub(foundation, fees.foundationBefore)
+	fees.Distributed = new(big.Int).Sub(fees.Reward, fees.Burned)
+	fees.Distributed.Sub(fees.Distributed, fees.Foundation)
+}
+
+// keepFeeBreakdown keeps the fee breakdown of the finalized block until the block
+// is written, unless it couldn't be measured.
+func (c *Npos) keepFeeBreakdown(header *types.Header, fees *feeBreakdown) {
+	if fees == nil || fees.failed {
+		return
+	}
+	c.fees.Add(header.Root, &fees.FeeBreakdown)
+}
+
+// FeeBreakdown implements consensus.PoSA, returning the fee breakdown of the
+// block last finalized with the state root of the header.
+func (c *Npos) FeeBreakdown(header *types.Header) *types.FeeBreakdown {
+	if fees, ok := c.fees.Get(header.Root); ok {
+		return fees.(*types.FeeBreakdown)
+	}
+	return nil
+}
@@ -126,7 +126,7 @@ func (c *Npos) executeProposal(ctx *systemcontract.CallContext, prop *Proposal,
 	}
 	//add nonce for validator
 	ctx.Statedb.SetNonce(c.validator, nonce+1)
-	receipt := c.executeProposalMsg(ctx, prop, totalTxIndex, tx.Hash(), ctx.Header.Hash())
+	receipt, _ := c.executeProposalMsg(ctx, prop, totalTxIndex, tx.Hash(), ctx.Header.Hash())
 
 	return tx, receipt, nil
 }
@@ -150,50 +150,49 @@ func (c *Npos) replayProposal(ctx *systemcontract.CallContext, prop *Proposal, t
 	nonce := ctx.Statedb.GetNonce(sender)
 	//add nonce for validator
 	ctx.Statedb.SetNonce(sender, nonce+1)
-	receipt := c.executeProposalMsg(ctx, prop, totalTxIndex, tx.Hash(), ctx.Header.Hash())
+	receipt, _ := c.executeProposalMsg(ctx, prop, totalTxIndex, tx.Hash(), ctx.Header.Hash())
 
 	return receipt, nil
 }
 
-func (c *Npos) executeProposalMsg(ctx *systemcontract.CallContext, prop *Proposal, totalTxIndex int, txHash, bHash common.Hash) *types.Receipt {
-	var receipt *types.Receipt
-	action := prop.Action.Uint64()
-	switch action {
-	case 0:
-		// evm action.
-		receipt = c.executeEvmCallProposal(ctx, prop, totalTxIndex, txHash, bHash)
-	case 1:
-		// delete code action
-		ok := ctx.Statedb.Erase(prop.To)
-		receipt = types.NewReceipt([]byte{}, !ok, ctx.Header.GasUsed)
-		log.Info("executeProposalMsg", "action", "erase", "id", prop.Id.String(), "to", prop.To, "txHash", txHash.String(), "success", ok)
-	default:
-		receipt = types.NewReceipt([]byte{}, true, ctx.Header.GasUsed)
-		log.Warn("executeProposalMsg failed, unsupported action", "action", action, "id", prop.Id.String(), "from", prop.From, "to", prop.To, "value", prop.Value.String(), "data", hexutil.Encode(prop.Data), "txHash", txHash.String())
+// executeProposalMsg executes the proposal with the registered action, the returned receipt is never nil.
+// The returned error is the reason the proposal failed, which only sets the receipt status and
+// doesn't invalidate the block.
+func (c *Npos) executeProposalMsg(ctx *systemcontract.CallContext, prop *Proposal, totalTxIndex int, txHash, bHash common.Hash) (*types.Receipt, error) {
+	env := &actionEnv{
+		chainConfig: c.chainConfig,
+		config:      c.config,
+		number:      ctx.Header.Number,
+		state:       ctx.Statedb,
+		call: func(from, to common.Address, data []byte, value *big.Int) ([]byte, error) {
+			return systemcontract.VmCallWithValue(ctx, from, to, data, value)
+		},
+	}
+	switch recorder := ctx.Tracer.(type) {
+	case *stateRecorder:
+		env.recorder = recorder
+	case *internalTxRecorder:
+		env.internalTxs = recorder
 	}
-
-	receipt.TxHash = txHash
-	receipt.BlockHash = bHash
-	receipt.BlockNumber = ctx.Header.Number
-	receipt.TransactionIndex = uint(ctx.Statedb.TxIndex())
-
-	return receipt
-}
-
-// the returned value should not nil.
-func (c *Npos) executeEvmCallProposal(ctx *systemcontract.CallContext, prop *Proposal, totalTxIndex int, txHash, bHash common.Hash) *types.Receipt {
-	// actually call evm with proposal infos
 	ctx.Statedb.SetTxContext(txHash, totalTxIndex)
-	_, err := systemcontract.VmCallWithValue(ctx, prop.From, prop.To, prop.Data, prop.Value)
+	action, _, err := c.applyProposal(env, prop)
+
 	// governance message will not actually consumes gas
 	receipt := types.NewReceipt([]byte{}, err != nil, ctx.Header.GasUsed)
 	// Set the receipt logs and create a bloom for filtering
 	receipt.Logs = ctx.Statedb.GetLogs(txHash, ctx.Header.Number.Uint64(), bHash)
 	receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+	receipt.TxHash = txHash
+	receipt.BlockHash = bHash
+	receipt.BlockNumber = ctx.Header.Number
+	receipt.TransactionIndex = uint(ctx.Statedb.TxIndex())
 
-	log.Info("executeProposalMsg", "action", "evmCall", "id", prop.Id.String(), "from", prop.From, "to", prop.To, "value", prop.Value.String(), "data", hexutil.Encode(prop.Data), "txHash", txHash.String(), "err", err)
-
-	return receipt
+	if errors.Is(err, errUnsupportedAction) {
+		log.Warn("executeProposalMsg failed, unsupported action", "action", prop.Action, "id", prop.Id.String(), "from", prop.From, "to", prop.To, "value", prop.Value.String(), "data", hexutil.Encode(prop.Data), "txHash", txHash.String())
+	} else {
+		log.Info("executeProposalMsg", "action", action, "id", prop.Id.String(), "from", prop.From, "to", prop.To, "value", prop.Value.String(), "data", hexutil.Encode(prop.Data), "txHash", txHash.String(), "err", err)
+	}
+	return receipt, err
 }
 
 // IsSysTransaction checks whether a specific transaction is a system transaction.
@@ -210,6 +209,12 @@ func (c *Npos) IsSysTransaction(sender common.Address, tx *types.Transaction, he
 	if sender == header.Coinbase && *to == systemcontract.SysGovContractAddr {
 		return true, nil
 	}
+	if c.config.IsDoubleSign(header.Number) && sender == header.Coinbase && *to == systemcontract.DoubleSignEvidenceToAddr && tx.GasPrice().Sign() == 0 {
+		return true, nil
+	}
+	if c.config.IsAttestation(header.Number) && sender == header.Coinbase && *to == systemcontract.AttestationEvidenceToAddr && tx.GasPrice().Sign() == 0 {
+		return true, nil
+	}
 	return false, nil
 }
 
@@ -218,6 +223,12 @@ func (c *Npos) IsSysTransaction(sender common.Address, tx *types.Transaction, he
 // ApplySysTx applies a system-transaction using a given evm,
 // the main purpose of this method is for tracing a system-transaction.
 func (c *Npos) ApplySysTx(evm *vm.EVM, state *state.StateDB, txIndex int, sender common.Address, tx *types.Transaction) (ret []byte, vmerr error, err error) {
+	if to := tx.To(); to != nil && *to == systemcontract.DoubleSignEvidenceToAddr {
+		return c.applyEvidenceTx(evm, state, txIndex, sender, tx)
+	}
+	if to := tx.To(); to != nil && *to == systemcontract.AttestationEvidenceToAddr {
+		return c.applyAttestationEvidenceTx(evm, state, txIndex, sender, tx)
+	}
 	var prop = &Proposal{}
 	if err = rlp.DecodeBytes(tx.Data(), prop); err != nil {
 		return
@@ -227,24 +238,24 @@ func (c *Npos) ApplySysTx(evm *vm.EVM, state *state.StateDB, txIndex int, sender
 	//add nonce for validator
 	evm.StateDB.SetNonce(sender, nonce+1)
 
-	action := prop.Action.Uint64()
-	switch action {
-	case 0:
-		// evm action.
-		// actually run the governance message
-		state.SetTxContext(tx.Hash(), txIndex)
-		evm.TxContext = vm.TxContext{
-			Origin:   prop.From,
-			GasPrice: new(big.Int),
-		}
-		ret, _, vmerr = evm.Call(vm.AccountRef(prop.From), prop.To, prop.Data, tx.Gas(), prop.Value)
-		state.Finalise(true)
-	case 1:
-		// delete code action
-		_ = state.Erase(prop.To)
-	default:
-		vmerr = errors.New("unsupported action")
+	state.SetTxContext(tx.Hash(), txIndex)
+	env := &actionEnv{
+		chainConfig: c.chainConfig,
+		config:      c.config,
+		number:      evm.Context.BlockNumber,
+		state:       state,
+		call: func(from, to common.Address, data []byte, value *big.Int) ([]byte, error) {
+			// actually run the governance message
+			evm.TxContext = vm.TxContext{
+				Origin:   from,
+				GasPrice: new(big.Int),
+			}
+			ret, _, err := evm.Call(vm.AccountRef(from), to, data, tx.Gas(), value)
+			state.Finalise(true)
+			return ret, err
+		},
 	}
+	_, ret, vmerr = c.applyProposal(env, prop)
 	return
 }
 
@@ -265,23 +276,46 @@ func (c *Npos) ValidateTx(sender common.Address, tx *types.Transaction, header *
 			log.Trace("Hit blacklist", "tx", tx.Hash().String(), "addr", to.String(), "direction", d)
 			return types.ErrAddressBanned
 		}
+		if c.config.IsAddressListV2(header.Number) {
+			rules, err := c.getCallRules(header, parentState)
+			if err != nil {
+				return err
+			}
+			if !rules.empty() {
+				if hit := rules.banned(*to, parentState.GetCodeHash(*to), tx.Data()); hit != common.CheckNone {
+					log.Trace("Hit call rules", "tx", tx.Hash().String(), "addr", to.String(), "checkType", hit)
+					return types.ErrCallBanned
+				}
+			}
+		}
+	} else if c.config.IsDevVerification(header.Number) {
+		developers, err := c.getDevelopers(header, parentState)
+		if err != nil {
+			// fail closed, nobody is known to be a developer
+			return fmt.Errorf("%w: %v", types.ErrUnauthorizedDeveloper, err)
+		}
+		if _, exist := developers[sender]; !exist {
+			log.Trace("Unauthorized developer", "tx", tx.Hash().String(), "addr", sender.String())
+			return types.ErrUnauthorizedDeveloper
+		}
 	}
 	return nil
 }
 
-func (c *Npos) getBlacklist(header *types.Header, parentState *state.StateDB) (map[common.Address]bannedDirection, error) {
+func (c *Npos) getBlacklist(header *types.Header, parentState *state.StateDB) (map[common.Address]BannedDirection, error) {
 	defer func(start time.Time) {
 		getblacklistTimer.UpdateSince(start)
 	}(time.Now())
 
 	if v, ok := c.blacklists.Get(header.ParentHash); ok {
-		return v.(map[common.Address]bannedDirection), nil
+		blacklistCacheHitMeter.Mark(1)
+		return v.(map[common.Address]BannedDirection), nil
 	}
 
 	c.blLock.Lock()
 	defer c.blLock.Unlock()
 	if v, ok := c.blacklists.Get(header.ParentHash); ok {
-		return v.(map[common.Address]bannedDirection), nil
+		return v.(map[common.Address]BannedDirection), nil
 	}
 
 	// if the last updates is long ago, we don't need to get blacklist from the contract.
@@ -291,7 +325,8 @@ func (c *Npos) getBlacklist(header *types.Header, parentState *state.StateDB) (m
 		parent := c.chain.GetHeader(header.ParentHash, num-1)
 		if parent != nil {
 			if v, ok := c.blacklists.Get(parent.ParentHash); ok {
-				m := v.(map[common.Address]bannedDirection)
+				blacklistCacheHitMeter.Mark(1)
+				m := v.(map[common.Address]BannedDirection)
 				c.blacklists.Add(header.ParentHash, m)
 				return m, nil
 			}
@@ -300,7 +335,18 @@ func (c *Npos) getBlacklist(header *types.Header, parentState *state.StateDB) (m
 		}
 	}
 
-	// can't get blacklist from cache, try to call the contract
+	// can't get blacklist from cache, try the index or call the contract
+	m, err := c.lookupBlacklist(header, parentState)
+	if err != nil {
+		return nil, err
+	}
+	c.blacklists.Add(header.ParentHash, m)
+	return m, nil
+}
+
+// readBlacklist reads the blacklist from the address list contract in the given
+// state, without any caching.
+func (c *Npos) readBlacklist(header *types.Header, parentState *state.StateDB) (map[common.Address]BannedDirection, error) {
 	alABI := c.abi[systemcontract.AddressListContractName]
 	get := func(method string) ([]common.Address, error) {
 		ret, err := c.commonCallContract(header, parentState, alABI, systemcontract.AddressListContractAddr, method, 1)
@@ -325,7 +371,7 @@ func (c *Npos) getBlacklist(header *types.Header, parentState *state.StateDB) (m
 		return nil, err
 	}
 
-	m := make(map[common.Address]bannedDirection)
+	m := make(map[common.Address]BannedDirection)
 	for _, from := range froms {
 		m[from] = DirectionFrom
 	}
@@ -336,25 +382,126 @@ func (c *Npos) getBlacklist(header *types.Header, parentState *state.StateDB) (m
 			m[to] = DirectionTo
 		}
 	}
-	c.blacklists.Add(header.ParentHash, m)
 	return m, nil
 }
 
-func (c *Npos) CreateEvmExtraValidator(header *types.Header, parentState *state.StateDB) types.EvmExtraValidator {
+// CreateEvmExtraValidator returns the validator of the rules enforced by the evm
+// in the block, read from the parent state. As ValidateTx does for the
+// transactions, it fails if any rule can't be read: the rules are part of the
+// consensus, neither allowing nor banning every call would match the nodes
+// able to read them, so the block is neither processed nor sealed.
+func (c *Npos) CreateEvmExtraValidator(header *types.Header, parentState *state.StateDB) (types.EvmExtraValidator, error) {
+	var developers map[common.Address]struct{}
+	if c.config.IsDevVerification(header.Number) {
+		var err error
+		if developers, err = c.getDevelopers(header, parentState); err != nil {
+			return nil, fmt.Errorf("failed to read the developers: %w", err)
+		}
+	}
+	var callRules *CallRules
+	if c.config.IsAddressListV2(header.Number) {
+		var err error
+		if callRules, err = c.getCallRules(header, parentState); err != nil {
+			return nil, fmt.Errorf("failed to read the call rules: %w", err)
+		}
+	}
 	blacks, err := c.getBlacklist(header, parentState)
 	if err != nil {
-		log.Error("getBlacklist failed", "err", err)
-		return nil
+		return nil, fmt.Errorf("failed to read the blacklist: %w", err)
 	}
 	rules, err := c.getEventCheckRules(header, parentState)
 	if err != nil {
-		log.Error("getEventCheckRules failed", "err", err)
-		return nil
+		return nil, fmt.Errorf("failed to read the event check rules: %w", err)
 	}
 	return &daoRulesValidator{
-		blacks: blacks,
-		rules:  rules,
+		blacks:     blacks,
+		rules:      rules,
+		developers: developers,
+		callRules:  callRules,
+		dataChecks: c.config.IsAddressListV2(header.Number),
+	}, nil
+}
+
+// getDevelopers returns the addresses allowed to create contracts when the
+// developer verification is enabled.
+func (c *Npos) getDevelopers(header *types.Header, parentState *state.StateDB) (map[common.Address]struct{}, error) {
+	if v, ok := c.developers.Get(header.ParentHash); ok {
+		return v.(map[common.Address]struct{}), nil
+	}
+
+	c.devLock.Lock()
+	defer c.devLock.Unlock()
+	if v, ok := c.developers.Get(header.ParentHash); ok {
+		return v.(map[common.Address]struct{}), nil
+	}
+
+	ret, err := c.commonCallContract(header, parentState, c.abi[systemcontract.AddressListContractName], systemcontract.AddressListContractAddr, "getDevelopers", 1)
+	if err != nil {
+		return nil, err
+	}
+	devs, ok := ret[0].([]common.Address)
+	if !ok {
+		return nil, errors.New("invalid developers format")
+	}
+	m := make(map[common.Address]struct{}, len(devs))
+	for _, dev := range devs {
+		m[dev] = struct{}{}
+	}
+	c.developers.Add(header.ParentHash, m)
+	return m, nil
+}
+
+// getCallRules returns the calls banned by the AddressList contract v2.
+func (c *Npos) getCallRules(header *types.Header, parentState *state.StateDB) (*CallRules, error) {
+	if v, ok := c.callRules.Get(header.ParentHash); ok {
+		return v.(*CallRules), nil
+	}
+
+	c.callRulesLock.Lock()
+	defer c.callRulesLock.Unlock()
+	if v, ok := c.callRules.Get(header.ParentHash); ok {
+		return v.(*CallRules), nil
+	}
+
+	alABI := c.abi[systemcontract.AddressListContractName]
+	get := func(method string) ([][32]byte, error) {
+		ret, err := c.commonCallContract(header, parentState, alABI, systemcontract.AddressListContractAddr, method, 1)
+		if err != nil {
+			return nil, err
+		}
+		keys, ok := ret[0].([][32]byte)
+		if !ok {
+			return nil, fmt.Errorf("unexpected output type, value: %v", ret[0])
+		}
+		return keys, nil
+	}
+	hashes, err := get("getCodeHashRules")
+	if err != nil {
+		return nil, err
+	}
+	selectors, err := get("getSelectorRules")
+	if err != nil {
+		return nil, err
+	}
+	rules := &CallRules{
+		CodeHashes: make(map[common.Hash]struct{}, len(hashes)),
+		Selectors:  make(map[common.Address]map[[4]byte]struct{}),
+	}
+	for _, hash := range hashes {
+		rules.CodeHashes[hash] = struct{}{}
+	}
+	// a selector rule is the target address followed by the selector
+	for _, key := range selectors {
+		target := common.BytesToAddress(key[:common.AddressLength])
+		if rules.Selectors[target] == nil {
+			rules.Selectors[target] = make(map[[4]byte]struct{})
+		}
+		var selector [4]byte
+		copy(selector[:], key[common.AddressLength:])
+		rules.Selectors[target][selector] = struct{}{}
 	}
+	c.callRules.Add(header.ParentHash, rules)
+	return rules, nil
 }
 
 func (c *Npos) getEventCheckRules(header *types.Header, parentState *state.StateDB) (map[common.Hash]*EventCheckRule, error) {
@@ -363,6 +510,7 @@ func (c *Npos) getEventCheckRules(header *types.Header, parentState *state.State
 	}(time.Now())
 
 	if v, ok := c.eventCheckRules.Get(header.ParentHash); ok {
+		rulesCacheHitMeter.Mark(1)
 		return v.(map[common.Hash]*EventCheckRule), nil
 	}
 
@@ -379,6 +527,7 @@ func (c *Npos) getEventCheckRules(header *types.Header, parentState *state.State
 		parent := c.chain.GetHeader(header.ParentHash, num-1)
 		if parent != nil {
 			if v, ok := c.eventCheckRules.Get(parent.ParentHash); ok {
+				rulesCacheHitMeter.Mark(1)
 				m := v.(map[common.Hash]*EventCheckRule)
 				c.eventCheckRules.Add(header.ParentHash, m)
 				return m, nil
@@ -388,10 +537,21 @@ func (c *Npos) getEventCheckRules(header *types.Header, parentState *state.State
 		}
 	}
 
-	// can't get blacklist from cache, try to call the contract
+	// can't get rules from cache, try the index or call the contract
+	rules, err := c.lookupEventCheckRules(header, parentState)
+	if err != nil {
+		return nil, err
+	}
+	c.eventCheckRules.Add(header.ParentHash, rules)
+	return rules, nil
+}
+
+// readEventCheckRules reads the event check rules from the address list contract
+// in the given state, without any caching.
+func (c *Npos) readEventCheckRules(header *types.Header, parentState *state.StateDB) (map[common.Hash]*EventCheckRule, error) {
 	alABI := c.abi[systemcontract.AddressListContractName]
 	method := "getRuleByIndex"
-	get := func(i uint32) (common.Hash, int, common.AddressCheckType, error) {
+	get := func(i uint32) (common.Hash, uint64, common.AddressCheckType, error) {
 		ret, err := c.commonCallContract(header, parentState, alABI, systemcontract.AddressListContractAddr, method, 3, i)
 		if err != nil {
 			return common.Hash{}, 0, common.CheckNone, err
@@ -400,7 +560,7 @@ func (c *Npos) getEventCheckRules(header *types.Header, parentState *state.State
 		idx := ret[1].(*big.Int).Uint64()
 		ct := ret[2].(uint8)
 
-		return sig, int(idx), common.AddressCheckType(ct), nil
+		return sig, idx, common.AddressCheckType(ct), nil
 	}
 
 	cnt, err := c.getEventCheckRulesLen(header, parentState)
@@ -412,21 +572,16 @@ func (c *Npos) getEventCheckRules(header *types.Header, parentState *state.State
 	for i := 0; i < cnt; i++ {
 		sig, idx, ct, err := get(uint32(i))
 		if err != nil {
-			log.Error("getRuleByIndex failed", "index", i, "number", num, "blockHash", header.Hash(), "err", err)
+			log.Error("getRuleByIndex failed", "index", i, "number", header.Number, "blockHash", header.Hash(), "err", err)
 			return nil, err
 		}
 		rule, exist := rules[sig]
 		if !exist {
-			rule = &EventCheckRule{
-				EventSig: sig,
-				Checks:   make(map[int]common.AddressCheckType),
-			}
+			rule = newEventCheckRule(sig)
 			rules[sig] = rule
 		}
-		rule.Checks[idx] = ct
+		rule.setCheck(idx, ct)
 	}
-
-	c.eventCheckRules.Add(header.ParentHash, rules)
 	return rules, nil
 }
 
@@ -0,0 +1,443 @@
+package npos
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// The actions of the system governance proposals.
+const (
+	ActionEvmCall      uint64 = iota // Call To from From with Value and Data
+	ActionEraseCode                  // Erase the account To
+	ActionSetStorage                 // Set the storage slots of To, Data is a list of 32-byte (slot, value) pairs
+	ActionSetBalance                 // Set the balance of To to Value, the difference is paid by (or refunded to) From, or minted (burnt) if From is zero
+	ActionUpgradeCode                // Upgrade the system contract To to its embedded release of version Value
+	ActionUpdateConfig               // Schedule a consensus config update, Data is the RLP of a ConfigUpdate
+)
+
+var (
+	errUnsupportedAction = errors.New("unsupported action")
+	errInvalidActionData = errors.New("invalid action data")
+
+	// errInvalidPeriodSchedule is returned if an epoch header carries an invalid
+	// period schedule, or one that doesn't match the chain.
+	errInvalidPeriodSchedule = errors.New("invalid period schedule")
+)
+
+// The logs of the actions not running on the evm, emitted from the governance
+// contract with the proposal id and the account as the first indexed topics, so
+// every state change made outside the evm can be followed from the receipts.
+var (
+	// BalanceSet(uint256 indexed id, address indexed account, address indexed from, uint256 previous, uint256 balance)
+	balanceSetTopic = crypto.Keccak256Hash([]byte("BalanceSet(uint256,address,address,uint256,uint256)"))
+	// AccountErased(uint256 indexed id, address indexed account, bytes32 codeHash), the balance is kept
+	accountErasedTopic = crypto.Keccak256Hash([]byte("AccountErased(uint256,address,bytes32)"))
+	// StorageSet(uint256 indexed id, address indexed account, bytes32 slot, bytes32 previous, bytes32 value)
+	storageSetTopic = crypto.Keccak256Hash([]byte("StorageSet(uint256,address,bytes32,bytes32,bytes32)"))
+	// CodeUpgraded(uint256 indexed id, address indexed account, uint256 version, bytes32 previousCodeHash, bytes32 codeHash)
+	codeUpgradedTopic = crypto.Keccak256Hash([]byte("CodeUpgraded(uint256,address,uint256,bytes32,bytes32)"))
+)
+
+// govAction executes a kind of system governance proposals.
+type govAction interface {
+	// name returns the name of the action for logging.
+	name() string
+
+	// enabled returns whether the action can be executed in the given block.
+	enabled(config *params.NposConfig, number *big.Int) bool
+
+	// execute applies the proposal in the given environment.
+	execute(env *actionEnv, prop *Proposal) ([]byte, error)
+}
+
+// actionEnv is the environment the governance actions are executed in. It's
+// shared by the block processing and the tracing of system transactions, so
+// both always have the same effects.
+type actionEnv struct {
+	chainConfig *params.ChainConfig
+	config      *params.NposConfig
+	number      *big.Int
+	state       *state.StateDB
+
+	// call runs an evm message, finalising its state changes.
+	call func(from, to common.Address, data []byte, value *big.Int) ([]byte, error)
+
+	// recorder collects the state modified by actions not running on the evm, if set.
+	recorder *stateRecorder
+
+	// internalTxs records the actions not running on the evm, if set.
+	internalTxs *internalTxRecorder
+}
+
+func (env *actionEnv) touch(addr common.Address) {
+	if env.recorder != nil {
+		env.recorder.touch(addr)
+	}
+}
+
+// touchErased records an account whose storage is dropped as a whole.
+func (env *actionEnv) touchErased(addr common.Address) {
+	if env.recorder != nil {
+		env.recorder.touch(addr)
+		env.recorder.erased[addr] = struct{}{}
+	}
+}
+
+func (env *actionEnv) setState(addr common.Address, slot, value common.Hash) {
+	if env.recorder != nil {
+		env.recorder.touch(addr)[slot] = struct{}{}
+	}
+	env.state.SetState(addr, slot, value)
+}
+
+// addLog emits a log of an action on the account, with the given data words.
+func (env *actionEnv) addLog(topic common.Hash, prop *Proposal, account common.Address, topics []common.Hash, words ...common.Hash) {
+	data := make([]byte, 0, len(words)*common.HashLength)
+	for _, word := range words {
+		data = append(data, word.Bytes()...)
+	}
+	env.state.AddLog(&types.Log{
+		Address:     systemcontract.SysGovContractAddr,
+		Topics:      append([]common.Hash{topic, common.BigToHash(prop.Id), common.BytesToHash(account.Bytes())}, topics...),
+		Data:        data,
+		BlockNumber: env.number.Uint64(),
+	})
+}
+
+var govActions = make(map[uint64]govAction)
+
+// registerGovAction registers the executor of an action id. It panics if the id
+// is already registered.
+func registerGovAction(id uint64, action govAction) {
+	if _, exist := govActions[id]; exist {
+		panic(fmt.Sprintf("governance action %d registered twice", id))
+	}
+	govActions[id] = action
+}
+
+func init() {
+	registerGovAction(ActionEvmCall, evmCallAction{})
+	registerGovAction(ActionEraseCode, eraseCodeAction{})
+	registerGovAction(ActionSetStorage, setStorageAction{})
+	registerGovAction(ActionSetBalance, setBalanceAction{})
+	registerGovAction(ActionUpgradeCode, upgradeCodeAction{})
+	registerGovAction(ActionUpdateConfig, updateConfigAction{})
+}
+
+// applyProposal executes the proposal with the registered action.
+func (c *Npos) applyProposal(env *actionEnv, prop *Proposal) (string, []byte, error) {
+	if !prop.Action.IsUint64() {
+		return "unknown", nil, errUnsupportedAction
+	}
+	action, ok := govActions[prop.Action.Uint64()]
+	if !ok {
+		return "unknown", nil, errUnsupportedAction
+	}
+	if !action.enabled(c.config, env.number) {
+		return action.name(), nil, errUnsupportedAction
+	}
+	ret, err := action.execute(env, prop)
+	return action.name(), ret, err
+}
+
+type evmCallAction struct{}
+
+func (evmCallAction) name() string { return "evmCall" }
+
+func (evmCallAction) enabled(*params.NposConfig, *big.Int) bool { return true }
+
+func (evmCallAction) execute(env *actionEnv, prop *Proposal) ([]byte, error) {
+	return env.call(prop.From, prop.To, prop.Data, prop.Value)
+}
+
+type eraseCodeAction struct{}
+
+func (eraseCodeAction) name() string { return "erase" }
+
+func (eraseCodeAction) enabled(*params.NposConfig, *big.Int) bool { return true }
+
+func (eraseCodeAction) execute(env *actionEnv, prop *Proposal) ([]byte, error) {
+	env.touchErased(prop.To)
+	codeHash := env.state.GetCodeHash(prop.To)
+	if !env.state.Erase(prop.To) {
+		return nil, errors.New("erase failed")
+	}
+	env.internalTxs.add(engineAction(types.EraseOpCode, systemcontract.EngineCaller, prop.To, nil))
+	env.addLog(accountErasedTopic, prop, prop.To, nil, codeHash)
+	return nil, nil
+}
+
+type setStorageAction struct{}
+
+func (setStorageAction) name() string { return "setStorage" }
+
+func (setStorageAction) enabled(config *params.NposConfig, number *big.Int) bool {
+	return config.IsGovActions(number)
+}
+
+func (setStorageAction) execute(env *actionEnv, prop *Proposal) ([]byte, error) {
+	if len(prop.Data) == 0 || len(prop.Data)%(2*common.HashLength) != 0 {
+		return nil, errInvalidActionData
+	}
+	// the config updates must go through updateConfig, which validates them
+	for data := prop.Data; prop.To == systemcontract.SysGovContractAddr && len(data) > 0; data = data[2*common.HashLength:] {
+		if isConfigSlot(common.BytesToHash(data[:common.HashLength])) {
+			return nil, errInvalidActionData
+		}
+	}
+	for data := prop.Data; len(data) > 0; data = data[2*common.HashLength:] {
+		slot, value := common.BytesToHash(data[:common.HashLength]), common.BytesToHash(data[common.HashLength:2*common.HashLength])
+		previous := env.state.GetState(prop.To, slot)
+		env.setState(prop.To, slot, value)
+		env.addLog(storageSetTopic, prop, prop.To, nil, slot, previous, value)
+	}
+	return nil, nil
+}
+
+type setBalanceAction struct{}
+
+func (setBalanceAction) name() string { return "setBalance" }
+
+func (setBalanceAction) enabled(config *params.NposConfig, number *big.Int) bool {
+	return config.IsGovActions(number)
+}
+
+func (setBalanceAction) execute(env *actionEnv, prop *Proposal) ([]byte, error) {
+	if prop.To == prop.From || prop.Value.Sign() < 0 {
+		return nil, errInvalidActionData
+	}
+	env.touch(prop.To)
+	previous := env.state.GetBalance(prop.To)
+	diff := new(big.Int).Sub(prop.Value, previous)
+	if prop.From != (common.Address{}) {
+		env.touch(prop.From)
+		if env.state.GetBalance(prop.From).Cmp(diff) < 0 {
+			return nil, errors.New("insufficient balance of the payer")
+		}
+		if diff.Sign() > 0 {
+			env.state.SubBalance(prop.From, diff)
+		} else {
+			env.state.AddBalance(prop.From, new(big.Int).Neg(diff))
+		}
+	}
+	env.state.SetBalance(prop.To, prop.Value)
+	if diff.Sign() > 0 {
+		env.internalTxs.add(engineAction(types.SetBalanceOpCode, prop.From, prop.To, diff))
+	} else if diff.Sign() < 0 {
+		env.internalTxs.add(engineAction(types.SetBalanceOpCode, prop.To, prop.From, new(big.Int).Neg(diff)))
+	}
+	env.addLog(balanceSetTopic, prop, prop.To, []common.Hash{common.BytesToHash(prop.From.Bytes())}, common.BigToHash(previous), common.BigToHash(prop.Value))
+	return nil, nil
+}
+
+type upgradeCodeAction struct{}
+
+func (upgradeCodeAction) name() string { return "upgradeCode" }
+
+func (upgradeCodeAction) enabled(config *params.NposConfig, number *big.Int) bool {
+	return config.IsGovActions(number)
+}
+
+func (upgradeCodeAction) execute(env *actionEnv, prop *Proposal) ([]byte, error) {
+	switch prop.To {
+	case systemcontract.ValidatorsContractAddr, systemcontract.PunishContractAddr,
+		systemcontract.SysGovContractAddr, systemcontract.AddressListContractAddr:
+	default:
+		return nil, errors.New("not a system contract")
+	}
+	// only the releases embedded in the client are installed, never raw code
+	if len(prop.Data) != 0 || !prop.Value.IsInt64() || prop.Value.Sign() <= 0 {
+		return nil, errInvalidActionData
+	}
+	version := systemcontract.SysContractVersion(prop.Value.Int64())
+	env.touch(prop.To)
+	previous := env.state.GetCodeHash(prop.To)
+	v0, migration, err := systemcontract.UpgradeCode(version, prop.To, env.chainConfig, env.state)
+	if err != nil {
+		return nil, err
+	}
+	if v0 != (common.Address{}) {
+		env.touch(v0)
+	}
+	// the code stays upgraded if the migration fails, the evm changes are
+	// finalised and can't be reverted
+	if migration != nil {
+		if _, err := env.call(systemcontract.EngineCaller, prop.To, migration, new(big.Int)); err != nil {
+			return nil, err
+		}
+	}
+	env.addLog(codeUpgradedTopic, prop, prop.To, nil, common.BigToHash(prop.Value), previous, env.state.GetCodeHash(prop.To))
+	return nil, nil
+}
+
+// ConfigUpdate is the data of an ActionUpdateConfig proposal, it sets the
+// parameter to the value from the given block on.
+type ConfigUpdate struct {
+	Param string
+	Value uint64
+	Block uint64
+}
+
+// configParams are the consensus parameters that can be updated by governance,
+// with the validation of their values.
+//
+// The epoch can't be updated: the epoch blocks, the checkpoints and the
+// validator set rotations are all derived from the block number and the
+// configured epoch, without any history to look up another length.
+var configParams = map[string]func(value uint64) error{
+	"period": func(value uint64) error {
+		if value == 0 {
+			return errors.New("zero period")
+		}
+		return nil
+	},
+}
+
+type updateConfigAction struct{}
+
+func (updateConfigAction) name() string { return "updateConfig" }
+
+func (updateConfigAction) enabled(config *params.NposConfig, number *big.Int) bool {
+	return config.IsGovActions(number)
+}
+
+func (updateConfigAction) execute(env *actionEnv, prop *Proposal) ([]byte, error) {
+	var update ConfigUpdate
+	if err := rlp.DecodeBytes(prop.Data, &update); err != nil {
+		return nil, errInvalidActionData
+	}
+	validate, ok := configParams[update.Param]
+	if !ok {
+		return nil, fmt.Errorf("unknown config parameter %q", update.Param)
+	}
+	if err := validate(update.Value); err != nil {
+		return nil, err
+	}
+	// The update is announced by the next epoch header, it can't be in effect before.
+	if next := (env.number.Uint64()/env.config.Epoch + 1) * env.config.Epoch; update.Block <= next {
+		return nil, fmt.Errorf("config update not after the next epoch block %d", next)
+	}
+	pending, block := configSlots(update.Param)
+	env.setState(systemcontract.SysGovContractAddr, pending, common.BigToHash(new(big.Int).SetUint64(update.Value)))
+	env.setState(systemcontract.SysGovContractAddr, block, common.BigToHash(new(big.Int).SetUint64(update.Block)))
+	return nil, nil
+}
+
+// configSlots returns the storage slots of the governance contract holding the
+// pending update of a config parameter and its activation block.
+func configSlots(param string) (pending, block common.Hash) {
+	base := crypto.Keccak256Hash([]byte("npos.config." + param)).Big()
+	pending = common.BigToHash(base)
+	block = common.BigToHash(new(big.Int).Add(base, common.Big1))
+	return
+}
+
+// isConfigSlot returns whether the slot of the governance contract holds a config update.
+func isConfigSlot(slot common.Hash) bool {
+	for param := range configParams {
+		if pending, block := configSlots(param); slot == pending || slot == block {
+			return true
+		}
+	}
+	return false
+}
+
+// scheduledConfig returns the update of a config parameter scheduled in the state
+// after the given block, or zeros if there is none.
+func scheduledConfig(state consensus.StateReader, param string, number uint64) (value, block uint64) {
+	pending, blockSlot := configSlots(param)
+	b := state.GetState(systemcontract.SysGovContractAddr, blockSlot).Big()
+	v := state.GetState(systemcontract.SysGovContractAddr, pending).Big()
+	if !b.IsUint64() || b.Uint64() <= number || !v.IsUint64() || configParams[param](v.Uint64()) != nil {
+		return 0, 0
+	}
+	return v.Uint64(), b.Uint64()
+}
+
+// periodScheduleLength is the size of a PeriodSchedule in the header extra-data.
+const periodScheduleLength = 3 * 8
+
+// PeriodSchedule is the block period in effect and its next update scheduled by
+// governance. Once the governance actions are enabled, every epoch header carries
+// it after the validators, so the period is verified from the headers alone.
+type PeriodSchedule struct {
+	Period  uint64 `json:"period"`            // Block period in effect
+	Pending uint64 `json:"pending,omitempty"` // Next block period, zero if none is scheduled
+	Block   uint64 `json:"block,omitempty"`   // Block the next period is in effect from
+}
+
+// at returns the block period in effect at the given block.
+func (s PeriodSchedule) at(number uint64) uint64 {
+	if s.Pending != 0 && s.Block <= number {
+		return s.Pending
+	}
+	return s.Period
+}
+
+func (s PeriodSchedule) bytes() []byte {
+	b := make([]byte, periodScheduleLength)
+	binary.BigEndian.PutUint64(b, s.Period)
+	binary.BigEndian.PutUint64(b[8:], s.Pending)
+	binary.BigEndian.PutUint64(b[16:], s.Block)
+	return b
+}
+
+// extraScheduleLength returns the size of the period schedule in the header extra-data.
+func extraScheduleLength(config *params.NposConfig, number *big.Int) int {
+	if config.IsGovActions(number) && number.Uint64()%config.Epoch == 0 {
+		return periodScheduleLength
+	}
+	return 0
+}
+
+// parsePeriodSchedule extracts the period schedule from the extra-data of an
+// epoch header, it returns nil if the header doesn't carry any.
+func parsePeriodSchedule(config *params.NposConfig, header *types.Header) (*PeriodSchedule, error) {
+	length := extraScheduleLength(config, header.Number)
+	if length == 0 {
+		return nil, nil
+	}
+	end := len(header.Extra) - extraSuffix(config, header.Number) - extraCheckpointsLength(config, header.Number)
+	if end-length < extraVanity {
+		return nil, errInvalidPeriodSchedule
+	}
+	b := header.Extra[end-length : end]
+	schedule := &PeriodSchedule{
+		Period:  binary.BigEndian.Uint64(b),
+		Pending: binary.BigEndian.Uint64(b[8:]),
+		Block:   binary.BigEndian.Uint64(b[16:]),
+	}
+	if configParams["period"](schedule.Period) != nil || (schedule.Pending == 0) != (schedule.Block == 0) ||
+		(schedule.Pending != 0 && schedule.Block <= header.Number.Uint64()) {
+		return nil, errInvalidPeriodSchedule
+	}
+	return schedule, nil
+}
+
+// periodSchedule returns the period schedule of the epoch header on top of the
+// snapshot: the period in effect, with the update scheduled in the parent state.
+func (c *Npos) periodSchedule(chain consensus.ChainHeaderReader, snap *Snapshot, header *types.Header) (*PeriodSchedule, error) {
+	number := header.Number.Uint64()
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return nil, consensus.ErrUnknownAncestor
+	}
+	statedb, err := c.stateFn(parent.Root)
+	if err != nil {
+		return nil, err
+	}
+	schedule := &PeriodSchedule{Period: snap.Period.at(number)}
+	schedule.Pending, schedule.Block = scheduledConfig(statedb, "period", number)
+	return schedule, nil
+}
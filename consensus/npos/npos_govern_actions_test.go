@@ -0,0 +1,203 @@
+package npos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+// propose commits a proposal in the next block, and returns the receipt of its execution.
+func (tc *testChain) propose(action uint64, from, to common.Address, value *big.Int, data []byte) *types.Receipt {
+	tc.t.Helper()
+	blocks := tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(tc.adminTx(b, systemcontract.SysGovContractAddr, "commitProposal", new(big.Int).SetUint64(action), from, to, value, data))
+	})
+	receipts := tc.chain.GetReceiptsByHash(blocks[0].Hash())
+	require.Len(tc.t, receipts, 2)
+	return receipts[1]
+}
+
+// requireActionLog checks the receipt carries the single log of an action on the
+// account, from is only a topic of the balance updates.
+func requireActionLog(t *testing.T, receipt *types.Receipt, topic common.Hash, account, from common.Address, words ...common.Hash) {
+	t.Helper()
+	require.Len(t, receipt.Logs, 1)
+	l := receipt.Logs[0]
+	require.Equal(t, systemcontract.SysGovContractAddr, l.Address)
+	topics := []common.Hash{topic, l.Topics[1], common.BytesToHash(account.Bytes())}
+	if topic == balanceSetTopic {
+		topics = append(topics, common.BytesToHash(from.Bytes()))
+	}
+	require.Equal(t, topics, l.Topics)
+	var data []byte
+	for _, word := range words {
+		data = append(data, word.Bytes()...)
+	}
+	require.Equal(t, data, l.Data)
+}
+
+// requireEngineAction checks the internal tx of the proposal carries the single
+// action applied by the engine off the evm.
+func requireEngineAction(t *testing.T, tc *testChain, receipt *types.Receipt, opCode string, from, to common.Address, value *big.Int) {
+	t.Helper()
+	itxs := rawdb.ReadInternalTxs(tc.db, receipt.BlockHash, receipt.BlockNumber.Uint64())
+	actions := internalTx(t, itxs, receipt.TxHash).Actions
+	require.Len(t, actions, 1)
+	action := actions[0]
+	require.Equal(t, opCode, action.OpCode)
+	require.Equal(t, from, action.From)
+	require.Equal(t, to, action.To)
+	require.Zero(t, value.Cmp(action.Value))
+	require.Equal(t, types.EngineActionDepth, action.Depth)
+	require.True(t, action.Success)
+}
+
+func TestGovActions(t *testing.T) {
+	tc := newTestChain(t)
+	account := common.Address{0xaa}
+	slot, value := common.Hash{0x01}, common.Hash{0x02}
+	slots := append(slot.Bytes(), value.Bytes()...)
+
+	// the new actions are rejected before the fork
+	receipt := tc.propose(ActionSetStorage, common.Address{}, account, common.Big0, slots)
+	require.Equal(t, types.ReceiptStatusFailed, receipt.Status)
+
+	// minting
+	ether := big.NewInt(params.Ether)
+	receipt = tc.propose(ActionSetBalance, common.Address{}, account, ether, nil)
+	require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	require.Equal(t, ether, mustState(t, tc.chain).GetBalance(account))
+	requireActionLog(t, receipt, balanceSetTopic, account, common.Address{}, common.Hash{}, common.BigToHash(ether))
+	requireEngineAction(t, tc, receipt, types.SetBalanceOpCode, common.Address{}, account, ether)
+
+	// the account must not be empty, or the storage is discarded
+	receipt = tc.propose(ActionSetStorage, common.Address{}, account, common.Big0, slots)
+	require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	require.Equal(t, value, mustState(t, tc.chain).GetState(account, slot))
+	requireActionLog(t, receipt, storageSetTopic, account, common.Address{}, slot, common.Hash{}, value)
+
+	receipt = tc.propose(ActionSetStorage, common.Address{}, account, common.Big0, slots[1:])
+	require.Equal(t, types.ReceiptStatusFailed, receipt.Status)
+	require.Empty(t, receipt.Logs)
+
+	// the difference is paid by the sender
+	payer := common.Address{0xbb}
+	receipt = tc.propose(ActionSetBalance, payer, account, new(big.Int).Mul(ether, common.Big2), nil)
+	require.Equal(t, types.ReceiptStatusFailed, receipt.Status)
+	receipt = tc.propose(ActionSetBalance, account, payer, big.NewInt(1), nil)
+	require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	require.Equal(t, big.NewInt(1), mustState(t, tc.chain).GetBalance(payer))
+	require.Equal(t, new(big.Int).Sub(ether, common.Big1), mustState(t, tc.chain).GetBalance(account))
+	requireActionLog(t, receipt, balanceSetTopic, payer, account, common.Hash{}, common.BigToHash(common.Big1))
+	requireEngineAction(t, tc, receipt, types.SetBalanceOpCode, account, payer, common.Big1)
+
+	// only the embedded releases of the system contracts can be installed
+	v1 := big.NewInt(int64(systemcontract.SysContractV1))
+	punishCode := mustState(t, tc.chain).GetCode(systemcontract.PunishContractAddr)
+	receipt = tc.propose(ActionUpgradeCode, common.Address{}, account, v1, nil)
+	require.Equal(t, types.ReceiptStatusFailed, receipt.Status)
+	receipt = tc.propose(ActionUpgradeCode, common.Address{}, systemcontract.PunishContractAddr, common.Big0, append(punishCode, 0x00))
+	require.Equal(t, types.ReceiptStatusFailed, receipt.Status)
+	receipt = tc.propose(ActionUpgradeCode, common.Address{}, systemcontract.PunishContractAddr, big.NewInt(100), nil)
+	require.Equal(t, types.ReceiptStatusFailed, receipt.Status)
+	receipt = tc.propose(ActionUpgradeCode, common.Address{}, systemcontract.ValidatorsContractAddr, v1, nil)
+	require.Equal(t, types.ReceiptStatusFailed, receipt.Status)
+	require.Equal(t, punishCode, mustState(t, tc.chain).GetCode(systemcontract.PunishContractAddr))
+
+	receipt = tc.propose(ActionUpgradeCode, common.Address{}, systemcontract.PunishContractAddr, v1, nil)
+	require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	code, err := systemcontract.Bytecode(systemcontract.SysContractV1, systemcontract.PunishContractName)
+	require.NoError(t, err)
+	require.Equal(t, code, mustState(t, tc.chain).GetCode(systemcontract.PunishContractAddr))
+	require.Equal(t, punishCode, mustState(t, tc.chain).GetCode(systemcontract.PunishV0ContractAddr))
+	requireActionLog(t, receipt, codeUpgradedTopic, systemcontract.PunishContractAddr, common.Address{}, common.BigToHash(v1), crypto.Keccak256Hash(punishCode), crypto.Keccak256Hash(code))
+
+	// erasing keeps the balance
+	receipt = tc.propose(ActionEraseCode, common.Address{}, account, common.Big0, nil)
+	require.Equal(t, types.ReceiptStatusSuccessful, receipt.Status)
+	require.Equal(t, new(big.Int).Sub(ether, common.Big1), mustState(t, tc.chain).GetBalance(account))
+	requireActionLog(t, receipt, accountErasedTopic, account, common.Address{}, types.EmptyCodeHash)
+	requireEngineAction(t, tc, receipt, types.EraseOpCode, systemcontract.EngineCaller, account, common.Big0)
+
+	// unknown actions fail
+	receipt = tc.propose(100, common.Address{}, account, common.Big0, nil)
+	require.Equal(t, types.ReceiptStatusFailed, receipt.Status)
+}
+
+func TestGovActionUpdatePeriod(t *testing.T) {
+	tc := newTestChain(t)
+	tc.extend(2, nil)
+
+	update := func(param string, value, block uint64) *types.Receipt {
+		data, err := rlp.EncodeToBytes(&ConfigUpdate{Param: param, Value: value, Block: block})
+		require.NoError(t, err)
+		return tc.propose(ActionUpdateConfig, common.Address{}, common.Address{}, common.Big0, data)
+	}
+	require.Equal(t, types.ReceiptStatusFailed, update("epoch", 10, 20).Status)
+	require.Equal(t, types.ReceiptStatusFailed, update("period", 0, 20).Status)
+	require.Equal(t, types.ReceiptStatusFailed, update("period", 3, 10).Status) // committed in block 5, announced by block 10
+	require.Equal(t, types.ReceiptStatusSuccessful, update("period", 3, 12).Status)
+
+	// the config slots can't be set directly
+	pending, _ := configSlots("period")
+	receipt := tc.propose(ActionSetStorage, common.Address{}, systemcontract.SysGovContractAddr, common.Big0, append(pending.Bytes(), common.Hash{}.Bytes()...))
+	require.Equal(t, types.ReceiptStatusFailed, receipt.Status)
+
+	blocks := tc.extend(8, nil)
+	for _, block := range blocks {
+		parent := tc.chain.GetHeaderByHash(block.ParentHash())
+		period := tc.config.Npos.Period
+		if block.NumberU64() >= 12 {
+			period = 3
+		}
+		require.Equal(t, parent.Time+period, block.Time(), "block %d", block.NumberU64())
+	}
+	// the epoch headers announce the update
+	schedule, err := parsePeriodSchedule(tc.config.Npos, tc.chain.GetHeaderByNumber(10))
+	require.NoError(t, err)
+	require.Equal(t, &PeriodSchedule{Period: tc.config.Npos.Period, Pending: 3, Block: 12}, schedule)
+	schedule, err = parsePeriodSchedule(tc.config.Npos, tc.chain.GetHeaderByNumber(15))
+	require.NoError(t, err)
+	require.Equal(t, &PeriodSchedule{Period: 3}, schedule)
+
+	// a later update keeps the current one until it's in effect
+	require.Equal(t, types.ReceiptStatusFailed, update("period", 2, 20).Status) // committed in block 16
+	require.Equal(t, types.ReceiptStatusSuccessful, update("period", 2, 22).Status)
+	blocks = tc.extend(5, nil)
+	for _, block := range blocks {
+		period := uint64(3)
+		if block.NumberU64() >= 22 {
+			period = 2
+		}
+		require.Equal(t, period, block.Time()-tc.chain.GetHeaderByHash(block.ParentHash()).Time, "block %d", block.NumberU64())
+	}
+
+	// blocks sealed too early are rejected from the headers alone
+	header := types.CopyHeader(tc.chain.CurrentHeader())
+	header.Time = tc.chain.GetHeaderByHash(header.ParentHash).Time + 1
+	require.ErrorIs(t, tc.engine.VerifyHeader(tc.chain, header, false), ErrInvalidTimestamp)
+
+	// so are epoch headers announcing another period
+	header = types.CopyHeader(tc.chain.GetHeaderByNumber(20))
+	end := len(header.Extra) - extraSuffix(tc.config.Npos, header.Number)
+	header.Extra[end-periodScheduleLength+7]++
+	require.ErrorIs(t, tc.engine.VerifyHeader(tc.chain, header, false), errInvalidPeriodSchedule)
+
+	// or an update not scheduled in the state
+	header = types.CopyHeader(tc.chain.GetHeaderByNumber(20))
+	statedb, err := tc.chain.StateAt(tc.chain.GetHeaderByNumber(19).Root)
+	require.NoError(t, err)
+	require.NoError(t, tc.engine.Finalize(tc.chain, types.CopyHeader(header), statedb.Copy(), nil, nil, nil, nil))
+	end = len(header.Extra) - extraSuffix(tc.config.Npos, header.Number)
+	header.Extra[end-1]++
+	require.ErrorIs(t, tc.engine.Finalize(tc.chain, header, statedb.Copy(), nil, nil, nil, nil), errInvalidPeriodSchedule)
+}
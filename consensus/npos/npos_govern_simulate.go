@@ -0,0 +1,225 @@
+package npos
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ProposalSimulation is the outcome of executing a governance proposal on a copy
+// of the chain state.
+type ProposalSimulation struct {
+	Id        *hexutil.Big       `json:"id,omitempty"`
+	Action    *hexutil.Big       `json:"action"`
+	From      common.Address     `json:"from"`
+	To        common.Address     `json:"to"`
+	Value     *hexutil.Big       `json:"value"`
+	Data      hexutil.Bytes      `json:"data"`
+	Success   bool               `json:"success"`
+	Error     string             `json:"error,omitempty"` // Revert reason or failure cause
+	Logs      []*types.Log       `json:"logs"`
+	StateDiff *ProposalStateDiff `json:"stateDiff"`
+}
+
+// ProposalStateDiff lists the accounts modified by a proposal, with only the
+// modified fields set, before and after its execution.
+//
+// The state doesn't keep the keys of the storage slots, so the slots dropped by
+// an erase proposal aren't listed: the erased account is flagged by
+// StorageCleared in Post instead.
+type ProposalStateDiff struct {
+	Pre  map[common.Address]*AccountState `json:"pre"`
+	Post map[common.Address]*AccountState `json:"post"`
+}
+
+// AccountState is a partial view of an account.
+type AccountState struct {
+	Balance *hexutil.Big                `json:"balance,omitempty"`
+	Nonce   *hexutil.Uint64             `json:"nonce,omitempty"`
+	Code    *hexutil.Bytes              `json:"code,omitempty"`
+	Storage map[common.Hash]common.Hash `json:"storage,omitempty"`
+
+	StorageCleared bool `json:"storageCleared,omitempty"` // Whether the whole storage was dropped
+}
+
+func (c *Npos) getProposalById(ctx *systemcontract.CallContext, id *big.Int) (*Proposal, error) {
+	method := "getProposalById"
+	data, err := c.abi[systemcontract.SysGovContractName].Pack(method, id)
+	if err != nil {
+		return nil, err
+	}
+	result, err := systemcontract.VmCall(ctx, systemcontract.SysGovContractAddr, data)
+	if err != nil {
+		return nil, err
+	}
+	prop := &Proposal{}
+	if err := c.abi[systemcontract.SysGovContractName].UnpackIntoInterface(prop, method, result); err != nil {
+		return nil, err
+	}
+	if prop.Id.Cmp(id) != 0 {
+		return nil, errors.New("unknown proposal")
+	}
+	return prop, nil
+}
+
+// simulateProposal executes the proposal the way FinalizeAndAssemble would in
+// the block following parent, and reports its effects. The given state is modified.
+func (c *Npos) simulateProposal(chain consensus.ChainHeaderReader, parent *types.Header, statedb *state.StateDB, prop *Proposal) (*ProposalSimulation, error) {
+	snap, err := c.snapshot(chain, parent.Number.Uint64(), parent.Hash(), nil)
+	if err != nil {
+		return nil, err
+	}
+	header := &types.Header{
+		ParentHash: parent.Hash(),
+		Number:     new(big.Int).Add(parent.Number, common.Big1),
+		Time:       parent.Time + snap.Period.at(parent.Number.Uint64()+1),
+		GasLimit:   parent.GasLimit,
+		Difficulty: new(big.Int).Set(diffInTurn),
+		Coinbase:   parent.Coinbase,
+		BaseFee:    parent.BaseFee,
+	}
+	recorder := newStateRecorder()
+	ctx := &systemcontract.CallContext{
+		Statedb:      statedb,
+		Header:       header,
+		ChainContext: newChainContext(chain, c),
+		ChainConfig:  c.chainConfig,
+		Tracer:       recorder,
+	}
+	propRLP, err := rlp.EncodeToBytes(prop)
+	if err != nil {
+		return nil, err
+	}
+	pre := statedb.Copy()
+	receipt, execErr := c.executeProposalMsg(ctx, prop, 0, crypto.Keccak256Hash(propRLP), common.Hash{})
+
+	result := &ProposalSimulation{
+		Id:        (*hexutil.Big)(prop.Id),
+		Action:    (*hexutil.Big)(prop.Action),
+		From:      prop.From,
+		To:        prop.To,
+		Value:     (*hexutil.Big)(prop.Value),
+		Data:      prop.Data,
+		Success:   receipt.Status == types.ReceiptStatusSuccessful,
+		Logs:      receipt.Logs,
+		StateDiff: recorder.diff(pre, statedb),
+	}
+	if result.Logs == nil {
+		result.Logs = []*types.Log{}
+	}
+	switch {
+	case recorder.err != nil:
+		result.Error = systemcontract.WrapVMError(recorder.err, recorder.output).Error()
+	case execErr != nil:
+		// the proposal failed before reaching the evm, e.g. an unsupported action
+		// or a value transfer beyond the balance
+		result.Error = execErr.Error()
+	case !result.Success:
+		result.Error = "proposal execution failed"
+	}
+	return result, nil
+}
+
+// stateRecorder is an EVM tracer collecting the accounts and storage slots a call
+// may have modified.
+type stateRecorder struct {
+	accounts map[common.Address]map[common.Hash]struct{}
+	erased   map[common.Address]struct{}
+	output   []byte
+	err      error
+}
+
+func newStateRecorder() *stateRecorder {
+	return &stateRecorder{
+		accounts: make(map[common.Address]map[common.Hash]struct{}),
+		erased:   make(map[common.Address]struct{}),
+	}
+}
+
+func (r *stateRecorder) touch(addr common.Address) map[common.Hash]struct{} {
+	if r.accounts[addr] == nil {
+		r.accounts[addr] = make(map[common.Hash]struct{})
+	}
+	return r.accounts[addr]
+}
+
+func (r *stateRecorder) CaptureTxStart(gasLimit uint64) {}
+
+func (r *stateRecorder) CaptureTxEnd(restGas uint64) {}
+
+func (r *stateRecorder) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+	r.touch(from)
+	r.touch(to)
+}
+
+func (r *stateRecorder) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	r.output, r.err = common.CopyBytes(output), err
+}
+
+func (r *stateRecorder) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+	r.touch(from)
+	r.touch(to)
+}
+
+func (r *stateRecorder) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (r *stateRecorder) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if op == vm.SSTORE && len(scope.Stack.Data()) > 0 {
+		slot := common.Hash(scope.Stack.Back(0).Bytes32())
+		r.touch(scope.Contract.Address())[slot] = struct{}{}
+	}
+}
+
+func (r *stateRecorder) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// diff compares the recorded accounts between the two states.
+func (r *stateRecorder) diff(pre, post *state.StateDB) *ProposalStateDiff {
+	diff := &ProposalStateDiff{
+		Pre:  make(map[common.Address]*AccountState),
+		Post: make(map[common.Address]*AccountState),
+	}
+	for addr, slots := range r.accounts {
+		before, after := new(AccountState), new(AccountState)
+		changed := false
+		if b, a := pre.GetBalance(addr), post.GetBalance(addr); b.Cmp(a) != 0 {
+			before.Balance, after.Balance = (*hexutil.Big)(b), (*hexutil.Big)(a)
+			changed = true
+		}
+		if b, a := pre.GetNonce(addr), post.GetNonce(addr); b != a {
+			before.Nonce, after.Nonce = (*hexutil.Uint64)(&b), (*hexutil.Uint64)(&a)
+			changed = true
+		}
+		if b, a := pre.GetCode(addr), post.GetCode(addr); !bytes.Equal(b, a) {
+			before.Code, after.Code = (*hexutil.Bytes)(&b), (*hexutil.Bytes)(&a)
+			changed = true
+		}
+		if _, ok := r.erased[addr]; ok {
+			after.StorageCleared = true
+			changed = true
+		}
+		for slot := range slots {
+			if b, a := pre.GetState(addr, slot), post.GetState(addr, slot); b != a {
+				if before.Storage == nil {
+					before.Storage, after.Storage = make(map[common.Hash]common.Hash), make(map[common.Hash]common.Hash)
+				}
+				before.Storage[slot], after.Storage[slot] = b, a
+				changed = true
+			}
+		}
+		if changed {
+			diff.Pre[addr], diff.Post[addr] = before, after
+		}
+	}
+	return diff
+}
@@ -0,0 +1,103 @@
+package npos
+
+import (
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+)
+
+// internalTxRecorder records the value transfers and the calls made by the engine
+// while finalizing a block as internal txs, the same way the block processing
+// records those of the transactions. It traces the evm calls run with a traced
+// call context, and collects the state changes the engine applies off the evm,
+// so the balances can be reconciled from the internal txs alone. A nil recorder
+// records nothing.
+type internalTxRecorder struct {
+	*vm.ActionLogger
+	traceAll    bool
+	actions     []*types.Action // actions made since the last record
+	internalTxs types.InternalTxs
+}
+
+func newInternalTxRecorder(traceAll bool) *internalTxRecorder {
+	return &internalTxRecorder{ActionLogger: vm.NewActionLogger(traceAll), traceAll: traceAll}
+}
+
+// traced returns a copy of the call context running the calls with the recorder
+// as tracer.
+func (r *internalTxRecorder) traced(ctx *systemcontract.CallContext) *systemcontract.CallContext {
+	if r == nil {
+		return ctx
+	}
+	cpy := *ctx
+	cpy.Tracer = r
+	return &cpy
+}
+
+// CaptureEnd implements vm.EVMLogger, collecting the actions of a call run by the
+// engine. The top-level call is recorded like the nested ones, only if it carries
+// value or all the calls are traced, so the calls reading the system contracts
+// are left out.
+func (r *internalTxRecorder) CaptureEnd(output []byte, gasUsed uint64, err error) {
+	r.ActionLogger.CaptureEnd(output, gasUsed, err)
+	traced, _ := r.GetResult()
+	r.Clear()
+	if len(traced) == 0 {
+		return
+	}
+	top := traced[0]
+	top.Depth = types.EngineActionDepth
+	if !r.traceAll && (top.Value == nil || top.Value.Sign() == 0) {
+		traced = traced[1:]
+	}
+	r.actions = append(r.actions, traced...)
+}
+
+// add records an action applied by the engine off the evm.
+func (r *internalTxRecorder) add(action *types.Action) {
+	if r == nil {
+		return
+	}
+	r.actions = append(r.actions, action)
+}
+
+// record adds an internal tx with the actions made since the last record, if any.
+// The actions of a failed transaction are all marked as failed. The actions made
+// by the engine out of any transaction have an empty tx hash.
+func (r *internalTxRecorder) record(txHash common.Hash, failed bool) {
+	if r == nil {
+		return
+	}
+	actions := r.actions
+	r.actions = nil
+	if failed {
+		for _, action := range actions {
+			action.Success = false
+		}
+	}
+	if len(actions) > 0 {
+		r.internalTxs = append(r.internalTxs, &types.InternalTx{TxHash: txHash, Actions: actions})
+	}
+}
+
+// engineAction returns an action applied by the engine off the evm.
+func engineAction(opCode string, from, to common.Address, value *big.Int) *types.Action {
+	return &types.Action{
+		From:    from,
+		To:      to,
+		Value:   value,
+		Success: true,
+		OpCode:  opCode,
+		Depth:   types.EngineActionDepth,
+	}
+}
+
+// feeSweepAction returns the action moving the fees collected by the FeeRecoder
+// to the engine caller, to be distributed as the block reward.
+func feeSweepAction(fee *big.Int) *types.Action {
+	return engineAction(types.FeeSweepOpCode, consensus.FeeRecoder, systemcontract.EngineCaller, new(big.Int).Set(fee))
+}
@@ -78,11 +78,15 @@ func (c *Npos) updateValidators(ctx *systemcontract.CallContext, vals []common.A
 	return nil
 }
 
-func (c *Npos) trySendBlockReward(ctx *systemcontract.CallContext) error {
+// trySendBlockReward distributes the fees collected in the block as the block
+// reward, recording the transfers into the recorder, whose tracer the context
+// runs with, and the shares of the reward into the fee breakdown if any.
+func (c *Npos) trySendBlockReward(ctx *systemcontract.CallContext, recorder *internalTxRecorder, fees *feeBreakdown) error {
 	fee := ctx.Statedb.GetBalance(consensus.FeeRecoder)
 	if fee.Cmp(common.Big0) <= 0 {
 		return nil
 	}
+	fees.beforeReward(c, ctx, fee)
 
 	// Caller will send tx to deposit block fees to contract, add to his balance first.
 	ctx.Statedb.AddBalance(systemcontract.EngineCaller, fee)
@@ -96,11 +100,14 @@ func (c *Npos) trySendBlockReward(ctx *systemcontract.CallContext) error {
 		return err
 	}
 
+	recorder.add(feeSweepAction(fee))
 	_, err = systemcontract.VmCallWithValue(ctx, systemcontract.EngineCaller, systemcontract.ValidatorsContractAddr, data, fee)
 
 	if err != nil {
 		return err
 	}
+	fees.afterReward(c, ctx)
+	recorder.record(common.Hash{}, false)
 	return nil
 }
 
@@ -146,17 +153,7 @@ func (c *Npos) tryPunishValidator(ctx *systemcontract.CallContext, chain consens
 	if err != nil {
 		return err
 	}
-	validators := snap.validators()
-	outTurnValidator := validators[number%uint64(len(validators))]
-	// check sigend recently or not
-	signedRecently := false
-	for _, recent := range snap.Recents {
-		if recent == outTurnValidator {
-			signedRecently = true
-			break
-		}
-	}
-	if !signedRecently {
+	if outTurnValidator, punished := snap.missedInTurn(number); punished {
 		if err := c.punishValidator(ctx, outTurnValidator); err != nil {
 			return err
 		}
@@ -174,11 +171,8 @@ func (c *Npos) syncWithSysContractAtEpoch(ctx *systemcontract.CallContext, chain
 		return nil, consensus.ErrUnknownAncestor
 	}
 	// get validators from headers and use that for new validator set
-	validators := make([]common.Address, (len(checkpointHeader.Extra)-extraVanity-extraSeal)/common.AddressLength)
-	for i := 0; i < len(validators); i++ {
-		copy(validators[i][:], checkpointHeader.Extra[extraVanity+i*common.AddressLength:])
-	}
-	if len(validators) < 1 {
+	validators, err := parseValidators(c.config, checkpointHeader)
+	if err != nil || len(validators) < 1 {
 		return []common.Address{}, errInvalidExtraValidators
 	}
 	if err := c.updateValidators(ctx, validators); err != nil {
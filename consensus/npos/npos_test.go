@@ -0,0 +1,56 @@
+package npos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestNpos(config *params.NposConfig) *Npos {
+	chainConfig := &params.ChainConfig{ChainID: big.NewInt(1), Npos: config}
+	return New(chainConfig, rawdb.NewMemoryDatabase())
+}
+
+func TestAuthor(t *testing.T) {
+	var (
+		key    = common.Hex2Bytes("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		other  = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+		engine = newTestNpos(&params.NposConfig{Epoch: 200})
+	)
+	k, _ := crypto.ToECDSA(key)
+	signer := crypto.PubkeyToAddress(k.PublicKey)
+
+	// the signer is returned, whatever the coinbase
+	header := signedTestHeader(t, 10, common.Hash{}, 100, key)
+	header.Coinbase = other
+	sealTestHeader(t, header, key)
+	author, err := engine.Author(header)
+	require.NoError(t, err)
+	require.Equal(t, signer, author)
+
+	// an unsealed header has no author, even with a coinbase
+	header.Extra = make([]byte, extraVanity+extraSeal)
+	_, err = engine.Author(header)
+	require.Error(t, err)
+}
+
+func TestVerifyCoinbase(t *testing.T) {
+	var (
+		key    = common.Hex2Bytes("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		engine = newTestNpos(&params.NposConfig{Epoch: 200})
+	)
+	header := signedTestHeader(t, 1, common.Hash{}, 100, key)
+	signer, err := engine.verifyCoinbase(header)
+	require.NoError(t, err)
+	require.Equal(t, header.Coinbase, signer)
+
+	header.Coinbase = common.HexToAddress("0x00000000000000000000000000000000000000aa")
+	sealTestHeader(t, header, key)
+	_, err = engine.verifyCoinbase(header)
+	require.ErrorIs(t, err, errInvalidCoinbase)
+}
@@ -19,6 +19,7 @@ package npos
 import (
 	"bytes"
 	"encoding/json"
+	"math/big"
 	"sort"
 
 	"github.com/ethereum/go-ethereum/common"
@@ -38,6 +39,19 @@ type Snapshot struct {
 	Hash       common.Hash                 `json:"hash"`       // Block hash where the snapshot was created
 	Validators map[common.Address]struct{} `json:"validators"` // Set of authorized validators at this moment
 	Recents    map[uint64]common.Address   `json:"recents"`    // Set of recent validators for spam protections
+
+	Votes      map[common.Address]AttestationData `json:"votes"`      // Latest attestation of each validator
+	Checkpoint BlockRef                           `json:"checkpoint"` // Latest checkpoint since the attestation fork
+	Justified  BlockRef                           `json:"justified"`  // Latest checkpoint attested by more than 2/3 of the validators
+	Finalized  BlockRef                           `json:"finalized"`  // Latest justified checkpoint whose next checkpoint is justified from it
+
+	Period PeriodSchedule `json:"period"` // Block period announced by the latest epoch header
+}
+
+// BlockRef identifies a block by its number and hash.
+type BlockRef struct {
+	Number uint64      `json:"number"`
+	Hash   common.Hash `json:"hash"`
 }
 
 // validatorsAscending implements the sort interface to allow sorting a list of addresses
@@ -58,10 +72,20 @@ func newSnapshot(config *params.NposConfig, sigcache *lru.ARCCache, number uint6
 		Hash:       hash,
 		Validators: make(map[common.Address]struct{}),
 		Recents:    make(map[uint64]common.Address),
+		Votes:      make(map[common.Address]AttestationData),
+	}
+	if config != nil {
+		snap.Period = PeriodSchedule{Period: config.Period}
 	}
 	for _, validator := range validators {
 		snap.Validators[validator] = struct{}{}
 	}
+	// A trusted checkpoint is the root of the justified checkpoints, unless its
+	// header carries the ones it builds on
+	if config != nil && config.IsAttestation(new(big.Int).SetUint64(number)) && number%config.Epoch == 0 {
+		snap.Checkpoint = BlockRef{Number: number, Hash: hash}
+		snap.Justified = snap.Checkpoint
+	}
 	return snap
 }
 
@@ -77,6 +101,14 @@ func loadSnapshot(config *params.NposConfig, sigcache *lru.ARCCache, db ethdb.Da
 	}
 	snap.config = config
 	snap.sigcache = sigcache
+	// Snapshots stored before the attestation fork have no votes
+	if snap.Votes == nil {
+		snap.Votes = make(map[common.Address]AttestationData)
+	}
+	// Snapshots stored before the governance actions fork have the configured period
+	if snap.Period.Period == 0 && config != nil {
+		snap.Period = PeriodSchedule{Period: config.Period}
+	}
 
 	return snap, nil
 }
@@ -99,6 +131,11 @@ func (s *Snapshot) copy() *Snapshot {
 		Hash:       s.Hash,
 		Validators: make(map[common.Address]struct{}),
 		Recents:    make(map[uint64]common.Address),
+		Votes:      make(map[common.Address]AttestationData),
+		Checkpoint: s.Checkpoint,
+		Justified:  s.Justified,
+		Finalized:  s.Finalized,
+		Period:     s.Period,
 	}
 	for validator := range s.Validators {
 		cpy.Validators[validator] = struct{}{}
@@ -106,6 +143,9 @@ func (s *Snapshot) copy() *Snapshot {
 	for block, validator := range s.Recents {
 		cpy.Recents[block] = validator
 	}
+	for validator, vote := range s.Votes {
+		cpy.Votes[validator] = vote
+	}
 
 	return cpy
 }
@@ -141,6 +181,9 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 		if err != nil {
 			return nil, err
 		}
+		if validator != header.Coinbase {
+			return nil, errInvalidCoinbase
+		}
 		if _, ok := snap.Validators[validator]; !ok {
 			return nil, errUnauthorizedValidator
 		}
@@ -151,6 +194,38 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 		}
 		snap.Recents[number] = validator
 
+		// every epoch block is a checkpoint, the first one after the fork is
+		// the root of the justified checkpoints. The checkpoint moves before the
+		// attestation of its header is counted, so the justified and finalized
+		// checkpoints of its snapshot are the ones carried by the header.
+		if number%s.config.Epoch == 0 && s.config.IsAttestation(header.Number) {
+			snap.Checkpoint = BlockRef{Number: number, Hash: header.Hash()}
+			if snap.Justified == (BlockRef{}) {
+				snap.Justified = snap.Checkpoint
+			}
+		}
+		// count the attestation of the validator, which has been checked against
+		// the snapshot by the seal verification
+		if s.config.IsAttestation(header.Number) {
+			attestation, err := parseAttestation(s.config, header)
+			if err != nil {
+				return nil, err
+			}
+			if attestation != nil {
+				snap.Votes[validator] = attestation.Data
+				snap.updateJustified()
+			}
+		}
+
+		// the schedule has been checked against the snapshot by the header verification
+		schedule, err := parsePeriodSchedule(s.config, header)
+		if err != nil {
+			return nil, err
+		}
+		if schedule != nil {
+			snap.Period = *schedule
+		}
+
 		// update validators at the first block at epoch
 		// use a look-back validators set for NPoS.
 		// Which means: the blocks in the first two epoch will use the genesis validators;
@@ -174,9 +249,9 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 				}
 			}
 			// get validators from headers and use that for new validator set
-			validators := make([]common.Address, (len(checkpointHeader.Extra)-extraVanity-extraSeal)/common.AddressLength)
-			for i := 0; i < len(validators); i++ {
-				copy(validators[i][:], checkpointHeader.Extra[extraVanity+i*common.AddressLength:])
+			validators, err := parseValidators(s.config, checkpointHeader)
+			if err != nil {
+				return nil, err
 			}
 
 			newValidators := make(map[common.Address]struct{})
@@ -192,6 +267,11 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 			}
 
 			snap.Validators = newValidators
+			for validator := range snap.Votes {
+				if _, ok := snap.Validators[validator]; !ok {
+					delete(snap.Votes, validator)
+				}
+			}
 		}
 	}
 
@@ -201,6 +281,39 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 	return snap, nil
 }
 
+// attestation returns the attestation expected from the validators on top of
+// the snapshot, linking the justified checkpoint to the latest one. There is
+// nothing to attest once the latest checkpoint is justified.
+func (s *Snapshot) attestation() (AttestationData, bool) {
+	if s.Justified == (BlockRef{}) || s.Checkpoint.Number <= s.Justified.Number {
+		return AttestationData{}, false
+	}
+	return AttestationData{Source: s.Justified, Target: s.Checkpoint}, true
+}
+
+// updateJustified justifies the latest checkpoint once more than 2/3 of the
+// validators attested the link from the justified checkpoint to it. The source
+// of the link is finalized if the target is the next checkpoint.
+func (s *Snapshot) updateJustified() {
+	link, ok := s.attestation()
+	if !ok {
+		return
+	}
+	votes := 0
+	for validator := range s.Validators {
+		if vote, ok := s.Votes[validator]; ok && vote == link {
+			votes++
+		}
+	}
+	if votes*3 <= len(s.Validators)*2 {
+		return
+	}
+	if link.Target.Number == link.Source.Number+s.config.Epoch {
+		s.Finalized = link.Source
+	}
+	s.Justified = link.Target
+}
+
 // validators retrieves the list of authorized validators in ascending order.
 func (s *Snapshot) validators() []common.Address {
 	sigs := make([]common.Address, 0, len(s.Validators))
@@ -211,6 +324,20 @@ func (s *Snapshot) validators() []common.Address {
 	return sigs
 }
 
+// missedInTurn returns the in-turn validator of a block sealed out of turn on top
+// of the snapshot, and whether it's punished for missing it, which is only if it
+// didn't sign recently.
+func (s *Snapshot) missedInTurn(number uint64) (common.Address, bool) {
+	validators := s.validators()
+	validator := validators[number%uint64(len(validators))]
+	for _, recent := range s.Recents {
+		if recent == validator {
+			return validator, false
+		}
+	}
+	return validator, true
+}
+
 // inturn returns if a validator at a given block height is in-turn or not.
 func (s *Snapshot) inturn(number uint64, validator common.Address) bool {
 	validators, offset := s.validators(), 0
@@ -0,0 +1,82 @@
+package npos
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSnapshotUpdateJustified(t *testing.T) {
+	vals := []common.Address{{0x1}, {0x2}, {0x3}, {0x4}}
+	config := &params.NposConfig{Epoch: 10, AttestationBlock: common.Big0}
+	snap := newSnapshot(config, nil, 0, common.Hash{0xff}, vals)
+	require.Equal(t, BlockRef{Hash: common.Hash{0xff}}, snap.Justified)
+
+	// nothing to attest until the next checkpoint
+	_, ok := snap.attestation()
+	require.False(t, ok)
+
+	attest := func(val common.Address) {
+		data, ok := snap.attestation()
+		require.True(t, ok)
+		snap.Votes[val] = data
+		snap.updateJustified()
+	}
+	checkpoint := func(number uint64) {
+		snap.Checkpoint = BlockRef{Number: number, Hash: common.Hash{byte(number)}}
+	}
+	// 4 validators need 3 attestations
+	checkpoint(10)
+	attest(vals[0])
+	attest(vals[1])
+	require.Equal(t, uint64(0), snap.Justified.Number)
+	attest(vals[2])
+	require.Equal(t, uint64(10), snap.Justified.Number)
+	require.Equal(t, uint64(0), snap.Finalized.Number)
+
+	// the votes for another link, or of non-validators, are not counted
+	checkpoint(20)
+	snap.Votes[vals[0]] = AttestationData{Source: BlockRef{Number: 0, Hash: common.Hash{0xff}}, Target: snap.Checkpoint}
+	snap.Votes[common.Address{0x5}], _ = snap.attestation()
+	attest(vals[1])
+	attest(vals[2])
+	require.Equal(t, uint64(10), snap.Justified.Number)
+
+	// justifying the next checkpoint finalizes the source
+	attest(vals[3])
+	require.Equal(t, uint64(20), snap.Justified.Number)
+	require.Equal(t, BlockRef{Number: 10, Hash: common.Hash{10}}, snap.Finalized)
+
+	// skipping a checkpoint justifies the target without finalizing the source
+	checkpoint(40)
+	for _, val := range vals[:3] {
+		attest(val)
+	}
+	require.Equal(t, uint64(40), snap.Justified.Number)
+	require.Equal(t, uint64(10), snap.Finalized.Number)
+}
+
+func TestSnapshotApplyInvalidCoinbase(t *testing.T) {
+	key := common.Hex2Bytes("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	sigcache, _ := lru.NewARC(inmemorySignatures)
+	k, _ := crypto.ToECDSA(key)
+	signer := crypto.PubkeyToAddress(k.PublicKey)
+
+	header := &types.Header{
+		Number:     big.NewInt(1),
+		Difficulty: diffInTurn,
+		Extra:      make([]byte, extraVanity+extraSeal),
+		Coinbase:   common.HexToAddress("0x00000000000000000000000000000000000000aa"),
+	}
+	sealTestHeader(t, header, key)
+
+	snap := newSnapshot(&params.NposConfig{Epoch: 200}, sigcache, 0, common.Hash{}, []common.Address{signer})
+	_, err := snap.apply([]*types.Header{header}, nil, nil)
+	require.ErrorIs(t, err, errInvalidCoinbase)
+}
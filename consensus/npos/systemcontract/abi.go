@@ -722,12 +722,142 @@ const PunishInteractiveABI = `
 		"outputs": [],
 		"stateMutability": "nonpayable",
 		"type": "function"
-	  }
+	},
+	{
+		"inputs": [
+		  {
+			"internalType": "bytes32",
+			"name": "evidenceHash",
+			"type": "bytes32"
+		  }
+		],
+		"name": "isDoubleSignPunished",
+		"outputs": [
+		  {
+			"internalType": "bool",
+			"name": "",
+			"type": "bool"
+		  }
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [
+		  {
+			"internalType": "address",
+			"name": "val",
+			"type": "address"
+		  },
+		  {
+			"internalType": "uint256",
+			"name": "number",
+			"type": "uint256"
+		  },
+		  {
+			"internalType": "bytes32",
+			"name": "evidenceHash",
+			"type": "bytes32"
+		  }
+		],
+		"name": "punishDoubleSign",
+		"outputs": [],
+		"stateMutability": "nonpayable",
+		"type": "function"
+	},
+	{
+		"inputs": [
+		  {
+			"internalType": "address",
+			"name": "val",
+			"type": "address"
+		  }
+		],
+		"name": "getPunishRecord",
+		"outputs": [
+		  {
+			"internalType": "uint256",
+			"name": "",
+			"type": "uint256"
+		  }
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"anonymous": false,
+		"inputs": [
+		  {
+			"indexed": true,
+			"internalType": "address",
+			"name": "val",
+			"type": "address"
+		  },
+		  {
+			"indexed": false,
+			"internalType": "uint256",
+			"name": "number",
+			"type": "uint256"
+		  },
+		  {
+			"indexed": false,
+			"internalType": "bytes32",
+			"name": "evidenceHash",
+			"type": "bytes32"
+		  }
+		],
+		"name": "DoubleSignPunished",
+		"type": "event"
+	}
 ]
 `
 
 const SysGovInteractiveABI = `
 [
+	{
+		"inputs": [
+			{
+				"internalType": "uint256",
+				"name": "id",
+				"type": "uint256"
+			}
+		],
+		"name": "getProposalById",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "_id",
+				"type": "uint256"
+			},
+			{
+				"internalType": "uint256",
+				"name": "action",
+				"type": "uint256"
+			},
+			{
+				"internalType": "address",
+				"name": "from",
+				"type": "address"
+			},
+			{
+				"internalType": "address",
+				"name": "to",
+				"type": "address"
+			},
+			{
+				"internalType": "uint256",
+				"name": "value",
+				"type": "uint256"
+			},
+			{
+				"internalType": "bytes",
+				"name": "data",
+				"type": "bytes"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
     {
 		"inputs": [
 			{
@@ -879,6 +1009,32 @@ const AddrListInteractiveABI = `
       "name": "BlackAddrRemoved",
       "type": "event"
     },
+    {
+      "anonymous": false,
+      "inputs": [
+        {
+          "indexed": true,
+          "internalType": "bytes32",
+          "name": "codeHash",
+          "type": "bytes32"
+        }
+      ],
+      "name": "CodeHashRuleAdded",
+      "type": "event"
+    },
+    {
+      "anonymous": false,
+      "inputs": [
+        {
+          "indexed": true,
+          "internalType": "bytes32",
+          "name": "codeHash",
+          "type": "bytes32"
+        }
+      ],
+      "name": "CodeHashRuleRemoved",
+      "type": "event"
+    },
     {
       "anonymous": false,
       "inputs": [
@@ -993,6 +1149,44 @@ const AddrListInteractiveABI = `
       "name": "RuleUpdated",
       "type": "event"
     },
+    {
+      "anonymous": false,
+      "inputs": [
+        {
+          "indexed": true,
+          "internalType": "address",
+          "name": "target",
+          "type": "address"
+        },
+        {
+          "indexed": true,
+          "internalType": "bytes4",
+          "name": "selector",
+          "type": "bytes4"
+        }
+      ],
+      "name": "SelectorRuleAdded",
+      "type": "event"
+    },
+    {
+      "anonymous": false,
+      "inputs": [
+        {
+          "indexed": true,
+          "internalType": "address",
+          "name": "target",
+          "type": "address"
+        },
+        {
+          "indexed": true,
+          "internalType": "bytes4",
+          "name": "selector",
+          "type": "bytes4"
+        }
+      ],
+      "name": "SelectorRuleRemoved",
+      "type": "event"
+    },
     {
       "inputs": [
         {
@@ -1011,6 +1205,32 @@ const AddrListInteractiveABI = `
       "stateMutability": "nonpayable",
       "type": "function"
     },
+    {
+      "inputs": [
+        {
+          "internalType": "bytes32",
+          "name": "codeHash",
+          "type": "bytes32"
+        }
+      ],
+      "name": "addCodeHashRule",
+      "outputs": [],
+      "stateMutability": "nonpayable",
+      "type": "function"
+    },
+    {
+      "inputs": [
+        {
+          "internalType": "address",
+          "name": "a",
+          "type": "address"
+        }
+      ],
+      "name": "addDeveloper",
+      "outputs": [],
+      "stateMutability": "nonpayable",
+      "type": "function"
+    },
     {
       "inputs": [
         {
@@ -1040,6 +1260,24 @@ const AddrListInteractiveABI = `
       "stateMutability": "nonpayable",
       "type": "function"
     },
+    {
+      "inputs": [
+        {
+          "internalType": "address",
+          "name": "target",
+          "type": "address"
+        },
+        {
+          "internalType": "bytes4",
+          "name": "selector",
+          "type": "bytes4"
+        }
+      ],
+      "name": "addSelectorRule",
+      "outputs": [],
+      "stateMutability": "nonpayable",
+      "type": "function"
+    },
     {
       "inputs": [],
       "name": "admin",
@@ -1066,6 +1304,19 @@ const AddrListInteractiveABI = `
       "stateMutability": "view",
       "type": "function"
     },
+    {
+      "inputs": [],
+      "name": "callRulesLastUpdatedNumber",
+      "outputs": [
+        {
+          "internalType": "uint256",
+          "name": "",
+          "type": "uint256"
+        }
+      ],
+      "stateMutability": "view",
+      "type": "function"
+    },
     {
       "inputs": [
         {
@@ -1086,6 +1337,19 @@ const AddrListInteractiveABI = `
       "stateMutability": "nonpayable",
       "type": "function"
     },
+    {
+      "inputs": [],
+      "name": "developersLastUpdatedNumber",
+      "outputs": [
+        {
+          "internalType": "uint256",
+          "name": "",
+          "type": "uint256"
+        }
+      ],
+      "stateMutability": "view",
+      "type": "function"
+    },
     {
       "inputs": [],
       "name": "getBlacksFrom",
@@ -1112,6 +1376,32 @@ const AddrListInteractiveABI = `
       "stateMutability": "view",
       "type": "function"
     },
+    {
+      "inputs": [],
+      "name": "getCodeHashRules",
+      "outputs": [
+        {
+          "internalType": "bytes32[]",
+          "name": "",
+          "type": "bytes32[]"
+        }
+      ],
+      "stateMutability": "view",
+      "type": "function"
+    },
+    {
+      "inputs": [],
+      "name": "getDevelopers",
+      "outputs": [
+        {
+          "internalType": "address[]",
+          "name": "",
+          "type": "address[]"
+        }
+      ],
+      "stateMutability": "view",
+      "type": "function"
+    },
     {
       "inputs": [
         {
@@ -1175,6 +1465,19 @@ const AddrListInteractiveABI = `
       "stateMutability": "view",
       "type": "function"
     },
+    {
+      "inputs": [],
+      "name": "getSelectorRules",
+      "outputs": [
+        {
+          "internalType": "bytes32[]",
+          "name": "",
+          "type": "bytes32[]"
+        }
+      ],
+      "stateMutability": "view",
+      "type": "function"
+    },
     {
       "inputs": [
         {
@@ -1188,6 +1491,13 @@ const AddrListInteractiveABI = `
       "stateMutability": "nonpayable",
       "type": "function"
     },
+    {
+      "inputs": [],
+      "name": "initializeV1",
+      "outputs": [],
+      "stateMutability": "nonpayable",
+      "type": "function"
+    },
     {
       "inputs": [],
       "name": "initialized",
@@ -1225,6 +1535,25 @@ const AddrListInteractiveABI = `
       "stateMutability": "view",
       "type": "function"
     },
+    {
+      "inputs": [
+        {
+          "internalType": "address",
+          "name": "a",
+          "type": "address"
+        }
+      ],
+      "name": "isDeveloper",
+      "outputs": [
+        {
+          "internalType": "bool",
+          "name": "",
+          "type": "bool"
+        }
+      ],
+      "stateMutability": "view",
+      "type": "function"
+    },
     {
       "inputs": [],
       "name": "pendingAdmin",
@@ -1256,6 +1585,32 @@ const AddrListInteractiveABI = `
       "stateMutability": "nonpayable",
       "type": "function"
     },
+    {
+      "inputs": [
+        {
+          "internalType": "bytes32",
+          "name": "codeHash",
+          "type": "bytes32"
+        }
+      ],
+      "name": "removeCodeHashRule",
+      "outputs": [],
+      "stateMutability": "nonpayable",
+      "type": "function"
+    },
+    {
+      "inputs": [
+        {
+          "internalType": "address",
+          "name": "a",
+          "type": "address"
+        }
+      ],
+      "name": "removeDeveloper",
+      "outputs": [],
+      "stateMutability": "nonpayable",
+      "type": "function"
+    },
     {
       "inputs": [
         {
@@ -1280,6 +1635,24 @@ const AddrListInteractiveABI = `
       "stateMutability": "nonpayable",
       "type": "function"
     },
+    {
+      "inputs": [
+        {
+          "internalType": "address",
+          "name": "target",
+          "type": "address"
+        },
+        {
+          "internalType": "bytes4",
+          "name": "selector",
+          "type": "bytes4"
+        }
+      ],
+      "name": "removeSelectorRule",
+      "outputs": [],
+      "stateMutability": "nonpayable",
+      "type": "function"
+    },
     {
       "inputs": [],
       "name": "rulesLastUpdatedNumber",
@@ -1308,6 +1681,209 @@ const AddrListInteractiveABI = `
     }
   ]`
 
+// VotePoolInteractiveABI contains the view methods of the vote pool contract
+// deployed by the validators contract for every validator.
+const VotePoolInteractiveABI = `
+[
+	{
+		"inputs": [],
+		"name": "JailPeriod",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [],
+		"name": "exitBlk",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{
+				"internalType": "address",
+				"name": "_voter",
+				"type": "address"
+			}
+		],
+		"name": "getPendingReward",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [],
+		"name": "getValidatorPendingReward",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [],
+		"name": "manager",
+		"outputs": [
+			{
+				"internalType": "address",
+				"name": "",
+				"type": "address"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [],
+		"name": "margin",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [],
+		"name": "percent",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [],
+		"name": "punishBlk",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [],
+		"name": "state",
+		"outputs": [
+			{
+				"internalType": "enum State",
+				"name": "",
+				"type": "uint8"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [],
+		"name": "totalVote",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [],
+		"name": "validator",
+		"outputs": [
+			{
+				"internalType": "address",
+				"name": "",
+				"type": "address"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [],
+		"name": "validatorType",
+		"outputs": [
+			{
+				"internalType": "enum ValidatorType",
+				"name": "",
+				"type": "uint8"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	},
+	{
+		"inputs": [
+			{
+				"internalType": "address",
+				"name": "",
+				"type": "address"
+			}
+		],
+		"name": "voters",
+		"outputs": [
+			{
+				"internalType": "uint256",
+				"name": "amount",
+				"type": "uint256"
+			},
+			{
+				"internalType": "uint256",
+				"name": "rewardDebt",
+				"type": "uint256"
+			},
+			{
+				"internalType": "uint256",
+				"name": "withdrawPendingAmount",
+				"type": "uint256"
+			},
+			{
+				"internalType": "uint256",
+				"name": "withdrawExitBlock",
+				"type": "uint256"
+			}
+		],
+		"stateMutability": "view",
+		"type": "function"
+	}
+]
+`
+
 var (
 	BlackLastUpdatedNumberPosition = common.BytesToHash([]byte{0x06})
 	RulesLastUpdatedNumberPosition = common.BytesToHash([]byte{0x07})
@@ -1318,12 +1894,21 @@ var (
 	PunishContractName      = "punish"
 	SysGovContractName      = "governance"
 	AddressListContractName = "address_list"
+	VotePoolContractName    = "vote_pool"
 	ValidatorsContractAddr  = common.HexToAddress("0x000000000000000000000000000000000000d001")
 	PunishContractAddr      = common.HexToAddress("0x000000000000000000000000000000000000D002")
 	SysGovContractAddr      = common.HexToAddress("0x000000000000000000000000000000000000D003")
 	AddressListContractAddr = common.HexToAddress("0x000000000000000000000000000000000000D004")
+	// The version 0 code of the upgraded system contracts is kept at these addresses,
+	// the new versions delegate the calls they don't implement to it.
+	PunishV0ContractAddr      = common.HexToAddress("0x000000000000000000000000000000000000e002")
+	AddressListV0ContractAddr = common.HexToAddress("0x000000000000000000000000000000000000e004")
 	// SysGovToAddr is the To address for the system governance transaction, NOT contract address
-	SysGovToAddr = common.HexToAddress("0x000000000000000000000000000000000000ffff")
+	SysGovToAddr = params.NposSysGovToAddr
+	// DoubleSignEvidenceToAddr is the To address for the double-sign evidence transaction, NOT contract address
+	DoubleSignEvidenceToAddr = params.NposDoubleSignEvidenceToAddr
+	// AttestationEvidenceToAddr is the To address for the conflicting attestations evidence transaction, NOT contract address
+	AttestationEvidenceToAddr = params.NposAttestationEvidenceToAddr
 	// engine caller is a dedicated address for the Engine code to interactive with the system contracts.
 	EngineCaller = common.HexToAddress("0x0000000000000000004E506F5320456E67696e65")
 
@@ -1340,6 +1925,8 @@ func init() {
 	abiMap[SysGovContractName] = tmpABI
 	tmpABI, _ = abi.JSON(strings.NewReader(AddrListInteractiveABI))
 	abiMap[AddressListContractName] = tmpABI
+	tmpABI, _ = abi.JSON(strings.NewReader(VotePoolInteractiveABI))
+	abiMap[VotePoolContractName] = tmpABI
 }
 
 func GetInteractiveABI() map[string]abi.ABI {
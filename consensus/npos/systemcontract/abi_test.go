@@ -9,8 +9,8 @@ import (
 )
 
 func TestJsonUnmarshalABI(t *testing.T) {
-	for _, abiStr := range []string{ValidatorsInteractiveABI, PunishInteractiveABI, SysGovInteractiveABI, AddrListInteractiveABI} {
-		_, err := abi.JSON(strings.NewReader(ValidatorsInteractiveABI))
+	for _, abiStr := range []string{ValidatorsInteractiveABI, PunishInteractiveABI, SysGovInteractiveABI, AddrListInteractiveABI, VotePoolInteractiveABI} {
+		_, err := abi.JSON(strings.NewReader(abiStr))
 		require.NoError(t, err, abiStr)
 	}
 }
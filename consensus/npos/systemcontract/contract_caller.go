@@ -20,6 +20,7 @@ type CallContext struct {
 	Header       *types.Header
 	ChainContext core.ChainContext
 	ChainConfig  *params.ChainConfig
+	Tracer       vm.EVMLogger // Optional tracer of the calls, e.g. to simulate them
 }
 
 // VmCall is used for the consensus engine to interact with system contracts.
@@ -33,11 +34,12 @@ func VmCallWithValue(ctx *CallContext, from common.Address, to common.Address, d
 	if ctx == nil || ctx.Statedb == nil || ctx.Header == nil || ctx.ChainConfig == nil {
 		return nil, errors.New("missing required call context")
 	}
-	blockContext := core.NewEVMBlockContext(ctx.Header, ctx.ChainContext, nil)
+	// The header may not be sealed yet, its coinbase is the signer once it is.
+	blockContext := core.NewEVMBlockContext(ctx.Header, ctx.ChainContext, &ctx.Header.Coinbase)
 	vmenv := vm.NewEVM(blockContext, vm.TxContext{
 		Origin:   from,
 		GasPrice: big.NewInt(0),
-	}, ctx.Statedb, ctx.ChainConfig, vm.Config{})
+	}, ctx.Statedb, ctx.ChainConfig, vm.Config{Tracer: ctx.Tracer})
 
 	ret, _, err = vmenv.Call(vm.AccountRef(from), to, data, math.MaxUint64, value)
 	// Finalise the statedb so any changes can take effect,
@@ -1,8 +1,17 @@
 package systemcontract
 
 import (
+	"embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
 	"math/big"
+	"path"
+	"sort"
+	"strings"
 
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
@@ -12,6 +21,7 @@ import (
 
 const (
 	SysContractV1 SysContractVersion = iota + 1
+	SysContractV2
 )
 
 type SysContractVersion int
@@ -22,16 +32,188 @@ type IUpgradeAction interface {
 	Execute(state *state.StateDB, header *types.Header, chainContext core.ChainContext, config *params.ChainConfig) error
 }
 
-// for future to upgrade system contract(need hardfork)
+// contracts holds the runtime bytecode of the system contract versions, in
+// hex files named contracts/v<version>/<contract name>.hex
+//
+//go:embed contracts
+var contracts embed.FS
+
+// contractsFS is the file system the bytecode is loaded from, it's replaced by the tests.
+var contractsFS fs.FS = contracts
+
+// versionForks returns the fork block of every system contract version.
+var versionForks = map[SysContractVersion]func(config *params.NposConfig) *big.Int{
+	SysContractV1: func(config *params.NposConfig) *big.Int { return config.SysContractV1Block },
+	SysContractV2: func(config *params.NposConfig) *big.Int { return config.SysContractV2Block },
+}
+
+// versionUpgrades returns the upgrade actions of every system contract version.
+var versionUpgrades = map[SysContractVersion][]IUpgradeAction{
+	SysContractV1: {
+		&codeUpgrade{version: SysContractV1, name: PunishContractName, addr: PunishContractAddr, v0: PunishV0ContractAddr},
+		&codeUpgrade{version: SysContractV1, name: AddressListContractName, addr: AddressListContractAddr, v0: AddressListV0ContractAddr,
+			migration: func(*params.ChainConfig) ([]byte, error) {
+				return abiMap[AddressListContractName].Pack("initializeV1")
+			},
+		},
+	},
+	SysContractV2: {
+		&codeUpgrade{version: SysContractV2, name: AddressListContractName, addr: AddressListContractAddr, v0: AddressListV0ContractAddr},
+	},
+}
+
+// codeUpgrade replaces the code of a system contract with the embedded bytecode
+// of a version, then runs the migration call, if any.
+type codeUpgrade struct {
+	version SysContractVersion
+	name    string
+	addr    common.Address
+
+	// v0 is where the version 0 code is moved to before its first upgrade, if the
+	// new code delegates to it.
+	v0 common.Address
+
+	// migration returns the input of the call migrating the contract state, or nil if there is none.
+	migration func(config *params.ChainConfig) ([]byte, error)
+}
+
+func (u *codeUpgrade) GetName() string {
+	return u.name
+}
+
+func (u *codeUpgrade) Update(config *params.ChainConfig, height *big.Int, state *state.StateDB) error {
+	code, err := Bytecode(u.version, u.name)
+	if err != nil {
+		return err
+	}
+	if u.v0 != (common.Address{}) && state.GetCodeSize(u.v0) == 0 {
+		state.SetCode(u.v0, state.GetCode(u.addr))
+	}
+	state.SetCode(u.addr, code)
+	return nil
+}
+
+func (u *codeUpgrade) Execute(state *state.StateDB, header *types.Header, chainContext core.ChainContext, config *params.ChainConfig) error {
+	if u.migration == nil {
+		return nil
+	}
+	data, err := u.migration(config)
+	if err != nil {
+		return err
+	}
+	ctx := &CallContext{
+		Statedb:      state,
+		Header:       header,
+		ChainContext: chainContext,
+		ChainConfig:  config,
+	}
+	_, err = VmCall(ctx, u.addr, data)
+	return err
+}
+
+// UpgradeCode replaces the code of the system contract at addr with its embedded
+// release of the given version, out of the scheduled upgrades. It returns where
+// the version 0 code is kept if the release delegates to it, and the input of
+// the migration call to run afterwards, nil if there is none.
+func UpgradeCode(version SysContractVersion, addr common.Address, config *params.ChainConfig, state *state.StateDB) (v0 common.Address, migration []byte, err error) {
+	for _, action := range versionUpgrades[version] {
+		u, ok := action.(*codeUpgrade)
+		if !ok || u.addr != addr {
+			continue
+		}
+		if err := u.Update(config, nil, state); err != nil {
+			return common.Address{}, nil, err
+		}
+		if u.migration == nil {
+			return u.v0, nil, nil
+		}
+		migration, err := u.migration(config)
+		return u.v0, migration, err
+	}
+	return common.Address{}, nil, fmt.Errorf("no release v%d of the system contract %x", version, addr)
+}
+
+// Bytecode returns the embedded runtime bytecode of a system contract version.
+func Bytecode(version SysContractVersion, name string) ([]byte, error) {
+	file := path.Join("contracts", fmt.Sprintf("v%d", version), name+".hex")
+	blob, err := fs.ReadFile(contractsFS, file)
+	if err != nil {
+		return nil, fmt.Errorf("missing bytecode of %s v%d: %w", name, version, err)
+	}
+	code, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(string(blob)), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid bytecode of %s v%d: %w", name, version, err)
+	}
+	if len(code) == 0 {
+		return nil, fmt.Errorf("empty bytecode of %s v%d", name, version)
+	}
+	return code, nil
+}
+
+// ScheduledUpgrade is a system contract upgrade enabled by a chain config.
+type ScheduledUpgrade struct {
+	Version   SysContractVersion
+	Block     *big.Int
+	Contracts []string
+	Err       error // The reason the upgrade can't be applied, e.g. missing bytecode
+}
+
+// ScheduledUpgrades returns the system contract upgrades of the config, ordered by version.
+func ScheduledUpgrades(config *params.NposConfig) []*ScheduledUpgrade {
+	var scheduled []*ScheduledUpgrade
+	for version, fork := range versionForks {
+		block := fork(config)
+		if block == nil {
+			continue
+		}
+		upgrade := &ScheduledUpgrade{Version: version, Block: block}
+		if block.Sign() == 0 {
+			upgrade.Err = errors.New("upgrade at the genesis, the genesis must contain the upgraded contracts")
+		}
+		for _, action := range versionUpgrades[version] {
+			upgrade.Contracts = append(upgrade.Contracts, action.GetName())
+			if code, ok := action.(*codeUpgrade); ok && upgrade.Err == nil {
+				_, upgrade.Err = Bytecode(version, code.name)
+			}
+		}
+		scheduled = append(scheduled, upgrade)
+	}
+	sort.Slice(scheduled, func(i, j int) bool { return scheduled[i].Version < scheduled[j].Version })
+	return scheduled
+}
+
+// ValidateUpgrades returns an error if a scheduled upgrade of the config can't be
+// applied, so that a node doesn't start with a chain that would halt at the fork.
+func ValidateUpgrades(config *params.NposConfig) error {
+	for _, upgrade := range ScheduledUpgrades(config) {
+		if upgrade.Err != nil {
+			return fmt.Errorf("system contract upgrade to v%d at block %v: %w", upgrade.Version, upgrade.Block, upgrade.Err)
+		}
+	}
+	return nil
+}
+
+// UpgradesAt returns the system contract versions to upgrade to in the given block, in order.
+func UpgradesAt(config *params.NposConfig, number *big.Int) []SysContractVersion {
+	var versions []SysContractVersion
+	for version, fork := range versionForks {
+		if block := fork(config); block != nil && block.Cmp(number) == 0 {
+			versions = append(versions, version)
+		}
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions
+}
+
+// ApplySystemContractUpgrade upgrades the system contracts to the given version.
 func ApplySystemContractUpgrade(version SysContractVersion, state *state.StateDB, header *types.Header, chainContext core.ChainContext, config *params.ChainConfig) (err error) {
 	if config == nil || header == nil || state == nil {
 		return
 	}
 
-	var sysContracts []IUpgradeAction
-	switch version {
-	default:
-		log.Crit("unsupported SysContractVersion", "version", version)
+	sysContracts, ok := versionUpgrades[version]
+	if !ok {
+		return fmt.Errorf("unsupported SysContractVersion %d", version)
 	}
 
 	for _, contract := range sysContracts {
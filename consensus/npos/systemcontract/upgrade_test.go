@@ -0,0 +1,117 @@
+package systemcontract
+
+import (
+	"flag"
+	"io/fs"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/asm"
+	"github.com/ethereum/go-ethereum/params"
+	"github.com/stretchr/testify/require"
+)
+
+// withContracts replaces the embedded bytecode during a test.
+func withContracts(t *testing.T, files map[string]string) {
+	mapFS := make(fstest.MapFS)
+	for name, data := range files {
+		mapFS["contracts/"+name] = &fstest.MapFile{Data: []byte(data)}
+	}
+	contractsFS = mapFS
+	t.Cleanup(func() { contractsFS = contracts })
+}
+
+func TestBytecode(t *testing.T) {
+	withContracts(t, map[string]string{
+		"v1/punish.hex":       "0x6001\n",
+		"v1/address_list.hex": "6002",
+		"v1/governance.hex":   "0xzz",
+		"v1/validators.hex":   "",
+	})
+	code, err := Bytecode(SysContractV1, PunishContractName)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x60, 0x01}, code)
+	code, err = Bytecode(SysContractV1, AddressListContractName)
+	require.NoError(t, err)
+	require.Equal(t, []byte{0x60, 0x02}, code)
+
+	for _, name := range []string{ValidatorsContractName, SysGovContractName, VotePoolContractName} {
+		_, err := Bytecode(SysContractV1, name)
+		require.Error(t, err, name)
+	}
+}
+
+// updateContracts rewrites the bytecode compiled from the sources, run
+//
+//	go test ./consensus/npos/systemcontract -run TestContractSources -update
+var updateContracts = flag.Bool("update", false, "update the bytecode of the system contracts from their sources")
+
+// TestContractSources checks the embedded bytecode is compiled from the sources,
+// and that every bytecode has a source.
+func TestContractSources(t *testing.T) {
+	sources, err := fs.Glob(contracts, "contracts/v*/*.easm")
+	require.NoError(t, err)
+	require.NotEmpty(t, sources)
+	for _, source := range sources {
+		src, err := fs.ReadFile(contracts, source)
+		require.NoError(t, err)
+		compiler := asm.NewCompiler(false)
+		compiler.Feed(asm.Lex(src, false))
+		bin, errs := compiler.Compile()
+		require.Empty(t, errs, source)
+
+		hex := strings.TrimSuffix(source, ".easm") + ".hex"
+		if *updateContracts {
+			require.NoError(t, os.WriteFile(hex, []byte(bin+"\n"), 0644), source)
+			continue
+		}
+		blob, err := fs.ReadFile(contracts, hex)
+		require.NoError(t, err, source)
+		require.Equal(t, bin, strings.TrimSpace(string(blob)), source)
+	}
+	bins, err := fs.Glob(contracts, "contracts/v*/*.hex")
+	require.NoError(t, err)
+	for _, bin := range bins {
+		_, err := fs.Stat(contracts, strings.TrimSuffix(bin, ".hex")+".easm")
+		require.NoError(t, err, "bytecode without source")
+	}
+}
+
+func TestScheduledUpgrades(t *testing.T) {
+	require.Empty(t, ScheduledUpgrades(&params.NposConfig{}))
+	require.NoError(t, ValidateUpgrades(&params.NposConfig{}))
+
+	config := &params.NposConfig{SysContractV1Block: big.NewInt(10)}
+	upgrades := ScheduledUpgrades(config)
+	require.Len(t, upgrades, 1)
+	require.Equal(t, SysContractV1, upgrades[0].Version)
+	require.Equal(t, big.NewInt(10), upgrades[0].Block)
+	require.Equal(t, []string{PunishContractName, AddressListContractName}, upgrades[0].Contracts)
+	require.NoError(t, upgrades[0].Err)
+	require.NoError(t, ValidateUpgrades(config))
+
+	require.Equal(t, []SysContractVersion{SysContractV1}, UpgradesAt(config, big.NewInt(10)))
+	require.Empty(t, UpgradesAt(config, big.NewInt(11)))
+
+	// the later versions are applied in order, even at the same block
+	config = &params.NposConfig{SysContractV1Block: big.NewInt(10), SysContractV2Block: big.NewInt(10)}
+	upgrades = ScheduledUpgrades(config)
+	require.Len(t, upgrades, 2)
+	require.Equal(t, []string{AddressListContractName}, upgrades[1].Contracts)
+	require.NoError(t, ValidateUpgrades(config))
+	require.Equal(t, []SysContractVersion{SysContractV1, SysContractV2}, UpgradesAt(config, big.NewInt(10)))
+
+	// the genesis can't be upgraded
+	upgrades = ScheduledUpgrades(&params.NposConfig{SysContractV1Block: common.Big0})
+	require.Error(t, upgrades[0].Err)
+	require.Error(t, ValidateUpgrades(&params.NposConfig{SysContractV1Block: common.Big0}))
+
+	withContracts(t, map[string]string{"v1/punish.hex": "0x01"})
+	upgrades = ScheduledUpgrades(config)
+	require.Error(t, upgrades[0].Err)
+	require.Error(t, ValidateUpgrades(config))
+}
@@ -0,0 +1,379 @@
+package npos
+
+import (
+	"fmt"
+	"math/big"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/npos/systemcontract"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// upgradedContracts are the system contracts with a release after version 0.
+var upgradedContracts = map[string]common.Address{
+	systemcontract.PunishContractName:      systemcontract.PunishContractAddr,
+	systemcontract.AddressListContractName: systemcontract.AddressListContractAddr,
+}
+
+var (
+	// dispatchRe matches the selectors dispatched by a contract source, with the
+	// signature they're commented with.
+	dispatchRe = regexp.MustCompile(`(?m)^\s*PUSH 0x([0-9a-f]{8}) ;; (\w+\([^)]*\))\s*$`)
+	// namespaceRe matches the storage namespaces documented by a contract source.
+	namespaceRe = regexp.MustCompile(`keccak256\("([\w.]+)"\)`)
+)
+
+// contractSource returns the source of the latest release of a system contract
+// up to the given version, and its version. It returns version 0 if the
+// contract was never upgraded.
+func contractSource(t *testing.T, name string, version systemcontract.SysContractVersion) (string, systemcontract.SysContractVersion) {
+	for ; version > 0; version-- {
+		src, err := os.ReadFile(fmt.Sprintf("systemcontract/contracts/v%d/%s.easm", version, name))
+		if os.IsNotExist(err) {
+			continue
+		}
+		require.NoError(t, err)
+		return string(src), version
+	}
+	return "", 0
+}
+
+// nativeSelectors returns the selectors a contract source implements, every
+// other one is delegated to the version 0 code. The dispatch ends at the first
+// label, the later selectors are the ones of the contracts it calls.
+func nativeSelectors(t *testing.T, src string) map[[4]byte]string {
+	dispatch := src
+	if label := regexp.MustCompile(`(?m)^\w+:$`).FindStringIndex(src); label != nil {
+		dispatch = src[:label[0]]
+	}
+	selectors := make(map[[4]byte]string)
+	for _, match := range dispatchRe.FindAllStringSubmatch(dispatch, -1) {
+		var selector [4]byte
+		copy(selector[:], common.FromHex(match[1]))
+		require.Equal(t, crypto.Keccak256([]byte(match[2]))[:4], selector[:], "selector of %s", match[2])
+		selectors[selector] = match[2]
+	}
+	require.NotEmpty(t, selectors)
+	return selectors
+}
+
+// upgradeContracts installs the releases of the system contracts up to the
+// given version, as their fork blocks do.
+func upgradeContracts(t *testing.T, ctx *systemcontract.CallContext, version systemcontract.SysContractVersion) {
+	for v := systemcontract.SysContractV1; v <= version; v++ {
+		for name, addr := range upgradedContracts {
+			if _, err := systemcontract.Bytecode(v, name); err != nil {
+				continue
+			}
+			_, migration, err := systemcontract.UpgradeCode(v, addr, ctx.ChainConfig, ctx.Statedb)
+			require.NoError(t, err)
+			if migration != nil {
+				_, err := systemcontract.VmCall(ctx, addr, migration)
+				require.NoError(t, err)
+			}
+		}
+	}
+}
+
+// sampleArg returns an argument of the given type, different for every seed.
+// The addresses are taken from the given ones, so the calls go past the checks
+// of the known accounts.
+func sampleArg(typ abi.Type, seed int, addrs []common.Address) interface{} {
+	rt := typ.GetType()
+	switch typ.T {
+	case abi.IntTy, abi.UintTy:
+		if typ.Size > 64 {
+			return big.NewInt(int64(seed))
+		}
+		v := reflect.New(rt).Elem()
+		if typ.T == abi.UintTy {
+			v.SetUint(uint64(seed))
+		} else {
+			v.SetInt(int64(seed))
+		}
+		return v.Interface()
+	case abi.BoolTy:
+		return seed%2 == 1
+	case abi.AddressTy:
+		return addrs[seed%len(addrs)]
+	case abi.StringTy:
+		return strings.Repeat("npos", seed)
+	case abi.BytesTy:
+		return make([]byte, seed)
+	case abi.FixedBytesTy:
+		v := reflect.New(rt).Elem()
+		v.Index(typ.Size - 1).SetUint(uint64(seed))
+		return v.Interface()
+	case abi.SliceTy:
+		v := reflect.MakeSlice(rt, seed, seed)
+		for i := 0; i < seed; i++ {
+			v.Index(i).Set(reflect.ValueOf(sampleArg(*typ.Elem, seed+i, addrs)))
+		}
+		return v.Interface()
+	case abi.ArrayTy:
+		v := reflect.New(rt).Elem()
+		for i := 0; i < typ.Size; i++ {
+			v.Index(i).Set(reflect.ValueOf(sampleArg(*typ.Elem, seed+i, addrs)))
+		}
+		return v.Interface()
+	case abi.TupleTy:
+		v := reflect.New(rt).Elem()
+		for i, elem := range typ.TupleElems {
+			v.Field(i).Set(reflect.ValueOf(sampleArg(*elem, seed+i, addrs)))
+		}
+		return v.Interface()
+	}
+	panic(fmt.Sprintf("no sample of %v", typ))
+}
+
+// storageTracer records the storage a contract writes, and the preimages of the
+// hashes computed on the way.
+type storageTracer struct {
+	addr      common.Address
+	stores    []common.Hash
+	preimages map[common.Hash][]byte
+	hashing   []byte // preimage of the KECCAK256 being executed
+}
+
+func newStorageTracer(addr common.Address) *storageTracer {
+	return &storageTracer{addr: addr, preimages: make(map[common.Hash][]byte)}
+}
+
+func (st *storageTracer) CaptureTxStart(gasLimit uint64) {}
+
+func (st *storageTracer) CaptureTxEnd(restGas uint64) {}
+
+func (st *storageTracer) CaptureStart(env *vm.EVM, from common.Address, to common.Address, create bool, input []byte, gas uint64, value *big.Int) {
+}
+
+func (st *storageTracer) CaptureEnd(output []byte, gasUsed uint64, err error) {}
+
+func (st *storageTracer) CaptureEnter(typ vm.OpCode, from common.Address, to common.Address, input []byte, gas uint64, value *big.Int) {
+}
+
+func (st *storageTracer) CaptureExit(output []byte, gasUsed uint64, err error) {}
+
+func (st *storageTracer) CaptureState(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if st.hashing != nil {
+		st.preimages[scope.Stack.Back(0).Bytes32()] = st.hashing
+		st.hashing = nil
+	}
+	switch op {
+	case vm.KECCAK256:
+		st.hashing = scope.Memory.GetCopy(int64(scope.Stack.Back(0).Uint64()), int64(scope.Stack.Back(1).Uint64()))
+	case vm.SSTORE:
+		if scope.Contract.Address() == st.addr {
+			st.stores = append(st.stores, scope.Stack.Back(0).Bytes32())
+		}
+	}
+}
+
+func (st *storageTracer) CaptureFault(pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// callResult is the outcome of a system contract call, as seen by its caller.
+// An exceptional halt of the version 0 code is bubbled by the delegating code
+// as a revert without data, so only the failure is compared, with the data it
+// returns.
+type callResult struct {
+	ret    hexutil.Bytes
+	failed bool
+	logs   []*types.Log
+	stores []common.Hash
+}
+
+// sysContractCall runs a call on a state, and returns its outcome.
+func sysContractCall(ctx *systemcontract.CallContext, index int, from, to common.Address, data []byte) (callResult, *storageTracer) {
+	tracer := newStorageTracer(to)
+	ctx.Tracer = tracer
+	defer func() { ctx.Tracer = nil }()
+	// the zero hash holds the logs of the migrations
+	hash := common.BigToHash(big.NewInt(int64(index + 1)))
+	ctx.Statedb.SetTxContext(hash, index)
+	ret, err := systemcontract.VmCallWithValue(ctx, from, to, data, new(big.Int))
+	// the logs are numbered in the state, after the ones of the migrations
+	var logs []*types.Log
+	for i, l := range ctx.Statedb.GetLogs(hash, ctx.Header.Number.Uint64(), common.Hash{}) {
+		cpy := *l
+		cpy.Index = uint(i)
+		logs = append(logs, &cpy)
+	}
+	return callResult{ret: ret, failed: err != nil, logs: logs, stores: tracer.stores}, tracer
+}
+
+// newUpgradeTestChain returns a chain at version 0 with some state in the system
+// contracts, with the accounts worth calling them from or with.
+func newUpgradeTestChain(t *testing.T) (*testChain, []common.Address) {
+	tc := newTestChain(t)
+	alABI := tc.engine.abi[systemcontract.AddressListContractName]
+	tc.extend(1, func(i int, b *core.BlockGen) {
+		b.AddTx(tc.adminTx(b, systemcontract.AddressListContractAddr, "addBlacklist", common.Address{0xaa}, uint8(DirectionTo)))
+		b.AddTx(tc.adminTx(b, systemcontract.AddressListContractAddr, "addBlacklist", common.Address{0xbb}, uint8(DirectionBoth)))
+		b.AddTx(tc.adminTx(b, systemcontract.AddressListContractAddr, "addOrUpdateRule", common.Hash{0x01}, big.NewInt(1), uint8(1)))
+	})
+	// a missed block leaves a punish record
+	validators := tc.snapshot().validators()
+	tc.signers[3] = validators[4%len(validators)]
+	tc.extend(2, nil)
+	require.NotEmpty(t, tc.call(alABI, systemcontract.AddressListContractAddr, "getBlacksTo")[0])
+
+	head := tc.chain.CurrentBlock()
+	addrs := []common.Address{
+		{},
+		systemcontract.EngineCaller,
+		testAdmin,
+		head.Coinbase,
+		validators[0],
+		systemcontract.ValidatorsContractAddr,
+		systemcontract.PunishContractAddr,
+		systemcontract.SysGovContractAddr,
+		{0xaa},
+	}
+	return tc, addrs
+}
+
+// TestSysContractDelegation checks the upgraded system contracts behave as their
+// version 0 code for every call they delegate: the same calls from the same
+// accounts return the same data, emit the same logs and write the same storage.
+func TestSysContractDelegation(t *testing.T) {
+	for version := systemcontract.SysContractV1; version <= systemcontract.SysContractV2; version++ {
+		tc, addrs := newUpgradeTestChain(t)
+		head := tc.chain.CurrentBlock()
+		statedb := mustState(t, tc.chain)
+		newCtx := func(statedb *state.StateDB) *systemcontract.CallContext {
+			return &systemcontract.CallContext{
+				Statedb:      statedb,
+				Header:       head,
+				ChainContext: newChainContext(tc.chain, tc.engine),
+				ChainConfig:  tc.config,
+			}
+		}
+		v0, upgraded := newCtx(statedb.Copy()), newCtx(statedb.Copy())
+		upgradeContracts(t, upgraded, version)
+
+		delegated := func(name string, method abi.Method) bool {
+			src, _ := contractSource(t, name, version)
+			var selector [4]byte
+			copy(selector[:], method.ID)
+			_, ok := nativeSelectors(t, src)[selector]
+			return !ok
+		}
+		calls := 0
+		for name, addr := range upgradedContracts {
+			var inputs [][]byte
+			for _, method := range tc.engine.abi[name].Methods {
+				if !delegated(name, method) {
+					continue
+				}
+				for seed := 0; seed < 3; seed++ {
+					args := make([]interface{}, len(method.Inputs))
+					for i, input := range method.Inputs {
+						args[i] = sampleArg(input.Type, seed+i, addrs)
+					}
+					input, err := tc.engine.abi[name].Pack(method.Name, args...)
+					require.NoError(t, err, method.Sig)
+					inputs = append(inputs, input)
+				}
+			}
+			// the unknown selectors and the plain transfers are delegated too
+			inputs = append(inputs, nil, []byte{0xde, 0xad, 0xbe, 0xef}, []byte{0x01})
+
+			for _, input := range inputs {
+				for _, from := range addrs {
+					want, _ := sysContractCall(v0, calls, from, addr, input)
+					have, _ := sysContractCall(upgraded, calls, from, addr, input)
+					require.Equal(t, want, have, "v%d %s from %x: %x", version, name, from, input)
+					calls++
+				}
+			}
+		}
+		// the version 0 state read back after all the calls is the same too
+		for name, addr := range upgradedContracts {
+			for _, method := range tc.engine.abi[name].Methods {
+				if len(method.Inputs) != 0 || !method.IsConstant() || !delegated(name, method) {
+					continue
+				}
+				want, _ := sysContractCall(v0, calls, common.Address{}, addr, method.ID)
+				have, _ := sysContractCall(upgraded, calls, common.Address{}, addr, method.ID)
+				require.Equal(t, want, have, "v%d %s", version, method.Sig)
+				calls++
+			}
+		}
+	}
+}
+
+// TestSysContractNamespaces checks the methods added by the upgrades only write
+// the storage of their namespaces, which can't collide with the version 0 layout:
+// a documented namespace N, a slot close to it, or a mapping key hashed with one.
+func TestSysContractNamespaces(t *testing.T) {
+	for version := systemcontract.SysContractV1; version <= systemcontract.SysContractV2; version++ {
+		tc, addrs := newUpgradeTestChain(t)
+		head := tc.chain.CurrentBlock()
+		ctx := &systemcontract.CallContext{
+			Statedb:      mustState(t, tc.chain),
+			Header:       head,
+			ChainContext: newChainContext(tc.chain, tc.engine),
+			ChainConfig:  tc.config,
+		}
+		upgradeContracts(t, ctx, version)
+
+		calls, stores := 0, 0
+		for name, addr := range upgradedContracts {
+			src, release := contractSource(t, name, version)
+			var namespaces []*big.Int
+			for _, match := range namespaceRe.FindAllStringSubmatch(src, -1) {
+				hash := crypto.Keccak256Hash([]byte(match[1]))
+				require.Contains(t, src, "PUSH "+hash.Hex(), "v%d %s namespace %s", release, name, match[1])
+				namespaces = append(namespaces, hash.Big())
+			}
+			require.NotEmpty(t, namespaces, "v%d %s", release, name)
+			inNamespace := func(slot common.Hash) bool {
+				for _, ns := range namespaces {
+					if offset := new(big.Int).Sub(slot.Big(), ns); offset.Sign() >= 0 && offset.Cmp(big.NewInt(1<<32)) < 0 {
+						return true
+					}
+				}
+				return false
+			}
+
+			native := nativeSelectors(t, src)
+			contractABI := tc.engine.abi[name]
+			for selector, sig := range native {
+				method, err := contractABI.MethodById(selector[:])
+				require.NoError(t, err, sig)
+				for seed := 0; seed < 3; seed++ {
+					args := make([]interface{}, len(method.Inputs))
+					for i, input := range method.Inputs {
+						args[i] = sampleArg(input.Type, seed+i, addrs)
+					}
+					input, err := contractABI.Pack(method.Name, args...)
+					require.NoError(t, err, sig)
+					for _, from := range addrs {
+						result, tracer := sysContractCall(ctx, calls, from, addr, input)
+						calls++
+						for _, slot := range result.stores {
+							preimage, hashed := tracer.preimages[slot]
+							ok := inNamespace(slot) || (hashed && len(preimage) == 2*common.HashLength && inNamespace(common.BytesToHash(preimage[common.HashLength:])))
+							require.True(t, ok, "v%d %s from %x writes slot %x", release, sig, from, slot)
+							stores++
+						}
+					}
+				}
+			}
+		}
+		// the methods are called with their expected callers and arguments
+		require.NotZero(t, stores, "v%d", version)
+	}
+}
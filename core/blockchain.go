@@ -91,6 +91,7 @@ var (
 	errChainStopped         = errors.New("blockchain is stopped")
 	errInvalidOldChain      = errors.New("invalid old chain")
 	errInvalidNewChain      = errors.New("invalid new chain")
+	errSetHeadFinalized     = errors.New("rewind below the finalized block")
 )
 
 const (
@@ -140,6 +141,7 @@ type CacheConfig struct {
 	TrieTimeLimit       time.Duration // Time limit after which to flush the current in-memory trie to disk
 	SnapshotLimit       int           // Memory allowance (MB) to use for caching snapshot entries in memory
 	Preimages           bool          // Whether to store preimage of trie key to the disk
+	InternalTxHistory   uint64        // Number of recent blocks to keep the internal txs of (0 = all)
 
 	SnapshotNoBuild bool // Whether the background generation is allowed
 	SnapshotWait    bool // Wait for snapshot construction on startup. TODO(karalabe): This is a dirty hack for testing, nuke it
@@ -195,6 +197,7 @@ type BlockChain struct {
 	chainSideFeed event.Feed
 	chainHeadFeed event.Feed
 	logsFeed      event.Feed
+	itxsFeed      event.Feed
 	blockProcFeed event.Feed
 	scope         event.SubscriptionScope
 	genesisBlock  *types.Block
@@ -208,6 +211,8 @@ type BlockChain struct {
 	currentFinalBlock atomic.Pointer[types.Header] // Latest (consensus) finalized block
 	currentSafeBlock  atomic.Pointer[types.Header] // Latest (consensus) safe block
 
+	finalityCh chan struct{} // Notifies the finality updater of a new head
+
 	bodyCache     *lru.Cache[common.Hash, *types.Body]
 	bodyRLPCache  *lru.Cache[common.Hash, rlp.RawValue]
 	receiptsCache *lru.Cache[common.Hash, []*types.Receipt]
@@ -265,6 +270,7 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		triedb:        triedb,
 		triegc:        prque.New[int64, common.Hash](nil),
 		quit:          make(chan struct{}),
+		finalityCh:    make(chan struct{}, 1),
 		chainmu:       syncx.NewClosableMutex(),
 		bodyCache:     lru.NewCache[common.Hash, *types.Body](bodyCacheLimit),
 		bodyRLPCache:  lru.NewCache[common.Hash, rlp.RawValue](bodyCacheLimit),
@@ -416,6 +422,12 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	bc.wg.Add(1)
 	go bc.updateFutureBlocks()
 
+	// Start the finality updater if the engine finalizes blocks by itself.
+	if fe, ok := bc.engine.(consensus.FinalityEngine); ok {
+		bc.wg.Add(1)
+		go bc.updateFinality(fe)
+	}
+
 	// If periodic cache journal is required, spin it up.
 	if bc.cacheConfig.TrieCleanRejournal > 0 {
 		if bc.cacheConfig.TrieCleanRejournal < time.Minute {
@@ -432,9 +444,9 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 	if compat, ok := genesisErr.(*params.ConfigCompatError); ok {
 		log.Warn("Rewinding chain to upgrade configuration", "err", compat)
 		if compat.RewindToTime > 0 {
-			bc.SetHeadWithTimestamp(compat.RewindToTime)
+			bc.setHead(0, compat.RewindToTime)
 		} else {
-			bc.SetHead(compat.RewindToBlock)
+			bc.setHead(compat.RewindToBlock, 0)
 		}
 		rawdb.WriteChainConfig(db, genesisHash, chainConfig)
 	}
@@ -445,6 +457,18 @@ func NewBlockChain(db ethdb.Database, cacheConfig *CacheConfig, genesis *Genesis
 		bc.wg.Add(1)
 		go bc.maintainTxIndex()
 	}
+	// Start internal tx pruner if required. The ancient store can't drop single
+	// items, so the history can't reach past the blocks not yet frozen.
+	if limit := uint64(params.FullImmutabilityThreshold); bc.cacheConfig.InternalTxHistory > limit {
+		log.Warn("Internal tx history beyond the ancient threshold, capping", "history", bc.cacheConfig.InternalTxHistory, "cap", limit)
+		cacheConfig := *bc.cacheConfig
+		cacheConfig.InternalTxHistory = limit
+		bc.cacheConfig = &cacheConfig
+	}
+	if bc.cacheConfig.InternalTxHistory != 0 {
+		bc.wg.Add(1)
+		go bc.maintainInternalTxs()
+	}
 	return bc, nil
 }
 
@@ -542,31 +566,31 @@ func (bc *BlockChain) loadLastState() error {
 
 // SetHead rewinds the local chain to a new head. Depending on whether the node
 // was snap synced or full synced and in which state, the method will try to
-// delete minimal data from disk whilst retaining chain consistency.
+// delete minimal data from disk whilst retaining chain consistency. Rewinding
+// below the block finalized by the consensus engine is refused.
 func (bc *BlockChain) SetHead(head uint64) error {
-	if _, err := bc.setHeadBeyondRoot(head, 0, common.Hash{}, false); err != nil {
-		return err
+	if finalized := bc.engineFinalBlock(); finalized != nil && head < finalized.Number.Uint64() {
+		return fmt.Errorf("%w: #%d below #%d", errSetHeadFinalized, head, finalized.Number)
 	}
-	// Send chain head event to update the transaction pool
-	header := bc.CurrentBlock()
-	block := bc.GetBlock(header.Hash(), header.Number.Uint64())
-	if block == nil {
-		// This should never happen. In practice, previously currentBlock
-		// contained the entire block whereas now only a "marker", so there
-		// is an ever so slight chance for a race we should handle.
-		log.Error("Current block not found in database", "block", header.Number, "hash", header.Hash())
-		return fmt.Errorf("current block missing: #%d [%x..]", header.Number, header.Hash().Bytes()[:4])
-	}
-	bc.chainHeadFeed.Send(ChainHeadEvent{Block: block})
-	return nil
+	return bc.setHead(head, 0)
 }
 
 // SetHeadWithTimestamp rewinds the local chain to a new head that has at max
 // the given timestamp. Depending on whether the node was snap synced or full
 // synced and in which state, the method will try to delete minimal data from
-// disk whilst retaining chain consistency.
+// disk whilst retaining chain consistency. Rewinding below the block finalized
+// by the consensus engine is refused.
 func (bc *BlockChain) SetHeadWithTimestamp(timestamp uint64) error {
-	if _, err := bc.setHeadBeyondRoot(0, timestamp, common.Hash{}, false); err != nil {
+	if finalized := bc.engineFinalBlock(); finalized != nil && timestamp < finalized.Time {
+		return fmt.Errorf("%w: time %d before #%d", errSetHeadFinalized, timestamp, finalized.Number)
+	}
+	return bc.setHead(0, timestamp)
+}
+
+// setHead rewinds the local chain to the given head or timestamp, even below the
+// finalized block, which is cleared then.
+func (bc *BlockChain) setHead(head uint64, timestamp uint64) error {
+	if _, err := bc.setHeadBeyondRoot(head, timestamp, common.Hash{}, false); err != nil {
 		return err
 	}
 	// Send chain head event to update the transaction pool
@@ -583,6 +607,15 @@ func (bc *BlockChain) SetHeadWithTimestamp(timestamp uint64) error {
 	return nil
 }
 
+// engineFinalBlock returns the finalized block if the consensus engine finalizes
+// blocks by itself, such a block can never be reverted.
+func (bc *BlockChain) engineFinalBlock() *types.Header {
+	if _, ok := bc.engine.(consensus.FinalityEngine); !ok {
+		return nil
+	}
+	return bc.CurrentFinalBlock()
+}
+
 // SetFinalized sets the finalized block.
 func (bc *BlockChain) SetFinalized(header *types.Header) {
 	bc.currentFinalBlock.Store(header)
@@ -742,6 +775,8 @@ func (bc *BlockChain) setHeadBeyondRoot(head uint64, time uint64, root common.Ha
 			// removed in the hc.SetHead function.
 			rawdb.DeleteBody(db, hash, num)
 			rawdb.DeleteReceipts(db, hash, num)
+			rawdb.DeleteInternalTxs(db, hash, num)
+			rawdb.DeleteFeeBreakdown(db, hash, num)
 		}
 		// Todo(rjl493456442) txlookup, bloombits, etc
 	}
@@ -912,6 +947,81 @@ func (bc *BlockChain) writeHeadBlock(block *types.Block) {
 
 	bc.currentBlock.Store(block.Header())
 	headBlockGauge.Update(int64(block.NumberU64()))
+
+	// Notify the finality updater, which catches up with the latest head
+	select {
+	case bc.finalityCh <- struct{}{}:
+	default:
+	}
+}
+
+// updateFinality advances the finalized block of an engine able to finalize
+// blocks by itself whenever the head changes. The finality is computed off the
+// write path, so the finalized block may lag behind the head for a while.
+func (bc *BlockChain) updateFinality(fe consensus.FinalityEngine) {
+	defer bc.wg.Done()
+	for {
+		select {
+		case <-bc.finalityCh:
+			bc.updateFinalized(fe)
+		case <-bc.quit:
+			return
+		}
+	}
+}
+
+// updateFinalized advances the finalized block to the one of the current head,
+// the finalized block never moves backward.
+func (bc *BlockChain) updateFinalized(fe consensus.FinalityEngine) {
+	finalized := fe.GetFinalizedHeader(bc, bc.CurrentBlock())
+	if finalized == nil {
+		return
+	}
+	// The head may have been reorged meanwhile, only a canonical block is
+	// finalized, the next head retries otherwise.
+	if !bc.chainmu.TryLock() {
+		return
+	}
+	defer bc.chainmu.Unlock()
+
+	if bc.GetCanonicalHash(finalized.Number.Uint64()) != finalized.Hash() {
+		return
+	}
+	if current := bc.CurrentFinalBlock(); current != nil && current.Number.Cmp(finalized.Number) >= 0 {
+		return
+	}
+	bc.SetFinalized(finalized)
+}
+
+// reorgNeeded returns whether the reorg should be applied, it's the same as the
+// fork choice, except that a reorg which would revert the finalized block is
+// always refused.
+func (bc *BlockChain) reorgNeeded(current *types.Header, extern *types.Header) (bool, error) {
+	reorg, err := bc.forker.ReorgNeeded(current, extern)
+	if err != nil || !reorg {
+		return reorg, err
+	}
+	finalized := bc.CurrentFinalBlock()
+	if finalized == nil {
+		return true, nil
+	}
+	if _, ok := bc.engine.(consensus.FinalityEngine); !ok {
+		return true, nil
+	}
+	number := finalized.Number.Uint64()
+	if extern.Number.Uint64() < number {
+		log.Warn("Refused reorg below finalized block", "finalized", number, "extern", extern.Number)
+		return false, nil
+	}
+	ancestor := extern
+	for ancestor != nil && ancestor.Number.Uint64() > number {
+		ancestor = bc.GetHeader(ancestor.ParentHash, ancestor.Number.Uint64()-1)
+	}
+	if ancestor == nil || ancestor.Hash() != finalized.Hash() {
+		log.Warn("Refused reorg conflicting with finalized block", "finalized", number, "hash", finalized.Hash(), "extern", extern.Number, "externHash", extern.Hash())
+		return false, nil
+	}
+	return true, nil
 }
 
 // stopWithoutSaving stops the blockchain service. If any imports are currently in progress
@@ -1366,6 +1476,9 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 		if len(internalTxs) > 0 {
 			rawdb.WriteInternalTxs(blockBatch, block.Hash(), block.NumberU64(), internalTxs)
 		}
+		if fees := bc.feeBreakdown(block.Header()); fees != nil {
+			rawdb.WriteFeeBreakdown(blockBatch, block.Hash(), block.NumberU64(), fees)
+		}
 		rawdb.WritePreimages(blockBatch, state.Preimages())
 		if err := blockBatch.Write(); err != nil {
 			log.Crit("Failed to write block into disk", "err", err)
@@ -1438,6 +1551,15 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 	return state.AsyncCommit(bc.chainConfig.IsEIP158(block.Number()), afterCommit)
 }
 
+// feeBreakdown returns the fee breakdown the engine measured when finalizing the
+// block, if any.
+func (bc *BlockChain) feeBreakdown(header *types.Header) *types.FeeBreakdown {
+	if posa, ok := bc.engine.(consensus.PoSA); ok {
+		return posa.FeeBreakdown(header)
+	}
+	return nil
+}
+
 // WriteBlockAndSetHead writes the given block and all associated state to the database,
 // and applies the block as the new chain head.
 func (bc *BlockChain) WriteBlockAndSetHead(block *types.Block, receipts []*types.Receipt, logs []*types.Log, state *state.StateDB, emitHeadEvent bool) (status WriteStatus, err error) {
@@ -1456,7 +1578,7 @@ func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types
 		return NonStatTy, err
 	}
 	currentBlock := bc.CurrentBlock()
-	reorg, err := bc.forker.ReorgNeeded(currentBlock, block.Header())
+	reorg, err := bc.reorgNeeded(currentBlock, block.Header())
 	if err != nil {
 		return NonStatTy, err
 	}
@@ -1482,6 +1604,9 @@ func (bc *BlockChain) writeBlockAndSetHead(block *types.Block, receipts []*types
 		if len(logs) > 0 {
 			bc.logsFeed.Send(logs)
 		}
+		if itxs := bc.collectInternalTxs(block, internalTxs, false); len(itxs) > 0 {
+			bc.itxsFeed.Send(InternalTxsEvent{itxs})
+		}
 		// In theory, we should fire a ChainHeadEvent when we inject
 		// a canonical block, but sometimes we can insert a batch of
 		// canonical blocks. Avoid firing too many ChainHeadEvents,
@@ -1605,7 +1730,7 @@ func (bc *BlockChain) insertChain(chain types.Blocks, verifySeals, setHead bool)
 			current = bc.CurrentBlock()
 		)
 		for block != nil && bc.skipBlock(err, it) {
-			reorg, err = bc.forker.ReorgNeeded(current, block.Header())
+			reorg, err = bc.reorgNeeded(current, block.Header())
 			if err != nil {
 				return it.index, err
 			}
@@ -1972,7 +2097,7 @@ func (bc *BlockChain) insertSideChain(block *types.Block, it *insertIterator) (i
 	//
 	// If the externTd was larger than our local TD, we now need to reimport the previous
 	// blocks to regenerate the required state
-	reorg, err := bc.forker.ReorgNeeded(current, lastBlock.Header())
+	reorg, err := bc.reorgNeeded(current, lastBlock.Header())
 	if err != nil {
 		return it.index, err
 	}
@@ -2098,6 +2223,25 @@ func (bc *BlockChain) collectLogs(b *types.Block, removed bool) []*types.Log {
 	return logs
 }
 
+// collectInternalTxs collects the internal txs that were generated during the
+// processing of the given block, reading them from the database unless given.
+// These internal txs are later announced as deleted or reborn.
+func (bc *BlockChain) collectInternalTxs(b *types.Block, internalTxs []*types.InternalTx, removed bool) []*types.InternalTx {
+	if internalTxs == nil {
+		internalTxs = rawdb.ReadInternalTxs(bc.db, b.Hash(), b.NumberU64())
+	}
+	collected := make([]*types.InternalTx, 0, len(internalTxs))
+	for _, itx := range internalTxs {
+		// Don't modify the processed or stored internal txs
+		cpy := *itx
+		cpy.BlockHash = b.Hash()
+		cpy.BlockNumber = b.Number()
+		cpy.Removed = removed
+		collected = append(collected, &cpy)
+	}
+	return collected
+}
+
 // reorg takes two blocks, an old chain and a new chain and will reconstruct the
 // blocks and inserts them to be part of the new canonical chain and accumulates
 // potential missing transactions and post an event about them.
@@ -2215,6 +2359,13 @@ func (bc *BlockChain) reorg(oldHead *types.Header, newHead *types.Block) error {
 	for _, tx := range diffs {
 		rawdb.DeleteTxLookupEntry(indexesBatch, tx)
 	}
+	// Collect the internal txs of the blocks dropped from the canonical chain
+	// to be announced as removed. Like the receipts, they are kept with the
+	// side chain blocks, which aren't re-executed if they become canonical again.
+	var deletedInternalTxs []*types.InternalTx
+	for i := len(oldChain) - 1; i >= 0; i-- {
+		deletedInternalTxs = append(deletedInternalTxs, bc.collectInternalTxs(oldChain[i], nil, true)...)
+	}
 	// Delete all hash markers that are not part of the new canonical chain.
 	// Because the reorg function does not handle new chain head, all hash
 	// markers greater than or equal to new chain head should be deleted.
@@ -2270,6 +2421,16 @@ func (bc *BlockChain) reorg(oldHead *types.Header, newHead *types.Block) error {
 	if len(rebirthLogs) > 0 {
 		bc.logsFeed.Send(rebirthLogs)
 	}
+
+	// Removed and reborn internal txs:
+	if len(deletedInternalTxs) > 0 {
+		bc.itxsFeed.Send(InternalTxsEvent{deletedInternalTxs})
+	}
+	for i := len(newChain) - 1; i >= 1; i-- {
+		if itxs := bc.collectInternalTxs(newChain[i], nil, false); len(itxs) > 0 {
+			bc.itxsFeed.Send(InternalTxsEvent{itxs})
+		}
+	}
 	return nil
 }
 
@@ -2319,6 +2480,9 @@ func (bc *BlockChain) SetCanonical(head *types.Block) (common.Hash, error) {
 	if len(logs) > 0 {
 		bc.logsFeed.Send(logs)
 	}
+	if itxs := bc.collectInternalTxs(head, nil, false); len(itxs) > 0 {
+		bc.itxsFeed.Send(InternalTxsEvent{itxs})
+	}
 	bc.chainHeadFeed.Send(ChainHeadEvent{Block: head})
 
 	context := []interface{}{
@@ -0,0 +1,180 @@
+package core
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/trie"
+)
+
+// pruneInternalTxs deletes the internal txs of the blocks which are more than
+// the configured history below the given head, from the oldest block not yet
+// pruned on. The side chain blocks keep their internal txs on reorgs, as they
+// aren't executed again if they become canonical, so they are pruned as well. The history is capped to the ancient threshold, so
+// the blocks are pruned before they are frozen and get empty ancient items.
+func (bc *BlockChain) pruneInternalTxs(head uint64, done chan struct{}) {
+	defer close(done)
+
+	limit := bc.cacheConfig.InternalTxHistory
+	if head < limit {
+		return
+	}
+	var (
+		from  uint64
+		to    = head - limit + 1
+		start = time.Now()
+		batch = bc.db.NewBatch()
+	)
+	if tail := rawdb.ReadInternalTxTail(bc.db); tail != nil {
+		from = *tail
+	}
+	if from >= to {
+		return
+	}
+	for number := from; number < to; number++ {
+		for _, hash := range rawdb.ReadAllHashes(bc.db, number) {
+			rawdb.DeleteInternalTxs(batch, hash, number)
+		}
+		if batch.ValueSize() > ethdb.IdealBatchSize {
+			rawdb.WriteInternalTxTail(batch, number+1)
+			if err := batch.Write(); err != nil {
+				log.Crit("Failed to prune internal txs", "err", err)
+			}
+			batch.Reset()
+
+			select {
+			case <-bc.quit:
+				return
+			default:
+			}
+		}
+	}
+	rawdb.WriteInternalTxTail(batch, to)
+	if err := batch.Write(); err != nil {
+		log.Crit("Failed to prune internal txs", "err", err)
+	}
+	log.Debug("Pruned internal txs", "from", from, "to", to, "elapsed", common.PrettyDuration(time.Since(start)))
+}
+
+// maintainInternalTxs is responsible for the deletion of the internal txs of
+// the blocks older than the configured history as the chain head moves.
+func (bc *BlockChain) maintainInternalTxs() {
+	defer bc.wg.Done()
+
+	var (
+		done   chan struct{}                  // Non-nil if background pruning routine is active.
+		headCh = make(chan ChainHeadEvent, 1) // Buffered to avoid locking up the event feed
+	)
+	sub := bc.SubscribeChainHeadEvent(headCh)
+	if sub == nil {
+		return
+	}
+	defer sub.Unsubscribe()
+
+	if head := rawdb.ReadHeadBlock(bc.db); head != nil {
+		done = make(chan struct{})
+		go bc.pruneInternalTxs(head.NumberU64(), done)
+	}
+	for {
+		select {
+		case head := <-headCh:
+			if done == nil {
+				done = make(chan struct{})
+				go bc.pruneInternalTxs(head.Block.NumberU64(), done)
+			}
+		case <-done:
+			done = nil
+		case <-bc.quit:
+			if done != nil {
+				log.Info("Waiting background internal tx pruner to exit")
+				<-done
+			}
+			return
+		}
+	}
+}
+
+// BackfillInternalTxs regenerates the internal txs of the canonical blocks in
+// the range [from, to] by executing them again, and stores them replacing the
+// ones stored before. The state of the parent of the first block must be
+// available, the states of the blocks of the range are only kept in memory.
+func (bc *BlockChain) BackfillInternalTxs(from, to uint64) error {
+	if from == 0 {
+		from = 1 // the genesis block has no transactions
+	}
+	if from > to {
+		return fmt.Errorf("invalid block range %d - %d", from, to)
+	}
+	if head := bc.CurrentBlock().Number.Uint64(); to > head {
+		return fmt.Errorf("block %d is above the chain head %d", to, head)
+	}
+	// The internal txs below the pruning tail are not served, move it back
+	// to the backfilled range, the pruner deletes the ones out of the history.
+	if tail := rawdb.ReadInternalTxTail(bc.db); tail != nil && *tail > from {
+		rawdb.WriteInternalTxTail(bc.db, from)
+	}
+	parent := bc.GetHeaderByNumber(from - 1)
+	if parent == nil {
+		return fmt.Errorf("block #%d not found", from-1)
+	}
+	// Execute the blocks over an ephemeral trie.Database, as the states they
+	// produce are not needed once their internal txs are stored.
+	database := state.NewDatabaseWithConfig(bc.db, &trie.Config{Cache: 16})
+	statedb, err := state.New(parent.Root, database, nil)
+	if err != nil {
+		return fmt.Errorf("missing state of block %d: %w", from-1, err)
+	}
+	var (
+		root    common.Hash
+		prev    common.Hash
+		vmcfg   = bc.vmConfig
+		start   = time.Now()
+		logged  = time.Now()
+		written int
+	)
+	vmcfg.InternalTxTraceDisabled = false
+	for number := from; number <= to; number++ {
+		block := bc.GetBlockByNumber(number)
+		if block == nil {
+			return fmt.Errorf("block #%d not found", number)
+		}
+		_, _, internalTxs, _, err := bc.processor.Process(block, statedb, vmcfg)
+		if err != nil {
+			return fmt.Errorf("processing block %d failed: %v", number, err)
+		}
+		if root, err = statedb.Commit(bc.chainConfig.IsEIP158(block.Number())); err != nil {
+			return fmt.Errorf("state commit of block %d failed: %v", number, err)
+		}
+		if root != block.Root() {
+			return fmt.Errorf("state root mismatch of block %d: have %x want %x", number, root, block.Root())
+		}
+		if len(internalTxs) > 0 {
+			rawdb.WriteInternalTxs(bc.db, block.Hash(), number, internalTxs)
+			written++
+		} else {
+			rawdb.DeleteInternalTxs(bc.db, block.Hash(), number)
+		}
+		if statedb, err = state.New(root, database, nil); err != nil {
+			return fmt.Errorf("state reset after block %d failed: %v", number, err)
+		}
+		// Hold the state reference and also drop the parent state
+		// to prevent accumulating too many nodes in memory.
+		database.TrieDB().Reference(root, common.Hash{})
+		if prev != (common.Hash{}) {
+			database.TrieDB().Dereference(prev)
+		}
+		prev = root
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Backfilling internal txs", "block", number, "target", to, "remaining", to-number, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	database.TrieDB().Dereference(root)
+	log.Info("Backfilled internal txs", "from", from, "to", to, "blocks", written, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
@@ -0,0 +1,343 @@
+package core
+
+import (
+	"bytes"
+	"math/big"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+var (
+	itxTestKey, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+	itxTestAddress = crypto.PubkeyToAddress(itxTestKey.PublicKey)
+	// itxTestRelay sends its call value to 0xdad
+	itxTestRelay = common.HexToAddress("0x0000000000000000000000000000000000000ba1")
+)
+
+func newInternalTxTestGenesis() *Genesis {
+	return &Genesis{
+		Config: params.TestChainConfig,
+		Alloc: GenesisAlloc{
+			itxTestAddress: {Balance: big.NewInt(params.Ether)},
+			itxTestRelay:   {Code: common.FromHex("0x600060006000600034610dad5af100")},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+}
+
+// generateInternalTxBlocks generates n blocks on top of parent, each relaying
+// the value of a transaction starting at the given one.
+func generateInternalTxBlocks(gspec *Genesis, db ethdb.Database, parent *types.Block, n int, value int64) []*types.Block {
+	signer := types.LatestSigner(gspec.Config)
+	blocks, _ := GenerateChain(gspec.Config, parent, ethash.NewFaker(), db, n, func(i int, b *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(b.TxNonce(itxTestAddress), itxTestRelay, big.NewInt(value+int64(i)), 100000, b.header.BaseFee, nil), signer, itxTestKey)
+		if err != nil {
+			panic(err)
+		}
+		b.AddTx(tx)
+	})
+	return blocks
+}
+
+func newInternalTxTestChain(t *testing.T, db ethdb.Database, gspec *Genesis, cacheConfig *CacheConfig, vmConfig vm.Config, blocks []*types.Block) *BlockChain {
+	t.Helper()
+
+	chain, err := NewBlockChain(db, cacheConfig, gspec, nil, ethash.NewFaker(), vmConfig, nil, nil)
+	if err != nil {
+		t.Fatalf("failed to create tester chain: %v", err)
+	}
+	if n, err := chain.InsertChain(blocks); err != nil {
+		t.Fatalf("block %d: failed to insert into chain: %v", n, err)
+	}
+	return chain
+}
+
+func checkInternalTxs(t *testing.T, db ethdb.Reader, blocks []*types.Block, stored bool) {
+	t.Helper()
+
+	for _, block := range blocks {
+		itxs := rawdb.ReadInternalTxs(db, block.Hash(), block.NumberU64())
+		if stored && (len(itxs) != 1 || len(itxs[0].Actions) != 2) {
+			t.Errorf("block %d: have %d internal txs, want 1 with 2 actions", block.NumberU64(), len(itxs))
+		}
+		if !stored && itxs != nil {
+			t.Errorf("block %d: have %d internal txs, want none", block.NumberU64(), len(itxs))
+		}
+	}
+}
+
+// Tests that the internal txs of the blocks dropped from the canonical chain
+// are kept by reorgs, and deleted by rewinding the chain.
+func TestInternalTxsReorgAndSetHead(t *testing.T) {
+	var (
+		gspec       = newInternalTxTestGenesis()
+		genDb, _, _ = GenerateChainWithGenesis(gspec, ethash.NewFaker(), 0, nil)
+		first       = generateInternalTxBlocks(gspec, genDb, gspec.ToBlock(), 4, 1)
+	)
+	chain := newInternalTxTestChain(t, rawdb.NewMemoryDatabase(), gspec, nil, vm.Config{}, first)
+	defer chain.Stop()
+	checkInternalTxs(t, chain.db, first, true)
+
+	// Reorg to a longer fork from block 2
+	second := generateInternalTxBlocks(gspec, genDb, first[1], 3, 100)
+	if _, err := chain.InsertChain(second); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if head := chain.CurrentBlock().Hash(); head != second[2].Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", head, second[2].Hash())
+	}
+	checkInternalTxs(t, chain.db, first, true)
+	checkInternalTxs(t, chain.db, second, true)
+
+	// Reorg back to the original chain, whose known blocks aren't re-executed
+	third := generateInternalTxBlocks(gspec, genDb, first[3], 2, 1000)
+	if _, err := chain.InsertChain(third); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	if head := chain.CurrentBlock().Hash(); head != third[1].Hash() {
+		t.Fatalf("head mismatch: have %x, want %x", head, third[1].Hash())
+	}
+	checkInternalTxs(t, chain.db, first, true)
+	checkInternalTxs(t, chain.db, second, true)
+	checkInternalTxs(t, chain.db, third, true)
+
+	// Rewind the chain below the fork
+	if err := chain.SetHead(1); err != nil {
+		t.Fatalf("failed to rewind the chain: %v", err)
+	}
+	checkInternalTxs(t, chain.db, first[:1], true)
+	checkInternalTxs(t, chain.db, first[1:], false)
+	checkInternalTxs(t, chain.db, third, false)
+}
+
+// Tests that the pruner deletes the internal txs of the side chain blocks too.
+func TestInternalTxsPruneSideChain(t *testing.T) {
+	var (
+		gspec       = newInternalTxTestGenesis()
+		genDb, _, _ = GenerateChainWithGenesis(gspec, ethash.NewFaker(), 0, nil)
+		first       = generateInternalTxBlocks(gspec, genDb, gspec.ToBlock(), 4, 1)
+		second      = generateInternalTxBlocks(gspec, genDb, first[1], 3, 100)
+	)
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.InternalTxHistory = 2
+	chain := newInternalTxTestChain(t, rawdb.NewMemoryDatabase(), gspec, &cacheConfig, vm.Config{}, first)
+	defer chain.Stop()
+	if _, err := chain.InsertChain(second); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	done := make(chan struct{})
+	chain.pruneInternalTxs(chain.CurrentBlock().Number.Uint64(), done)
+	<-done
+
+	checkInternalTxs(t, chain.db, first[:3], false)
+	checkInternalTxs(t, chain.db, first[3:], true)
+	checkInternalTxs(t, chain.db, second[:1], false)
+	checkInternalTxs(t, chain.db, second[1:], true)
+}
+
+// Tests that the chain freezer moves the internal txs into the ancient store,
+// and that the pruned ones are frozen as empty items.
+func TestInternalTxsFreezeAndPrune(t *testing.T) {
+	var (
+		gspec       = newInternalTxTestGenesis()
+		genDb, _, _ = GenerateChainWithGenesis(gspec, ethash.NewFaker(), 0, nil)
+		blocks      = generateInternalTxBlocks(gspec, genDb, gspec.ToBlock(), 8, 1)
+	)
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer db.Close()
+
+	cacheConfig := *defaultCacheConfig
+	cacheConfig.InternalTxHistory = 6
+	chain := newInternalTxTestChain(t, db, gspec, &cacheConfig, vm.Config{}, blocks)
+	defer chain.Stop()
+
+	// Prune the blocks below the history
+	done := make(chan struct{})
+	chain.pruneInternalTxs(chain.CurrentBlock().Number.Uint64(), done)
+	<-done
+	if tail := rawdb.ReadInternalTxTail(db); tail == nil || *tail != 3 {
+		t.Fatalf("internal tx tail mismatch: have %v, want 3", tail)
+	}
+	checkInternalTxs(t, db, blocks[:2], false)
+	checkInternalTxs(t, db, blocks[2:], true)
+
+	// Freeze the blocks up to 5, both the pruned and the kept ones
+	type freezer interface {
+		Freeze(threshold uint64) error
+		Ancients() (uint64, error)
+	}
+	db.(freezer).Freeze(3)
+	if frozen, _ := db.Ancients(); frozen != 6 {
+		t.Fatalf("frozen blocks mismatch: have %d, want 6", frozen)
+	}
+	for _, block := range blocks {
+		itxs, _ := db.Ancient("internalTx", block.NumberU64())
+		if frozen := block.NumberU64() < 6; frozen && (block.NumberU64() < 3) != (len(itxs) == 0) {
+			t.Errorf("block %d: frozen internal txs %x", block.NumberU64(), itxs)
+		}
+	}
+	checkInternalTxs(t, db, blocks[:2], false)
+	checkInternalTxs(t, db, blocks[2:], true)
+
+	// The frozen internal txs below the tail are not served
+	rawdb.WriteInternalTxTail(db, 5)
+	checkInternalTxs(t, db, blocks[:4], false)
+	checkInternalTxs(t, db, blocks[4:], true)
+	rawdb.WriteInternalTxTail(db, 3)
+
+	// Rewinding into the ancient store drops the frozen internal txs too
+	if err := chain.SetHead(3); err != nil {
+		t.Fatalf("failed to rewind the chain: %v", err)
+	}
+	if frozen, _ := db.Ancients(); frozen != 4 {
+		t.Fatalf("frozen blocks mismatch: have %d, want 4", frozen)
+	}
+	checkInternalTxs(t, db, blocks[2:3], true)
+	checkInternalTxs(t, db, blocks[3:], false)
+}
+
+// Tests that the internal txs of the blocks imported without tracing them are
+// regenerated by executing the blocks again, also the frozen ones.
+func TestBackfillInternalTxs(t *testing.T) {
+	var (
+		gspec       = newInternalTxTestGenesis()
+		genDb, _, _ = GenerateChainWithGenesis(gspec, ethash.NewFaker(), 0, nil)
+		blocks      = generateInternalTxBlocks(gspec, genDb, gspec.ToBlock(), 8, 1)
+	)
+	// Import the blocks with and without the internal txs trace
+	traced := newInternalTxTestChain(t, rawdb.NewMemoryDatabase(), gspec, nil, vm.Config{}, blocks)
+	defer traced.Stop()
+
+	db, err := rawdb.NewDatabaseWithFreezer(rawdb.NewMemoryDatabase(), t.TempDir(), "", false)
+	if err != nil {
+		t.Fatalf("failed to create database with ancient backend: %v", err)
+	}
+	defer db.Close()
+	chain := newInternalTxTestChain(t, db, gspec, nil, vm.Config{InternalTxTraceDisabled: true}, blocks)
+	defer chain.Stop()
+	checkInternalTxs(t, db, blocks, false)
+
+	type freezer interface {
+		Freeze(threshold uint64) error
+	}
+	db.(freezer).Freeze(4)
+	if frozen, _ := db.Ancients(); frozen != 5 {
+		t.Fatalf("frozen blocks mismatch: have %d, want 5", frozen)
+	}
+	// The state of the blocks is not available except for the genesis one
+	if err := chain.BackfillInternalTxs(3, 8); err == nil {
+		t.Fatal("backfilled internal txs without the parent state")
+	}
+	if err := chain.BackfillInternalTxs(0, 9); err == nil {
+		t.Fatal("backfilled internal txs above the chain head")
+	}
+	if err := chain.BackfillInternalTxs(0, 8); err != nil {
+		t.Fatalf("failed to backfill internal txs: %v", err)
+	}
+	for _, block := range blocks {
+		have := rawdb.ReadInternalTxsRLP(db, block.Hash(), block.NumberU64())
+		want := rawdb.ReadInternalTxsRLP(traced.db, block.Hash(), block.NumberU64())
+		if len(want) == 0 || !bytes.Equal(have, want) {
+			t.Errorf("block %d: internal txs mismatch: have %x, want %x", block.NumberU64(), have, want)
+		}
+	}
+}
+
+// Tests that the internal txs of the blocks entering the canonical chain are
+// announced, and those of the blocks dropped by a reorg announced as removed.
+func TestInternalTxsEvents(t *testing.T) {
+	var (
+		gspec       = newInternalTxTestGenesis()
+		genDb, _, _ = GenerateChainWithGenesis(gspec, ethash.NewFaker(), 0, nil)
+		first       = generateInternalTxBlocks(gspec, genDb, gspec.ToBlock(), 3, 1)
+		second      = generateInternalTxBlocks(gspec, genDb, first[0], 3, 100)
+	)
+	chain := newInternalTxTestChain(t, rawdb.NewMemoryDatabase(), gspec, nil, vm.Config{}, nil)
+	defer chain.Stop()
+
+	itxsCh := make(chan InternalTxsEvent, 10)
+	sub := chain.SubscribeInternalTxsEvent(itxsCh)
+	defer sub.Unsubscribe()
+
+	type announced struct {
+		number  uint64
+		hash    common.Hash
+		removed bool
+	}
+	var want []announced
+	for _, block := range first {
+		want = append(want, announced{block.NumberU64(), block.Hash(), false})
+	}
+	for _, block := range first[1:] {
+		want = append(want, announced{block.NumberU64(), block.Hash(), true})
+	}
+	for _, block := range second {
+		want = append(want, announced{block.NumberU64(), block.Hash(), false})
+	}
+	if _, err := chain.InsertChain(first); err != nil {
+		t.Fatalf("failed to insert chain: %v", err)
+	}
+	if _, err := chain.InsertChain(second); err != nil {
+		t.Fatalf("failed to insert fork: %v", err)
+	}
+	var have []announced
+	for len(have) < len(want) {
+		select {
+		case ev := <-itxsCh:
+			for _, itx := range ev.InternalTxs {
+				if len(itx.Actions) != 2 {
+					t.Errorf("block %d: have %d actions, want 2", itx.BlockNumber, len(itx.Actions))
+				}
+				have = append(have, announced{itx.BlockNumber.Uint64(), itx.BlockHash, itx.Removed})
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("internal txs events missing: have %d, want %d", len(have), len(want))
+		}
+	}
+	if !reflect.DeepEqual(have, want) {
+		t.Errorf("announced internal txs mismatch: have %v, want %v", have, want)
+	}
+}
+
+// Tests that the transfer of the balance of a self-destructed contract to its
+// beneficiary is recorded.
+func TestInternalTxsSelfdestruct(t *testing.T) {
+	var (
+		gspec    = newInternalTxTestGenesis()
+		suicidal = common.HexToAddress("0x0000000000000000000000000000000000005d")
+		signer   = types.LatestSigner(gspec.Config)
+	)
+	// suicidal self-destructs sending its balance to the caller
+	gspec.Alloc[suicidal] = GenesisAccount{Code: common.FromHex("0x33ff")}
+	_, blocks, _ := GenerateChainWithGenesis(gspec, ethash.NewFaker(), 1, func(i int, b *BlockGen) {
+		tx, err := types.SignTx(types.NewTransaction(b.TxNonce(itxTestAddress), suicidal, big.NewInt(5), 100000, b.header.BaseFee, nil), signer, itxTestKey)
+		if err != nil {
+			panic(err)
+		}
+		b.AddTx(tx)
+	})
+	chain := newInternalTxTestChain(t, rawdb.NewMemoryDatabase(), gspec, nil, vm.Config{}, blocks)
+	defer chain.Stop()
+
+	itxs := rawdb.ReadInternalTxs(chain.db, blocks[0].Hash(), blocks[0].NumberU64())
+	if len(itxs) != 1 || len(itxs[0].Actions) != 2 {
+		t.Fatalf("have %d internal txs, want 1 with 2 actions", len(itxs))
+	}
+	action := itxs[0].Actions[1]
+	if action.OpCode != "SELFDESTRUCT" || action.From != suicidal || action.To != itxTestAddress || action.Value.Cmp(big.NewInt(5)) != 0 || !action.Success {
+		t.Errorf("selfdestruct action mismatch: have %+v", action)
+	}
+}
@@ -405,6 +405,11 @@ func (bc *BlockChain) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscript
 	return bc.scope.Track(bc.logsFeed.Subscribe(ch))
 }
 
+// SubscribeInternalTxsEvent registers a subscription of InternalTxsEvent.
+func (bc *BlockChain) SubscribeInternalTxsEvent(ch chan<- InternalTxsEvent) event.Subscription {
+	return bc.scope.Track(bc.itxsFeed.Subscribe(ch))
+}
+
 // SubscribeBlockProcessingEvent registers a subscription of bool where true means
 // block processing has started while false means it has stopped.
 func (bc *BlockChain) SubscribeBlockProcessingEvent(ch chan<- bool) event.Subscription {
@@ -4342,3 +4342,79 @@ func TestEIP3651(t *testing.T) {
 		t.Fatalf("sender balance incorrect: expected %d, got %d", expected, actual)
 	}
 }
+
+// finalityEngine is a consensus engine finalizing the ancestor of the head at
+// a fixed height.
+type finalityEngine struct {
+	consensus.Engine
+	number uint64
+}
+
+func (e *finalityEngine) GetFinalizedHeader(chain consensus.ChainHeaderReader, header *types.Header) *types.Header {
+	for header != nil && header.Number.Uint64() > e.number {
+		header = chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+	}
+	return header
+}
+
+// waitFinalized waits for the background finality updater to finalize the
+// given block.
+func waitFinalized(t *testing.T, chain *BlockChain, header *types.Header) {
+	t.Helper()
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if finalized := chain.CurrentFinalBlock(); finalized != nil && finalized.Hash() == header.Hash() {
+			return
+		}
+	}
+	t.Fatalf("finalized block mismatch: have %v, want #%d", chain.CurrentFinalBlock(), header.Number)
+}
+
+// Tests that a reorg below, or conflicting with the block finalized by the
+// consensus engine is refused, whatever the total difficulty of the new chain.
+func TestReorgFinalized(t *testing.T) {
+	engine := &finalityEngine{Engine: ethash.NewFaker(), number: 5}
+	genDb, _, chain, err := newCanonical(engine, 10, true)
+	if err != nil {
+		t.Fatalf("failed to create canonical chain: %v", err)
+	}
+	defer chain.Stop()
+
+	head := chain.CurrentBlock()
+	waitFinalized(t, chain, chain.GetHeaderByNumber(5))
+
+	// Rewinding below the finalized block is refused
+	if err := chain.SetHead(4); !errors.Is(err, errSetHeadFinalized) {
+		t.Fatalf("rewind below finalized block: have %v, want %v", err, errSetHeadFinalized)
+	}
+	if current := chain.CurrentBlock(); current.Hash() != head.Hash() {
+		t.Fatalf("head mismatch: have #%d [%x], want #%d [%x]", current.Number, current.Hash(), head.Number, head.Hash())
+	}
+	// A heavier chain forking before the finalized block is refused
+	fork := makeBlockChain(chain.chainConfig, chain.GetBlockByNumber(3), 12, engine, genDb, forkSeed)
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert forking chain: %v", err)
+	}
+	if chain.GetTd(fork[len(fork)-1].Hash(), fork[len(fork)-1].NumberU64()).Cmp(chain.GetTd(head.Hash(), head.Number.Uint64())) <= 0 {
+		t.Fatalf("forking chain not heavier")
+	}
+	if current := chain.CurrentBlock(); current.Hash() != head.Hash() {
+		t.Fatalf("head mismatch: have #%d [%x], want #%d [%x]", current.Number, current.Hash(), head.Number, head.Hash())
+	}
+	// A chain shorter than the finalized block is refused
+	below := fork[0].Header()
+	rawdb.WriteTd(chain.db, below.Hash(), below.Number.Uint64(), new(big.Int).Lsh(common.Big1, 128))
+	if reorg, err := chain.reorgNeeded(head, below); err != nil || reorg {
+		t.Fatalf("reorg below finalized block: have %v, %v, want false", reorg, err)
+	}
+	// A heavier chain forking after the finalized block is accepted
+	fork = makeBlockChain(chain.chainConfig, chain.GetBlockByNumber(7), 6, engine, genDb, forkSeed)
+	if _, err := chain.InsertChain(fork); err != nil {
+		t.Fatalf("failed to insert forking chain: %v", err)
+	}
+	if current := chain.CurrentBlock(); current.Hash() != fork[len(fork)-1].Hash() {
+		t.Fatalf("head mismatch: have #%d [%x], want #%d [%x]", current.Number, current.Hash(), fork[len(fork)-1].NumberU64(), fork[len(fork)-1].Hash())
+	}
+	if finalized := chain.CurrentFinalBlock(); finalized.Hash() != chain.GetHeaderByNumber(5).Hash() {
+		t.Fatalf("finalized block moved: have #%d [%x]", finalized.Number, finalized.Hash())
+	}
+}
@@ -48,6 +48,8 @@ type BlockGen struct {
 
 	config *params.ChainConfig
 	engine consensus.Engine
+
+	extraValidator types.EvmExtraValidator // validator of the PoSA engine rules, sealed chains only
 }
 
 // SetCoinbase sets the coinbase of the generated block.
@@ -97,7 +99,7 @@ func (b *BlockGen) addTx(bc *BlockChain, vmConfig vm.Config, tx *types.Transacti
 		b.SetCoinbase(common.Address{})
 	}
 	b.statedb.SetTxContext(tx.Hash(), len(b.txs))
-	receipt, err := ApplyTransaction(b.config, bc, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, &b.header.GasUsed, vmConfig, nil)
+	receipt, err := ApplyTransaction(b.config, bc, &b.header.Coinbase, b.gasPool, b.statedb, b.header, tx, &b.header.GasUsed, vmConfig, b.extraValidator)
 	if err != nil {
 		panic(err)
 	}
@@ -248,14 +250,48 @@ func (b *BlockGen) OffsetTime(seconds int64) {
 // values. Inserting them into BlockChain requires use of FakePow or
 // a similar non-validating proof of work implementation.
 func GenerateChain(config *params.ChainConfig, parent *types.Block, engine consensus.Engine, db ethdb.Database, n int, gen func(int, *BlockGen)) ([]*types.Block, []types.Receipts) {
+	return generateChain(config, parent, engine, db, n, gen, nil)
+}
+
+// ChainSealer fills in the consensus fields of blocks created by
+// GenerateSealedChain.
+type ChainSealer interface {
+	// Prepare initializes the consensus fields of the header before the
+	// generator function is called. The chain gives access to the parent
+	// block and all its ancestors.
+	Prepare(chain consensus.ChainHeaderReader, header *types.Header) error
+
+	// Seal returns the sealed version of the finalized block.
+	Seal(block *types.Block) (*types.Block, error)
+}
+
+// GenerateSealedChain is like GenerateChain, but every header is prepared and
+// every block is sealed by the given sealer before the next one is generated.
+// It is meant for engines like clique or npos, whose state transition depends
+// on the signers of the ancestor blocks. db must contain the canonical chain
+// up to parent.
+func GenerateSealedChain(config *params.ChainConfig, parent *types.Block, engine consensus.Engine, db ethdb.Database, n int, sealer ChainSealer, gen func(int, *BlockGen)) ([]*types.Block, []types.Receipts) {
+	return generateChain(config, parent, engine, db, n, gen, sealer)
+}
+
+func generateChain(config *params.ChainConfig, parent *types.Block, engine consensus.Engine, db ethdb.Database, n int, gen func(int, *BlockGen), sealer ChainSealer) ([]*types.Block, []types.Receipts) {
 	if config == nil {
 		config = params.TestChainConfig
 	}
 	blocks, receipts := make(types.Blocks, n), make([]types.Receipts, n)
-	chainreader := &fakeChainReader{config: config}
+	var chainreader consensus.ChainReader = &fakeChainReader{config: config}
+	if sealer != nil {
+		chainreader = &sealedChainReader{config: config, db: db, blocks: blocks}
+	}
 	genblock := func(i int, parent *types.Block, statedb *state.StateDB) (*types.Block, types.Receipts) {
 		b := &BlockGen{i: i, chain: blocks, parent: parent, statedb: statedb, config: config, engine: engine}
 		b.header = makeHeader(chainreader, parent, statedb, b.engine)
+		if sealer != nil {
+			if err := sealer.Prepare(chainreader, b.header); err != nil {
+				panic(fmt.Sprintf("header prepare error: %v", err))
+			}
+			b.gasPool = new(GasPool).AddGas(b.header.GasLimit)
+		}
 
 		// Set the difficulty for clique block. The chain maker doesn't have access
 		// to a chain, so the difficulty will be left unset (nil). Set it here to the
@@ -286,6 +322,13 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 			if err := posa.PreHandle(chainreader, b.header, statedb); err != nil {
 				return nil, nil
 			}
+			if sealer != nil {
+				validator, err := posa.CreateEvmExtraValidator(b.header, statedb)
+				if err != nil {
+					return nil, nil
+				}
+				b.extraValidator = validator
+			}
 		}
 		// Execute any user modifications to the block
 		if gen != nil {
@@ -295,6 +338,12 @@ func GenerateChain(config *params.ChainConfig, parent *types.Block, engine conse
 			// Finalize and seal the block
 			block, receipts, _ := b.engine.FinalizeAndAssemble(chainreader, b.header, statedb, b.txs, b.uncles, b.receipts)
 			b.receipts = receipts
+			if sealer != nil {
+				var err error
+				if block, err = sealer.Seal(block); err != nil {
+					panic(fmt.Sprintf("block seal error: %v", err))
+				}
+			}
 
 			// Write state changes to db
 			root, err := statedb.Commit(config.IsEIP158(b.header.Number))
@@ -416,3 +465,61 @@ func (cr *fakeChainReader) GetHeaderByHash(hash common.Hash) *types.Header
 func (cr *fakeChainReader) GetHeader(hash common.Hash, number uint64) *types.Header { return nil }
 func (cr *fakeChainReader) GetBlock(hash common.Hash, number uint64) *types.Block   { return nil }
 func (cr *fakeChainReader) GetTd(hash common.Hash, number uint64) *big.Int          { return nil }
+
+// sealedChainReader serves the blocks generated so far by GenerateSealedChain,
+// falling back to the canonical chain stored in db for their ancestors.
+type sealedChainReader struct {
+	config *params.ChainConfig
+	db     ethdb.Database
+	blocks []*types.Block
+}
+
+// Config returns the chain configuration.
+func (cr *sealedChainReader) Config() *params.ChainConfig {
+	return cr.config
+}
+
+func (cr *sealedChainReader) CurrentHeader() *types.Header                   { return nil }
+func (cr *sealedChainReader) GetTd(hash common.Hash, number uint64) *big.Int { return nil }
+
+func (cr *sealedChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	for _, block := range cr.blocks {
+		if block != nil && block.NumberU64() == number {
+			return block.Header()
+		}
+	}
+	hash := rawdb.ReadCanonicalHash(cr.db, number)
+	if hash == (common.Hash{}) {
+		return nil
+	}
+	return rawdb.ReadHeader(cr.db, hash, number)
+}
+
+func (cr *sealedChainReader) GetHeaderByHash(hash common.Hash) *types.Header {
+	for _, block := range cr.blocks {
+		if block != nil && block.Hash() == hash {
+			return block.Header()
+		}
+	}
+	number := rawdb.ReadHeaderNumber(cr.db, hash)
+	if number == nil {
+		return nil
+	}
+	return rawdb.ReadHeader(cr.db, hash, *number)
+}
+
+func (cr *sealedChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	if block := cr.GetBlock(hash, number); block != nil {
+		return block.Header()
+	}
+	return rawdb.ReadHeader(cr.db, hash, number)
+}
+
+func (cr *sealedChainReader) GetBlock(hash common.Hash, number uint64) *types.Block {
+	for _, block := range cr.blocks {
+		if block != nil && block.Hash() == hash && block.NumberU64() == number {
+			return block
+		}
+	}
+	return rawdb.ReadBlock(cr.db, hash, number)
+}
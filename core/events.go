@@ -30,6 +30,10 @@ type NewMinedBlockEvent struct{ Block *types.Block }
 // RemovedLogsEvent is posted when a reorg happens
 type RemovedLogsEvent struct{ Logs []*types.Log }
 
+// InternalTxsEvent is posted when the internal txs of blocks enter the canonical
+// chain, or leave it on a reorg with their Removed field set.
+type InternalTxsEvent struct{ InternalTxs []*types.InternalTx }
+
 type ChainEvent struct {
 	Block *types.Block
 	Hash  common.Hash
@@ -0,0 +1,93 @@
+package core
+
+import (
+	"context"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+)
+
+const (
+	// internalTxThrottling is the time to wait between processing two consecutive
+	// index sections.
+	internalTxThrottling = 100 * time.Millisecond
+)
+
+// IsValueTransferAction returns whether an action is an internal value transfer,
+// that is a call, creation or self-destruct with a nonzero value made by a
+// contract, as indexed by the internal tx address index.
+//
+// The actions of the consensus engine, at types.EngineActionDepth, are value
+// transfers too, even at the top level: they are made by no transaction. Those
+// made out of any transaction are indexed by their position in the block, like
+// the others, as their internal txs all have an empty tx hash.
+func IsValueTransferAction(action *types.Action) bool {
+	// the top-level call of a transaction is the transaction itself
+	return action.Depth != types.TopActionDepth && action.Value != nil && action.Value.Sign() > 0
+}
+
+// InternalTxIndexer implements a core.ChainIndexer, indexing the internal value
+// transfers of the canonical chain by the addresses sending or receiving them.
+type InternalTxIndexer struct {
+	db    ethdb.Database // database instance to read the internal txs from and write index data into
+	batch ethdb.Batch    // batch of the index data of the section being processed
+}
+
+// NewInternalTxIndexer returns a chain indexer that generates the internal tx
+// address index for the canonical chain.
+func NewInternalTxIndexer(db ethdb.Database, size, confirms uint64) *ChainIndexer {
+	backend := &InternalTxIndexer{
+		db: db,
+	}
+	table := rawdb.NewTable(db, string(rawdb.InternalTxIndexPrefix))
+
+	return NewChainIndexer(db, table, backend, size, confirms, internalTxThrottling, "internaltxs")
+}
+
+// Reset implements core.ChainIndexerBackend, starting a new index section.
+func (b *InternalTxIndexer) Reset(ctx context.Context, section uint64, lastSectionHead common.Hash) error {
+	b.batch = b.db.NewBatch()
+	return nil
+}
+
+// Process implements core.ChainIndexerBackend, adding the internal value
+// transfers of a new header's block into the index.
+func (b *InternalTxIndexer) Process(ctx context.Context, header *types.Header) error {
+	var (
+		hash   = header.Hash()
+		number = header.Number.Uint64()
+	)
+	for i, tx := range rawdb.ReadInternalTxs(b.db, hash, number) {
+		for j, action := range tx.Actions {
+			if !IsValueTransferAction(action) {
+				continue
+			}
+			pos := rawdb.InternalTxPosition{Number: number, TxIndex: uint32(i), ActionIndex: uint32(j)}
+			rawdb.WriteInternalTxAddrIndex(b.batch, action.From, pos, hash)
+			if action.To != action.From && action.To != (common.Address{}) {
+				rawdb.WriteInternalTxAddrIndex(b.batch, action.To, pos, hash)
+			}
+		}
+	}
+	if b.batch.ValueSize() >= ethdb.IdealBatchSize {
+		if err := b.batch.Write(); err != nil {
+			return err
+		}
+		b.batch.Reset()
+	}
+	return nil
+}
+
+// Commit implements core.ChainIndexerBackend, writing out the index data of the
+// section into the database.
+func (b *InternalTxIndexer) Commit() error {
+	return b.batch.Write()
+}
+
+// Prune returns an empty error since we don't support pruning here.
+func (b *InternalTxIndexer) Prune(threshold uint64) error {
+	return nil
+}
@@ -814,12 +814,22 @@ func writeAncientBlock(op ethdb.AncientWriteOp, block *types.Block, header *type
 	if err := op.Append(ChainFreezerDifficultyTable, num, td); err != nil {
 		return fmt.Errorf("can't append block %d total difficulty: %v", num, err)
 	}
+	// The internal txs and the fee breakdown of blocks written without executing
+	// them are unknown.
+	if err := op.AppendRaw(freezerInternalTxTable, num, nil); err != nil {
+		return fmt.Errorf("can't append block %d internal txs: %v", num, err)
+	}
+	if err := op.AppendRaw(freezerFeesTable, num, nil); err != nil {
+		return fmt.Errorf("can't append block %d fee breakdown: %v", num, err)
+	}
 	return nil
 }
 
 // DeleteBlock removes all block data associated with a hash.
 func DeleteBlock(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	DeleteReceipts(db, hash, number)
+	DeleteInternalTxs(db, hash, number)
+	DeleteFeeBreakdown(db, hash, number)
 	DeleteHeader(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
@@ -829,6 +839,8 @@ func DeleteBlock(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 // the hash to number mapping.
 func DeleteBlockWithoutNumber(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
 	DeleteReceipts(db, hash, number)
+	DeleteInternalTxs(db, hash, number)
+	DeleteFeeBreakdown(db, hash, number)
 	deleteHeaderWithoutNumber(db, hash, number)
 	DeleteBody(db, hash, number)
 	DeleteTd(db, hash, number)
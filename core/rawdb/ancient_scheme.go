@@ -35,6 +35,9 @@ const (
 
 	// freezerInternalTxTable indicates the name of the freezer internal tx table.
 	freezerInternalTxTable = "internalTx"
+
+	// freezerFeesTable indicates the name of the freezer fee breakdown table.
+	freezerFeesTable = "fees"
 )
 
 // chainFreezerNoSnappy configures whether compression is disabled for the ancient-tables.
@@ -45,6 +48,16 @@ var chainFreezerNoSnappy = map[string]bool{
 	ChainFreezerBodiesTable:     false,
 	ChainFreezerReceiptTable:    false,
 	ChainFreezerDifficultyTable: true,
+	freezerInternalTxTable:      false,
+	freezerFeesTable:            false,
+}
+
+// chainFreezerAddedTables are the ancient-tables added after the chain freezer
+// was first released. Opening an existing freezer fills them with empty items,
+// instead of truncating all the other tables to their length.
+var chainFreezerAddedTables = map[string]bool{
+	freezerInternalTxTable: true,
+	freezerFeesTable:       true,
 }
 
 // The list of identifiers of ancient stores.
@@ -276,6 +276,10 @@ func (f *chainFreezer) freezeRange(nfdb *nofreezedb, number, limit uint64) (hash
 			if len(td) == 0 {
 				return fmt.Errorf("total difficulty missing, can't freeze block %d", number)
 			}
+			// The internal txs and the fee breakdown are optional, blocks without
+			// them get an empty item.
+			internalTxs := ReadInternalTxsRLP(nfdb, hash, number)
+			fees := ReadFeeBreakdownRLP(nfdb, hash, number)
 
 			// Write to the batch.
 			if err := op.AppendRaw(ChainFreezerHashTable, number, hash[:]); err != nil {
@@ -293,6 +297,12 @@ func (f *chainFreezer) freezeRange(nfdb *nofreezedb, number, limit uint64) (hash
 			if err := op.AppendRaw(ChainFreezerDifficultyTable, number, td); err != nil {
 				return fmt.Errorf("can't write td to Freezer: %v", err)
 			}
+			if err := op.AppendRaw(freezerInternalTxTable, number, internalTxs); err != nil {
+				return fmt.Errorf("can't write internal txs to Freezer: %v", err)
+			}
+			if err := op.AppendRaw(freezerFeesTable, number, fees); err != nil {
+				return fmt.Errorf("can't write fee breakdown to Freezer: %v", err)
+			}
 
 			hashes = append(hashes, hash)
 		}
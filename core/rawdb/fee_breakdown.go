@@ -0,0 +1,58 @@
+package rawdb
+
+import (
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rlp"
+)
+
+// ReadFeeBreakdownRLP retrieves the fee breakdown of a block in RLP encoding.
+func ReadFeeBreakdownRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
+	var data []byte
+	db.ReadAncients(func(reader ethdb.AncientReaderOp) error {
+		// Check if the data is in ancients
+		if isCanon(reader, number, hash) {
+			data, _ = reader.Ancient(freezerFeesTable, number)
+			return nil
+		}
+		// If not, try reading from leveldb
+		data, _ = db.Get(blockFeesKey(number, hash))
+		return nil
+	})
+	return data
+}
+
+// ReadFeeBreakdown retrieves the fee breakdown of a block, nil if it wasn't
+// recorded.
+func ReadFeeBreakdown(db ethdb.Reader, hash common.Hash, number uint64) *types.FeeBreakdown {
+	data := ReadFeeBreakdownRLP(db, hash, number)
+	if len(data) == 0 {
+		return nil
+	}
+	fees := new(types.FeeBreakdown)
+	if err := rlp.DecodeBytes(data, fees); err != nil {
+		log.Error("Invalid fee breakdown RLP", "hash", hash, "err", err)
+		return nil
+	}
+	return fees
+}
+
+// WriteFeeBreakdown stores the fee breakdown of a block.
+func WriteFeeBreakdown(db ethdb.KeyValueWriter, hash common.Hash, number uint64, fees *types.FeeBreakdown) {
+	data, err := rlp.EncodeToBytes(fees)
+	if err != nil {
+		log.Crit("Failed to encode fee breakdown", "err", err)
+	}
+	if err := db.Put(blockFeesKey(number, hash), data); err != nil {
+		log.Crit("Failed to store fee breakdown", "err", err)
+	}
+}
+
+// DeleteFeeBreakdown removes the fee breakdown of a block.
+func DeleteFeeBreakdown(db ethdb.KeyValueWriter, hash common.Hash, number uint64) {
+	if err := db.Delete(blockFeesKey(number, hash)); err != nil {
+		log.Crit("Failed to delete fee breakdown", "err", err)
+	}
+}
@@ -79,7 +79,7 @@ type Freezer struct {
 // NewChainFreezer is a small utility method around NewFreezer that sets the
 // default parameters for the chain storage.
 func NewChainFreezer(datadir string, namespace string, readonly bool) (*Freezer, error) {
-	return NewFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy)
+	return newFreezer(datadir, namespace, readonly, freezerTableSize, chainFreezerNoSnappy, chainFreezerAddedTables)
 }
 
 // NewFreezer creates a freezer instance for maintaining immutable ordered
@@ -88,6 +88,12 @@ func NewChainFreezer(datadir string, namespace string, readonly bool) (*Freezer,
 // The 'tables' argument defines the data tables. If the value of a map
 // entry is true, snappy compression is disabled for the table.
 func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool) (*Freezer, error) {
+	return newFreezer(datadir, namespace, readonly, maxTableSize, tables, nil)
+}
+
+// newFreezer creates a freezer instance like NewFreezer. The tables in 'added'
+// are filled with empty items if they are new to an existing freezer.
+func newFreezer(datadir string, namespace string, readonly bool, maxTableSize uint32, tables map[string]bool, added map[string]bool) (*Freezer, error) {
 	// Create the initial freezer object
 	var (
 		readMeter  = metrics.NewRegisteredMeter(namespace+"ancient/read", nil)
@@ -142,8 +148,10 @@ func NewFreezer(datadir string, namespace string, readonly bool, maxTableSize ui
 		// validate also sets `freezer.frozen`.
 		err = freezer.validate()
 	} else {
-		// Truncate all tables to common length.
-		err = freezer.repair()
+		// Fill the added tables and truncate all tables to common length.
+		if err = freezer.fillAddedTables(added); err == nil {
+			err = freezer.repair()
+		}
 	}
 	if err != nil {
 		for _, table := range freezer.tables {
@@ -393,6 +401,47 @@ func (f *Freezer) repair() error {
 	return nil
 }
 
+// fillAddedTables fills the given tables with empty items up to the length of
+// the others, if they have just been added to an existing freezer. Otherwise
+// repairing the freezer would truncate all the tables to the empty ones.
+func (f *Freezer) fillAddedTables(added map[string]bool) error {
+	head := uint64(math.MaxUint64)
+	for kind, table := range f.tables {
+		if items := table.items.Load(); !added[kind] && items < head {
+			head = items
+		}
+	}
+	if head == math.MaxUint64 || head == 0 {
+		return nil
+	}
+	for kind := range added {
+		table := f.tables[kind]
+		if table == nil || table.items.Load() != 0 || table.itemHidden.Load() != 0 {
+			continue
+		}
+		log.Info("Filling added freezer table", "table", kind, "items", head)
+		batch := table.newBatch()
+		for item := uint64(0); item < head; item++ {
+			if err := batch.AppendRaw(item, nil); err != nil {
+				return err
+			}
+			// The empty items don't fill the data buffer, flush the index regularly
+			if batch.curItem%freezerBatchFillLimit == 0 {
+				if err := batch.commit(); err != nil {
+					return err
+				}
+			}
+		}
+		if err := batch.commit(); err != nil {
+			return err
+		}
+		if err := table.Sync(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // convertLegacyFn takes a raw freezer entry in an older format and
 // returns it in the new format.
 type convertLegacyFn = func([]byte) ([]byte, error)
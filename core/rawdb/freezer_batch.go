@@ -28,6 +28,10 @@ import (
 // for a single freezer table batch.
 const freezerBatchBufferLimit = 2 * 1024 * 1024
 
+// This is the number of empty items after which a batch filling a freezer
+// table is written out.
+const freezerBatchFillLimit = 100000
+
 // freezerBatch is a write operation of multiple items on a freezer.
 type freezerBatch struct {
 	tables map[string]*freezerTableBatch
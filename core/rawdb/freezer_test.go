@@ -283,6 +283,72 @@ func TestFreezerReadonlyValidate(t *testing.T) {
 	}
 }
 
+// This checks that a table added to an existing freezer is filled with empty
+// items, instead of the other tables being truncated to its length.
+func TestFreezerFillAddedTables(t *testing.T) {
+	dir := t.TempDir()
+	f, err := NewFreezer(dir, "", false, 2049, map[string]bool{"a": true, "b": false})
+	if err != nil {
+		t.Fatal("can't open freezer", err)
+	}
+	var item = make([]byte, 1024)
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for i := uint64(0); i < 5; i++ {
+			if err := op.AppendRaw("a", i, item); err != nil {
+				return err
+			}
+			if err := op.AppendRaw("b", i, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// Reopen with the added tables, one of them compressed.
+	tables := map[string]bool{"a": true, "b": false, "c": true, "d": false}
+	added := map[string]bool{"c": true, "d": true}
+	f, err = newFreezer(dir, "", false, 2049, tables, added)
+	if err != nil {
+		t.Fatal("can't open freezer", err)
+	}
+	for _, kind := range []string{"a", "c", "d"} {
+		checkAncientCount(t, f, kind, 5)
+	}
+	for _, kind := range []string{"c", "d"} {
+		for i := uint64(0); i < 5; i++ {
+			if v, err := f.Ancient(kind, i); err != nil || len(v) != 0 {
+				t.Fatalf("table %s item %d: have %x, %v, want empty", kind, i, v, err)
+			}
+		}
+	}
+	// The filled tables are appended to along with the others.
+	_, err = f.ModifyAncients(func(op ethdb.AncientWriteOp) error {
+		for _, kind := range []string{"a", "b", "c", "d"} {
+			if err := op.AppendRaw(kind, 5, item); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.NoError(t, f.Close())
+
+	// Reopening again keeps everything, also in readonly mode.
+	f, err = newFreezer(dir, "", true, 2049, tables, added)
+	if err != nil {
+		t.Fatal("can't open freezer", err)
+	}
+	defer f.Close()
+	for _, kind := range []string{"a", "b", "c", "d"} {
+		checkAncientCount(t, f, kind, 6)
+		if v, _ := f.Ancient(kind, 5); !bytes.Equal(v, item) {
+			t.Fatalf("table %s: wrong item 5 %x", kind, v)
+		}
+	}
+}
+
 func TestFreezerConcurrentReadonly(t *testing.T) {
 	t.Parallel()
 
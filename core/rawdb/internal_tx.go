@@ -1,6 +1,9 @@
 package rawdb
 
 import (
+	"encoding/binary"
+	"errors"
+
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/ethdb"
@@ -8,14 +11,22 @@ import (
 	"github.com/ethereum/go-ethereum/rlp"
 )
 
-// ReadInternalTxsRLP retrieves all the transaction receipts belonging to a block in RLP encoding.
+// ReadInternalTxsRLP retrieves all the internal transactions belonging to a block in RLP encoding.
+// The internal txs of the blocks below the pruning tail are not served, even if
+// the ancient store still holds them.
 func ReadInternalTxsRLP(db ethdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
+	if tail := ReadInternalTxTail(db); tail != nil && number < *tail {
+		return nil
+	}
 	var data []byte
 	db.ReadAncients(func(reader ethdb.AncientReaderOp) error {
 		// Check if the data is in ancients
 		if isCanon(reader, number, hash) {
 			data, _ = reader.Ancient(freezerInternalTxTable, number)
-			return nil
+			if len(data) > 0 {
+				return nil
+			}
+			// Blocks frozen without internal txs may have them backfilled
 		}
 		// If not, try reading from leveldb
 		data, _ = db.Get(blockInternalTxsKey(number, hash))
@@ -64,3 +75,80 @@ func DeleteInternalTxs(db ethdb.KeyValueWriter, hash common.Hash, number uint64)
 		log.Crit("Failed to delete block internal txs", "err", err)
 	}
 }
+
+// ReadInternalTxTail retrieves the number of the oldest block whose internal
+// txs have not been pruned.
+func ReadInternalTxTail(db ethdb.KeyValueReader) *uint64 {
+	data, _ := db.Get(internalTxTailKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteInternalTxTail stores the number of the oldest block whose internal txs
+// have not been pruned.
+func WriteInternalTxTail(db ethdb.KeyValueWriter, number uint64) {
+	if err := db.Put(internalTxTailKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store the internal tx tail", "err", err)
+	}
+}
+
+// InternalTxPosition is the position of an action among the internal txs of a
+// block, which are ordered by the position of their transactions.
+type InternalTxPosition struct {
+	Number      uint64 // Number of the block
+	TxIndex     uint32 // Index of the internal tx in the internal txs of the block
+	ActionIndex uint32 // Index of the action in the actions of the internal tx
+}
+
+// Bytes encodes the position so that the encodings sort as the positions.
+func (pos InternalTxPosition) Bytes() []byte {
+	enc := make([]byte, 16)
+	binary.BigEndian.PutUint64(enc, pos.Number)
+	binary.BigEndian.PutUint32(enc[8:], pos.TxIndex)
+	binary.BigEndian.PutUint32(enc[12:], pos.ActionIndex)
+	return enc
+}
+
+// ParseInternalTxPosition decodes a position encoded by InternalTxPosition.Bytes.
+func ParseInternalTxPosition(enc []byte) (InternalTxPosition, error) {
+	if len(enc) != 16 {
+		return InternalTxPosition{}, errors.New("invalid internal tx position")
+	}
+	return InternalTxPosition{
+		Number:      binary.BigEndian.Uint64(enc),
+		TxIndex:     binary.BigEndian.Uint32(enc[8:]),
+		ActionIndex: binary.BigEndian.Uint32(enc[12:]),
+	}, nil
+}
+
+// WriteInternalTxAddrIndex stores the position of an action of the block with
+// the given hash in the index of an address.
+func WriteInternalTxAddrIndex(db ethdb.KeyValueWriter, address common.Address, pos InternalTxPosition, hash common.Hash) {
+	if err := db.Put(internalTxAddrKey(address, pos), hash.Bytes()); err != nil {
+		log.Crit("Failed to store internal tx address index", "err", err)
+	}
+}
+
+// IterateInternalTxAddrIndex calls fn with the positions in the index of an
+// address from the given position on, in order, along with the hashes of their
+// blocks, until fn returns false. The index may hold positions of blocks that
+// are no longer canonical.
+func IterateInternalTxAddrIndex(db ethdb.Iteratee, address common.Address, from InternalTxPosition, fn func(pos InternalTxPosition, hash common.Hash) bool) error {
+	prefix := append(append([]byte{}, internalTxAddrPrefix...), address.Bytes()...)
+	it := db.NewIterator(prefix, from.Bytes())
+	defer it.Release()
+
+	for it.Next() {
+		pos, err := ParseInternalTxPosition(it.Key()[len(prefix):])
+		if err != nil || len(it.Value()) != common.HashLength {
+			continue
+		}
+		if !fn(pos, common.BytesToHash(it.Value())) {
+			break
+		}
+	}
+	return it.Error()
+}
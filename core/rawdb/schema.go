@@ -72,6 +72,9 @@ var (
 	// txIndexTailKey tracks the oldest block whose transactions have been indexed.
 	txIndexTailKey = []byte("TransactionIndexTail")
 
+	// internalTxTailKey tracks the oldest block whose internal txs have not been pruned.
+	internalTxTailKey = []byte("InternalTxTail")
+
 	// fastTxLookupLimitKey tracks the transaction lookup limit during fast sync.
 	fastTxLookupLimitKey = []byte("FastTransactionLookupLimit")
 
@@ -93,6 +96,8 @@ var (
 	blockBodyPrefix       = []byte("b") // blockBodyPrefix + num (uint64 big endian) + hash -> block body
 	blockReceiptsPrefix   = []byte("r") // blockReceiptsPrefix + num (uint64 big endian) + hash -> block receipts
 	blockInternalTxPrefix = []byte("x") // blockInternalTxPrefix + num (uint64 big endian) + hash -> block actions
+	blockFeesPrefix       = []byte("f") // blockFeesPrefix + num (uint64 big endian) + hash -> block fee breakdown
+	internalTxAddrPrefix  = []byte("X") // internalTxAddrPrefix + address + num (uint64 big endian) + internal tx index (uint32 big endian) + action index (uint32 big endian) -> block hash
 
 	txLookupPrefix        = []byte("l") // txLookupPrefix + hash -> transaction/receipt lookup metadata
 	bloomBitsPrefix       = []byte("B") // bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash -> bloom bits
@@ -112,6 +117,9 @@ var (
 	// BloomBitsIndexPrefix is the data table of a chain indexer to track its progress
 	BloomBitsIndexPrefix = []byte("iB")
 
+	// InternalTxIndexPrefix is the data table of the internal tx address indexer to track its progress
+	InternalTxIndexPrefix = []byte("iX")
+
 	ChtPrefix           = []byte("chtRootV2-") // ChtPrefix + chtNum (uint64 big endian) -> trie root hash
 	ChtTablePrefix      = []byte("cht-")
 	ChtIndexTablePrefix = []byte("chtIndexV2-")
@@ -185,6 +193,11 @@ func blockInternalTxsKey(number uint64, hash common.Hash) []byte {
 	return append(append(blockInternalTxPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
 }
 
+// blockFeesKey = blockFeesPrefix + num (uint64 big endian) + hash
+func blockFeesKey(number uint64, hash common.Hash) []byte {
+	return append(append(blockFeesPrefix, encodeBlockNumber(number)...), hash.Bytes()...)
+}
+
 // txLookupKey = txLookupPrefix + hash
 func txLookupKey(hash common.Hash) []byte {
 	return append(txLookupPrefix, hash.Bytes()...)
@@ -209,6 +222,15 @@ func storageSnapshotsKey(accountHash common.Hash) []byte {
 	return append(SnapshotStoragePrefix, accountHash.Bytes()...)
 }
 
+// internalTxAddrKey = internalTxAddrPrefix + address + num (uint64 big endian) + internal tx index (uint32 big endian) + action index (uint32 big endian)
+func internalTxAddrKey(address common.Address, pos InternalTxPosition) []byte {
+	key := make([]byte, len(internalTxAddrPrefix)+common.AddressLength+16)
+	copy(key, internalTxAddrPrefix)
+	copy(key[len(internalTxAddrPrefix):], address.Bytes())
+	copy(key[len(internalTxAddrPrefix)+common.AddressLength:], pos.Bytes())
+	return key
+}
+
 // bloomBitsKey = bloomBitsPrefix + bit (uint16 big endian) + section (uint64 big endian) + hash
 func bloomBitsKey(bit uint, section uint64, hash common.Hash) []byte {
 	key := append(append(bloomBitsPrefix, make([]byte, 10)...), hash.Bytes()...)
@@ -101,7 +101,11 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 		if err := posa.PreHandle(p.bc, header, statedb); err != nil {
 			return nil, nil, nil, 0, err
 		}
-		vmenv.Context.ExtraValidator = posa.CreateEvmExtraValidator(header, statedb)
+		validator, err := posa.CreateEvmExtraValidator(header, statedb)
+		if err != nil {
+			return nil, nil, nil, 0, err
+		}
+		vmenv.Context.ExtraValidator = validator
 	}
 
 	// preload from and to of txs
@@ -170,7 +174,13 @@ func (p *StateProcessor) Process(block *types.Block, statedb *state.StateDB, cfg
 	returnErrBeforeWaitGroup = false
 
 	// Finalize the block, applying any consensus engine specific extras (e.g. block rewards)
-	if err := p.engine.Finalize(p.bc, header, statedb, &commonTxs, block.Uncles(), &receipts, systemTxs); err != nil {
+	if isPoSA && !cfg.InternalTxTraceDisabled {
+		engineTxs, err := posa.FinalizeWithInternalTxs(p.bc, header, statedb, &commonTxs, block.Uncles(), &receipts, systemTxs, cfg.InternalTxTraceAll)
+		if err != nil {
+			return nil, nil, nil, 0, err
+		}
+		internalTxs = append(internalTxs, engineTxs...)
+	} else if err := p.engine.Finalize(p.bc, header, statedb, &commonTxs, block.Uncles(), &receipts, systemTxs); err != nil {
 		return nil, nil, nil, 0, err
 	}
 
@@ -207,6 +217,9 @@ func applyTransaction(msg *Message, config *params.ChainConfig, gp *GasPool, sta
 	}
 	receipt.TxHash = tx.Hash()
 	receipt.GasUsed = result.UsedGas
+	if config.Npos != nil && result.Fee != nil {
+		receipt.Fee, receipt.FeeRecipient = result.Fee, &result.FeeRecipient
+	}
 
 	// If the transaction created a contract, store the creation address in the receipt.
 	if msg.To == nil {
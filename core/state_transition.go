@@ -35,6 +35,9 @@ type ExecutionResult struct {
 	UsedGas    uint64 // Total used gas but include the refunded gas
 	Err        error  // Any error encountered during the execution(listed in core/vm/errors.go)
 	ReturnData []byte // Returned data from evm(function result or data supplied with revert opcode)
+
+	Fee          *big.Int       // Fee paid to the fee recipient, nil if not charged
+	FeeRecipient common.Address // Coinbase, or the FeeRecoder collecting the fees on NPoS
 }
 
 // Unwrap returns the internal evm error which allows us for further
@@ -384,6 +387,10 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 		// After EIP-3529: refunds are capped to gasUsed / 5
 		st.refundGas(params.RefundQuotientEIP3529)
 	}
+	var (
+		fee          *big.Int
+		feeRecipient common.Address
+	)
 	effectiveTip := msg.GasPrice
 	if rules.IsLondon {
 		effectiveTip = cmath.BigMin(msg.GasTipCap, new(big.Int).Sub(msg.GasFeeCap, st.evm.Context.BaseFee))
@@ -393,20 +400,30 @@ func (st *StateTransition) TransitionDb() (*ExecutionResult, error) {
 		// Skip fee payment when NoBaseFee is set and the fee fields
 		// are 0. This avoids a negative effectiveTip being applied to
 		// the coinbase when simulating calls.
-	} else if st.evm.ChainConfig().Npos != nil {
-		fee := new(big.Int).SetUint64(st.gasUsed())
-		fee.Mul(fee, st.msg.GasPrice)
-		st.state.AddBalance(consensus.FeeRecoder, fee)
+	} else if npos := st.evm.ChainConfig().Npos; npos != nil {
+		// The fees are collected for the block reward distributed by the engine,
+		// only the tips once the base fee is burned.
+		price := msg.GasPrice
+		if rules.IsLondon && npos.IsFeeBurn(st.evm.Context.BlockNumber) {
+			price = effectiveTip
+		}
+		fee = new(big.Int).SetUint64(st.gasUsed())
+		fee.Mul(fee, price)
+		feeRecipient = consensus.FeeRecoder
+		st.state.AddBalance(feeRecipient, fee)
 	} else {
-		fee := new(big.Int).SetUint64(st.gasUsed())
+		fee = new(big.Int).SetUint64(st.gasUsed())
 		fee.Mul(fee, effectiveTip)
-		st.state.AddBalance(st.evm.Context.Coinbase, fee)
+		feeRecipient = st.evm.Context.Coinbase
+		st.state.AddBalance(feeRecipient, fee)
 	}
 
 	return &ExecutionResult{
-		UsedGas:    st.gasUsed(),
-		Err:        vmerr,
-		ReturnData: ret,
+		UsedGas:      st.gasUsed(),
+		Err:          vmerr,
+		ReturnData:   ret,
+		Fee:          fee,
+		FeeRecipient: feeRecipient,
 	}, nil
 }
 
@@ -0,0 +1,178 @@
+package txpool
+
+import (
+	"errors"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/metrics"
+)
+
+var (
+	// ErrExValidationUnavailable is returned in the strict mode if the extra
+	// validation of a transaction can not be evaluated.
+	ErrExValidationUnavailable = errors.New("extra transaction validation unavailable")
+)
+
+var (
+	exValidationFailureMeter = metrics.NewRegisteredMeter("txpool/exvalidation/failure", nil)  // Validator calls failed
+	exValidationSkipMeter    = metrics.NewRegisteredMeter("txpool/exvalidation/skip", nil)     // Txs admitted unchecked
+	exValidationDropMeter    = metrics.NewRegisteredMeter("txpool/exvalidation/drop", nil)     // Unchecked txs rejected later
+	exValidationDegraded     = metrics.NewRegisteredGauge("txpool/exvalidation/degraded", nil) // 1 while the validator is degraded
+)
+
+const (
+	exValidationMinBackoff = 100 * time.Millisecond // Delay before retrying a failed validator
+	exValidationMaxBackoff = 5 * time.Second        // Maximum delay between the retries on one head
+)
+
+type exTxValidator interface {
+	ValidateTx(sender common.Address, tx *types.Transaction, header *types.Header, parentState *state.StateDB) error
+}
+
+// exValidationResult is the outcome of the extra validation of a transaction.
+type exValidationResult int
+
+const (
+	exValidationPassed      exValidationResult = iota // The transaction is valid
+	exValidationRejected                              // The transaction is rejected by the validator
+	exValidationUnavailable                           // The validator could not evaluate the transaction
+)
+
+// exValidationState tracks the failures of the extra validator on the current
+// head. A degraded validator is retried with an exponential backoff, and the
+// transactions admitted meanwhile are checked again once it recovers.
+type exValidationState struct {
+	failures  int                                // Consecutive failures on the current head
+	retryAt   time.Time                          // Time before which the validator is not called
+	unchecked map[common.Hash]*types.Transaction // Transactions admitted without validation
+}
+
+func newExValidationState() *exValidationState {
+	return &exValidationState{unchecked: make(map[common.Hash]*types.Transaction)}
+}
+
+func (s *exValidationState) degraded() bool {
+	return s.failures > 0
+}
+
+// fail records a failure of the validator and schedules the next retry.
+func (s *exValidationState) fail(err error) {
+	exValidationFailureMeter.Mark(1)
+	if s.failures == 0 {
+		log.Warn("Extra transaction validation degraded", "err", err)
+		exValidationDegraded.Update(1)
+	}
+	backoff := exValidationMaxBackoff
+	if s.failures < 16 {
+		if b := exValidationMinBackoff << s.failures; b < backoff {
+			backoff = b
+		}
+	}
+	s.failures++
+	s.retryAt = time.Now().Add(backoff)
+}
+
+// recover clears the failures, as the validator works again or the head changed.
+func (s *exValidationState) recover() {
+	if s.failures > 0 {
+		log.Info("Extra transaction validation recovered", "failures", s.failures, "unchecked", len(s.unchecked))
+		exValidationDegraded.Update(0)
+	}
+	s.failures = 0
+	s.retryAt = time.Time{}
+}
+
+// exValidate runs the extra validation of a transaction, skipping the validator
+// while it backs off.
+func (pool *TxPool) exValidate(from common.Address, tx *types.Transaction) (exValidationResult, error) {
+	if pool.exState.degraded() && time.Now().Before(pool.exState.retryAt) {
+		return exValidationUnavailable, ErrExValidationUnavailable
+	}
+	err := pool.txValidator.ValidateTx(from, tx, pool.nextFakeHeader, pool.currentState)
+	switch {
+	case err == nil:
+		pool.exState.recover()
+		return exValidationPassed, nil
+	case errors.Is(err, types.ErrAddressBanned) || errors.Is(err, types.ErrUnauthorizedDeveloper) || errors.Is(err, types.ErrCallBanned):
+		pool.exState.recover()
+		return exValidationRejected, err
+	default:
+		pool.exState.fail(err)
+		return exValidationUnavailable, err
+	}
+}
+
+// validateTxEx applies the extra validation to a transaction entering the pool.
+// If the validation is unavailable, the transaction is rejected in the strict
+// mode, or admitted to the queue and checked again later otherwise.
+func (pool *TxPool) validateTxEx(from common.Address, tx *types.Transaction) error {
+	if pool.txValidator == nil {
+		return nil
+	}
+	result, err := pool.exValidate(from, tx)
+	switch result {
+	case exValidationRejected:
+		return err
+	case exValidationUnavailable:
+		if pool.config.StrictExValidation {
+			log.Trace("Rejecting unchecked transaction", "hash", tx.Hash(), "err", err)
+			return ErrExValidationUnavailable
+		}
+		exValidationSkipMeter.Mark(1)
+		pool.exState.unchecked[tx.Hash()] = tx
+	}
+	return nil
+}
+
+// holdUnchecked returns the index of the first transaction admitted unchecked
+// among the ready ones of an account. The transactions from it on are neither
+// promoted nor announced until the extra validation accepts them.
+func (pool *TxPool) holdUnchecked(readies types.Transactions) int {
+	if len(pool.exState.unchecked) == 0 {
+		return len(readies)
+	}
+	for i, tx := range readies {
+		if _, ok := pool.exState.unchecked[tx.Hash()]; ok {
+			return i
+		}
+	}
+	return len(readies)
+}
+
+// recheckUnchecked validates again the transactions admitted while the extra
+// validation was unavailable, drops the rejected ones and returns the accounts
+// whose accepted transactions can be promoted.
+func (pool *TxPool) recheckUnchecked() []common.Address {
+	if pool.txValidator == nil || len(pool.exState.unchecked) == 0 {
+		return nil
+	}
+	accepted := make(map[common.Address]struct{})
+	for hash, tx := range pool.exState.unchecked {
+		if pool.all.Get(hash) == nil {
+			delete(pool.exState.unchecked, hash)
+			continue
+		}
+		from, _ := types.Sender(pool.signer, tx) // already validated
+		result, err := pool.exValidate(from, tx)
+		if result == exValidationUnavailable {
+			break
+		}
+		delete(pool.exState.unchecked, hash)
+		if result == exValidationRejected {
+			log.Debug("Dropping rejected unchecked transaction", "hash", hash, "err", err)
+			exValidationDropMeter.Mark(1)
+			pool.removeTx(hash, true)
+			continue
+		}
+		accepted[from] = struct{}{}
+	}
+	addrs := make([]common.Address, 0, len(accepted))
+	for addr := range accepted {
+		addrs = append(addrs, addr)
+	}
+	return addrs
+}
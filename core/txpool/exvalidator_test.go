@@ -0,0 +1,191 @@
+package txpool
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/event"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+// testExValidator bans a set of senders, or fails while err is set.
+type testExValidator struct {
+	mu     sync.Mutex
+	banned map[common.Address]bool
+	err    error
+	calls  int
+}
+
+func (v *testExValidator) ValidateTx(sender common.Address, tx *types.Transaction, header *types.Header, parentState *state.StateDB) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.calls++
+	if v.err != nil {
+		return v.err
+	}
+	if v.banned[sender] {
+		return types.ErrAddressBanned
+	}
+	return nil
+}
+
+func (v *testExValidator) set(err error, banned ...common.Address) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.err = err
+	v.banned = make(map[common.Address]bool)
+	for _, addr := range banned {
+		v.banned[addr] = true
+	}
+}
+
+func (v *testExValidator) callCount() int {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.calls
+}
+
+// exTestBlockChain serves headers with a difficulty, as a consensus engine does.
+type exTestBlockChain struct {
+	*testBlockChain
+}
+
+func (bc *exTestBlockChain) CurrentBlock() *types.Header {
+	head := bc.testBlockChain.CurrentBlock()
+	head.Difficulty = big.NewInt(2)
+	return head
+}
+
+func setupExValidatorPool(strict bool) (*TxPool, *testExValidator) {
+	statedb, _ := state.New(types.EmptyRootHash, state.NewDatabase(rawdb.NewMemoryDatabase()), nil)
+	blockchain := &exTestBlockChain{newTestBlockChain(10000000, statedb, new(event.Feed))}
+
+	config := testTxPoolConfig
+	config.StrictExValidation = strict
+	pool := NewTxPool(config, params.TestChainConfig, blockchain)
+	<-pool.initDoneCh
+
+	validator := new(testExValidator)
+	pool.InitExTxValidator(validator)
+	return pool, validator
+}
+
+// Tests that a failing extra validator neither disables the validation nor lets
+// banned transactions stay in the pool once it recovers.
+func TestExValidationDegraded(t *testing.T) {
+	t.Parallel()
+
+	pool, validator := setupExValidatorPool(false)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	sender := crypto.PubkeyToAddress(key.PublicKey)
+	testAddBalance(pool, sender, big.NewInt(1000000000))
+
+	validator.set(nil, sender)
+	if err := pool.AddRemote(transaction(0, 100000, key)); !errors.Is(err, types.ErrAddressBanned) {
+		t.Fatalf("want %v have %v", types.ErrAddressBanned, err)
+	}
+	// a failing validator admits the transaction unchecked, and backs off
+	validator.set(errors.New("missing trie node"), sender)
+	if err := pool.AddRemote(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add unchecked transaction: %v", err)
+	}
+	calls := validator.callCount()
+	if err := pool.AddRemote(transaction(1, 100000, key)); err != nil {
+		t.Fatalf("failed to add unchecked transaction: %v", err)
+	}
+	if have := validator.callCount(); have != calls {
+		t.Fatalf("validator called during backoff: have %d calls, want %d", have, calls)
+	}
+	pool.mu.RLock()
+	degraded, unchecked := pool.exState.degraded(), len(pool.exState.unchecked)
+	pool.mu.RUnlock()
+	if !degraded || unchecked != 2 {
+		t.Fatalf("validation state mismatch: degraded %v, unchecked %d", degraded, unchecked)
+	}
+	// the validator recovers on the next head and drops the banned transactions
+	validator.set(nil, sender)
+	<-pool.requestReset(nil, nil)
+	if pending, queued := pool.Stats(); pending != 0 || queued != 0 {
+		t.Fatalf("banned transactions kept: pending %d, queued %d", pending, queued)
+	}
+	pool.mu.RLock()
+	degraded, unchecked = pool.exState.degraded(), len(pool.exState.unchecked)
+	pool.mu.RUnlock()
+	if degraded || unchecked != 0 {
+		t.Fatalf("validation state mismatch: degraded %v, unchecked %d", degraded, unchecked)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the transactions admitted while the extra validator fails are
+// neither pending nor announced until the validator accepts them.
+func TestExValidationUncheckedNotAnnounced(t *testing.T) {
+	t.Parallel()
+
+	pool, validator := setupExValidatorPool(false)
+	defer pool.Stop()
+
+	events := make(chan core.NewTxsEvent, 32)
+	sub := pool.SubscribeNewTxsEvent(events)
+	defer sub.Unsubscribe()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	validator.set(errors.New("missing trie node"))
+	if err := pool.addRemoteSync(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add unchecked transaction: %v", err)
+	}
+	if err := validateEvents(events, 0); err != nil {
+		t.Fatalf("unchecked transaction announced: %v", err)
+	}
+	if pending, queued := pool.Stats(); pending != 0 || queued != 1 {
+		t.Fatalf("unchecked transaction promoted: pending %d, queued %d", pending, queued)
+	}
+	// the validator recovers on the next head, and the transaction is announced
+	validator.set(nil)
+	<-pool.requestReset(nil, nil)
+	if err := validateEvents(events, 1); err != nil {
+		t.Fatalf("checked transaction not announced: %v", err)
+	}
+	if pending, queued := pool.Stats(); pending != 1 || queued != 0 {
+		t.Fatalf("checked transaction not promoted: pending %d, queued %d", pending, queued)
+	}
+	if err := validatePoolInternals(pool); err != nil {
+		t.Fatalf("pool internal state corrupted: %v", err)
+	}
+}
+
+// Tests that the strict mode rejects the transactions that can not be validated.
+func TestExValidationStrict(t *testing.T) {
+	t.Parallel()
+
+	pool, validator := setupExValidatorPool(true)
+	defer pool.Stop()
+
+	key, _ := crypto.GenerateKey()
+	testAddBalance(pool, crypto.PubkeyToAddress(key.PublicKey), big.NewInt(1000000000))
+
+	validator.set(errors.New("missing trie node"))
+	if err := pool.AddRemote(transaction(0, 100000, key)); !errors.Is(err, ErrExValidationUnavailable) {
+		t.Fatalf("want %v have %v", ErrExValidationUnavailable, err)
+	}
+	// a new head retries the validator at once
+	validator.set(nil)
+	<-pool.requestReset(nil, nil)
+	if err := pool.AddRemote(transaction(0, 100000, key)); err != nil {
+		t.Fatalf("failed to add validated transaction: %v", err)
+	}
+}
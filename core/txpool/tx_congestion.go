@@ -13,6 +13,15 @@ import (
 
 var (
 	congestionMeter = metrics.NewRegisteredMeter("txpool/congestion", nil)
+
+	congestionIndexGauge       = metrics.NewRegisteredGauge("txpool/congestion/index", nil)
+	congestionUnderPricedGauge = metrics.NewRegisteredGauge("txpool/congestion/underpriced", nil)
+	congestionPenaltyGauge     = metrics.NewRegisteredGauge("txpool/congestion/penalty", nil)
+	congestionSampledGauge     = metrics.NewRegisteredGauge("txpool/congestion/sampled", nil)
+
+	// congestionAgeHistogram tracks the ages in milliseconds of the pending txs
+	// sampled by the evaluations.
+	congestionAgeHistogram = metrics.NewRegisteredHistogram("txpool/congestion/pendingage", nil, metrics.NewExpDecaySample(1028, 0.015))
 )
 
 var oneGwei = big.NewInt(1e9)
@@ -23,6 +32,7 @@ var DefaultCongestionConfig = TxCongestionConfig{
 	UnderPricedFactor:   3,
 	PendingFactor:       1,
 	MaxValidPendingSecs: 300,
+	HistorySize:         1200,
 }
 
 type TxCongestionConfig struct {
@@ -32,6 +42,8 @@ type TxCongestionConfig struct {
 	PendingFactor     int
 
 	MaxValidPendingSecs int //
+
+	HistorySize int // how many evaluations to keep for the congestion history
 }
 
 func (c *TxCongestionConfig) sanity() TxCongestionConfig {
@@ -56,9 +68,71 @@ func (c *TxCongestionConfig) sanity() TxCongestionConfig {
 		log.Info("CongestionConfig sanity MaxValidPendingSecs", "old", cfg.MaxValidPendingSecs, "new", DefaultCongestionConfig.MaxValidPendingSecs)
 		cfg.MaxValidPendingSecs = DefaultCongestionConfig.MaxValidPendingSecs
 	}
+	if cfg.HistorySize < 1 {
+		log.Info("CongestionConfig sanity HistorySize", "old", cfg.HistorySize, "new", DefaultCongestionConfig.HistorySize)
+		cfg.HistorySize = DefaultCongestionConfig.HistorySize
+	}
 	return cfg
 }
 
+// CongestionSample is an evaluation of the congestion of the pool, with the
+// components the congestion index is made of.
+type CongestionSample struct {
+	Time           time.Time
+	Index          int
+	UnderPriced    int             // underpriced txs rejected in the last period
+	PendingPenalty int             // penalty of the pending txs older than the congestion secs
+	SampleSize     int             // number of pending txs sampled
+	Percentiles    []time.Duration // ages of the sampled txs at every 10th percentile, or all of them if no more than 10
+}
+
+// congestionHistory is a ring buffer of the last congestion samples.
+type congestionHistory struct {
+	samples []CongestionSample
+	next    int // index of the slot of the next sample
+	full    bool
+}
+
+func newCongestionHistory(size int) *congestionHistory {
+	return &congestionHistory{samples: make([]CongestionSample, size)}
+}
+
+func (h *congestionHistory) add(sample CongestionSample) {
+	h.samples[h.next] = sample
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// last returns the last sample added, the zero sample if there is none.
+func (h *congestionHistory) last() CongestionSample {
+	if !h.full && h.next == 0 {
+		return CongestionSample{}
+	}
+	return h.samples[(h.next+len(h.samples)-1)%len(h.samples)]
+}
+
+// between returns the samples taken in the time range [from, to], oldest first.
+func (h *congestionHistory) between(from, to time.Time) []CongestionSample {
+	var (
+		start = 0
+		count = h.next
+	)
+	if h.full {
+		start, count = h.next, len(h.samples)
+	}
+	var samples []CongestionSample
+	for i := 0; i < count; i++ {
+		sample := h.samples[(start+i)%len(h.samples)]
+		if sample.Time.Before(from) || sample.Time.After(to) {
+			continue
+		}
+		samples = append(samples, sample)
+	}
+	return samples
+}
+
 // TxCongestionRecorder try to give a quantitative index to reflects the tx congestion.
 type TxCongestionRecorder struct {
 	cfg  TxCongestionConfig
@@ -66,7 +140,7 @@ type TxCongestionRecorder struct {
 	head *types.Header
 
 	underPricedCounter *underPricedCounter
-	currentCongestion  int
+	history            *congestionHistory
 
 	congestionLock sync.RWMutex
 
@@ -81,6 +155,7 @@ func NewTxCongestionRecorder(cfg TxCongestionConfig, pool *TxPool) *TxCongestion
 		cfg:                cfg,
 		pool:               pool,
 		underPricedCounter: newUnderPricedCounter(cfg.PeriodsSecs),
+		history:            newCongestionHistory(cfg.HistorySize),
 		quit:               make(chan struct{}),
 		chainHeadCh:        make(chan *types.Header, 1),
 	}
@@ -100,7 +175,30 @@ func (recorder *TxCongestionRecorder) Stop() {
 func (recorder *TxCongestionRecorder) CongestionRecord() int {
 	recorder.congestionLock.RLock()
 	defer recorder.congestionLock.RUnlock()
-	return recorder.currentCongestion
+	return recorder.history.last().Index
+}
+
+// Status returns the last evaluation of the congestion.
+func (recorder *TxCongestionRecorder) Status() CongestionSample {
+	recorder.congestionLock.RLock()
+	defer recorder.congestionLock.RUnlock()
+	return recorder.history.last()
+}
+
+// History returns the evaluations of the congestion made in the time range
+// [from, to] and still kept, oldest first.
+func (recorder *TxCongestionRecorder) History(from, to time.Time) []CongestionSample {
+	recorder.congestionLock.RLock()
+	defer recorder.congestionLock.RUnlock()
+	return recorder.history.between(from, to)
+}
+
+// PendingAges returns a sorted sample of the ages of the pending txs at the last
+// evaluation, and the age from which a pending tx means a tx-congestion.
+func (recorder *TxCongestionRecorder) PendingAges() ([]time.Duration, time.Duration) {
+	recorder.congestionLock.RLock()
+	defer recorder.congestionLock.RUnlock()
+	return recorder.history.last().Percentiles, time.Duration(recorder.cfg.CongestionSecs) * time.Second
 }
 
 func (recorder *TxCongestionRecorder) updateLoop() {
@@ -115,6 +213,8 @@ func (recorder *TxCongestionRecorder) updateLoop() {
 			d := recorder.underPricedCounter.Sum()
 			pendings := recorder.pool.Pending(false)
 			if d == 0 && len(pendings) == 0 {
+				// nothing is congested
+				recorder.record(CongestionSample{Time: time.Now()})
 				break
 			}
 			// flatten
@@ -155,10 +255,6 @@ func (recorder *TxCongestionRecorder) updateLoop() {
 			}
 
 			idx := d*recorder.cfg.UnderPricedFactor + p*recorder.cfg.PendingFactor
-			recorder.congestionLock.Lock()
-			recorder.currentCongestion = idx
-			recorder.congestionLock.Unlock()
-			congestionMeter.Mark(int64(idx))
 
 			var dists []time.Duration
 			sort.Slice(durs, func(i, j int) bool {
@@ -173,7 +269,17 @@ func (recorder *TxCongestionRecorder) updateLoop() {
 			} else {
 				dists = durs
 			}
-
+			for _, dur := range durs {
+				congestionAgeHistogram.Update(dur.Milliseconds())
+			}
+			recorder.record(CongestionSample{
+				Time:           time.Now(),
+				Index:          idx,
+				UnderPriced:    d,
+				PendingPenalty: p,
+				SampleSize:     nTotal,
+				Percentiles:    dists,
+			})
 			log.Trace("TxCongestion", "congestion", idx, "d", d, "p", p, "n", nTotal, "dists", dists)
 		case <-recorder.quit:
 			return
@@ -181,6 +287,19 @@ func (recorder *TxCongestionRecorder) updateLoop() {
 	}
 }
 
+// record adds an evaluation of the congestion to the history and the metrics.
+func (recorder *TxCongestionRecorder) record(sample CongestionSample) {
+	recorder.congestionLock.Lock()
+	recorder.history.add(sample)
+	recorder.congestionLock.Unlock()
+
+	congestionMeter.Mark(int64(sample.Index))
+	congestionIndexGauge.Update(int64(sample.Index))
+	congestionUnderPricedGauge.Update(int64(sample.UnderPriced))
+	congestionPenaltyGauge.Update(int64(sample.PendingPenalty))
+	congestionSampledGauge.Update(int64(sample.SampleSize))
+}
+
 func (recorder *TxCongestionRecorder) UpdateHeader(h *types.Header) {
 	recorder.chainHeadCh <- h
 }
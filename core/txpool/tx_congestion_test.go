@@ -0,0 +1,46 @@
+package txpool
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCongestionHistory(t *testing.T) {
+	var (
+		history = newCongestionHistory(3)
+		start   = time.Unix(1000, 0)
+	)
+	if last := history.last(); !last.Time.IsZero() {
+		t.Fatalf("last sample of empty history: have %v, want zero", last)
+	}
+	if samples := history.between(time.Time{}, start.Add(time.Hour)); len(samples) != 0 {
+		t.Fatalf("samples of empty history: have %d, want 0", len(samples))
+	}
+	for i := 0; i < 5; i++ {
+		history.add(CongestionSample{Time: start.Add(time.Duration(i) * time.Second), Index: i})
+		if last := history.last(); last.Index != i {
+			t.Fatalf("last sample after %d: have %d", i, last.Index)
+		}
+	}
+	tests := []struct {
+		from, to time.Duration
+		want     []int
+	}{
+		{0, 10 * time.Second, []int{2, 3, 4}},
+		{3 * time.Second, 3 * time.Second, []int{3}},
+		{0, 2 * time.Second, []int{2}},
+		{0, time.Second, nil},
+		{5 * time.Second, 10 * time.Second, nil},
+	}
+	for i, tt := range tests {
+		samples := history.between(start.Add(tt.from), start.Add(tt.to))
+		if len(samples) != len(tt.want) {
+			t.Fatalf("test %d: samples: have %d, want %d", i, len(samples), len(tt.want))
+		}
+		for j, sample := range samples {
+			if sample.Index != tt.want[j] {
+				t.Errorf("test %d: sample %d: have index %d, want %d", i, j, sample.Index, tt.want[j])
+			}
+		}
+	}
+}
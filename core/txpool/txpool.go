@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/metrics"
@@ -160,10 +161,6 @@ type blockChain interface {
 	SubscribeChainHeadEvent(ch chan<- core.ChainHeadEvent) event.Subscription
 }
 
-type exTxValidator interface {
-	ValidateTx(sender common.Address, tx *types.Transaction, header *types.Header, parentState *state.StateDB) error
-}
-
 // Config are the configuration parameters of the transaction pool.
 type Config struct {
 	Locals    []common.Address // Addresses that should be treated by default as local
@@ -181,6 +178,8 @@ type Config struct {
 
 	Lifetime time.Duration // Maximum amount of time non-executable transaction are queued
 
+	StrictExValidation bool // Reject transactions while the extra validation is unavailable
+
 	CongestionConfig TxCongestionConfig
 }
 
@@ -264,6 +263,8 @@ type TxPool struct {
 	eip1559  atomic.Bool // Fork indicator whether we are using EIP-1559 type transactions.
 	shanghai atomic.Bool // Fork indicator whether we are in the Shanghai stage.
 
+	feeRecoderProtection atomic.Bool // Fork indicator whether the value transfers to the NPoS FeeRecoder are rejected.
+
 	currentState  *state.StateDB // Current state in the blockchain head
 	pendingNonces *noncer        // Pending state tracking virtual nonces
 	currentMaxGas atomic.Uint64  // Current gas limit for transaction caps
@@ -279,11 +280,11 @@ type TxPool struct {
 
 	txValidator    exTxValidator // A specific consensus can use this to do some extra validation to a transaction
 	nextFakeHeader *types.Header // A fake header of next block for extra transaction validation
-	// disableExValidate will disable the extra tx validation during a period if it's true,
+	// exState tracks the failures of the extra validation on the current head,
 	// there's a special case we need this:
 	// during a large chain insertion, the ChainHeadEvent will not be fired in time, then some old trie-nodes
 	// will be discarded due to GC, and it will cause failure to get blacklist.
-	disableExValidate  bool
+	exState            *exValidationState
 	congestionRecorder *TxCongestionRecorder // tx congestion indexer
 
 	chainHeadCh     chan core.ChainHeadEvent
@@ -327,6 +328,7 @@ func NewTxPool(config Config, chainconfig *params.ChainConfig, chain blockChain)
 		reorgShutdownCh: make(chan struct{}),
 		initDoneCh:      make(chan struct{}),
 		gasPrice:        new(big.Int).SetUint64(config.PriceLimit),
+		exState:         newExValidationState(),
 	}
 	pool.Init()
 	return pool
@@ -612,6 +614,23 @@ func (pool *TxPool) CongestionRecord() int {
 	return pool.congestionRecorder.CongestionRecord()
 }
 
+// CongestionPendingAges returns a sorted sample of the ages of the pending transactions
+// at the last congestion evaluation, and the age from which a transaction is congested.
+func (pool *TxPool) CongestionPendingAges() ([]time.Duration, time.Duration) {
+	return pool.congestionRecorder.PendingAges()
+}
+
+// CongestionStatus returns the last evaluation of the congestion of the pool.
+func (pool *TxPool) CongestionStatus() CongestionSample {
+	return pool.congestionRecorder.Status()
+}
+
+// CongestionHistory returns the evaluations of the congestion of the pool made
+// in the time range [from, to] and still kept, oldest first.
+func (pool *TxPool) CongestionHistory(from, to time.Time) []CongestionSample {
+	return pool.congestionRecorder.History(from, to)
+}
+
 // local retrieves all currently known local transactions, grouped by origin
 // account and sorted by nonce. The returned transaction set is a copy and can be
 // freely modified by calling code.
@@ -654,6 +673,10 @@ func (pool *TxPool) validateTxBasics(tx *types.Transaction, local bool) error {
 	if tx.Value().Sign() < 0 {
 		return ErrNegativeValue
 	}
+	// The fees collected by the FeeRecoder can't be mixed with transfers.
+	if pool.feeRecoderProtection.Load() && tx.Value().Sign() != 0 && tx.To() != nil && *tx.To() == params.NposFeeRecoder {
+		return vm.ErrFeeRecoderTransfer
+	}
 	// Ensure the transaction doesn't exceed the current block limit gas.
 	if pool.currentMaxGas.Load() < tx.Gas() {
 		return ErrGasLimit
@@ -718,17 +741,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		}
 	}
 	// do some extra validation if needed
-	if pool.txValidator != nil && !pool.disableExValidate {
-		err := pool.txValidator.ValidateTx(from, tx, pool.nextFakeHeader, pool.currentState)
-		if err == types.ErrAddressBanned {
-			return err
-		}
-		if err != nil {
-			log.Info("ValidateTx error", "err", err)
-			pool.disableExValidate = true
-		}
-	}
-	return nil
+	return pool.validateTxEx(from, tx)
 }
 
 // add validates a transaction and inserts it into the non-executable queue for later
@@ -768,6 +781,11 @@ func (pool *TxPool) add(tx *types.Transaction, local bool) (replaced bool, err e
 	}
 	// Try to replace an existing transaction in the pending pool
 	if list := pool.pending[from]; list != nil && list.Contains(tx.Nonce()) {
+		// An unchecked transaction can't wait in the queue behind a pending nonce
+		if _, ok := pool.exState.unchecked[hash]; ok {
+			delete(pool.exState.unchecked, hash)
+			return false, ErrExValidationUnavailable
+		}
 		return pool.replacePending(list, from, tx, isLocal)
 	}
 	// New transaction isn't replacing a pending one, push into queue
@@ -1318,6 +1336,14 @@ func (pool *TxPool) runReorg(done chan struct{}, reset *txpoolResetRequest, dirt
 			promoteAddrs = append(promoteAddrs, addr)
 		}
 	}
+	// Drop the transactions admitted unchecked that the validator now rejects,
+	// and promote the accepted ones
+	for _, addr := range pool.recheckUnchecked() {
+		if reset == nil && (dirtyAccounts == nil || !dirtyAccounts.contains(addr)) {
+			promoteAddrs = append(promoteAddrs, addr)
+		}
+	}
+
 	// Check for pending transactions for every account that sent new ones
 	promoted := pool.promoteExecutables(promoteAddrs)
 
@@ -1444,7 +1470,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	// Update fake next header if necessary
 	if pool.txValidator != nil {
 		pool.makeFakeHeader(newHead)
-		pool.disableExValidate = false
+		pool.exState.recover()
 	}
 
 	// Inject any transactions discarded due to reorgs
@@ -1458,6 +1484,7 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.eip2718.Store(pool.chainconfig.IsBerlin(next))
 	pool.eip1559.Store(pool.chainconfig.IsLondon(next))
 	pool.shanghai.Store(pool.chainconfig.IsShanghai(uint64(time.Now().Unix())))
+	pool.feeRecoderProtection.Store(pool.chainconfig.Npos != nil && pool.chainconfig.Npos.IsFeeRecoderProtection(next))
 }
 
 func (pool *TxPool) makeFakeHeader(currHead *types.Header) {
@@ -1502,6 +1529,12 @@ func (pool *TxPool) promoteExecutables(accounts []common.Address) []*types.Trans
 
 		// Gather all executable transactions and promote them
 		readies := list.Ready(pool.pendingNonces.get(addr))
+		if held := pool.holdUnchecked(readies); held < len(readies) {
+			for _, tx := range readies[held:] {
+				list.Add(tx, pool.config.PriceBump)
+			}
+			readies = readies[:held]
+		}
 		for _, tx := range readies {
 			hash := tx.Hash()
 			if pool.promoteTx(addr, hash, tx) {
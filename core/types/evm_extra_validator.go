@@ -9,4 +9,13 @@ type EvmExtraValidator interface {
 	IsAddressBanned(address common.Address, cType common.AddressCheckType) bool
 	// IsAddressBannedFromLog returns whether a log (contract event) is banned.
 	IsAddressBannedFromLog(log *Log) bool
+	// CanCreate returns whether an address is allowed to create contracts, it's
+	// checked against the account running CREATE or CREATE2, not the origin.
+	CanCreate(address common.Address) bool
+	// IsCallBanned returns the type of the rule banning a call of the code at an
+	// address with the given code hash and input, or CheckNone if it's allowed.
+	IsCallBanned(address common.Address, codeHash common.Hash, input []byte) common.AddressCheckType
+	// HasCallRules returns whether any call may be banned, so that the callers
+	// skip the code hash lookups of IsCallBanned otherwise.
+	HasCallRules() bool
 }
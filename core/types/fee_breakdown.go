@@ -0,0 +1,14 @@
+package types
+
+import "math/big"
+
+// FeeBreakdown is the accounting of the fees paid in a block, for the consensus
+// engines distributing them as the block reward. It's stored with the blocks
+// the node executed.
+type FeeBreakdown struct {
+	BaseFeeBurned *big.Int // base fees burned
+	Reward        *big.Int // fees collected as the block reward
+	Burned        *big.Int // share of the reward burned
+	Foundation    *big.Int // share of the reward credited to the foundation
+	Distributed   *big.Int // share of the reward distributed to the validators
+}
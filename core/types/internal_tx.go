@@ -14,6 +14,11 @@ func (d Data) MarshalText() ([]byte, error) {
 	return hexutil.Bytes(d[:]).MarshalText()
 }
 
+// UnmarshalText parses a hex string with 0x prefix.
+func (d *Data) UnmarshalText(input []byte) error {
+	return (*hexutil.Bytes)(d).UnmarshalText(input)
+}
+
 type Action struct {
 	From         common.Address `gencodec:"required" json:"from"`
 	To           common.Address `gencodec:"optional" json:"to,omitempty"`
@@ -29,6 +34,31 @@ type Action struct {
 	Error        string         `gencodec:"optional" json:"error,omitempty"`
 }
 
+const (
+	// TopActionDepth is the depth of the top-level call of a transaction, which
+	// is the transaction itself.
+	TopActionDepth = ^uint64(0)
+
+	// EngineActionDepth is the depth of the actions made by the consensus engine
+	// rather than by a transaction: the top-level calls it runs on the evm, and
+	// the state changes it applies off the evm. The ones made out of any
+	// transaction belong to internal txs with an empty tx hash.
+	EngineActionDepth = TopActionDepth - 1
+)
+
+// The opcodes of the actions made by the consensus engine off the evm.
+const (
+	// FeeSweepOpCode moves the fees collected in a block out of the fee recorder.
+	FeeSweepOpCode = "FEESWEEP"
+	// SetBalanceOpCode moves the difference of a balance set by governance from
+	// or to the payer, which is the zero address if the difference is minted or
+	// burnt.
+	SetBalanceOpCode = "SETBALANCE"
+	// EraseOpCode erases the code and the storage of an account, its balance is
+	// kept.
+	EraseOpCode = "ERASE"
+)
+
 type ActionFrame struct {
 	Action
 	Calls []ActionFrame
@@ -39,6 +69,10 @@ type InternalTx struct {
 	BlockHash   common.Hash `json:"blockHash,omitempty"`
 	BlockNumber *big.Int    `json:"blockNumber,omitempty"`
 	Actions     []*Action   `json:"logs" gencodec:"required"`
+
+	// Removed is true if the block of the internal tx was dropped from the
+	// canonical chain, as announced by subscriptions. It is not stored.
+	Removed bool `json:"removed,omitempty" rlp:"-"`
 }
 
 type InternalTxForStorage InternalTx
@@ -65,6 +65,11 @@ type Receipt struct {
 	GasUsed           uint64         `json:"gasUsed" gencodec:"required"`
 	EffectiveGasPrice *big.Int       `json:"effectiveGasPrice"` // required, but tag omitted for backwards compatibility
 
+	// Fee attribution fields: On NPoS the fees are collected by the FeeRecoder for
+	// the block reward. They are derived rather than stored.
+	Fee          *big.Int        `json:"fee,omitempty"`
+	FeeRecipient *common.Address `json:"feeRecipient,omitempty"`
+
 	// Inclusion information: These fields provide information about the inclusion of the
 	// transaction corresponding to this receipt.
 	BlockHash        common.Hash `json:"blockHash,omitempty"`
@@ -79,6 +84,7 @@ type receiptMarshaling struct {
 	CumulativeGasUsed hexutil.Uint64
 	GasUsed           hexutil.Uint64
 	EffectiveGasPrice *hexutil.Big
+	Fee               *hexutil.Big
 	BlockNumber       *hexutil.Big
 	TransactionIndex  hexutil.Uint
 }
@@ -319,6 +325,35 @@ func (rs Receipts) EncodeIndex(i int, w *bytes.Buffer) {
 	}
 }
 
+// nposSysTxs returns the number of system transactions of the NPoS engine in the
+// block with the given receipts, which pay no fee to the FeeRecoder. The engine
+// appends them after all the other transactions, and they use no gas, unlike any
+// transaction paying its intrinsic gas.
+func nposSysTxs(rs Receipts, txs []*Transaction) int {
+	n := 0
+	for i := len(rs) - 1; i >= 0 && rs[i].GasUsed == 0; i-- {
+		to := txs[i].To()
+		if to == nil || (*to != params.NposSysGovToAddr && *to != params.NposDoubleSignEvidenceToAddr && *to != params.NposAttestationEvidenceToAddr) {
+			break
+		}
+		n++
+	}
+	return n
+}
+
+// nposFee returns the fee of the receipt collected by the FeeRecoder on NPoS,
+// only the tip once the base fee is burned.
+func nposFee(config *params.ChainConfig, number uint64, baseFee *big.Int, r *Receipt) *big.Int {
+	price := new(big.Int).Set(r.EffectiveGasPrice)
+	if baseFee != nil && config.Npos.IsFeeBurn(new(big.Int).SetUint64(number)) {
+		price.Sub(price, baseFee)
+	}
+	if price.Sign() <= 0 {
+		return new(big.Int)
+	}
+	return price.Mul(price, new(big.Int).SetUint64(r.GasUsed))
+}
+
 // DeriveFields fills the receipts with their computed fields based on consensus
 // data and contextual infos like containing block and transactions.
 func (rs Receipts) DeriveFields(config *params.ChainConfig, hash common.Hash, number uint64, baseFee *big.Int, txs []*Transaction) error {
@@ -367,5 +402,11 @@ func (rs Receipts) DeriveFields(config *params.ChainConfig, hash common.Hash, nu
 			logIndex++
 		}
 	}
+	if config.Npos != nil {
+		recipient := params.NposFeeRecoder
+		for i, n := 0, len(rs)-nposSysTxs(rs, txs); i < n; i++ {
+			rs[i].Fee, rs[i].FeeRecipient = nposFee(config, number, baseFee, rs[i]), &recipient
+		}
+	}
 	return nil
 }
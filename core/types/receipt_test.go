@@ -276,6 +276,36 @@ func TestDeriveFields(t *testing.T) {
 	}
 }
 
+// Tests that only the system transactions appended by the NPoS engine, which use
+// no gas, are derived without a fee, whatever their recipient and gas price.
+func TestDeriveFieldsNposSysTxs(t *testing.T) {
+	config := *params.TestChainConfig
+	config.Npos = &params.NposConfig{}
+	sysGov := params.NposSysGovToAddr
+	evidence := params.NposDoubleSignEvidenceToAddr
+	txs := Transactions{
+		NewTx(&LegacyTx{To: &sysGov, Gas: 50000, GasPrice: new(big.Int)}),
+		NewTx(&LegacyTx{Nonce: 1, To: &sysGov, GasPrice: new(big.Int)}),
+		NewTx(&LegacyTx{Nonce: 2, To: &evidence, GasPrice: new(big.Int)}),
+	}
+	rs := Receipts{
+		{CumulativeGasUsed: 21000},
+		{CumulativeGasUsed: 21000},
+		{CumulativeGasUsed: 21000},
+	}
+	if err := rs.DeriveFields(&config, blockHash, blockNumber.Uint64(), nil, txs); err != nil {
+		t.Fatalf("DeriveFields(...) = %v, want <nil>", err)
+	}
+	if rs[0].FeeRecipient == nil || *rs[0].FeeRecipient != params.NposFeeRecoder || rs[0].Fee == nil {
+		t.Errorf("transaction 0: have fee %v to %v, want a fee to the fee recoder", rs[0].Fee, rs[0].FeeRecipient)
+	}
+	for i := 1; i < len(rs); i++ {
+		if rs[i].Fee != nil || rs[i].FeeRecipient != nil {
+			t.Errorf("system transaction %d: have fee %v to %v, want none", i, rs[i].Fee, rs[i].FeeRecipient)
+		}
+	}
+}
+
 // Test that we can marshal/unmarshal receipts to/from json without errors.
 // This also confirms that our test receipts contain all the required fields.
 func TestReceiptJSON(t *testing.T) {
@@ -33,13 +33,15 @@ import (
 )
 
 var (
-	ErrInvalidSig           = errors.New("invalid transaction v, r, s values")
-	ErrUnexpectedProtection = errors.New("transaction type does not supported EIP-155 protected signatures")
-	ErrInvalidTxType        = errors.New("transaction type not valid in this context")
-	ErrTxTypeNotSupported   = errors.New("transaction type not supported")
-	ErrGasFeeCapTooLow      = errors.New("fee cap less than base fee")
-	errShortTypedTx         = errors.New("typed transaction too short")
-	ErrAddressBanned        = errors.New("address banned")
+	ErrInvalidSig            = errors.New("invalid transaction v, r, s values")
+	ErrUnexpectedProtection  = errors.New("transaction type does not supported EIP-155 protected signatures")
+	ErrInvalidTxType         = errors.New("transaction type not valid in this context")
+	ErrTxTypeNotSupported    = errors.New("transaction type not supported")
+	ErrGasFeeCapTooLow       = errors.New("fee cap less than base fee")
+	errShortTypedTx          = errors.New("typed transaction too short")
+	ErrAddressBanned         = errors.New("address banned")
+	ErrUnauthorizedDeveloper = errors.New("unauthorized developer")
+	ErrCallBanned            = errors.New("call banned")
 )
 
 // Transaction types.
@@ -169,6 +169,15 @@ func (evm *EVM) SetBlockContext(blockCtx BlockContext) {
 	evm.chainRules = evm.chainConfig.Rules(num, blockCtx.Random != nil, timestamp)
 }
 
+// isCallBanned returns whether the call of the code at addr with the input is
+// denied by the extra validator, if any.
+func (evm *EVM) isCallBanned(addr common.Address, input []byte) bool {
+	if evm.Context.ExtraValidator == nil || !evm.Context.ExtraValidator.HasCallRules() {
+		return false
+	}
+	return evm.Context.ExtraValidator.IsCallBanned(addr, evm.StateDB.GetCodeHash(addr), input) != common.CheckNone
+}
+
 // Call executes the contract associated with the addr with the given input as
 // parameters. It also handles any necessary value transfer required and takes
 // the necessary steps to create accounts and reverses the state in case of an
@@ -186,6 +195,13 @@ func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas
 			return nil, gas, types.ErrAddressBanned
 		}
 	}
+	if evm.isCallBanned(addr, input) {
+		return nil, gas, types.ErrCallBanned
+	}
+	// The fees collected by the FeeRecoder can't be mixed with transfers
+	if value.Sign() != 0 && addr == params.NposFeeRecoder && evm.chainRules.IsFeeRecoderProtection {
+		return nil, gas, ErrFeeRecoderTransfer
+	}
 
 	// Fail if we're trying to transfer more than the available balance
 	if value.Sign() != 0 && !evm.Context.CanTransfer(evm.StateDB, caller.Address(), value) {
@@ -282,6 +298,9 @@ func (evm *EVM) CallCode(caller ContractRef, addr common.Address, input []byte,
 			return nil, gas, types.ErrAddressBanned
 		}
 	}
+	if evm.isCallBanned(addr, input) {
+		return nil, gas, types.ErrCallBanned
+	}
 
 	// Fail if we're trying to transfer more than the available balance
 	// Note although it's noop to transfer X ether to caller itself. But
@@ -339,6 +358,9 @@ func (evm *EVM) DelegateCall(caller ContractRef, addr common.Address, input []by
 			return nil, gas, types.ErrAddressBanned
 		}
 	}
+	if evm.isCallBanned(addr, input) {
+		return nil, gas, types.ErrCallBanned
+	}
 
 	var snapshot = evm.StateDB.Snapshot()
 
@@ -391,6 +413,9 @@ func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte
 			return nil, gas, types.ErrAddressBanned
 		}
 	}
+	if evm.isCallBanned(addr, input) {
+		return nil, gas, types.ErrCallBanned
+	}
 
 	// We take a snapshot here. This is a bit counter-intuitive, and could probably be skipped.
 	// However, even a staticcall is considered a 'touch'. On mainnet, static calls were introduced
@@ -477,6 +502,13 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 	if evm.StateDB.GetNonce(address) != 0 || (contractHash != (common.Hash{}) && contractHash != types.EmptyCodeHash) {
 		return nil, common.Address{}, 0, ErrContractAddressCollision
 	}
+	// Check whether the creator is allowed to deploy contracts if needed. It's the
+	// immediate caller rather than the origin: a factory contract must be allowed
+	// itself, and then creates contracts in the transactions of anyone, while a
+	// developer can't deploy through the factories that aren't.
+	if evm.Context.ExtraValidator != nil && !evm.Context.ExtraValidator.CanCreate(caller.Address()) {
+		return nil, common.Address{}, gas, types.ErrUnauthorizedDeveloper
+	}
 	// Create a new account on the state
 	snapshot := evm.StateDB.Snapshot()
 	evm.StateDB.CreateAccount(address)
@@ -522,6 +554,10 @@ func (evm *EVM) create(caller ContractRef, codeAndHash *codeAndHash, gas uint64,
 			err = ErrCodeStoreOutOfGas
 		}
 	}
+	// Reject the code banned by its hash if needed, its deployment is reverted below
+	if err == nil && evm.isCallBanned(address, nil) {
+		err = types.ErrCallBanned
+	}
 
 	// When an error was returned by the EVM or when setting the creation code
 	// above we revert to the snapshot and consume any gas remaining. Additionally
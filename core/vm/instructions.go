@@ -819,6 +819,11 @@ func opSelfdestruct(pc *uint64, interpreter *EVMInterpreter, scope *ScopeContext
 	}
 	beneficiary := scope.Stack.pop()
 	balance := interpreter.evm.StateDB.GetBalance(scope.Contract.Address())
+	// The credit of the FeeRecoder is refused like the calls transferring value
+	// to it, the frame fails and the contract keeps its balance.
+	if balance.Sign() != 0 && beneficiary.Bytes20() == params.NposFeeRecoder && interpreter.evm.chainRules.IsFeeRecoderProtection {
+		return nil, ErrFeeRecoderTransfer
+	}
 	interpreter.evm.StateDB.AddBalance(beneficiary.Bytes20(), balance)
 	interpreter.evm.StateDB.Suicide(scope.Contract.Address())
 	if tracer := interpreter.evm.Config.Tracer; tracer != nil {
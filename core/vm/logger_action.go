@@ -40,7 +40,7 @@ func (t *ActionLogger) CaptureStart(env *EVM, from common.Address, to common.Add
 		From:         from,
 		To:           to,
 		Value:        value,
-		Depth:        ^uint64(0),
+		Depth:        types.TopActionDepth,
 		Gas:          gas,
 		TraceAddress: nil,
 	}
@@ -271,6 +271,10 @@ func (b *EthAPIBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.
 	return b.eth.miner.SubscribePendingLogs(ch)
 }
 
+func (b *EthAPIBackend) SubscribeInternalTxsEvent(ch chan<- core.InternalTxsEvent) event.Subscription {
+	return b.eth.BlockChain().SubscribeInternalTxsEvent(ch)
+}
+
 func (b *EthAPIBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subscription {
 	return b.eth.BlockChain().SubscribeChainEvent(ch)
 }
@@ -329,6 +333,14 @@ func (b *EthAPIBackend) CongestionRecord() int {
 	return b.eth.TxPool().CongestionRecord()
 }
 
+func (b *EthAPIBackend) CongestionStatus() txpool.CongestionSample {
+	return b.eth.TxPool().CongestionStatus()
+}
+
+func (b *EthAPIBackend) CongestionHistory(from, to time.Time) []txpool.CongestionSample {
+	return b.eth.TxPool().CongestionHistory(from, to)
+}
+
 func (b *EthAPIBackend) TxPool() *txpool.TxPool {
 	return b.eth.TxPool()
 }
@@ -345,6 +357,20 @@ func (b *EthAPIBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error)
 	return b.gpo.SuggestTipCap(ctx)
 }
 
+func (b *EthAPIBackend) SuggestGasTipCaps(ctx context.Context) (*big.Int, *big.Int, *big.Int, error) {
+	return b.gpo.SuggestTipCaps(ctx)
+}
+
+// TxPoolCongestion implements gasprice.CongestionBackend.
+func (b *EthAPIBackend) TxPoolCongestion() gasprice.Congestion {
+	ages, threshold := b.eth.TxPool().CongestionPendingAges()
+	return gasprice.Congestion{
+		Index:       b.eth.TxPool().CongestionRecord(),
+		PendingAges: ages,
+		Threshold:   threshold,
+	}
+}
+
 func (b *EthAPIBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (firstBlock *big.Int, reward [][]*big.Int, baseFee []*big.Int, gasUsedRatio []float64, err error) {
 	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
 }
@@ -386,6 +412,14 @@ func (b *EthAPIBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, sections
 }
 
+func (b *EthAPIBackend) InternalTxIndexStatus() (uint64, uint64) {
+	if b.eth.internalTxIndexer == nil {
+		return params.InternalTxIndexBlocks, 0
+	}
+	sections, _, _ := b.eth.internalTxIndexer.Sections()
+	return params.InternalTxIndexBlocks, sections
+}
+
 func (b *EthAPIBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
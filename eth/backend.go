@@ -90,6 +90,8 @@ type Ethereum struct {
 	bloomIndexer      *core.ChainIndexer             // Bloom indexer operating during block imports
 	closeBloomHandler chan struct{}
 
+	internalTxIndexer *core.ChainIndexer // Internal tx address indexer, nil if disabled
+
 	APIBackend *EthAPIBackend
 
 	miner     *miner.Miner
@@ -218,6 +220,7 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 			TrieTimeLimit:       config.TrieTimeout,
 			SnapshotLimit:       config.SnapshotCache,
 			Preimages:           config.Preimages,
+			InternalTxHistory:   config.InternalTxHistory,
 		}
 	)
 
@@ -233,6 +236,14 @@ func New(stack *node.Node, config *ethconfig.Config) (*Ethereum, error) {
 		rawdb.WriteChainConfig(chainDb, genesisHash, chainConfig)
 	}
 	eth.bloomIndexer.Start(eth.blockchain)
+	if config.InternalTxIndex {
+		if config.InternalTxTraceDisabled {
+			log.Warn("Internal tx address index requires the internal txs trace, disabling it")
+		} else {
+			eth.internalTxIndexer = core.NewInternalTxIndexer(chainDb, params.InternalTxIndexBlocks, params.InternalTxIndexConfirms)
+			eth.internalTxIndexer.Start(eth.blockchain)
+		}
+	}
 
 	if config.TxPool.Journal != "" {
 		config.TxPool.Journal = stack.ResolvePath(config.TxPool.Journal)
@@ -558,6 +569,9 @@ func (s *Ethereum) Stop() error {
 	// Then stop everything else.
 	s.bloomIndexer.Close()
 	close(s.closeBloomHandler)
+	if s.internalTxIndexer != nil {
+		s.internalTxIndexer.Close()
+	}
 	s.txPool.Stop()
 	s.miner.Close()
 	s.blockchain.Stop()
@@ -43,12 +43,13 @@ import (
 
 // FullNodeGPO contains default gasprice oracle settings for full node.
 var FullNodeGPO = gasprice.Config{
-	Blocks:           20,
-	Percentile:       60,
-	MaxHeaderHistory: 1024,
-	MaxBlockHistory:  1024,
-	MaxPrice:         gasprice.DefaultMaxPrice,
-	IgnorePrice:      gasprice.DefaultIgnorePrice,
+	Blocks:               20,
+	Percentile:           60,
+	MaxHeaderHistory:     1024,
+	MaxBlockHistory:      1024,
+	MaxPrice:             gasprice.DefaultMaxPrice,
+	IgnorePrice:          gasprice.DefaultIgnorePrice,
+	MaxCongestionPremium: gasprice.DefaultMaxCongestionPremium,
 }
 
 // LightClientGPO contains default gasprice oracle settings for light client.
@@ -203,6 +204,10 @@ type Config struct {
 	InternalTxTraceDisabled bool `toml:",omitempty"`
 	// Trace and save all internal txs action, with input and output data. By default the node will only trace and save those with value greater then 0.
 	InternalTxTraceAll bool `toml:",omitempty"`
+	// Index the internal value transfers by address, to look them up by eth_getInternalTransactionsByAddress.
+	InternalTxIndex bool `toml:",omitempty"`
+	// Number of recent blocks to keep the internal txs of, 0 keeps them for all blocks.
+	InternalTxHistory uint64 `toml:",omitempty"`
 	// OverrideCancun (TODO: remove after the fork)
 	OverrideCancun *uint64 `toml:",omitempty"`
 }
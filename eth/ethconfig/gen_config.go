@@ -59,6 +59,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 		OverrideCancun          *uint64 `toml:",omitempty"`
 		InternalTxTraceDisabled bool    `toml:",omitempty"`
 		InternalTxTraceAll      bool    `toml:",omitempty"`
+		InternalTxIndex         bool    `toml:",omitempty"`
+		InternalTxHistory       uint64  `toml:",omitempty"`
 	}
 	var enc Config
 	enc.Genesis = c.Genesis
@@ -102,6 +104,8 @@ func (c Config) MarshalTOML() (interface{}, error) {
 	enc.RPCTxFeeCap = c.RPCTxFeeCap
 	enc.InternalTxTraceDisabled = c.InternalTxTraceDisabled
 	enc.InternalTxTraceAll = c.InternalTxTraceAll
+	enc.InternalTxIndex = c.InternalTxIndex
+	enc.InternalTxHistory = c.InternalTxHistory
 	enc.OverrideCancun = c.OverrideCancun
 	return &enc, nil
 }
@@ -151,6 +155,8 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 		OverrideCancun          *uint64 `toml:",omitempty"`
 		InternalTxTraceDisabled *bool   `toml:",omitempty"`
 		InternalTxTraceAll      *bool   `toml:",omitempty"`
+		InternalTxIndex         *bool   `toml:",omitempty"`
+		InternalTxHistory       *uint64 `toml:",omitempty"`
 	}
 	var dec Config
 	if err := unmarshal(&dec); err != nil {
@@ -282,5 +288,11 @@ func (c *Config) UnmarshalTOML(unmarshal func(interface{}) error) error {
 	if dec.InternalTxTraceAll != nil {
 		c.InternalTxTraceAll = *dec.InternalTxTraceAll
 	}
+	if dec.InternalTxIndex != nil {
+		c.InternalTxIndex = *dec.InternalTxIndex
+	}
+	if dec.InternalTxHistory != nil {
+		c.InternalTxHistory = *dec.InternalTxHistory
+	}
 	return nil
 }
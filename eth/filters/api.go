@@ -29,6 +29,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
 	"github.com/ethereum/go-ethereum/rpc"
 )
@@ -290,6 +291,40 @@ func (api *FilterAPI) Logs(ctx context.Context, crit FilterCriteria) (*rpc.Subsc
 	return rpcSub, nil
 }
 
+// InternalTransactions creates a subscription that fires for the internal txs of
+// each new canonical block, and for those of the blocks dropped by a reorg with
+// removed set, with only their actions matching the given filter.
+func (api *FilterAPI) InternalTransactions(ctx context.Context, crit *tracers.ActionConfig) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	var (
+		rpcSub      = notifier.CreateSubscription()
+		internalTxs = make(chan []*types.InternalTx)
+	)
+	internalTxsSub := api.events.SubscribeInternalTxs(crit, internalTxs)
+
+	go func() {
+		defer internalTxsSub.Unsubscribe()
+		for {
+			select {
+			case itxs := <-internalTxs:
+				for _, itx := range itxs {
+					notifier.Notify(rpcSub.ID, itx)
+				}
+			case <-rpcSub.Err(): // client send an unsubscribe request
+				return
+			case <-notifier.Closed(): // connection dropped
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
 // FilterCriteria represents a request to create a new filter.
 // Same as ethereum.FilterQuery but with UnmarshalJSON() method.
 type FilterCriteria ethereum.FilterQuery
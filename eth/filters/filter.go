@@ -24,6 +24,7 @@ import (
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
@@ -392,6 +393,23 @@ func filterLogs(logs []*types.Log, fromBlock, toBlock *big.Int, addresses []comm
 	return ret
 }
 
+// filterInternalTxs creates a slice of internal txs with only their actions
+// matching the given criteria, dropping those left without actions.
+func filterInternalTxs(internalTxs []*types.InternalTx, crit *tracers.ActionConfig) []*types.InternalTx {
+	var ret []*types.InternalTx
+	for _, itx := range internalTxs {
+		actions := tracers.FilterActions(itx.Actions, crit)
+		if len(actions) == 0 {
+			continue
+		}
+		// Don't modify the internal txs shared by the subscriptions
+		cpy := *itx
+		cpy.Actions = actions
+		ret = append(ret, &cpy)
+	}
+	return ret
+}
+
 func bloomFilter(bloom types.Bloom, addresses []common.Address, topics [][]common.Hash) bool {
 	if len(addresses) > 0 {
 		var included bool
@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/log"
@@ -71,6 +72,7 @@ type Backend interface {
 	SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEvent) event.Subscription
 	SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscription
 	SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription
+	SubscribeInternalTxsEvent(ch chan<- core.InternalTxsEvent) event.Subscription
 
 	BloomStatus() (uint64, uint64)
 	ServiceFilter(ctx context.Context, session *bloombits.MatcherSession)
@@ -161,6 +163,8 @@ const (
 	PendingTransactionsSubscription
 	// BlocksSubscription queries hashes for blocks that are imported
 	BlocksSubscription
+	// InternalTxsSubscription queries for new or removed (chain reorg) internal txs
+	InternalTxsSubscription
 	// LastIndexSubscription keeps track of the last index
 	LastIndexSubscription
 )
@@ -175,18 +179,22 @@ const (
 	logsChanSize = 10
 	// chainEvChanSize is the size of channel listening to ChainEvent.
 	chainEvChanSize = 10
+	// internalTxsChanSize is the size of channel listening to InternalTxsEvent.
+	internalTxsChanSize = 10
 )
 
 type subscription struct {
-	id        rpc.ID
-	typ       Type
-	created   time.Time
-	logsCrit  ethereum.FilterQuery
-	logs      chan []*types.Log
-	txs       chan []*types.Transaction
-	headers   chan *types.Header
-	installed chan struct{} // closed when the filter is installed
-	err       chan error    // closed when the filter is uninstalled
+	id          rpc.ID
+	typ         Type
+	created     time.Time
+	logsCrit    ethereum.FilterQuery
+	logs        chan []*types.Log
+	txs         chan []*types.Transaction
+	headers     chan *types.Header
+	actionCrit  *tracers.ActionConfig
+	internalTxs chan []*types.InternalTx
+	installed   chan struct{} // closed when the filter is installed
+	err         chan error    // closed when the filter is uninstalled
 }
 
 // EventSystem creates subscriptions, processes events and broadcasts them to the
@@ -203,6 +211,7 @@ type EventSystem struct {
 	rmLogsSub      event.Subscription // Subscription for removed log event
 	pendingLogsSub event.Subscription // Subscription for pending log event
 	chainSub       event.Subscription // Subscription for new chain event
+	internalTxsSub event.Subscription // Subscription for new or removed internal txs event
 
 	// Channels
 	install       chan *subscription         // install filter for event notification
@@ -212,6 +221,7 @@ type EventSystem struct {
 	pendingLogsCh chan []*types.Log          // Channel to receive new log event
 	rmLogsCh      chan core.RemovedLogsEvent // Channel to receive removed log event
 	chainCh       chan core.ChainEvent       // Channel to receive new chain event
+	internalTxsCh chan core.InternalTxsEvent // Channel to receive new or removed internal txs event
 }
 
 // NewEventSystem creates a new manager that listens for event on the given mux,
@@ -232,6 +242,7 @@ func NewEventSystem(sys *FilterSystem, lightMode bool) *EventSystem {
 		rmLogsCh:      make(chan core.RemovedLogsEvent, rmLogsChanSize),
 		pendingLogsCh: make(chan []*types.Log, logsChanSize),
 		chainCh:       make(chan core.ChainEvent, chainEvChanSize),
+		internalTxsCh: make(chan core.InternalTxsEvent, internalTxsChanSize),
 	}
 
 	// Subscribe events
@@ -240,9 +251,10 @@ func NewEventSystem(sys *FilterSystem, lightMode bool) *EventSystem {
 	m.rmLogsSub = m.backend.SubscribeRemovedLogsEvent(m.rmLogsCh)
 	m.chainSub = m.backend.SubscribeChainEvent(m.chainCh)
 	m.pendingLogsSub = m.backend.SubscribePendingLogsEvent(m.pendingLogsCh)
+	m.internalTxsSub = m.backend.SubscribeInternalTxsEvent(m.internalTxsCh)
 
 	// Make sure none of the subscriptions are empty
-	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil {
+	if m.txsSub == nil || m.logsSub == nil || m.rmLogsSub == nil || m.chainSub == nil || m.pendingLogsSub == nil || m.internalTxsSub == nil {
 		log.Crit("Subscribe for event system failed")
 	}
 
@@ -278,6 +290,7 @@ func (sub *Subscription) Unsubscribe() {
 			case <-sub.f.logs:
 			case <-sub.f.txs:
 			case <-sub.f.headers:
+			case <-sub.f.internalTxs:
 			}
 		}
 
@@ -341,15 +354,16 @@ func (es *EventSystem) SubscribeLogs(crit ethereum.FilterQuery, logs chan []*typ
 // pending logs that match the given criteria.
 func (es *EventSystem) subscribeMinedPendingLogs(crit ethereum.FilterQuery, logs chan []*types.Log) *Subscription {
 	sub := &subscription{
-		id:        rpc.NewID(),
-		typ:       MinedAndPendingLogsSubscription,
-		logsCrit:  crit,
-		created:   time.Now(),
-		logs:      logs,
-		txs:       make(chan []*types.Transaction),
-		headers:   make(chan *types.Header),
-		installed: make(chan struct{}),
-		err:       make(chan error),
+		id:          rpc.NewID(),
+		typ:         MinedAndPendingLogsSubscription,
+		logsCrit:    crit,
+		created:     time.Now(),
+		logs:        logs,
+		txs:         make(chan []*types.Transaction),
+		headers:     make(chan *types.Header),
+		internalTxs: make(chan []*types.InternalTx),
+		installed:   make(chan struct{}),
+		err:         make(chan error),
 	}
 	return es.subscribe(sub)
 }
@@ -358,15 +372,16 @@ func (es *EventSystem) subscribeMinedPendingLogs(crit ethereum.FilterQuery, logs
 // given criteria to the given logs channel.
 func (es *EventSystem) subscribeLogs(crit ethereum.FilterQuery, logs chan []*types.Log) *Subscription {
 	sub := &subscription{
-		id:        rpc.NewID(),
-		typ:       LogsSubscription,
-		logsCrit:  crit,
-		created:   time.Now(),
-		logs:      logs,
-		txs:       make(chan []*types.Transaction),
-		headers:   make(chan *types.Header),
-		installed: make(chan struct{}),
-		err:       make(chan error),
+		id:          rpc.NewID(),
+		typ:         LogsSubscription,
+		logsCrit:    crit,
+		created:     time.Now(),
+		logs:        logs,
+		txs:         make(chan []*types.Transaction),
+		headers:     make(chan *types.Header),
+		internalTxs: make(chan []*types.InternalTx),
+		installed:   make(chan struct{}),
+		err:         make(chan error),
 	}
 	return es.subscribe(sub)
 }
@@ -375,15 +390,16 @@ func (es *EventSystem) subscribeLogs(crit ethereum.FilterQuery, logs chan []*typ
 // transactions that enter the transaction pool.
 func (es *EventSystem) subscribePendingLogs(crit ethereum.FilterQuery, logs chan []*types.Log) *Subscription {
 	sub := &subscription{
-		id:        rpc.NewID(),
-		typ:       PendingLogsSubscription,
-		logsCrit:  crit,
-		created:   time.Now(),
-		logs:      logs,
-		txs:       make(chan []*types.Transaction),
-		headers:   make(chan *types.Header),
-		installed: make(chan struct{}),
-		err:       make(chan error),
+		id:          rpc.NewID(),
+		typ:         PendingLogsSubscription,
+		logsCrit:    crit,
+		created:     time.Now(),
+		logs:        logs,
+		txs:         make(chan []*types.Transaction),
+		headers:     make(chan *types.Header),
+		internalTxs: make(chan []*types.InternalTx),
+		installed:   make(chan struct{}),
+		err:         make(chan error),
 	}
 	return es.subscribe(sub)
 }
@@ -392,14 +408,15 @@ func (es *EventSystem) subscribePendingLogs(crit ethereum.FilterQuery, logs chan
 // imported in the chain.
 func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscription {
 	sub := &subscription{
-		id:        rpc.NewID(),
-		typ:       BlocksSubscription,
-		created:   time.Now(),
-		logs:      make(chan []*types.Log),
-		txs:       make(chan []*types.Transaction),
-		headers:   headers,
-		installed: make(chan struct{}),
-		err:       make(chan error),
+		id:          rpc.NewID(),
+		typ:         BlocksSubscription,
+		created:     time.Now(),
+		logs:        make(chan []*types.Log),
+		txs:         make(chan []*types.Transaction),
+		headers:     headers,
+		internalTxs: make(chan []*types.InternalTx),
+		installed:   make(chan struct{}),
+		err:         make(chan error),
 	}
 	return es.subscribe(sub)
 }
@@ -408,14 +425,34 @@ func (es *EventSystem) SubscribeNewHeads(headers chan *types.Header) *Subscripti
 // transactions that enter the transaction pool.
 func (es *EventSystem) SubscribePendingTxs(txs chan []*types.Transaction) *Subscription {
 	sub := &subscription{
-		id:        rpc.NewID(),
-		typ:       PendingTransactionsSubscription,
-		created:   time.Now(),
-		logs:      make(chan []*types.Log),
-		txs:       txs,
-		headers:   make(chan *types.Header),
-		installed: make(chan struct{}),
-		err:       make(chan error),
+		id:          rpc.NewID(),
+		typ:         PendingTransactionsSubscription,
+		created:     time.Now(),
+		logs:        make(chan []*types.Log),
+		txs:         txs,
+		headers:     make(chan *types.Header),
+		internalTxs: make(chan []*types.InternalTx),
+		installed:   make(chan struct{}),
+		err:         make(chan error),
+	}
+	return es.subscribe(sub)
+}
+
+// SubscribeInternalTxs creates a subscription that writes the internal txs of the
+// blocks entering or leaving (chain reorg) the canonical chain, with only their
+// actions matching the given criteria.
+func (es *EventSystem) SubscribeInternalTxs(crit *tracers.ActionConfig, internalTxs chan []*types.InternalTx) *Subscription {
+	sub := &subscription{
+		id:          rpc.NewID(),
+		typ:         InternalTxsSubscription,
+		created:     time.Now(),
+		logs:        make(chan []*types.Log),
+		txs:         make(chan []*types.Transaction),
+		headers:     make(chan *types.Header),
+		actionCrit:  crit,
+		internalTxs: internalTxs,
+		installed:   make(chan struct{}),
+		err:         make(chan error),
 	}
 	return es.subscribe(sub)
 }
@@ -452,6 +489,14 @@ func (es *EventSystem) handleTxsEvent(filters filterIndex, ev core.NewTxsEvent)
 	}
 }
 
+func (es *EventSystem) handleInternalTxsEvent(filters filterIndex, ev core.InternalTxsEvent) {
+	for _, f := range filters[InternalTxsSubscription] {
+		if matched := filterInternalTxs(ev.InternalTxs, f.actionCrit); len(matched) > 0 {
+			f.internalTxs <- matched
+		}
+	}
+}
+
 func (es *EventSystem) handleChainEvent(filters filterIndex, ev core.ChainEvent) {
 	for _, f := range filters[BlocksSubscription] {
 		f.headers <- ev.Block.Header()
@@ -552,6 +597,7 @@ func (es *EventSystem) eventLoop() {
 		es.rmLogsSub.Unsubscribe()
 		es.pendingLogsSub.Unsubscribe()
 		es.chainSub.Unsubscribe()
+		es.internalTxsSub.Unsubscribe()
 	}()
 
 	index := make(filterIndex)
@@ -571,6 +617,8 @@ func (es *EventSystem) eventLoop() {
 			es.handlePendingLogs(index, ev)
 		case ev := <-es.chainCh:
 			es.handleChainEvent(index, ev)
+		case ev := <-es.internalTxsCh:
+			es.handleInternalTxsEvent(index, ev)
 
 		case f := <-es.install:
 			if f.typ == MinedAndPendingLogsSubscription {
@@ -601,6 +649,8 @@ func (es *EventSystem) eventLoop() {
 			return
 		case <-es.chainSub.Err():
 			return
+		case <-es.internalTxsSub.Err():
+			return
 		}
 	}
 }
@@ -35,6 +35,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/eth/tracers"
 	"github.com/ethereum/go-ethereum/ethdb"
 	"github.com/ethereum/go-ethereum/event"
 	"github.com/ethereum/go-ethereum/internal/ethapi"
@@ -50,6 +51,7 @@ type testBackend struct {
 	rmLogsFeed      event.Feed
 	pendingLogsFeed event.Feed
 	chainFeed       event.Feed
+	internalTxsFeed event.Feed
 	pendingBlock    *types.Block
 	pendingReceipts types.Receipts
 }
@@ -147,6 +149,10 @@ func (b *testBackend) SubscribeChainEvent(ch chan<- core.ChainEvent) event.Subsc
 	return b.chainFeed.Subscribe(ch)
 }
 
+func (b *testBackend) SubscribeInternalTxsEvent(ch chan<- core.InternalTxsEvent) event.Subscription {
+	return b.internalTxsFeed.Subscribe(ch)
+}
+
 func (b *testBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocks, b.sections
 }
@@ -766,6 +772,119 @@ func TestPendingLogsSubscription(t *testing.T) {
 	}
 }
 
+// TestInternalTxsSubscription tests if an internal txs subscription returns the
+// actions of the posted internal txs matching its criteria, and the removed flag.
+func TestInternalTxsSubscription(t *testing.T) {
+	t.Parallel()
+
+	var (
+		db           = rawdb.NewMemoryDatabase()
+		backend, sys = newTestFilterSystem(t, db, Config{})
+		api          = NewFilterAPI(sys, false)
+
+		firstAddr  = common.HexToAddress("0x1111111111111111111111111111111111111111")
+		secondAddr = common.HexToAddress("0x2222222222222222222222222222222222222222")
+		thirdAddr  = common.HexToAddress("0x3333333333333333333333333333333333333333")
+		call       = "CALL"
+		create     = "CREATE"
+
+		firstAction  = &types.Action{From: firstAddr, To: secondAddr, Value: big.NewInt(10), OpCode: call}
+		secondAction = &types.Action{From: secondAddr, To: thirdAddr, Value: big.NewInt(5), OpCode: call}
+		thirdAction  = &types.Action{From: secondAddr, To: thirdAddr, Value: big.NewInt(0), OpCode: create}
+
+		firstTx  = &types.InternalTx{TxHash: common.HexToHash("0x01"), BlockNumber: big.NewInt(1), Actions: []*types.Action{firstAction, secondAction}}
+		secondTx = &types.InternalTx{TxHash: common.HexToHash("0x02"), BlockNumber: big.NewInt(1), Actions: []*types.Action{firstAction, thirdAction}, Removed: true}
+
+		testCases = []struct {
+			crit     *tracers.ActionConfig
+			expected []*types.InternalTx
+		}{
+			// match all
+			{
+				nil,
+				[]*types.InternalTx{firstTx, secondTx},
+			},
+			// match the actions from an address
+			{
+				&tracers.ActionConfig{From: &secondAddr},
+				[]*types.InternalTx{
+					{TxHash: firstTx.TxHash, BlockNumber: firstTx.BlockNumber, Actions: []*types.Action{secondAction}},
+					{TxHash: secondTx.TxHash, BlockNumber: secondTx.BlockNumber, Actions: []*types.Action{thirdAction}, Removed: true},
+				},
+			},
+			// match the actions to an address with an opcode, dropping the internal txs left empty
+			{
+				&tracers.ActionConfig{To: &thirdAddr, OpCode: &create},
+				[]*types.InternalTx{
+					{TxHash: secondTx.TxHash, BlockNumber: secondTx.BlockNumber, Actions: []*types.Action{thirdAction}, Removed: true},
+				},
+			},
+			// match the actions with a minimum value
+			{
+				&tracers.ActionConfig{MinValue: big.NewInt(6)},
+				[]*types.InternalTx{
+					{TxHash: firstTx.TxHash, BlockNumber: firstTx.BlockNumber, Actions: []*types.Action{firstAction}},
+					{TxHash: secondTx.TxHash, BlockNumber: secondTx.BlockNumber, Actions: []*types.Action{firstAction}, Removed: true},
+				},
+			},
+			// match none
+			{
+				&tracers.ActionConfig{From: &thirdAddr},
+				nil,
+			},
+		}
+		chans = make([]chan []*types.InternalTx, len(testCases))
+		subs  = make([]*Subscription, len(testCases))
+	)
+
+	for i := range testCases {
+		chans[i] = make(chan []*types.InternalTx)
+		subs[i] = api.events.SubscribeInternalTxs(testCases[i].crit, chans[i])
+	}
+
+	errs := make(chan error, len(testCases))
+	for n, test := range testCases {
+		i := n
+		tt := test
+		go func() {
+			defer subs[i].Unsubscribe()
+
+			var fetched []*types.InternalTx
+
+			timeout := time.After(1 * time.Second)
+		fetchLoop:
+			for {
+				select {
+				case itxs := <-chans[i]:
+					fetched = append(fetched, itxs...)
+				case <-timeout:
+					break fetchLoop
+				}
+			}
+			if !reflect.DeepEqual(fetched, tt.expected) {
+				errs <- fmt.Errorf("internal txs mismatch for case %d: have %v, want %v", i, fetched, tt.expected)
+				return
+			}
+			errs <- nil
+		}()
+	}
+
+	// raise events
+	backend.internalTxsFeed.Send(core.InternalTxsEvent{InternalTxs: []*types.InternalTx{firstTx}})
+	backend.internalTxsFeed.Send(core.InternalTxsEvent{InternalTxs: []*types.InternalTx{secondTx}})
+
+	for i := range testCases {
+		if err := <-errs; err != nil {
+			t.Fatal(err)
+		}
+		<-subs[i].Err()
+	}
+	// The posted internal txs are shared, they must not be modified by the filters
+	if len(firstTx.Actions) != 2 || len(secondTx.Actions) != 2 {
+		t.Errorf("posted internal txs modified")
+	}
+}
+
 func TestLightFilterLogs(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,64 @@
+package gasprice
+
+import (
+	"math/big"
+	"sort"
+	"time"
+)
+
+// Congestion is the congestion of the local transaction pool.
+type Congestion struct {
+	Index       int             // Congestion index of the pool, zero if it isn't congested
+	PendingAges []time.Duration // Sorted sample of the ages of the pending txs
+	Threshold   time.Duration   // Age from which a pending tx is congested
+}
+
+// CongestionBackend is implemented by the oracle backends reporting the
+// congestion of their transaction pool, which light clients don't have.
+type CongestionBackend interface {
+	TxPoolCongestion() Congestion
+}
+
+// congestionPremiums returns the percentages added to the slow, standard and
+// fast suggestions on congestion, all zero unless enabled.
+//
+// The standard premium is the congestion index, capped by the configured max
+// premium, and the fast premium is the double. The slow premium is the share of
+// the standard one of the pending txs older than the congestion threshold, so
+// that the slow suggestions are only raised if the pool isn't draining.
+func (oracle *Oracle) congestionPremiums() (slow, standard, fast int) {
+	if !oracle.congestion {
+		return 0, 0, 0
+	}
+	backend, ok := oracle.backend.(CongestionBackend)
+	if !ok {
+		return 0, 0, 0
+	}
+	congestion := backend.TxPoolCongestion()
+	if congestion.Index <= 0 {
+		return 0, 0, 0
+	}
+	standard = congestion.Index
+	if standard > oracle.maxCongestionPremium {
+		standard = oracle.maxCongestionPremium
+	}
+	if n := len(congestion.PendingAges); n > 0 {
+		fresh := sort.Search(n, func(i int) bool { return congestion.PendingAges[i] >= congestion.Threshold })
+		slow = standard * (n - fresh) / n
+	}
+	return slow, standard, 2 * standard
+}
+
+// addPremium returns the price raised by the given percentage, capped by the
+// max price.
+func (oracle *Oracle) addPremium(price *big.Int, premium int) *big.Int {
+	if premium <= 0 {
+		return price
+	}
+	price = new(big.Int).Mul(price, big.NewInt(int64(100+premium)))
+	price.Div(price, big.NewInt(100))
+	if price.Cmp(oracle.maxPrice) > 0 {
+		price = new(big.Int).Set(oracle.maxPrice)
+	}
+	return price
+}
@@ -242,6 +242,12 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks uint64, unresolvedL
 	}
 	oldestBlock := lastBlock + 1 - blocks
 
+	// The rewards of the pending block are estimates, raised on congestion like
+	// the tip cap suggestions.
+	var pendingPremium int
+	if pendingBlock != nil {
+		_, pendingPremium, _ = oracle.congestionPremiums()
+	}
 	var next atomic.Uint64
 	next.Store(oldestBlock)
 	results := make(chan *blockFees, blocks)
@@ -264,6 +270,9 @@ func (oracle *Oracle) FeeHistory(ctx context.Context, blocks uint64, unresolvedL
 					fees.block, fees.receipts = pendingBlock, pendingReceipts
 					fees.header = fees.block.Header()
 					oracle.processBlock(fees, rewardPercentiles)
+					for i, reward := range fees.results.reward {
+						fees.results.reward[i] = oracle.addPremium(reward, pendingPremium)
+					}
 					results <- fees
 				} else {
 					cacheKey := cacheKey{number: blockNumber, percentiles: string(percentileKey)}
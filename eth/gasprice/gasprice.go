@@ -39,6 +39,8 @@ var (
 	DefaultIgnorePrice = big.NewInt(2 * params.Wei)
 )
 
+const DefaultMaxCongestionPremium = 100 // Percentage of the suggestion added at most on congestion
+
 type Config struct {
 	Blocks           int
 	Percentile       int
@@ -47,6 +49,9 @@ type Config struct {
 	Default          *big.Int `toml:",omitempty"`
 	MaxPrice         *big.Int `toml:",omitempty"`
 	IgnorePrice      *big.Int `toml:",omitempty"`
+
+	Congestion           bool `toml:",omitempty"` // Whether to raise the suggestions on tx pool congestion
+	MaxCongestionPremium int  `toml:",omitempty"` // Percentage of the standard suggestion added at most on congestion
 }
 
 // OracleBackend includes all necessary background APIs for oracle.
@@ -65,6 +70,8 @@ type Oracle struct {
 	backend     OracleBackend
 	lastHead    common.Hash
 	lastPrice   *big.Int
+	lastSlow    *big.Int
+	lastFast    *big.Int
 	maxPrice    *big.Int
 	ignorePrice *big.Int
 	cacheLock   sync.RWMutex
@@ -73,6 +80,9 @@ type Oracle struct {
 	checkBlocks, percentile           int
 	maxHeaderHistory, maxBlockHistory uint64
 
+	congestion           bool
+	maxCongestionPremium int
+
 	historyCache *lru.Cache[cacheKey, processedFees]
 }
 
@@ -114,6 +124,11 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 		maxBlockHistory = 1
 		log.Warn("Sanitizing invalid gasprice oracle max block history", "provided", params.MaxBlockHistory, "updated", maxBlockHistory)
 	}
+	maxCongestionPremium := params.MaxCongestionPremium
+	if params.Congestion && maxCongestionPremium <= 0 {
+		maxCongestionPremium = DefaultMaxCongestionPremium
+		log.Warn("Sanitizing invalid gasprice oracle max congestion premium", "provided", params.MaxCongestionPremium, "updated", maxCongestionPremium)
+	}
 
 	cache := lru.NewCache[cacheKey, processedFees](2048)
 	headEvent := make(chan core.ChainHeadEvent, 1)
@@ -129,15 +144,19 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 	}()
 
 	return &Oracle{
-		backend:          backend,
-		lastPrice:        params.Default,
-		maxPrice:         maxPrice,
-		ignorePrice:      ignorePrice,
-		checkBlocks:      blocks,
-		percentile:       percent,
-		maxHeaderHistory: maxHeaderHistory,
-		maxBlockHistory:  maxBlockHistory,
-		historyCache:     cache,
+		backend:              backend,
+		lastPrice:            params.Default,
+		lastSlow:             params.Default,
+		lastFast:             params.Default,
+		maxPrice:             maxPrice,
+		ignorePrice:          ignorePrice,
+		checkBlocks:          blocks,
+		percentile:           percent,
+		maxHeaderHistory:     maxHeaderHistory,
+		maxBlockHistory:      maxBlockHistory,
+		congestion:           params.Congestion,
+		maxCongestionPremium: maxCongestionPremium,
+		historyCache:         cache,
 	}
 }
 
@@ -148,25 +167,49 @@ func NewOracle(backend OracleBackend, params Config) *Oracle {
 // necessary to add the basefee to the returned number to fall back to the legacy
 // behavior.
 func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
+	_, price, _, err := oracle.sampleTipCaps(ctx)
+	if err != nil {
+		return price, err
+	}
+	_, premium, _ := oracle.congestionPremiums()
+	return oracle.addPremium(price, premium), nil
+}
+
+// SuggestTipCaps returns the slow, standard and fast tip cap suggestions. The
+// standard one is the one of SuggestTipCap, the slow and fast ones are sampled
+// at the half of the percentile below and above it, and get lower and higher
+// premiums on congestion.
+func (oracle *Oracle) SuggestTipCaps(ctx context.Context) (slow, standard, fast *big.Int, err error) {
+	slow, standard, fast, err = oracle.sampleTipCaps(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	slowPremium, premium, fastPremium := oracle.congestionPremiums()
+	return oracle.addPremium(slow, slowPremium), oracle.addPremium(standard, premium), oracle.addPremium(fast, fastPremium), nil
+}
+
+// sampleTipCaps returns the slow, standard and fast tip caps sampled from the
+// recent blocks, caching them until the head changes.
+func (oracle *Oracle) sampleTipCaps(ctx context.Context) (*big.Int, *big.Int, *big.Int, error) {
 	head, _ := oracle.backend.HeaderByNumber(ctx, rpc.LatestBlockNumber)
 	headHash := head.Hash()
 
 	// If the latest gasprice is still available, return it.
 	oracle.cacheLock.RLock()
-	lastHead, lastPrice := oracle.lastHead, oracle.lastPrice
+	lastHead, lastSlow, lastPrice, lastFast := oracle.lastHead, oracle.lastSlow, oracle.lastPrice, oracle.lastFast
 	oracle.cacheLock.RUnlock()
 	if headHash == lastHead {
-		return new(big.Int).Set(lastPrice), nil
+		return new(big.Int).Set(lastSlow), new(big.Int).Set(lastPrice), new(big.Int).Set(lastFast), nil
 	}
 	oracle.fetchLock.Lock()
 	defer oracle.fetchLock.Unlock()
 
 	// Try checking the cache again, maybe the last fetch fetched what we need
 	oracle.cacheLock.RLock()
-	lastHead, lastPrice = oracle.lastHead, oracle.lastPrice
+	lastHead, lastSlow, lastPrice, lastFast = oracle.lastHead, oracle.lastSlow, oracle.lastPrice, oracle.lastFast
 	oracle.cacheLock.RUnlock()
 	if headHash == lastHead {
-		return new(big.Int).Set(lastPrice), nil
+		return new(big.Int).Set(lastSlow), new(big.Int).Set(lastPrice), new(big.Int).Set(lastFast), nil
 	}
 	var (
 		sent, exp int
@@ -185,7 +228,7 @@ func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 		res := <-result
 		if res.err != nil {
 			close(quit)
-			return new(big.Int).Set(lastPrice), res.err
+			return new(big.Int).Set(lastSlow), new(big.Int).Set(lastPrice), new(big.Int).Set(lastFast), res.err
 		}
 		exp--
 		// Nothing returned. There are two special cases here:
@@ -206,20 +249,30 @@ func (oracle *Oracle) SuggestTipCap(ctx context.Context) (*big.Int, error) {
 		}
 		results = append(results, res.values...)
 	}
-	price := lastPrice
+	slow, price, fast := lastSlow, lastPrice, lastFast
 	if len(results) > 0 {
 		slices.SortFunc(results, func(a, b *big.Int) bool { return a.Cmp(b) < 0 })
+		slow = results[(len(results)-1)*(oracle.percentile/2)/100]
 		price = results[(len(results)-1)*oracle.percentile/100]
+		fast = results[(len(results)-1)*((oracle.percentile+100)/2)/100]
+	}
+	if slow.Cmp(oracle.maxPrice) > 0 {
+		slow = new(big.Int).Set(oracle.maxPrice)
 	}
 	if price.Cmp(oracle.maxPrice) > 0 {
 		price = new(big.Int).Set(oracle.maxPrice)
 	}
+	if fast.Cmp(oracle.maxPrice) > 0 {
+		fast = new(big.Int).Set(oracle.maxPrice)
+	}
 	oracle.cacheLock.Lock()
 	oracle.lastHead = headHash
+	oracle.lastSlow = slow
 	oracle.lastPrice = price
+	oracle.lastFast = fast
 	oracle.cacheLock.Unlock()
 
-	return new(big.Int).Set(price), nil
+	return new(big.Int).Set(slow), new(big.Int).Set(price), new(big.Int).Set(fast), nil
 }
 
 type results struct {
@@ -21,6 +21,7 @@ import (
 	"math"
 	"math/big"
 	"testing"
+	"time"
 
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/consensus/ethash"
@@ -213,3 +214,60 @@ func TestSuggestTipCap(t *testing.T) {
 		}
 	}
 }
+
+// congestedBackend is a test backend reporting a congested transaction pool.
+type congestedBackend struct {
+	*testBackend
+	congestion Congestion
+}
+
+func (b *congestedBackend) TxPoolCongestion() Congestion {
+	return b.congestion
+}
+
+func TestSuggestTipCaps(t *testing.T) {
+	var (
+		ages = []time.Duration{time.Second, 20 * time.Second, 30 * time.Second, 40 * time.Second}
+		gwei = func(n float64) *big.Int { return big.NewInt(int64(n * params.GWei)) }
+	)
+	var cases = []struct {
+		congestion bool       // Whether the congestion premium is enabled
+		maxPremium int        // Max congestion premium
+		pool       Congestion // Congestion of the pool
+		slow       *big.Int
+		standard   *big.Int
+		fast       *big.Int
+	}{
+		// No congestion
+		{true, 0, Congestion{}, gwei(28), gwei(30), gwei(31)},
+		// Congestion premium disabled
+		{false, 0, Congestion{Index: 50, PendingAges: ages, Threshold: 15 * time.Second}, gwei(28), gwei(30), gwei(31)},
+		// Slow premium is the share of the pending txs older than the threshold
+		{true, 0, Congestion{Index: 50, PendingAges: ages, Threshold: 15 * time.Second}, gwei(28 * 1.37), gwei(45), gwei(62)},
+		// No slow premium if the pool is draining
+		{true, 0, Congestion{Index: 50, PendingAges: ages, Threshold: time.Minute}, gwei(28), gwei(45), gwei(62)},
+		// Premium capped by the max premium
+		{true, 20, Congestion{Index: 500, PendingAges: ages, Threshold: 0}, gwei(28 * 1.2), gwei(36), gwei(31 * 1.4)},
+	}
+	for i, c := range cases {
+		config := Config{
+			Blocks:               3,
+			Percentile:           60,
+			Default:              big.NewInt(params.GWei),
+			Congestion:           c.congestion,
+			MaxCongestionPremium: c.maxPremium,
+		}
+		backend := newTestBackend(t, big.NewInt(0), false)
+		oracle := NewOracle(&congestedBackend{testBackend: backend, congestion: c.pool}, config)
+
+		// The gas price sampled is: 32G, 31G, 30G, 29G, 28G, 27G
+		slow, standard, fast, err := oracle.SuggestTipCaps(context.Background())
+		backend.teardown()
+		if err != nil {
+			t.Fatalf("case %d: failed to retrieve recommended gas prices: %v", i, err)
+		}
+		if slow.Cmp(c.slow) != 0 || standard.Cmp(c.standard) != 0 || fast.Cmp(c.fast) != 0 {
+			t.Errorf("case %d: gas price mismatch, want %d/%d/%d, got %d/%d/%d", i, c.slow, c.standard, c.fast, slow, standard, fast)
+		}
+	}
+}
@@ -216,7 +216,9 @@ func (eth *Ethereum) stateAtTransaction(ctx context.Context, block *types.Block,
 		return nil, vm.BlockContext{}, statedb, release, nil
 	}
 	if eth.isPoSA {
-		extraValidator = eth.posa.CreateEvmExtraValidator(header, statedb)
+		if extraValidator, err = eth.posa.CreateEvmExtraValidator(header, statedb); err != nil {
+			return nil, vm.BlockContext{}, nil, nil, err
+		}
 	}
 	// Recompute transactions up to the target index.
 	signer := types.MakeSigner(eth.blockchain.Config(), block.Number())
@@ -171,8 +171,8 @@ type TraceCallConfig struct {
 	TxIndex        *hexutil.Uint
 }
 
-// ActionConfig is the config for actionTrace API. It holds one more
-// field to override the state for tracing.
+// ActionConfig is the config for actionTrace API. It filters the actions by
+// their sender, recipient, opcode and minimum value.
 type ActionConfig struct {
 	From     *common.Address
 	To       *common.Address
@@ -283,12 +283,17 @@ func (api *API) traceChain(start, end *types.Block, config *TraceConfig, closed
 					header   = task.block.Header()
 					blockCtx = core.NewEVMBlockContext(header, api.chainContext(ctx), nil)
 				)
+				var validatorErr error
 				if api.isPoSA {
 					_ = api.posa.PreHandle(api.backend.ChainHeaderReader(), header, task.statedb)
-					blockCtx.ExtraValidator = api.posa.CreateEvmExtraValidator(header, task.statedb)
+					blockCtx.ExtraValidator, validatorErr = api.posa.CreateEvmExtraValidator(header, task.statedb)
 				}
 				// Trace all the transactions contained within
 				for i, tx := range task.block.Transactions() {
+					if validatorErr != nil {
+						task.results[i] = &txTraceResult{TxHash: tx.Hash(), Error: validatorErr.Error()}
+						continue
+					}
 					msg, _ := core.TransactionToMessage(tx, signer, task.block.BaseFee())
 					txctx := &Context{
 						BlockHash:   task.block.Hash(),
@@ -677,7 +682,10 @@ func (api *API) traceBlockParallel(ctx context.Context, block *types.Block, stat
 	var exValidator types.EvmExtraValidator
 	if api.isPoSA {
 		_ = api.posa.PreHandle(api.backend.ChainHeaderReader(), header, statedb)
-		exValidator = api.posa.CreateEvmExtraValidator(header, statedb)
+		var err error
+		if exValidator, err = api.posa.CreateEvmExtraValidator(header, statedb); err != nil {
+			return nil, err
+		}
 	}
 	jobs := make(chan *txTraceTask, threads)
 	for th := 0; th < threads; th++ {
@@ -811,7 +819,9 @@ func (api *API) standardTraceBlockToFile(ctx context.Context, block *types.Block
 	)
 	if api.isPoSA {
 		_ = api.posa.PreHandle(api.backend.ChainHeaderReader(), header, statedb)
-		vmctx.ExtraValidator = api.posa.CreateEvmExtraValidator(header, statedb)
+		if vmctx.ExtraValidator, err = api.posa.CreateEvmExtraValidator(header, statedb); err != nil {
+			return nil, err
+		}
 	}
 
 	// Check if there are any overrides: the caller may wish to enable a future
@@ -990,7 +1000,9 @@ func (api *API) TraceCall(ctx context.Context, args ethapi.TransactionArgs, bloc
 
 	vmctx := core.NewEVMBlockContext(block.Header(), api.chainContext(ctx), nil)
 	if api.isPoSA {
-		vmctx.ExtraValidator = api.posa.CreateEvmExtraValidator(block.Header(), statedb)
+		if vmctx.ExtraValidator, err = api.posa.CreateEvmExtraValidator(block.Header(), statedb); err != nil {
+			return nil, err
+		}
 	}
 	// Apply the customization rules if required.
 	if config != nil {
@@ -1141,7 +1153,7 @@ func (api *API) TraceActionByBlockNumber(ctx context.Context, number rpc.BlockNu
 
 	res := make([]*types.InternalTx, 0)
 	for _, tx := range iTx {
-		tx.Actions = api.filterAction(tx.Actions, filter)
+		tx.Actions = FilterActions(tx.Actions, filter)
 		if len(tx.Actions) > 0 {
 			res = append(res, tx)
 		}
@@ -1178,7 +1190,7 @@ func (api *API) TraceActionByTxHash(ctx context.Context, hash common.Hash, filte
 
 	for _, t := range txs {
 		if t.TxHash == hash {
-			t.Actions = api.filterAction(t.Actions, filter)
+			t.Actions = FilterActions(t.Actions, filter)
 			return t, nil
 		}
 	}
@@ -1186,7 +1198,9 @@ func (api *API) TraceActionByTxHash(ctx context.Context, hash common.Hash, filte
 	return nil, nil
 }
 
-func (api *API) filterAction(actions []*types.Action, filter *ActionConfig) []*types.Action {
+// FilterActions returns the actions matching all the criteria of the filter,
+// or all of them if there is no filter.
+func FilterActions(actions []*types.Action, filter *ActionConfig) []*types.Action {
 	if filter == nil {
 		return actions
 	}
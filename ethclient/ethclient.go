@@ -312,6 +312,28 @@ func (ec *Client) TransactionReceipt(ctx context.Context, txHash common.Hash) (*
 	return r, err
 }
 
+// InternalTransactions returns the internal transactions of a transaction by
+// transaction hash. Note that they are not available for pending transactions.
+func (ec *Client) InternalTransactions(ctx context.Context, txHash common.Hash) (*types.InternalTx, error) {
+	var r *types.InternalTx
+	err := ec.c.CallContext(ctx, &r, "eth_getInternalTransactions", txHash)
+	if err == nil && r == nil {
+		return nil, ethereum.NotFound
+	}
+	return r, err
+}
+
+// BlockInternalTransactions returns the internal transactions of the
+// transactions in a given block number or hash.
+func (ec *Client) BlockInternalTransactions(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (types.InternalTxs, error) {
+	var r types.InternalTxs
+	err := ec.c.CallContext(ctx, &r, "eth_getBlockInternalTransactions", blockNrOrHash.String())
+	if err == nil && r == nil {
+		return nil, ethereum.NotFound
+	}
+	return r, err
+}
+
 // SyncProgress retrieves the current progress of the sync algorithm. If there's
 // no sync currently running, it returns nil.
 func (ec *Client) SyncProgress(ctx context.Context) (*ethereum.SyncProgress, error) {
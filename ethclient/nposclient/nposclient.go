@@ -0,0 +1,167 @@
+// Package nposclient provides an RPC client for the NPoS consensus APIs.
+package nposclient
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/consensus/npos"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+// Client is a wrapper around rpc.Client that implements the npos namespace.
+type Client struct {
+	c *rpc.Client
+}
+
+// New creates a client that uses the given RPC client.
+func New(c *rpc.Client) *Client {
+	return &Client{c}
+}
+
+// GetValidators returns the authorized validators at the given block.
+func (ec *Client) GetValidators(ctx context.Context, blockNumber *big.Int) ([]common.Address, error) {
+	var result []common.Address
+	err := ec.c.CallContext(ctx, &result, "npos_getValidators", toBlockNumArg(blockNumber))
+	return result, err
+}
+
+// GetValidatorInfo returns the staking, reward and punishment state of a validator
+// at the given block.
+func (ec *Client) GetValidatorInfo(ctx context.Context, validator common.Address, blockNumber *big.Int) (*npos.ValidatorInfo, error) {
+	var result npos.ValidatorInfo
+	if err := ec.c.CallContext(ctx, &result, "npos_getValidatorInfo", validator, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetPendingReward returns the unclaimed reward of a voter of the validator at the
+// given block. If voter is nil, the unclaimed commission of the validator is returned.
+func (ec *Client) GetPendingReward(ctx context.Context, validator common.Address, voter *common.Address, blockNumber *big.Int) (*big.Int, error) {
+	var result hexutil.Big
+	if err := ec.c.CallContext(ctx, &result, "npos_getPendingReward", validator, voter, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return (*big.Int)(&result), nil
+}
+
+// GetBackupValidators returns the backup validators at the given block.
+func (ec *Client) GetBackupValidators(ctx context.Context, blockNumber *big.Int) ([]common.Address, error) {
+	var result []common.Address
+	err := ec.c.CallContext(ctx, &result, "npos_getBackupValidators", toBlockNumArg(blockNumber))
+	return result, err
+}
+
+// GetMissedBlocks returns the number of blocks missed by a validator at the given block.
+func (ec *Client) GetMissedBlocks(ctx context.Context, validator common.Address, blockNumber *big.Int) (uint64, error) {
+	var result hexutil.Uint64
+	err := ec.c.CallContext(ctx, &result, "npos_getMissedBlocks", validator, toBlockNumArg(blockNumber))
+	return uint64(result), err
+}
+
+// GetJailStatus returns whether a validator is jailed at the given block.
+func (ec *Client) GetJailStatus(ctx context.Context, validator common.Address, blockNumber *big.Int) (*npos.JailStatus, error) {
+	var result npos.JailStatus
+	if err := ec.c.CallContext(ctx, &result, "npos_getJailStatus", validator, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetVotePool returns the vote pool state of a validator at the given block.
+func (ec *Client) GetVotePool(ctx context.Context, validator common.Address, blockNumber *big.Int) (*npos.VotePool, error) {
+	var result npos.VotePool
+	if err := ec.c.CallContext(ctx, &result, "npos_getVotePool", validator, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetPerformance returns the sealing record of every validator between the two
+// blocks, inclusive. If to is nil, the report ends at the current block; a to
+// past the current block is an error.
+func (ec *Client) GetPerformance(ctx context.Context, from, to *big.Int) (*npos.PerformanceReport, error) {
+	var result npos.PerformanceReport
+	var end interface{}
+	if to != nil {
+		end = toBlockNumArg(to)
+	}
+	if err := ec.c.CallContext(ctx, &result, "npos_getPerformance", toBlockNumArg(from), end); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetEpochPerformance returns the sealing record of every validator from the
+// first to the last epoch, inclusive.
+func (ec *Client) GetEpochPerformance(ctx context.Context, first, last uint64) (*npos.PerformanceReport, error) {
+	var result npos.PerformanceReport
+	if err := ec.c.CallContext(ctx, &result, "npos_getEpochPerformance", hexutil.Uint64(first), hexutil.Uint64(last)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// SimulateProposal executes a governance proposal on a copy of the latest state
+// and returns its effects.
+func (ec *Client) SimulateProposal(ctx context.Context, args npos.ProposalArgs) (*npos.ProposalSimulation, error) {
+	var result npos.ProposalSimulation
+	if err := ec.c.CallContext(ctx, &result, "npos_simulateProposal", args); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetBlacklist returns the banned addresses and their directions after the given block.
+func (ec *Client) GetBlacklist(ctx context.Context, blockNumber *big.Int) (map[common.Address]npos.BannedDirection, error) {
+	var result map[common.Address]npos.BannedDirection
+	err := ec.c.CallContext(ctx, &result, "npos_getBlacklist", toBlockNumArg(blockNumber))
+	return result, err
+}
+
+// IsAddressBanned returns whether the address is on the blacklist after the given
+// block, and in which direction.
+func (ec *Client) IsAddressBanned(ctx context.Context, address common.Address, blockNumber *big.Int) (*npos.BanStatus, error) {
+	var result npos.BanStatus
+	if err := ec.c.CallContext(ctx, &result, "npos_isAddressBanned", address, toBlockNumArg(blockNumber)); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// RebuildAddressListIndex starts indexing the blacklist and the event check
+// rules between the two blocks, inclusive, in the background. If to is nil, the
+// rebuild ends at the current block.
+func (ec *Client) RebuildAddressListIndex(ctx context.Context, from, to *big.Int) error {
+	var end interface{}
+	if to != nil {
+		end = toBlockNumArg(to)
+	}
+	return ec.c.CallContext(ctx, nil, "npos_rebuildAddressListIndex", toBlockNumArg(from), end)
+}
+
+// SubscribeBlacklistEvents subscribes to the blacklist updates of the new canonical blocks.
+// The updates of the blocks dropped by a reorg are sent again with Removed set.
+func (ec *Client) SubscribeBlacklistEvents(ctx context.Context, ch chan<- *npos.BlacklistEvent) (ethereum.Subscription, error) {
+	return ec.c.Subscribe(ctx, "npos", ch, "blacklistEvents")
+}
+
+func toBlockNumArg(number *big.Int) string {
+	if number == nil {
+		return "latest"
+	}
+	if number.Sign() >= 0 {
+		return hexutil.EncodeBig(number)
+	}
+	// It's negative.
+	if number.IsInt64() {
+		return rpc.BlockNumber(number.Int64()).String()
+	}
+	// It's negative and large, which is invalid.
+	return fmt.Sprintf("<invalid %d>", number)
+}
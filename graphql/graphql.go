@@ -32,6 +32,7 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/common/math"
 	"github.com/ethereum/go-ethereum/consensus/misc"
+	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/eth/filters"
@@ -170,6 +171,92 @@ func (l *Log) Data(ctx context.Context) hexutil.Bytes {
 	return l.log.Data
 }
 
+// InternalTransaction represents a call, contract creation or self-destruct made
+// while executing a transaction.
+type InternalTransaction struct {
+	r           *Resolver
+	transaction *Transaction
+	action      *types.Action
+}
+
+func (i *InternalTransaction) Transaction(ctx context.Context) *Transaction {
+	return i.transaction
+}
+
+func (i *InternalTransaction) From(ctx context.Context, args BlockNumberArgs) *Account {
+	return &Account{
+		r:             i.r,
+		address:       i.action.From,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (i *InternalTransaction) To(ctx context.Context, args BlockNumberArgs) *Account {
+	if i.action.To == (common.Address{}) {
+		return nil
+	}
+	return &Account{
+		r:             i.r,
+		address:       i.action.To,
+		blockNrOrHash: args.NumberOrLatest(),
+	}
+}
+
+func (i *InternalTransaction) Value(ctx context.Context) hexutil.Big {
+	if i.action.Value == nil {
+		return hexutil.Big{}
+	}
+	return hexutil.Big(*i.action.Value)
+}
+
+func (i *InternalTransaction) Success(ctx context.Context) bool {
+	return i.action.Success
+}
+
+func (i *InternalTransaction) Opcode(ctx context.Context) string {
+	return i.action.OpCode
+}
+
+func (i *InternalTransaction) Depth(ctx context.Context) *hexutil.Uint64 {
+	// the top-level calls of the transaction and of the engine have no depth
+	if i.action.Depth == types.TopActionDepth || i.action.Depth == types.EngineActionDepth {
+		return nil
+	}
+	depth := hexutil.Uint64(i.action.Depth)
+	return &depth
+}
+
+func (i *InternalTransaction) Gas(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(i.action.Gas)
+}
+
+func (i *InternalTransaction) GasUsed(ctx context.Context) hexutil.Uint64 {
+	return hexutil.Uint64(i.action.GasUsed)
+}
+
+func (i *InternalTransaction) Input(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(i.action.Input)
+}
+
+func (i *InternalTransaction) Output(ctx context.Context) hexutil.Bytes {
+	return hexutil.Bytes(i.action.Output)
+}
+
+func (i *InternalTransaction) TraceAddress(ctx context.Context) []hexutil.Uint64 {
+	ret := make([]hexutil.Uint64, len(i.action.TraceAddress))
+	for j, index := range i.action.TraceAddress {
+		ret[j] = hexutil.Uint64(index)
+	}
+	return ret
+}
+
+func (i *InternalTransaction) Error(ctx context.Context) *string {
+	if i.action.Error == "" {
+		return nil
+	}
+	return &i.action.Error
+}
+
 // AccessTuple represents EIP-2930
 type AccessTuple struct {
 	address     common.Address
@@ -493,6 +580,39 @@ func (t *Transaction) getLogs(ctx context.Context, hash common.Hash) (*[]*Log, e
 	return &ret, nil
 }
 
+func (t *Transaction) InternalTransactions(ctx context.Context) (*[]*InternalTransaction, error) {
+	_, block := t.resolve(ctx)
+	// Pending tx
+	if block == nil {
+		return nil, nil
+	}
+	txs, err := block.resolveInternalTxs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret := make([]*InternalTransaction, 0)
+	for _, tx := range txs {
+		if tx.TxHash == t.hash {
+			ret = t.internalTransactions(tx)
+			break
+		}
+	}
+	return &ret, nil
+}
+
+// internalTransactions wraps the actions of the internal transactions of t.
+func (t *Transaction) internalTransactions(tx *types.InternalTx) []*InternalTransaction {
+	ret := make([]*InternalTransaction, 0, len(tx.Actions))
+	for _, action := range tx.Actions {
+		ret = append(ret, &InternalTransaction{
+			r:           t.r,
+			transaction: t,
+			action:      action,
+		})
+	}
+	return ret
+}
+
 func (t *Transaction) Type(ctx context.Context) *hexutil.Uint64 {
 	tx, _ := t.resolve(ctx)
 	txType := hexutil.Uint64(tx.Type())
@@ -578,10 +698,11 @@ type Block struct {
 	numberOrHash *rpc.BlockNumberOrHash // Field resolvers assume numberOrHash is always present
 	mu           sync.Mutex
 	// mu protects following resources
-	hash     common.Hash // Must be resolved during initialization
-	header   *types.Header
-	block    *types.Block
-	receipts []*types.Receipt
+	hash        common.Hash // Must be resolved during initialization
+	header      *types.Header
+	block       *types.Block
+	receipts    []*types.Receipt
+	internalTxs types.InternalTxs
 }
 
 // resolve returns the internal Block object representing this block, fetching
@@ -646,6 +767,25 @@ func (b *Block) resolveReceipts(ctx context.Context) ([]*types.Receipt, error) {
 	return receipts, nil
 }
 
+// resolveInternalTxs returns the internal transactions of this block, reading
+// them if necessary.
+func (b *Block) resolveInternalTxs(ctx context.Context) (types.InternalTxs, error) {
+	header, err := b.resolveHeader(ctx)
+	if err != nil {
+		return nil, err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.internalTxs != nil {
+		return b.internalTxs, nil
+	}
+	b.internalTxs = rawdb.ReadInternalTxs(b.r.backend.ChainDb(), b.hash, header.Number.Uint64())
+	if b.internalTxs == nil {
+		b.internalTxs = types.InternalTxs{}
+	}
+	return b.internalTxs, nil
+}
+
 func (b *Block) Number(ctx context.Context) (hexutil.Uint64, error) {
 	header, err := b.resolveHeader(ctx)
 	if err != nil {
@@ -924,6 +1064,37 @@ func (b *Block) Transactions(ctx context.Context) (*[]*Transaction, error) {
 	return &ret, nil
 }
 
+func (b *Block) InternalTransactions(ctx context.Context) (*[]*InternalTransaction, error) {
+	block, err := b.resolve(ctx)
+	if err != nil || block == nil {
+		return nil, err
+	}
+	txs, err := b.resolveInternalTxs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	indexes := make(map[common.Hash]int, len(block.Transactions()))
+	for i, tx := range block.Transactions() {
+		indexes[tx.Hash()] = i
+	}
+	ret := make([]*InternalTransaction, 0)
+	for _, itx := range txs {
+		i, ok := indexes[itx.TxHash]
+		if !ok {
+			continue
+		}
+		t := &Transaction{
+			r:     b.r,
+			hash:  itx.TxHash,
+			tx:    block.Transactions()[i],
+			block: b,
+			index: uint64(i),
+		}
+		ret = append(ret, t.internalTransactions(itx)...)
+	}
+	return &ret, nil
+}
+
 func (b *Block) TransactionAt(ctx context.Context, args struct{ Index Long }) (*Transaction, error) {
 	block, err := b.resolve(ctx)
 	if err != nil || block == nil {
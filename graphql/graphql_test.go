@@ -372,6 +372,86 @@ func TestGraphQLConcurrentResolvers(t *testing.T) {
 	}
 }
 
+func TestGraphQLInternalTransactions(t *testing.T) {
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		funds   = big.NewInt(1000000000000000)
+		dad     = common.HexToAddress("0x0000000000000000000000000000000000000dad")
+		relay   = common.HexToAddress("0x0000000000000000000000000000000000000ba1")
+	)
+	stack := createNode(t)
+	defer stack.Close()
+	genesis := &core.Genesis{
+		Config:     params.AllEthashProtocolChanges,
+		GasLimit:   11500000,
+		Difficulty: big.NewInt(1048576),
+		Alloc: core.GenesisAlloc{
+			address: {Balance: funds},
+			// The address 0xba1 sends its call value to 0xdad
+			relay: {
+				Code:    common.FromHex("0x600060006000600034610dad5af100"),
+				Nonce:   0,
+				Balance: big.NewInt(0),
+			},
+		},
+		BaseFee: big.NewInt(params.InitialBaseFee),
+	}
+	signer := types.LatestSigner(genesis.Config)
+	tx, _ := types.SignNewTx(key, signer, &types.LegacyTx{
+		Nonce:    uint64(0),
+		To:       &relay,
+		Value:    big.NewInt(100),
+		Gas:      100000,
+		GasPrice: big.NewInt(params.InitialBaseFee),
+	})
+	newGQLService(t, stack, genesis, 1, func(i int, gen *core.BlockGen) {
+		gen.AddTx(tx)
+	})
+	// start node
+	if err := stack.Start(); err != nil {
+		t.Fatalf("could not start node: %v", err)
+	}
+
+	var (
+		hash   = tx.Hash().Hex()
+		fields = `internalTransactions { transaction { hash } from { address } to { address } value opcode depth traceAddress success }`
+		itxs   = fmt.Sprintf(`[{"transaction":{"hash":"%s"},"from":{"address":"%s"},"to":{"address":"%s"},"value":"0x64","opcode":"CALL","depth":null,"traceAddress":[],"success":true},`+
+			`{"transaction":{"hash":"%s"},"from":{"address":"%s"},"to":{"address":"%s"},"value":"0x64","opcode":"CALL","depth":"0x0","traceAddress":["0x0"],"success":true}]`,
+			hash, strings.ToLower(address.Hex()), strings.ToLower(relay.Hex()), hash, strings.ToLower(relay.Hex()), strings.ToLower(dad.Hex()))
+	)
+	for i, tt := range []struct {
+		body string
+		want string
+	}{
+		{
+			body: fmt.Sprintf(`{"query": "{block { %s }}"}`, fields),
+			want: fmt.Sprintf(`{"data":{"block":{"internalTransactions":%s}}}`, itxs),
+		},
+		{
+			body: fmt.Sprintf(`{"query": "{transaction(hash: \"%s\") { %s }}"}`, hash, fields),
+			want: fmt.Sprintf(`{"data":{"transaction":{"internalTransactions":%s}}}`, itxs),
+		},
+		{
+			body: fmt.Sprintf(`{"query": "{block(number: 0) { %s }}"}`, fields),
+			want: `{"data":{"block":{"internalTransactions":[]}}}`,
+		},
+	} {
+		resp, err := http.Post(fmt.Sprintf("%s/graphql", stack.HTTPEndpoint()), "application/json", strings.NewReader(tt.body))
+		if err != nil {
+			t.Fatalf("could not post: %v", err)
+		}
+		bodyBytes, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("could not read from response body: %v", err)
+		}
+		if have := string(bodyBytes); have != tt.want {
+			t.Errorf("testcase %d %s,\nhave:\n%v\nwant:\n%v", i, tt.body, have, tt.want)
+		}
+	}
+}
+
 func createNode(t *testing.T) *node.Node {
 	stack, err := node.New(&node.Config{
 		HTTPHost:     "127.0.0.1",
@@ -71,6 +71,42 @@ const schema string = `
         transaction: Transaction!
     }
 
+    # InternalTransaction is a call, contract creation or self-destruct made
+    # while executing a transaction, including its top-level call.
+    type InternalTransaction {
+        # Transaction is the transaction this internal transaction was made in.
+        transaction: Transaction!
+        # From is the account making the call, creation or self-destruct.
+        from(block: Long): Account!
+        # To is the account called, created or receiving the balance of a
+        # self-destruct. This is null for failed contract creations.
+        to(block: Long): Account
+        # Value is the value, in wei, sent along with this internal transaction.
+        value: BigInt!
+        # Success is whether the internal transaction succeeded. It's false for
+        # all the internal transactions of a failed transaction.
+        success: Boolean!
+        # Opcode is the operation, CALL, CALLCODE, DELEGATECALL, STATICCALL,
+        # CREATE, CREATE2 or SELFDESTRUCT.
+        opcode: String!
+        # Depth is the call depth of the internal transaction. This is null for
+        # the top-level call, and for those made by the consensus engine.
+        depth: Long
+        # Gas is the amount of gas provided to the internal transaction.
+        gas: Long!
+        # GasUsed is the amount of gas used by the internal transaction.
+        gasUsed: Long!
+        # Input is the call data or the creation code, if recorded.
+        input: Bytes!
+        # Output is the return data, if recorded.
+        output: Bytes!
+        # TraceAddress is the position of the internal transaction in the call
+        # tree of the transaction.
+        traceAddress: [Long!]!
+        # Error is the error of a failed internal transaction.
+        error: String
+    }
+
     #EIP-2718
     type AccessTuple{
         address: Address!
@@ -136,6 +172,10 @@ const schema string = `
         # Logs is a list of log entries emitted by this transaction. If the
         # transaction has not yet been mined, this field will be null.
         logs: [Log!]
+        # InternalTransactions is a list of the calls, contract creations and
+        # self-destructs made by this transaction, starting with its top-level
+        # call. If the transaction has not yet been mined, this field will be null.
+        internalTransactions: [InternalTransaction!]
         r: BigInt!
         s: BigInt!
         v: BigInt!
@@ -238,6 +278,10 @@ const schema string = `
         transactionAt(index: Long!): Transaction
         # Logs returns a filtered set of logs from this block.
         logs(filter: BlockFilterCriteria!): [Log!]!
+        # InternalTransactions is a list of the internal transactions of the
+        # transactions in this block. If transactions are unavailable for this
+        # block, this field will be null.
+        internalTransactions: [InternalTransaction!]
         # Account fetches an Ethereum account at the current block's state.
         account(address: Address!): Account!
         # Call executes a local call operation at the current block's state.
@@ -39,6 +39,7 @@ import (
 	"github.com/ethereum/go-ethereum/consensus/misc"
 	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -82,6 +83,51 @@ func (s *EthereumAPI) MaxPriorityFeePerGas(ctx context.Context) (*hexutil.Big, e
 	return (*hexutil.Big)(tipcap), err
 }
 
+// feeSuggestion is a suggestion of the fees of both legacy and dynamic fee transactions.
+type feeSuggestion struct {
+	GasPrice             *hexutil.Big `json:"gasPrice"`
+	MaxPriorityFeePerGas *hexutil.Big `json:"maxPriorityFeePerGas,omitempty"`
+	MaxFeePerGas         *hexutil.Big `json:"maxFeePerGas,omitempty"`
+}
+
+type feeSuggestionsResult struct {
+	BaseFee    *hexutil.Big   `json:"baseFeePerGas,omitempty"`
+	Congestion int            `json:"congestion"`
+	Slow       *feeSuggestion `json:"slow"`
+	Standard   *feeSuggestion `json:"standard"`
+	Fast       *feeSuggestion `json:"fast"`
+}
+
+// FeeSuggestions returns the slow, standard and fast fee suggestions, with the
+// congestion index of the transaction pool they are raised by, if enabled. The
+// standard suggestion is the one of GasPrice and MaxPriorityFeePerGas.
+func (s *EthereumAPI) FeeSuggestions(ctx context.Context) (*feeSuggestionsResult, error) {
+	slow, standard, fast, err := s.b.SuggestGasTipCaps(ctx)
+	if err != nil {
+		return nil, err
+	}
+	head := s.b.CurrentHeader()
+	suggest := func(tipcap *big.Int) *feeSuggestion {
+		if head.BaseFee == nil {
+			return &feeSuggestion{GasPrice: (*hexutil.Big)(tipcap)}
+		}
+		// the fee cap follows the default of the transactions sent through the node
+		feecap := new(big.Int).Add(tipcap, new(big.Int).Mul(head.BaseFee, big.NewInt(2)))
+		return &feeSuggestion{
+			GasPrice:             (*hexutil.Big)(new(big.Int).Add(tipcap, head.BaseFee)),
+			MaxPriorityFeePerGas: (*hexutil.Big)(tipcap),
+			MaxFeePerGas:         (*hexutil.Big)(feecap),
+		}
+	}
+	return &feeSuggestionsResult{
+		BaseFee:    (*hexutil.Big)(head.BaseFee),
+		Congestion: s.b.CongestionRecord(),
+		Slow:       suggest(slow),
+		Standard:   suggest(standard),
+		Fast:       suggest(fast),
+	}, nil
+}
+
 type feeHistoryResult struct {
 	OldestBlock  *hexutil.Big     `json:"oldestBlock"`
 	Reward       [][]*hexutil.Big `json:"reward,omitempty"`
@@ -297,6 +343,59 @@ func (s *TxPoolAPI) CongestionRecord() int {
 	return s.b.CongestionRecord()
 }
 
+// congestionSample is an evaluation of the congestion of the transaction pool,
+// with its time as a unix timestamp and the ages of the pending txs in seconds.
+type congestionSample struct {
+	Time           uint64    `json:"time"`
+	Index          int       `json:"index"`
+	UnderPriced    int       `json:"underPriced"`
+	PendingPenalty int       `json:"pendingPenalty"`
+	SampleSize     int       `json:"sampleSize"`
+	Percentiles    []float64 `json:"percentiles"`
+}
+
+func newCongestionSample(sample txpool.CongestionSample) *congestionSample {
+	result := &congestionSample{
+		Index:          sample.Index,
+		UnderPriced:    sample.UnderPriced,
+		PendingPenalty: sample.PendingPenalty,
+		SampleSize:     sample.SampleSize,
+		Percentiles:    make([]float64, len(sample.Percentiles)),
+	}
+	if !sample.Time.IsZero() {
+		result.Time = uint64(sample.Time.Unix())
+	}
+	for i, age := range sample.Percentiles {
+		result.Percentiles[i] = age.Seconds()
+	}
+	return result
+}
+
+// CongestionStatus returns the last evaluation of the congestion of the
+// transaction pool with the components of its congestion index.
+func (s *TxPoolAPI) CongestionStatus() *congestionSample {
+	return newCongestionSample(s.b.CongestionStatus())
+}
+
+// CongestionHistory returns the evaluations of the congestion of the transaction
+// pool made between the given unix timestamps, up to now if to is omitted.
+func (s *TxPoolAPI) CongestionHistory(from uint64, to *uint64) ([]*congestionSample, error) {
+	end := time.Now()
+	if to != nil {
+		end = time.Unix(int64(*to), 0)
+	}
+	start := time.Unix(int64(from), 0)
+	if start.After(end) {
+		return nil, fmt.Errorf("invalid time range %d - %d", from, end.Unix())
+	}
+	samples := s.b.CongestionHistory(start, end)
+	result := make([]*congestionSample, len(samples))
+	for i, sample := range samples {
+		result[i] = newCongestionSample(sample)
+	}
+	return result, nil
+}
+
 // EthereumAccountAPI provides an API to access accounts managed by this node.
 // It offers only methods that can retrieve accounts.
 type EthereumAccountAPI struct {
@@ -1852,6 +1951,16 @@ func marshalReceipt(receipt *types.Receipt, blockHash common.Hash, blockNumber u
 	if receipt.Logs == nil {
 		fields["logs"] = []*types.Log{}
 	}
+	// The effective fee is paid by the sender, the fee is the part of it paid to
+	// the fee recipient, which collects it for the block reward on NPoS.
+	if receipt.EffectiveGasPrice != nil {
+		fee := new(big.Int).SetUint64(receipt.GasUsed)
+		fields["effectiveFee"] = (*hexutil.Big)(fee.Mul(fee, receipt.EffectiveGasPrice))
+	}
+	if receipt.Fee != nil {
+		fields["fee"] = (*hexutil.Big)(receipt.Fee)
+		fields["feeRecipient"] = receipt.FeeRecipient
+	}
 
 	// If the ContractAddress is 20 0x0 bytes, assume it is not a contract creation
 	if receipt.ContractAddress != (common.Address{}) {
@@ -6,11 +6,23 @@ import (
 	"math/big"
 
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+var (
+	// maxAddressInternalTxs is the maximum number of internal txs returned by a
+	// page of eth_getInternalTransactionsByAddress.
+	maxAddressInternalTxs = 1000
+
+	// maxAddressInternalTxsScan is the maximum number of blocks scanned by a page
+	// of eth_getInternalTransactionsByAddress beyond the address index.
+	maxAddressInternalTxsScan = 1024
+)
+
 // ActionFilter is the filter config for internal txs API. It holds one more
 // field to filters the result.
 type ActionFilter struct {
@@ -26,8 +38,11 @@ func (api *BlockChainAPI) GetInternalTxs(ctx context.Context, blockNrOrHash rpc.
 	if err != nil {
 		return nil, err
 	}
+	if block == nil {
+		return nil, fmt.Errorf("block %s not found", blockNrOrHash.String())
+	}
 
-	iTx, err := api.getInnerTx(block)
+	iTx, err := api.getInnerTx(block.Hash(), block.NumberU64())
 	if err != nil {
 		return nil, err
 	}
@@ -67,7 +82,7 @@ func (api *BlockChainAPI) GetInternalTxsByTxHash(ctx context.Context, hash commo
 		return nil, fmt.Errorf("block #%s not found", hash)
 	}
 
-	txs, err := api.getInnerTx(block)
+	txs, err := api.getInnerTx(block.Hash(), block.NumberU64())
 	if err != nil {
 		return nil, err
 	}
@@ -82,6 +97,55 @@ func (api *BlockChainAPI) GetInternalTxsByTxHash(ctx context.Context, hash commo
 	return nil, nil
 }
 
+// GetInternalTransactions returns the internal transactions of the given
+// transaction, that are the top-level call and the calls, creations and
+// self-destructs made by the contracts it executed. A transaction without
+// recorded internal transactions has no actions. The result is null if the
+// transaction is unknown or pending.
+func (api *BlockChainAPI) GetInternalTransactions(ctx context.Context, hash common.Hash) (*types.InternalTx, error) {
+	tx, blockHash, blockNumber, _, err := api.b.GetTransaction(ctx, hash)
+	if tx == nil || err != nil {
+		// When the transaction doesn't exist, the RPC method should return JSON null
+		// as per specification.
+		return nil, nil
+	}
+	txs, err := api.getInnerTx(blockHash, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	for _, t := range txs {
+		if t.TxHash == hash {
+			return t, nil
+		}
+	}
+	return &types.InternalTx{
+		TxHash:      hash,
+		BlockHash:   blockHash,
+		BlockNumber: new(big.Int).SetUint64(blockNumber),
+		Actions:     []*types.Action{},
+	}, nil
+}
+
+// GetBlockInternalTransactions returns the internal transactions of the
+// transactions in the given block, in the order of the transactions. The
+// result is null if the block is unknown.
+func (api *BlockChainAPI) GetBlockInternalTransactions(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (types.InternalTxs, error) {
+	block, err := api.b.BlockByNumberOrHash(ctx, blockNrOrHash)
+	if block == nil || err != nil {
+		// When the block doesn't exist, the RPC method should return JSON null
+		// as per specification.
+		return nil, nil
+	}
+	txs, err := api.getInnerTx(block.Hash(), block.NumberU64())
+	if err != nil {
+		return nil, err
+	}
+	if txs == nil {
+		txs = types.InternalTxs{}
+	}
+	return txs, nil
+}
+
 func (api *BlockChainAPI) filterAction(actions []*types.Action, filter *ActionFilter) []*types.Action {
 	if filter == nil {
 		return actions
@@ -112,14 +176,149 @@ func (api *BlockChainAPI) filterAction(actions []*types.Action, filter *ActionFi
 	return res
 }
 
-// getInnerTx returns internal txs
-func (api *BlockChainAPI) getInnerTx(block *types.Block) (types.InternalTxs, error) {
-	txs := rawdb.ReadInternalTxs(api.b.ChainDb(), block.Hash(), block.NumberU64())
+// getInnerTx returns internal txs of a block
+func (api *BlockChainAPI) getInnerTx(hash common.Hash, number uint64) (types.InternalTxs, error) {
+	txs := rawdb.ReadInternalTxs(api.b.ChainDb(), hash, number)
 
 	for _, tx := range txs {
-		tx.BlockHash = block.Hash()
-		tx.BlockNumber = block.Number()
+		tx.BlockHash = hash
+		tx.BlockNumber = new(big.Int).SetUint64(number)
 	}
 
 	return txs, nil
 }
+
+// AddressInternalTx is an internal value transfer sent or received by an address.
+type AddressInternalTx struct {
+	BlockHash   common.Hash    `json:"blockHash"`
+	BlockNumber hexutil.Uint64 `json:"blockNumber"`
+	TxHash      common.Hash    `json:"transactionHash"`
+	Action      *types.Action  `json:"action"`
+}
+
+// AddressInternalTxsPage is a page of the internal value transfers of an address.
+type AddressInternalTxsPage struct {
+	InternalTxs []*AddressInternalTx `json:"internalTransactions"`
+	Cursor      *hexutil.Bytes       `json:"cursor"` // Position to continue from, nil once the range is done
+}
+
+// GetInternalTransactionsByAddress returns the internal value transfers sent or
+// received by an address in the canonical blocks of the range, in the order
+// of the chain. The results are paginated, a page with a cursor is continued
+// by calling the method with the cursor and the same range again.
+//
+// The transfers are looked up by the internal tx address index if the node
+// maintains it, and the blocks not indexed yet are scanned.
+func (api *BlockChainAPI) GetInternalTransactionsByAddress(ctx context.Context, address common.Address, fromBlock, toBlock rpc.BlockNumber, cursor *hexutil.Bytes) (*AddressInternalTxsPage, error) {
+	from, err := api.resolveBlockNumber(ctx, fromBlock)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.resolveBlockNumber(ctx, toBlock)
+	if err != nil {
+		return nil, err
+	}
+	if from > to {
+		return nil, fmt.Errorf("invalid block range %d - %d", from, to)
+	}
+	pos := rawdb.InternalTxPosition{Number: from}
+	if cursor != nil {
+		if pos, err = rawdb.ParseInternalTxPosition(*cursor); err != nil {
+			return nil, err
+		}
+		if pos.Number < from || pos.Number > to {
+			return nil, fmt.Errorf("cursor out of block range %d - %d", from, to)
+		}
+	}
+	var (
+		db      = api.b.ChainDb()
+		page    = &AddressInternalTxsPage{InternalTxs: make([]*AddressInternalTx, 0)}
+		blocks  = make(map[common.Hash]types.InternalTxs)
+		size, n = api.b.InternalTxIndexStatus()
+		indexed = size * n // blocks below are indexed
+	)
+	// add appends the action at pos, and returns whether the page is full
+	add := func(pos rawdb.InternalTxPosition, hash common.Hash, tx *types.InternalTx) bool {
+		page.InternalTxs = append(page.InternalTxs, &AddressInternalTx{
+			BlockHash:   hash,
+			BlockNumber: hexutil.Uint64(pos.Number),
+			TxHash:      tx.TxHash,
+			Action:      tx.Actions[pos.ActionIndex],
+		})
+		if len(page.InternalTxs) < maxAddressInternalTxs {
+			return false
+		}
+		next := rawdb.InternalTxPosition{Number: pos.Number, TxIndex: pos.TxIndex, ActionIndex: pos.ActionIndex + 1}.Bytes()
+		page.Cursor = (*hexutil.Bytes)(&next)
+		return true
+	}
+	if pos.Number < indexed {
+		end := indexed
+		if to < end {
+			end = to + 1
+		}
+		var full bool
+		err := rawdb.IterateInternalTxAddrIndex(db, address, pos, func(p rawdb.InternalTxPosition, hash common.Hash) bool {
+			if p.Number >= end {
+				return false
+			}
+			// skip the blocks reorged after they were indexed
+			if rawdb.ReadCanonicalHash(db, p.Number) != hash {
+				return true
+			}
+			txs, ok := blocks[hash]
+			if !ok {
+				txs = rawdb.ReadInternalTxs(db, hash, p.Number)
+				blocks[hash] = txs
+			}
+			if int(p.TxIndex) >= len(txs) || int(p.ActionIndex) >= len(txs[p.TxIndex].Actions) {
+				return true
+			}
+			full = add(p, hash, txs[p.TxIndex])
+			return !full
+		})
+		if err != nil || full {
+			return page, err
+		}
+		pos = rawdb.InternalTxPosition{Number: end}
+	}
+	// scan the blocks not indexed yet
+	for scanned := 0; pos.Number <= to; scanned++ {
+		if scanned == maxAddressInternalTxsScan {
+			next := pos.Bytes()
+			page.Cursor = (*hexutil.Bytes)(&next)
+			return page, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		hash := rawdb.ReadCanonicalHash(db, pos.Number)
+		txs := rawdb.ReadInternalTxs(db, hash, pos.Number)
+		for i := int(pos.TxIndex); i < len(txs); i++ {
+			for j := int(pos.ActionIndex); j < len(txs[i].Actions); j++ {
+				action := txs[i].Actions[j]
+				if !core.IsValueTransferAction(action) || (action.From != address && action.To != address) {
+					continue
+				}
+				if add(rawdb.InternalTxPosition{Number: pos.Number, TxIndex: uint32(i), ActionIndex: uint32(j)}, hash, txs[i]) {
+					return page, nil
+				}
+			}
+			pos.ActionIndex = 0
+		}
+		pos = rawdb.InternalTxPosition{Number: pos.Number + 1}
+	}
+	return page, nil
+}
+
+// resolveBlockNumber returns the number of the block with the given number or tag.
+func (api *BlockChainAPI) resolveBlockNumber(ctx context.Context, number rpc.BlockNumber) (uint64, error) {
+	header, err := api.b.HeaderByNumber(ctx, number)
+	if err != nil {
+		return 0, err
+	}
+	if header == nil {
+		return 0, fmt.Errorf("block #%d not found", number)
+	}
+	return header.Number.Uint64(), nil
+}
@@ -37,6 +37,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/crypto"
@@ -183,12 +184,23 @@ func allTransactionTypes(addr common.Address, config *params.ChainConfig) []type
 type testBackend struct {
 	db    ethdb.Database
 	chain *core.BlockChain
+
+	internalTxIndexSize     uint64 // section size of the internal tx address index
+	internalTxIndexSections uint64 // sections indexed by the internal tx address index
 }
 
 func (b testBackend) CongestionRecord() int {
 	return 0
 }
 
+func (b testBackend) CongestionStatus() txpool.CongestionSample {
+	return txpool.CongestionSample{}
+}
+
+func (b testBackend) CongestionHistory(from, to time.Time) []txpool.CongestionSample {
+	return nil
+}
+
 func newTestBackend(t *testing.T, n int, gspec *core.Genesis, generator func(i int, b *core.BlockGen)) *testBackend {
 	var (
 		engine  = ethash.NewFaker()
@@ -220,6 +232,9 @@ func (b testBackend) SyncProgress() ethereum.SyncProgress { return ethereum.Sync
 func (b testBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error) {
 	return big.NewInt(0), nil
 }
+func (b testBackend) SuggestGasTipCaps(ctx context.Context) (*big.Int, *big.Int, *big.Int, error) {
+	return big.NewInt(0), big.NewInt(0), big.NewInt(0), nil
+}
 func (b testBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (*big.Int, [][]*big.Int, []*big.Int, []float64, error) {
 	return nil, nil, nil, nil, nil
 }
@@ -253,13 +268,16 @@ func (b testBackend) BlockByNumber(ctx context.Context, number rpc.BlockNumber)
 	return b.chain.GetBlockByNumber(uint64(number)), nil
 }
 func (b testBackend) BlockByHash(ctx context.Context, hash common.Hash) (*types.Block, error) {
-	panic("implement me")
+	return b.chain.GetBlockByHash(hash), nil
 }
 func (b testBackend) BlockByNumberOrHash(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (*types.Block, error) {
 	if blockNr, ok := blockNrOrHash.Number(); ok {
 		return b.BlockByNumber(ctx, blockNr)
 	}
-	panic("implement me")
+	if blockHash, ok := blockNrOrHash.Hash(); ok {
+		return b.BlockByHash(ctx, blockHash)
+	}
+	panic("unknown type rpc.BlockNumberOrHash")
 }
 func (b testBackend) GetBody(ctx context.Context, hash common.Hash, number rpc.BlockNumber) (*types.Body, error) {
 	return b.chain.GetBlock(hash, uint64(number.Int64())).Body(), nil
@@ -314,7 +332,8 @@ func (b testBackend) SendTx(ctx context.Context, signedTx *types.Transaction) er
 	panic("implement me")
 }
 func (b testBackend) GetTransaction(ctx context.Context, txHash common.Hash) (*types.Transaction, common.Hash, uint64, uint64, error) {
-	panic("implement me")
+	tx, blockHash, blockNumber, index := rawdb.ReadTransaction(b.db, txHash)
+	return tx, blockHash, blockNumber, index, nil
 }
 func (b testBackend) GetPoolTransactions() (types.Transactions, error)         { panic("implement me") }
 func (b testBackend) GetPoolTransaction(txHash common.Hash) *types.Transaction { panic("implement me") }
@@ -345,7 +364,13 @@ func (b testBackend) SubscribeLogsEvent(ch chan<- []*types.Log) event.Subscripti
 func (b testBackend) SubscribePendingLogsEvent(ch chan<- []*types.Log) event.Subscription {
 	panic("implement me")
 }
+func (b testBackend) SubscribeInternalTxsEvent(ch chan<- core.InternalTxsEvent) event.Subscription {
+	panic("implement me")
+}
 func (b testBackend) BloomStatus() (uint64, uint64) { panic("implement me") }
+func (b testBackend) InternalTxIndexStatus() (uint64, uint64) {
+	return b.internalTxIndexSize, b.internalTxIndexSections
+}
 func (b testBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	panic("implement me")
 }
@@ -858,3 +883,183 @@ func TestRPCMarshalBlock(t *testing.T) {
 		assert.JSONEqf(t, tc.want, string(out), "test %d", i)
 	}
 }
+
+func TestRPCGetInternalTransactions(t *testing.T) {
+	t.Parallel()
+	var (
+		accounts = newAccounts(1)
+		dad      = common.HexToAddress("0x0000000000000000000000000000000000000dad")
+		relay    = common.HexToAddress("0x0000000000000000000000000000000000000ba1")
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				// The address 0xba1 sends its call value to 0xdad
+				relay: {Code: common.FromHex("0x600060006000600034610dad5af100")},
+			},
+		}
+		signer = types.HomesteadSigner{}
+		txs    []*types.Transaction
+	)
+	backend := newTestBackend(t, 2, genesis, func(i int, b *core.BlockGen) {
+		if i == 1 {
+			tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: b.TxNonce(accounts[0].addr), To: &relay, Value: big.NewInt(1000), Gas: 100000, GasPrice: b.BaseFee()}), signer, accounts[0].key)
+			b.AddTx(tx)
+			txs = append(txs, tx)
+			tx, _ = types.SignTx(types.NewTx(&types.LegacyTx{Nonce: b.TxNonce(accounts[0].addr), To: &dad, Value: big.NewInt(1000), Gas: params.TxGas, GasPrice: b.BaseFee()}), signer, accounts[0].key)
+			b.AddTx(tx)
+			txs = append(txs, tx)
+		}
+	})
+	api := NewBlockChainAPI(backend)
+	block := backend.chain.GetBlockByNumber(2)
+
+	// a contract call has the top-level call and the internal call
+	itx, err := api.GetInternalTransactions(context.Background(), txs[0].Hash())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if itx.TxHash != txs[0].Hash() || itx.BlockHash != block.Hash() || itx.BlockNumber.Cmp(block.Number()) != 0 {
+		t.Fatalf("internal transactions mismatch: tx %x, block %x #%v", itx.TxHash, itx.BlockHash, itx.BlockNumber)
+	}
+	if len(itx.Actions) != 2 {
+		t.Fatalf("actions mismatch: have %d, want 2", len(itx.Actions))
+	}
+	if act := itx.Actions[1]; act.OpCode != "CALL" || act.From != relay || act.To != dad || act.Value.Cmp(big.NewInt(1000)) != 0 || !act.Success {
+		t.Fatalf("internal call mismatch: %+v", act)
+	}
+	// the result is decoded back by the clients
+	blob, err := json.Marshal(itx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded := new(types.InternalTx)
+	if err := json.Unmarshal(blob, decoded); err != nil {
+		t.Fatalf("failed to decode internal transactions: %v", err)
+	}
+	if reblob, _ := json.Marshal(decoded); string(reblob) != string(blob) {
+		t.Fatalf("decoded internal transactions mismatch: have %s, want %s", reblob, blob)
+	}
+	// a plain transfer has the top-level call
+	if itx, err = api.GetInternalTransactions(context.Background(), txs[1].Hash()); err != nil || len(itx.Actions) != 1 {
+		t.Fatalf("transfer mismatch: %v, err %v", itx, err)
+	}
+	// unknown transactions and blocks are null
+	if itx, err = api.GetInternalTransactions(context.Background(), common.Hash{0x01}); itx != nil || err != nil {
+		t.Fatalf("unknown transaction mismatch: %v, err %v", itx, err)
+	}
+	blockTxs, err := api.GetBlockInternalTransactions(context.Background(), rpc.BlockNumberOrHashWithNumber(100))
+	if blockTxs != nil || err != nil {
+		t.Fatalf("unknown block mismatch: %v, err %v", blockTxs, err)
+	}
+	// the block has the internal transactions of its transactions
+	blockTxs, err = api.GetBlockInternalTransactions(context.Background(), rpc.BlockNumberOrHashWithHash(block.Hash(), false))
+	if err != nil || len(blockTxs) != 2 || blockTxs[0].TxHash != txs[0].Hash() || blockTxs[1].TxHash != txs[1].Hash() {
+		t.Fatalf("block internal transactions mismatch: %v, err %v", blockTxs, err)
+	}
+	blockTxs, err = api.GetBlockInternalTransactions(context.Background(), rpc.BlockNumberOrHashWithNumber(1))
+	if err != nil || blockTxs == nil || len(blockTxs) != 0 {
+		t.Fatalf("empty block mismatch: %v, err %v", blockTxs, err)
+	}
+}
+
+func TestRPCGetInternalTransactionsByAddress(t *testing.T) {
+	var (
+		accounts = newAccounts(1)
+		dad      = common.HexToAddress("0x0000000000000000000000000000000000000dad")
+		relay    = common.HexToAddress("0x0000000000000000000000000000000000000ba1")
+		genesis  = &core.Genesis{
+			Config: params.TestChainConfig,
+			Alloc: core.GenesisAlloc{
+				accounts[0].addr: {Balance: big.NewInt(params.Ether)},
+				// The address 0xba1 sends its call value to 0xdad
+				relay: {Code: common.FromHex("0x600060006000600034610dad5af100")},
+			},
+		}
+		signer = types.HomesteadSigner{}
+	)
+	// every block relays a transfer to 0xdad, and transfers to it directly
+	backend := newTestBackend(t, 6, genesis, func(i int, b *core.BlockGen) {
+		for _, to := range []common.Address{relay, dad} {
+			tx, _ := types.SignTx(types.NewTx(&types.LegacyTx{Nonce: b.TxNonce(accounts[0].addr), To: &to, Value: big.NewInt(int64(i + 1)), Gas: 100000, GasPrice: b.BaseFee()}), signer, accounts[0].key)
+			b.AddTx(tx)
+		}
+	})
+	// index the blocks up to #3 in two sections, the later blocks are scanned
+	indexer := core.NewInternalTxIndexer(backend.db, 2, 2)
+	defer indexer.Close()
+	indexer.Start(backend.chain)
+	for start := time.Now(); ; time.Sleep(10 * time.Millisecond) {
+		if sections, _, _ := indexer.Sections(); sections == 2 {
+			break
+		}
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("internal tx address index not built")
+		}
+	}
+	// a stale entry of a reorged block is skipped
+	rawdb.WriteInternalTxAddrIndex(backend.db, dad, rawdb.InternalTxPosition{Number: 2, TxIndex: 1}, common.Hash{0x01})
+	backend.internalTxIndexSize, backend.internalTxIndexSections = 2, 2
+	api := NewBlockChainAPI(backend)
+
+	// fetch collects the transfers of an address page by page
+	fetch := func(address common.Address, from, to rpc.BlockNumber) ([]uint64, int) {
+		var (
+			values []uint64
+			pages  int
+			cursor *hexutil.Bytes
+		)
+		for {
+			page, err := api.GetInternalTransactionsByAddress(context.Background(), address, from, to, cursor)
+			if err != nil {
+				t.Fatalf("failed to get internal transactions: %v", err)
+			}
+			pages++
+			for _, itx := range page.InternalTxs {
+				if itx.Action.From != relay || itx.Action.To != dad {
+					t.Fatalf("unexpected transfer %+v", itx.Action)
+				}
+				block := backend.chain.GetBlockByNumber(uint64(itx.BlockNumber))
+				if itx.BlockHash != block.Hash() || itx.TxHash != block.Transactions()[0].Hash() {
+					t.Fatalf("transfer position mismatch: block %x, tx %x", itx.BlockHash, itx.TxHash)
+				}
+				values = append(values, itx.Action.Value.Uint64())
+			}
+			if page.Cursor == nil {
+				return values, pages
+			}
+			cursor = page.Cursor
+		}
+	}
+	for i, tt := range []struct {
+		address  common.Address
+		from, to rpc.BlockNumber
+		pageSize int
+		scan     int
+		values   []uint64
+		pages    int
+	}{
+		{dad, 0, rpc.LatestBlockNumber, 1000, 1024, []uint64{1, 2, 3, 4, 5, 6}, 1},
+		{relay, 0, rpc.LatestBlockNumber, 1000, 1024, []uint64{1, 2, 3, 4, 5, 6}, 1},
+		{accounts[0].addr, 0, rpc.LatestBlockNumber, 1000, 1024, nil, 1},
+		{dad, 2, 5, 1000, 1024, []uint64{2, 3, 4, 5}, 1},
+		// the pages are limited by the number of transfers and scanned blocks
+		{dad, 0, rpc.LatestBlockNumber, 2, 1024, []uint64{1, 2, 3, 4, 5, 6}, 4},
+		{dad, 0, rpc.LatestBlockNumber, 1000, 1, []uint64{1, 2, 3, 4, 5, 6}, 3},
+	} {
+		maxAddressInternalTxs, maxAddressInternalTxsScan = tt.pageSize, tt.scan
+		values, pages := fetch(tt.address, tt.from, tt.to)
+		if !reflect.DeepEqual(values, tt.values) || pages != tt.pages {
+			t.Errorf("test %d: have %v in %d pages, want %v in %d pages", i, values, pages, tt.values, tt.pages)
+		}
+	}
+	maxAddressInternalTxs, maxAddressInternalTxsScan = 1000, 1024
+
+	if _, err := api.GetInternalTransactionsByAddress(context.Background(), dad, 4, 2, nil); err == nil {
+		t.Error("expected error for an invalid range")
+	}
+	cursor := hexutil.Bytes(rawdb.InternalTxPosition{Number: 6}.Bytes())
+	if _, err := api.GetInternalTransactionsByAddress(context.Background(), dad, 0, 3, &cursor); err == nil {
+		t.Error("expected error for a cursor out of range")
+	}
+}
@@ -116,6 +116,90 @@ web3._extend({
 			call: 'npos_getValidatorsAtHash',
 			params: 1
 		}),
+		new web3._extend.Method({
+			name: 'getValidatorInfo',
+			call: 'npos_getValidatorInfo',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getPendingReward',
+			call: 'npos_getPendingReward',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getBackupValidators',
+			call: 'npos_getBackupValidators',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getMissedBlocks',
+			call: 'npos_getMissedBlocks',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter],
+			outputFormatter: web3._extend.utils.toDecimal
+		}),
+		new web3._extend.Method({
+			name: 'getJailStatus',
+			call: 'npos_getJailStatus',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getVotePool',
+			call: 'npos_getVotePool',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'status',
+			call: 'npos_status',
+			params: 1,
+			inputFormatter: [web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'getBlockRewardBreakdown',
+			call: 'npos_getBlockRewardBreakdown',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getPerformance',
+			call: 'npos_getPerformance',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'getEpochPerformance',
+			call: 'npos_getEpochPerformance',
+			params: 2,
+			inputFormatter: [web3._extend.utils.fromDecimal, web3._extend.utils.fromDecimal]
+		}),
+		new web3._extend.Method({
+			name: 'simulateProposal',
+			call: 'npos_simulateProposal',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getBlacklist',
+			call: 'npos_getBlacklist',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'isAddressBanned',
+			call: 'npos_isAddressBanned',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'rebuildAddressListIndex',
+			call: 'npos_rebuildAddressListIndex',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter]
+		}),
 	]
 });
 `
@@ -654,6 +738,11 @@ web3._extend({
 			params: 3,
 			inputFormatter: [null, web3._extend.formatters.inputBlockNumberFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'feeSuggestions',
+			call: 'eth_feeSuggestions',
+			params: 0,
+		}),
 		new web3._extend.Method({
 			name: 'getLogs',
 			call: 'eth_getLogs',
@@ -674,6 +763,22 @@ web3._extend({
 			call: 'eth_getInternalTxsByTxHash',
 			params: 2,
 		}),
+		new web3._extend.Method({
+			name: 'getInternalTransactions',
+			call: 'eth_getInternalTransactions',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'getBlockInternalTransactions',
+			call: 'eth_getBlockInternalTransactions',
+			params: 1,
+		}),
+		new web3._extend.Method({
+			name: 'getInternalTransactionsByAddress',
+			call: 'eth_getInternalTransactionsByAddress',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputBlockNumberFormatter, web3._extend.formatters.inputBlockNumberFormatter, null]
+		}),
 		new web3._extend.Method({
 			name: 'call',
 			call: 'eth_call',
@@ -837,7 +942,14 @@ web3._extend({
 const TxpoolJs = `
 web3._extend({
 	property: 'txpool',
-	methods: [],
+	methods: [
+		new web3._extend.Method({
+			name: 'congestionHistory',
+			call: 'txpool_congestionHistory',
+			params: 2,
+			inputFormatter: [null, null]
+		}),
+	],
 	properties:
 	[
 		new web3._extend.Property({
@@ -866,6 +978,10 @@ web3._extend({
 			name: 'congestionRecord',
 			getter: 'txpool_congestionRecord'
 		}),
+		new web3._extend.Property({
+			name: 'congestionStatus',
+			getter: 'txpool_congestionStatus'
+		}),
 	]
 });
 `
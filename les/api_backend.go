@@ -31,6 +31,7 @@ import (
 	"github.com/ethereum/go-ethereum/core/bloombits"
 	"github.com/ethereum/go-ethereum/core/rawdb"
 	"github.com/ethereum/go-ethereum/core/state"
+	"github.com/ethereum/go-ethereum/core/txpool"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/core/vm"
 	"github.com/ethereum/go-ethereum/eth/gasprice"
@@ -205,7 +206,11 @@ func (b *LesApiBackend) GetEVM(ctx context.Context, msg *core.Message, state *st
 			// make sure to use parent state to avoid mix up inner cache
 			parent := b.eth.blockchain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
 			parentState := light.NewState(ctx, parent, b.eth.odr)
-			context.ExtraValidator = posa.CreateEvmExtraValidator(header, parentState)
+			validator, err := posa.CreateEvmExtraValidator(header, parentState)
+			if err != nil {
+				return vm.NewEVM(context, txContext, state, b.eth.chainConfig, *vmConfig), func() error { return err }
+			}
+			context.ExtraValidator = validator
 		}
 	}
 	return vm.NewEVM(context, txContext, state, b.eth.chainConfig, *vmConfig), state.Error
@@ -251,6 +256,14 @@ func (b *LesApiBackend) CongestionRecord() int {
 	return 0 // not implement
 }
 
+func (b *LesApiBackend) CongestionStatus() txpool.CongestionSample {
+	return txpool.CongestionSample{} // not implement
+}
+
+func (b *LesApiBackend) CongestionHistory(from, to time.Time) []txpool.CongestionSample {
+	return nil // not implement
+}
+
 func (b *LesApiBackend) SubscribeNewTxsEvent(ch chan<- core.NewTxsEvent) event.Subscription {
 	return b.eth.txPool.SubscribeNewTxsEvent(ch)
 }
@@ -282,6 +295,13 @@ func (b *LesApiBackend) SubscribeRemovedLogsEvent(ch chan<- core.RemovedLogsEven
 	return b.eth.blockchain.SubscribeRemovedLogsEvent(ch)
 }
 
+func (b *LesApiBackend) SubscribeInternalTxsEvent(ch chan<- core.InternalTxsEvent) event.Subscription {
+	return event.NewSubscription(func(quit <-chan struct{}) error {
+		<-quit
+		return nil
+	})
+}
+
 func (b *LesApiBackend) SyncProgress() ethereum.SyncProgress {
 	return b.eth.Downloader().Progress()
 }
@@ -294,6 +314,10 @@ func (b *LesApiBackend) SuggestGasTipCap(ctx context.Context) (*big.Int, error)
 	return b.gpo.SuggestTipCap(ctx)
 }
 
+func (b *LesApiBackend) SuggestGasTipCaps(ctx context.Context) (*big.Int, *big.Int, *big.Int, error) {
+	return b.gpo.SuggestTipCaps(ctx)
+}
+
 func (b *LesApiBackend) FeeHistory(ctx context.Context, blockCount uint64, lastBlock rpc.BlockNumber, rewardPercentiles []float64) (firstBlock *big.Int, reward [][]*big.Int, baseFee []*big.Int, gasUsedRatio []float64, err error) {
 	return b.gpo.FeeHistory(ctx, blockCount, lastBlock, rewardPercentiles)
 }
@@ -334,6 +358,10 @@ func (b *LesApiBackend) BloomStatus() (uint64, uint64) {
 	return params.BloomBitsBlocksClient, sections
 }
 
+func (b *LesApiBackend) InternalTxIndexStatus() (uint64, uint64) {
+	return params.InternalTxIndexBlocks, 0
+}
+
 func (b *LesApiBackend) ServiceFilter(ctx context.Context, session *bloombits.MatcherSession) {
 	for i := 0; i < bloomFilterThreads; i++ {
 		go session.Multiplex(bloomRetrievalBatch, bloomRetrievalWait, b.eth.bloomRequests)
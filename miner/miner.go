@@ -56,6 +56,11 @@ type Config struct {
 	Noverify   bool           // Disable remote mining solution verification(only useful in ethash).
 
 	NewPayloadTimeout time.Duration // The maximum time allowance for creating a new payload
+
+	TxOrdering      string           `toml:",omitempty"` // Transaction ordering policy of the mined blocks ("price" or "fifo")
+	SenderTxCap     int              `toml:",omitempty"` // Maximum number of transactions of a sender in a block (0 = no cap)
+	ReservedGas     uint64           `toml:",omitempty"` // Percentage of the block gas reserved for the local and priority senders
+	PrioritySenders []common.Address `toml:",omitempty"` // Senders sharing the reserved block space with the local accounts
 }
 
 // DefaultConfig contains default settings for miner.
@@ -69,6 +74,8 @@ var DefaultConfig = Config{
 	// run 3 rounds.
 	Recommit:          2 * time.Second,
 	NewPayloadTimeout: 2 * time.Second,
+
+	TxOrdering: OrderingPrice,
 }
 
 // Miner creates blocks and searches for proof-of-work values.
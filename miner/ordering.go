@@ -0,0 +1,216 @@
+package miner
+
+import (
+	"bytes"
+	"container/heap"
+	"math/big"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// Transaction ordering policies of the sealed blocks.
+const (
+	OrderingPrice = "price" // by effective miner tip, then arrival time
+	OrderingFIFO  = "fifo"  // strictly by arrival time, regardless of the price
+)
+
+// orderedTransactions is a set of pending transactions retrieved in the order
+// they are committed to a block, honouring the nonces of every sender.
+type orderedTransactions interface {
+	// Peek returns the next transaction, nil if the set is exhausted.
+	Peek() *types.Transaction
+
+	// Shift replaces the next transaction with the following one of its sender.
+	Shift()
+
+	// Pop removes the next transaction along with the rest of its sender.
+	Pop()
+}
+
+// txOrdering is the transaction selection policy of the worker. The pending
+// transactions are split into two lanes: the local and priority senders are
+// committed first, and the others can't use the block gas reserved for them.
+// Within a lane the transactions are ordered by price or arrival time, and
+// the transactions of a sender in a block can be capped.
+type txOrdering struct {
+	policy     string                      // ordering policy within a lane
+	senderCap  int                         // maximum transactions of a sender in a block, 0 for no cap
+	reserved   uint64                      // percentage of the block gas reserved for the priority lane
+	priorities map[common.Address]struct{} // senders of the priority lane besides the local accounts
+}
+
+// newTxOrdering creates the transaction ordering of the given config,
+// sanitizing the invalid settings.
+func newTxOrdering(config *Config) *txOrdering {
+	ordering := &txOrdering{
+		policy:     config.TxOrdering,
+		senderCap:  config.SenderTxCap,
+		reserved:   config.ReservedGas,
+		priorities: make(map[common.Address]struct{}, len(config.PrioritySenders)),
+	}
+	switch ordering.policy {
+	case OrderingPrice, OrderingFIFO:
+	case "":
+		ordering.policy = OrderingPrice
+	default:
+		log.Warn("Sanitizing invalid miner transaction ordering", "provided", ordering.policy, "updated", OrderingPrice)
+		ordering.policy = OrderingPrice
+	}
+	if ordering.senderCap < 0 {
+		log.Warn("Sanitizing invalid miner sender transaction cap", "provided", ordering.senderCap, "updated", 0)
+		ordering.senderCap = 0
+	}
+	if ordering.reserved > 100 {
+		log.Warn("Sanitizing invalid miner reserved gas", "provided", ordering.reserved, "updated", 100)
+		ordering.reserved = 100
+	}
+	for _, sender := range config.PrioritySenders {
+		ordering.priorities[sender] = struct{}{}
+	}
+	return ordering
+}
+
+// split moves the pending transactions of the local and priority senders into
+// the priority lane, and returns the lanes.
+func (o *txOrdering) split(pending map[common.Address]types.Transactions, locals []common.Address) (map[common.Address]types.Transactions, map[common.Address]types.Transactions) {
+	priority, others := make(map[common.Address]types.Transactions), pending
+	for _, account := range locals {
+		if txs := others[account]; len(txs) > 0 {
+			delete(others, account)
+			priority[account] = txs
+		}
+	}
+	for account := range o.priorities {
+		if txs := others[account]; len(txs) > 0 {
+			delete(others, account)
+			priority[account] = txs
+		}
+	}
+	return priority, others
+}
+
+// reservedGas returns the gas of the block reserved for the priority lane.
+func (o *txOrdering) reservedGas(header *types.Header) uint64 {
+	return header.GasLimit / 100 * o.reserved
+}
+
+// order returns the transactions of a lane in the order of the policy. The
+// transactions of a sender are capped by the ones it already has in the block.
+//
+// Note, the input map is reowned so the caller should not interact any more with
+// it after providing it.
+func (o *txOrdering) order(env *environment, txs map[common.Address]types.Transactions) orderedTransactions {
+	if o.senderCap > 0 {
+		included := make(map[common.Address]int)
+		for _, tx := range env.txs {
+			from, _ := types.Sender(env.signer, tx)
+			included[from]++
+		}
+		for from, accTxs := range txs {
+			left := o.senderCap - included[from]
+			if left <= 0 {
+				delete(txs, from)
+				continue
+			}
+			if len(accTxs) > left {
+				txs[from] = accTxs[:left]
+			}
+		}
+	}
+	if o.policy == OrderingFIFO {
+		return newTransactionsByTimeAndNonce(env.signer, txs, env.header.BaseFee)
+	}
+	return types.NewTransactionsByPriceAndNonce(env.signer, txs, env.header.BaseFee)
+}
+
+// txByTime implements the heap interface, ordering the transactions by the
+// time they were first seen locally, and by hash for the same time.
+type txByTime []*types.Transaction
+
+func (s txByTime) Len() int { return len(s) }
+func (s txByTime) Less(i, j int) bool {
+	ti, tj := s[i].LocalSeenTime(), s[j].LocalSeenTime()
+	if ti.Equal(tj) {
+		hi, hj := s[i].Hash(), s[j].Hash()
+		return bytes.Compare(hi[:], hj[:]) < 0
+	}
+	return ti.Before(tj)
+}
+func (s txByTime) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+
+func (s *txByTime) Push(x interface{}) {
+	*s = append(*s, x.(*types.Transaction))
+}
+
+func (s *txByTime) Pop() interface{} {
+	old := *s
+	n := len(old)
+	x := old[n-1]
+	old[n-1] = nil
+	*s = old[0 : n-1]
+	return x
+}
+
+// transactionsByTimeAndNonce represents a set of transactions that can return
+// transactions in their arrival order, while supporting removing entire batches
+// of transactions for non-executable accounts.
+type transactionsByTimeAndNonce struct {
+	txs     map[common.Address]types.Transactions // Per account nonce-sorted list of transactions
+	heads   txByTime                              // Next transaction for each unique account (time heap)
+	signer  types.Signer                          // Signer for the set of transactions
+	baseFee *big.Int                              // Current base fee
+}
+
+// newTransactionsByTimeAndNonce creates a transaction set that can retrieve
+// transactions in their arrival order in a nonce-honouring way. Like the price
+// ordering, the transactions not paying the base fee are left out.
+func newTransactionsByTimeAndNonce(signer types.Signer, txs map[common.Address]types.Transactions, baseFee *big.Int) *transactionsByTimeAndNonce {
+	heads := make(txByTime, 0, len(txs))
+	for from, accTxs := range txs {
+		acc, _ := types.Sender(signer, accTxs[0])
+		// Remove transaction if sender doesn't match from, or if it can't pay the base fee.
+		if _, err := accTxs[0].EffectiveGasTip(baseFee); acc != from || err != nil {
+			delete(txs, from)
+			continue
+		}
+		heads = append(heads, accTxs[0])
+		txs[from] = accTxs[1:]
+	}
+	heap.Init(&heads)
+
+	return &transactionsByTimeAndNonce{
+		txs:     txs,
+		heads:   heads,
+		signer:  signer,
+		baseFee: baseFee,
+	}
+}
+
+// Peek returns the transaction seen first.
+func (t *transactionsByTimeAndNonce) Peek() *types.Transaction {
+	if len(t.heads) == 0 {
+		return nil
+	}
+	return t.heads[0]
+}
+
+// Shift replaces the current head with the next one from the same account.
+func (t *transactionsByTimeAndNonce) Shift() {
+	acc, _ := types.Sender(t.signer, t.heads[0])
+	if txs, ok := t.txs[acc]; ok && len(txs) > 0 {
+		if _, err := txs[0].EffectiveGasTip(t.baseFee); err == nil {
+			t.heads[0], t.txs[acc] = txs[0], txs[1:]
+			heap.Fix(&t.heads, 0)
+			return
+		}
+	}
+	heap.Pop(&t.heads)
+}
+
+// Pop removes the current head, *not* replacing it with the next one from the
+// same account.
+func (t *transactionsByTimeAndNonce) Pop() {
+	heap.Pop(&t.heads)
+}
@@ -0,0 +1,142 @@
+package miner
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/ethash"
+	"github.com/ethereum/go-ethereum/core/rawdb"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/params"
+)
+
+func newOrderingTx(t *testing.T, key *ecdsa.PrivateKey, nonce uint64, gasPrice int64) *types.Transaction {
+	tx, err := types.SignNewTx(key, types.LatestSigner(ethashChainConfig), &types.LegacyTx{
+		Nonce:    nonce,
+		To:       &testUserAddress,
+		Gas:      params.TxGas,
+		GasPrice: big.NewInt(gasPrice),
+	})
+	if err != nil {
+		t.Fatalf("failed to sign transaction: %v", err)
+	}
+	return tx
+}
+
+// Tests that the FIFO ordering returns the transactions in their arrival order
+// regardless of their price, while honouring the nonces.
+func TestTransactionsByTimeAndNonce(t *testing.T) {
+	var (
+		signer  = types.LatestSigner(ethashChainConfig)
+		keys    = make([]*ecdsa.PrivateKey, 3)
+		addrs   = make([]common.Address, 3)
+		txs     = make(map[common.Address]types.Transactions)
+		arrival []*types.Transaction
+	)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		addrs[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	// the later transactions pay more, except for the third account which
+	// can't pay the base fee
+	for nonce := uint64(0); nonce < 3; nonce++ {
+		for i := 0; i < 2; i++ {
+			tx := newOrderingTx(t, keys[i], nonce, int64(10*(len(arrival)+1)))
+			txs[addrs[i]] = append(txs[addrs[i]], tx)
+			arrival = append(arrival, tx)
+			time.Sleep(time.Millisecond)
+		}
+	}
+	txs[addrs[2]] = types.Transactions{newOrderingTx(t, keys[2], 0, 1)}
+
+	set := newTransactionsByTimeAndNonce(signer, txs, big.NewInt(5))
+	for i, want := range arrival {
+		tx := set.Peek()
+		if tx == nil {
+			t.Fatalf("transaction %d missing", i)
+		}
+		if tx.Hash() != want.Hash() {
+			t.Fatalf("transaction %d mismatch: have %x, want %x", i, tx.Hash(), want.Hash())
+		}
+		set.Shift()
+	}
+	if tx := set.Peek(); tx != nil {
+		t.Fatalf("unexpected transaction %x", tx.Hash())
+	}
+}
+
+// Tests that the worker reserves block gas for the priority senders, and caps
+// the transactions of every sender.
+func TestCommitPendingLanes(t *testing.T) {
+	w, _ := newTestWorker(t, ethashChainConfig, ethash.NewFaker(), rawdb.NewMemoryDatabase(), 0)
+	defer w.close()
+
+	var (
+		remoteKey, _   = crypto.GenerateKey()
+		remote         = crypto.PubkeyToAddress(remoteKey.PublicKey)
+		priorityKey, _ = crypto.GenerateKey()
+		priority       = crypto.PubkeyToAddress(priorityKey.PublicKey)
+	)
+	pending := func(key *ecdsa.PrivateKey, from uint64, n int) map[common.Address]types.Transactions {
+		var txs types.Transactions
+		for i := 0; i < n; i++ {
+			txs = append(txs, newOrderingTx(t, key, from+uint64(i), 10*params.InitialBaseFee))
+		}
+		return map[common.Address]types.Transactions{crypto.PubkeyToAddress(key.PublicKey): txs}
+	}
+	newEnv := func(config *Config) *environment {
+		w.ordering = newTxOrdering(config)
+		env, err := w.prepareWork(&generateParams{timestamp: uint64(time.Now().Unix()), coinbase: testBankAddress})
+		if err != nil {
+			t.Fatalf("failed to prepare work: %v", err)
+		}
+		env.header.GasLimit = 100_000
+		env.state.AddBalance(remote, big.NewInt(params.Ether))
+		env.state.AddBalance(priority, big.NewInt(params.Ether))
+		return env
+	}
+	count := func(env *environment, sender common.Address) (n int) {
+		for _, tx := range env.txs {
+			if from, _ := types.Sender(env.signer, tx); from == sender {
+				n++
+			}
+		}
+		return n
+	}
+	// the others can't use the half of the block gas reserved to the priority
+	// senders, which can use the whole block
+	env := newEnv(&Config{ReservedGas: 50, PrioritySenders: []common.Address{priority}})
+	defer env.discard()
+	if err := w.commitPending(env, pending(remoteKey, 0, 4), nil); err != nil {
+		t.Fatalf("failed to commit pending transactions: %v", err)
+	}
+	if n := count(env, remote); n != 2 {
+		t.Fatalf("remote transaction count mismatch: have %d, want %d", n, 2)
+	}
+	if err := w.commitPending(env, pending(priorityKey, 0, 4), nil); err != nil {
+		t.Fatalf("failed to commit pending transactions: %v", err)
+	}
+	if n := count(env, priority); n != 2 {
+		t.Fatalf("priority transaction count mismatch: have %d, want %d", n, 2)
+	}
+	if env.priorityGas != 2*params.TxGas {
+		t.Fatalf("priority gas mismatch: have %d, want %d", env.priorityGas, 2*params.TxGas)
+	}
+
+	// the transactions of a sender are capped over the whole block
+	env = newEnv(&Config{SenderTxCap: 3})
+	defer env.discard()
+	if err := w.commitPending(env, pending(remoteKey, 0, 2), nil); err != nil {
+		t.Fatalf("failed to commit pending transactions: %v", err)
+	}
+	if err := w.commitPending(env, pending(remoteKey, 2, 2), nil); err != nil {
+		t.Fatalf("failed to commit pending transactions: %v", err)
+	}
+	if n := count(env, remote); n != 3 {
+		t.Fatalf("remote transaction count mismatch: have %d, want %d", n, 3)
+	}
+}
@@ -89,18 +89,21 @@ type environment struct {
 	txs      []*types.Transaction
 	receipts []*types.Receipt
 
+	priorityGas uint64 // gas used by the priority lane of the transaction ordering
+
 	extraValidator types.EvmExtraValidator
 }
 
 // copy creates a deep copy of environment.
 func (env *environment) copy() *environment {
 	cpy := &environment{
-		signer:   env.signer,
-		state:    env.state.Copy(),
-		tcount:   env.tcount,
-		coinbase: env.coinbase,
-		header:   types.CopyHeader(env.header),
-		receipts: copyReceipts(env.receipts),
+		signer:      env.signer,
+		state:       env.state.Copy(),
+		tcount:      env.tcount,
+		coinbase:    env.coinbase,
+		header:      types.CopyHeader(env.header),
+		receipts:    copyReceipts(env.receipts),
+		priorityGas: env.priorityGas,
 	}
 	if env.gasPool != nil {
 		gasPool := *env.gasPool
@@ -174,6 +177,8 @@ type worker struct {
 	posa   consensus.PoSA
 	isPoSA bool
 
+	ordering *txOrdering // Transaction ordering policy of the sealing blocks
+
 	// Feeds
 	pendingLogsFeed event.Feed
 
@@ -244,6 +249,7 @@ func newWorker(config *Config, chainConfig *params.ChainConfig, engine consensus
 		engine:             engine,
 		isPoSA:             isPoSA,
 		posa:               posa,
+		ordering:           newTxOrdering(config),
 		eth:                eth,
 		chain:              eth.BlockChain(),
 		mux:                mux,
@@ -547,9 +553,8 @@ func (w *worker) mainLoop() {
 					acc, _ := types.Sender(w.current.signer, tx)
 					txs[acc] = append(txs[acc], tx)
 				}
-				txset := types.NewTransactionsByPriceAndNonce(w.current.signer, txs, w.current.header.BaseFee)
 				tcount := w.current.tcount
-				w.commitTransactions(w.current, txset, nil)
+				w.commitPending(w.current, txs, nil)
 
 				// Only update the snapshot if any new transactions were added
 				// to the pending block
@@ -754,7 +759,9 @@ func (w *worker) commitTransaction(env *environment, tx *types.Transaction) ([]*
 	return receipt.Logs, nil
 }
 
-func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByPriceAndNonce, interrupt *atomic.Int32) error {
+// commitTransactions commits the transactions to the block in their order,
+// leaving the reserved gas in the gas pool.
+func (w *worker) commitTransactions(env *environment, txs orderedTransactions, reserved uint64, interrupt *atomic.Int32) error {
 	gasLimit := env.header.GasLimit
 	if env.gasPool == nil {
 		env.gasPool = new(core.GasPool).AddGas(gasLimit)
@@ -769,8 +776,8 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 			}
 		}
 		// If we don't have enough gas for any further transactions then we're done.
-		if env.gasPool.Gas() < params.TxGas {
-			log.Trace("Not enough gas for further transactions", "have", env.gasPool, "want", params.TxGas)
+		if env.gasPool.Gas() < reserved+params.TxGas {
+			log.Trace("Not enough gas for further transactions", "have", env.gasPool, "want", params.TxGas, "reserved", reserved)
 			break
 		}
 		// Retrieve the next transaction and abort if all done.
@@ -782,6 +789,12 @@ func (w *worker) commitTransactions(env *environment, txs *types.TransactionsByP
 		// during transaction acceptance is the transaction pool.
 		from, _ := types.Sender(env.signer, tx)
 
+		// Leave the reserved gas to the priority lane.
+		if reserved > 0 && tx.Gas() > env.gasPool.Gas()-reserved {
+			log.Trace("Skipping transaction exceeding the unreserved gas", "hash", tx.Hash(), "gas", tx.Gas(), "reserved", reserved)
+			txs.Pop()
+			continue
+		}
 		// Check whether the tx is replay protected. If we're not in the EIP155 hf
 		// phase, start ignoring the sender until we do.
 		if tx.Protected() && !w.chainConfig.IsEIP155(env.header.Number) {
@@ -918,34 +931,44 @@ func (w *worker) prepareWork(genParams *generateParams) (*environment, error) {
 			log.Error("Failed to apply system contract upgrade", "err", err)
 			return nil, err
 		}
-		env.extraValidator = w.posa.CreateEvmExtraValidator(header, env.state)
+		if env.extraValidator, err = w.posa.CreateEvmExtraValidator(header, env.state); err != nil {
+			log.Error("Failed to read the evm rules", "err", err)
+			return nil, err
+		}
 	}
 	return env, nil
 }
 
 // fillTransactions retrieves the pending transactions from the txpool and fills them
-// into the given sealing block. The transaction selection and ordering strategy can
-// be customized with the plugin in the future.
+// into the given sealing block. The transaction selection and ordering strategy is
+// customized with the transaction ordering of the miner config.
 func (w *worker) fillTransactions(interrupt *atomic.Int32, env *environment) error {
-	// Split the pending transactions into locals and remotes
 	// Fill the block with all available pending transactions.
-	pending := w.eth.TxPool().Pending(true)
-	localTxs, remoteTxs := make(map[common.Address]types.Transactions), pending
-	for _, account := range w.eth.TxPool().Locals() {
-		if txs := remoteTxs[account]; len(txs) > 0 {
-			delete(remoteTxs, account)
-			localTxs[account] = txs
-		}
+	return w.commitPending(env, w.eth.TxPool().Pending(true), interrupt)
+}
+
+// commitPending commits the pending transactions to the given sealing block,
+// the local and priority senders first, then the others as long as the gas
+// reserved to the priority lane isn't used.
+func (w *worker) commitPending(env *environment, pending map[common.Address]types.Transactions, interrupt *atomic.Int32) error {
+	if env.gasPool == nil {
+		env.gasPool = new(core.GasPool).AddGas(env.header.GasLimit)
 	}
-	if len(localTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, localTxs, env.header.BaseFee)
-		if err := w.commitTransactions(env, txs, interrupt); err != nil {
+	priorityTxs, otherTxs := w.ordering.split(pending, w.eth.TxPool().Locals())
+	if len(priorityTxs) > 0 {
+		gas := env.gasPool.Gas()
+		err := w.commitTransactions(env, w.ordering.order(env, priorityTxs), 0, interrupt)
+		env.priorityGas += gas - env.gasPool.Gas()
+		if err != nil {
 			return err
 		}
 	}
-	if len(remoteTxs) > 0 {
-		txs := types.NewTransactionsByPriceAndNonce(env.signer, remoteTxs, env.header.BaseFee)
-		if err := w.commitTransactions(env, txs, interrupt); err != nil {
+	if len(otherTxs) > 0 {
+		var reserved uint64
+		if gas := w.ordering.reservedGas(env.header); gas > env.priorityGas {
+			reserved = gas - env.priorityGas
+		}
+		if err := w.commitTransactions(env, w.ordering.order(env, otherTxs), reserved, interrupt); err != nil {
 			return err
 		}
 	}
@@ -343,13 +343,79 @@ type NposConfig struct {
 	StakingAdmin          common.Address   `json:"stakingAdmin,omitempty"` // The administration address of NPoS consensus. NPoS requires a progressive decentralization process.
 	GovAdmin              common.Address   `json:"govAdmin,omitempty"`     // There are some governance features for the chain. it can be disabled by not providing this address.
 	EnableDevVerification bool             `json:"enableDevVerification"`  // Enable developer address verification
+
+	DoubleSignBlock      *big.Int `json:"doubleSignBlock,omitempty"`      // Double-sign slashing switch block (nil = no fork, 0 = already activated)
+	GovActionsBlock      *big.Int `json:"govActionsBlock,omitempty"`      // Switch block to enable the extended system governance actions (nil = no fork, 0 = already activated)
+	SysContractV1Block   *big.Int `json:"sysContractV1Block,omitempty"`   // Switch block to upgrade the system contracts to version 1 (nil = no fork, must be after the genesis)
+	SysContractV2Block   *big.Int `json:"sysContractV2Block,omitempty"`   // Switch block to upgrade the system contracts to version 2, enforcing the call rules and the log data checks after it (nil = no fork, must not be before the version 1)
+	AttestationBlock     *big.Int `json:"attestationBlock,omitempty"`     // Switch block to finalize the checkpoints by validator attestations (nil = no fork, must be at an epoch)
+	DevVerificationBlock *big.Int `json:"devVerificationBlock,omitempty"` // Switch block to restrict contract creation to developers if enabled (nil = no fork, must be after the system contracts v1)
+	FeeBurnBlock         *big.Int `json:"feeBurnBlock,omitempty"`         // Switch block to burn the base fee of the transactions rather than distributing it (nil = no fork, must be on london)
+
+	FeeRecoderProtectionBlock *big.Int `json:"feeRecoderProtectionBlock,omitempty"` // Switch block to reject the value transfers to the FeeRecoder (nil = no fork, 0 = already activated)
 }
 
+// NposFeeRecoder is the account collecting the fees of the transactions on NPoS,
+// which the engine distributes as the block reward.
+var NposFeeRecoder = common.HexToAddress("0xffffffffffffffffffffffffffffffffffffffff")
+
+// NposBurnAddress is the account the Validators system contract sends the burned
+// share of the block reward to. It's a constant of the contract code, which has
+// no getter for it, so it must follow the deployed contract.
+var NposBurnAddress = common.HexToAddress("0x000000000000000000000000000000000000dAaA")
+
+// The To addresses of the system transactions of the NPoS engine, which are sent
+// by the coinbase at a zero gas price. They are NOT contract addresses.
+var (
+	NposSysGovToAddr              = common.HexToAddress("0x000000000000000000000000000000000000ffff")
+	NposDoubleSignEvidenceToAddr  = common.HexToAddress("0x000000000000000000000000000000000000fffe")
+	NposAttestationEvidenceToAddr = common.HexToAddress("0x000000000000000000000000000000000000fffd")
+)
+
 // String implements the stringer interface, returning the consensus engine details.
 func (c *NposConfig) String() string {
 	return "npos"
 }
 
+// IsDoubleSign returns whether num is either equal to the double-sign slashing fork block or greater.
+func (c *NposConfig) IsDoubleSign(num *big.Int) bool {
+	return isBlockForked(c.DoubleSignBlock, num)
+}
+
+// IsGovActions returns whether num is either equal to the governance actions fork block or greater.
+func (c *NposConfig) IsGovActions(num *big.Int) bool {
+	return isBlockForked(c.GovActionsBlock, num)
+}
+
+// IsAttestation returns whether num is either equal to the attestation fork block or greater.
+func (c *NposConfig) IsAttestation(num *big.Int) bool {
+	return isBlockForked(c.AttestationBlock, num)
+}
+
+// IsDevVerification returns whether the developer verification is enabled and
+// num is either equal to its fork block or greater.
+func (c *NposConfig) IsDevVerification(num *big.Int) bool {
+	return c.EnableDevVerification && isBlockForked(c.DevVerificationBlock, num)
+}
+
+// IsFeeBurn returns whether num is either equal to the fee burn fork block or greater.
+func (c *NposConfig) IsFeeBurn(num *big.Int) bool {
+	return isBlockForked(c.FeeBurnBlock, num)
+}
+
+// IsFeeRecoderProtection returns whether num is either equal to the FeeRecoder
+// protection fork block or greater.
+func (c *NposConfig) IsFeeRecoderProtection(num *big.Int) bool {
+	return isBlockForked(c.FeeRecoderProtectionBlock, num)
+}
+
+// IsAddressListV2 returns whether the rules of the AddressList contract v2, the
+// call rules and the checks of the log data words, are enforced at num, that is
+// whether num is after the system contracts v2 fork block.
+func (c *NposConfig) IsAddressListV2(num *big.Int) bool {
+	return c.SysContractV2Block != nil && c.SysContractV2Block.Cmp(num) < 0
+}
+
 // ValidatorItem is the NPoS genesis validator information item
 type ValidatorItem struct {
 	Validator common.Address `json:"validator"`
@@ -387,6 +453,30 @@ func (c *ChainConfig) Description() string {
 		banner += "Consensus: NPoS\n"
 		banner += fmt.Sprintf(" - Period: %d seconds\n", c.Npos.Period)
 		banner += fmt.Sprintf(" - Epoch : %d \n", c.Npos.Epoch)
+		if c.Npos.DoubleSignBlock != nil {
+			banner += fmt.Sprintf(" - Double-sign slashing: #%-8v\n", c.Npos.DoubleSignBlock)
+		}
+		if c.Npos.GovActionsBlock != nil {
+			banner += fmt.Sprintf(" - Governance actions  : #%-8v\n", c.Npos.GovActionsBlock)
+		}
+		if c.Npos.SysContractV1Block != nil {
+			banner += fmt.Sprintf(" - System contracts v1 : #%-8v\n", c.Npos.SysContractV1Block)
+		}
+		if c.Npos.SysContractV2Block != nil {
+			banner += fmt.Sprintf(" - System contracts v2 : #%-8v\n", c.Npos.SysContractV2Block)
+		}
+		if c.Npos.AttestationBlock != nil {
+			banner += fmt.Sprintf(" - Attestations        : #%-8v\n", c.Npos.AttestationBlock)
+		}
+		if c.Npos.EnableDevVerification && c.Npos.DevVerificationBlock != nil {
+			banner += fmt.Sprintf(" - Dev verification    : #%-8v\n", c.Npos.DevVerificationBlock)
+		}
+		if c.Npos.FeeBurnBlock != nil {
+			banner += fmt.Sprintf(" - Base fee burn       : #%-8v\n", c.Npos.FeeBurnBlock)
+		}
+		if c.Npos.FeeRecoderProtectionBlock != nil {
+			banner += fmt.Sprintf(" - FeeRecoder guard    : #%-8v\n", c.Npos.FeeRecoderProtectionBlock)
+		}
 	default:
 		banner += "Consensus: unknown\n"
 	}
@@ -633,6 +723,22 @@ func (c *ChainConfig) CheckConfigForkOrder() error {
 			lastFork = cur
 		}
 	}
+	if c.Npos != nil {
+		return c.Npos.checkForkOrder(c)
+	}
+	return nil
+}
+
+// checkForkOrder checks the NPoS forks are scheduled after the ones they depend on.
+func (c *NposConfig) checkForkOrder(config *ChainConfig) error {
+	if c.SysContractV2Block != nil && (c.SysContractV1Block == nil || c.SysContractV2Block.Cmp(c.SysContractV1Block) < 0) {
+		return fmt.Errorf("unsupported fork ordering: sysContractV1Block enabled at block %v, but sysContractV2Block enabled at block %v",
+			c.SysContractV1Block, c.SysContractV2Block)
+	}
+	if c.FeeBurnBlock != nil && !config.IsLondon(c.FeeBurnBlock) {
+		return fmt.Errorf("unsupported fork ordering: londonBlock enabled at block %v, but feeBurnBlock enabled at block %v",
+			config.LondonBlock, c.FeeBurnBlock)
+	}
 	return nil
 }
 
@@ -692,6 +798,18 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	if isForkBlockIncompatible(c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock, headNumber) {
 		return newBlockCompatError("Merge netsplit fork block", c.MergeNetsplitBlock, newcfg.MergeNetsplitBlock)
 	}
+	if c.Npos != nil || newcfg.Npos != nil {
+		stored, npos := c.Npos, newcfg.Npos
+		if stored == nil {
+			stored = new(NposConfig)
+		}
+		if npos == nil {
+			npos = new(NposConfig)
+		}
+		if err := stored.checkCompatible(npos, headNumber); err != nil {
+			return err
+		}
+	}
 	if isForkTimestampIncompatible(c.ShanghaiTime, newcfg.ShanghaiTime, headTimestamp) {
 		return newTimestampCompatError("Shanghai fork timestamp", c.ShanghaiTime, newcfg.ShanghaiTime)
 	}
@@ -704,6 +822,45 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, headNumber *big.Int,
 	return nil
 }
 
+// checkCompatible checks the NPoS forks already passed by the head are unchanged.
+func (c *NposConfig) checkCompatible(newcfg *NposConfig, headNumber *big.Int) *ConfigCompatError {
+	if isForkBlockIncompatible(c.DoubleSignBlock, newcfg.DoubleSignBlock, headNumber) {
+		return newBlockCompatError("NPoS double-sign fork block", c.DoubleSignBlock, newcfg.DoubleSignBlock)
+	}
+	if isForkBlockIncompatible(c.GovActionsBlock, newcfg.GovActionsBlock, headNumber) {
+		return newBlockCompatError("NPoS governance actions fork block", c.GovActionsBlock, newcfg.GovActionsBlock)
+	}
+	if isForkBlockIncompatible(c.SysContractV1Block, newcfg.SysContractV1Block, headNumber) {
+		return newBlockCompatError("NPoS system contracts v1 fork block", c.SysContractV1Block, newcfg.SysContractV1Block)
+	}
+	if isForkBlockIncompatible(c.SysContractV2Block, newcfg.SysContractV2Block, headNumber) {
+		return newBlockCompatError("NPoS system contracts v2 fork block", c.SysContractV2Block, newcfg.SysContractV2Block)
+	}
+	if isForkBlockIncompatible(c.AttestationBlock, newcfg.AttestationBlock, headNumber) {
+		return newBlockCompatError("NPoS attestation fork block", c.AttestationBlock, newcfg.AttestationBlock)
+	}
+	// the verification is only in effect if it's enabled too
+	if isForkBlockIncompatible(c.devVerificationBlock(), newcfg.devVerificationBlock(), headNumber) {
+		return newBlockCompatError("NPoS developer verification fork block", c.devVerificationBlock(), newcfg.devVerificationBlock())
+	}
+	if isForkBlockIncompatible(c.FeeBurnBlock, newcfg.FeeBurnBlock, headNumber) {
+		return newBlockCompatError("NPoS fee burn fork block", c.FeeBurnBlock, newcfg.FeeBurnBlock)
+	}
+	if isForkBlockIncompatible(c.FeeRecoderProtectionBlock, newcfg.FeeRecoderProtectionBlock, headNumber) {
+		return newBlockCompatError("NPoS FeeRecoder protection fork block", c.FeeRecoderProtectionBlock, newcfg.FeeRecoderProtectionBlock)
+	}
+	return nil
+}
+
+// devVerificationBlock returns the block the developer verification is in effect
+// from, nil if it's not enabled.
+func (c *NposConfig) devVerificationBlock() *big.Int {
+	if !c.EnableDevVerification {
+		return nil
+	}
+	return c.DevVerificationBlock
+}
+
 // BaseFeeChangeDenominator bounds the amount the base fee can change between blocks.
 func (c *ChainConfig) BaseFeeChangeDenominator() uint64 {
 	return DefaultBaseFeeChangeDenominator
@@ -846,6 +1003,7 @@ type Rules struct {
 	IsByzantium, IsConstantinople, IsPetersburg, IsIstanbul bool
 	IsBerlin, IsLondon                                      bool
 	IsMerge, IsShanghai, IsCancun, IsPrague                 bool
+	IsFeeRecoderProtection                                  bool // NPoS
 }
 
 // Rules ensures c's ChainID is not nil.
@@ -872,5 +1030,7 @@ func (c *ChainConfig) Rules(num *big.Int, isMerge bool, timestamp uint64) Rules
 		IsShanghai:       c.IsShanghai(timestamp),
 		IsCancun:         c.IsCancun(timestamp),
 		IsPrague:         c.IsPrague(timestamp),
+
+		IsFeeRecoderProtection: c.Npos != nil && c.Npos.IsFeeRecoderProtection(num),
 	}
 }
@@ -109,6 +109,45 @@ func TestCheckCompatible(t *testing.T) {
 				RewindToTime: 9,
 			},
 		},
+		{
+			stored:    &ChainConfig{Npos: &NposConfig{SysContractV1Block: big.NewInt(10)}},
+			new:       &ChainConfig{Npos: &NposConfig{SysContractV1Block: big.NewInt(20)}},
+			headBlock: 9,
+			wantErr:   nil,
+		},
+		{
+			stored:    &ChainConfig{Npos: &NposConfig{FeeBurnBlock: big.NewInt(10)}},
+			new:       &ChainConfig{Npos: &NposConfig{FeeBurnBlock: big.NewInt(20)}},
+			headBlock: 15,
+			wantErr: &ConfigCompatError{
+				What:          "NPoS fee burn fork block",
+				StoredBlock:   big.NewInt(10),
+				NewBlock:      big.NewInt(20),
+				RewindToBlock: 9,
+			},
+		},
+		{
+			stored:    &ChainConfig{Npos: &NposConfig{DevVerificationBlock: big.NewInt(10)}},
+			new:       &ChainConfig{Npos: &NposConfig{EnableDevVerification: true, DevVerificationBlock: big.NewInt(10)}},
+			headBlock: 15,
+			wantErr: &ConfigCompatError{
+				What:          "NPoS developer verification fork block",
+				StoredBlock:   nil,
+				NewBlock:      big.NewInt(10),
+				RewindToBlock: 9,
+			},
+		},
+		{
+			stored:    &ChainConfig{Npos: &NposConfig{AttestationBlock: big.NewInt(10)}},
+			new:       &ChainConfig{},
+			headBlock: 15,
+			wantErr: &ConfigCompatError{
+				What:          "NPoS attestation fork block",
+				StoredBlock:   big.NewInt(10),
+				NewBlock:      nil,
+				RewindToBlock: 9,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -119,6 +158,59 @@ func TestCheckCompatible(t *testing.T) {
 	}
 }
 
+func TestCheckCompatibleNposForks(t *testing.T) {
+	forks := map[string]func(*NposConfig) **big.Int{
+		"NPoS double-sign fork block":           func(c *NposConfig) **big.Int { return &c.DoubleSignBlock },
+		"NPoS governance actions fork block":    func(c *NposConfig) **big.Int { return &c.GovActionsBlock },
+		"NPoS system contracts v1 fork block":   func(c *NposConfig) **big.Int { return &c.SysContractV1Block },
+		"NPoS system contracts v2 fork block":   func(c *NposConfig) **big.Int { return &c.SysContractV2Block },
+		"NPoS attestation fork block":           func(c *NposConfig) **big.Int { return &c.AttestationBlock },
+		"NPoS fee burn fork block":              func(c *NposConfig) **big.Int { return &c.FeeBurnBlock },
+		"NPoS FeeRecoder protection fork block": func(c *NposConfig) **big.Int { return &c.FeeRecoderProtectionBlock },
+	}
+	for what, fork := range forks {
+		stored, moved := new(NposConfig), new(NposConfig)
+		*fork(stored), *fork(moved) = big.NewInt(10), big.NewInt(11)
+		err := (&ChainConfig{Npos: stored}).CheckCompatible(&ChainConfig{Npos: moved}, 10, 0)
+		if err == nil || err.What != what {
+			t.Errorf("%s: error mismatch, have %v", what, err)
+		}
+	}
+}
+
+func TestCheckConfigForkOrderNpos(t *testing.T) {
+	tests := []struct {
+		config  *NposConfig
+		london  *big.Int
+		wantErr bool
+	}{
+		{config: &NposConfig{SysContractV1Block: big.NewInt(10), SysContractV2Block: big.NewInt(10)}},
+		{config: &NposConfig{SysContractV1Block: big.NewInt(10), SysContractV2Block: big.NewInt(9)}, wantErr: true},
+		{config: &NposConfig{SysContractV2Block: big.NewInt(10)}, wantErr: true},
+		{config: &NposConfig{FeeBurnBlock: big.NewInt(10)}, london: big.NewInt(10)},
+		{config: &NposConfig{FeeBurnBlock: big.NewInt(10)}, london: big.NewInt(11), wantErr: true},
+		{config: &NposConfig{FeeBurnBlock: big.NewInt(10)}, wantErr: true},
+	}
+	for i, test := range tests {
+		config := &ChainConfig{
+			HomesteadBlock:      big.NewInt(0),
+			EIP150Block:         big.NewInt(0),
+			EIP155Block:         big.NewInt(0),
+			EIP158Block:         big.NewInt(0),
+			ByzantiumBlock:      big.NewInt(0),
+			ConstantinopleBlock: big.NewInt(0),
+			PetersburgBlock:     big.NewInt(0),
+			IstanbulBlock:       big.NewInt(0),
+			BerlinBlock:         big.NewInt(0),
+			LondonBlock:         test.london,
+			Npos:                test.config,
+		}
+		if err := config.CheckConfigForkOrder(); (err != nil) != test.wantErr {
+			t.Errorf("test %d: error mismatch, have %v, want error %t", i, err, test.wantErr)
+		}
+	}
+}
+
 func TestConfigRules(t *testing.T) {
 	c := &ChainConfig{
 		ShanghaiTime: newUint64(500),
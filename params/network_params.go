@@ -32,6 +32,14 @@ const (
 	// considered probably final and its rotated bits are calculated.
 	BloomConfirms = 256
 
+	// InternalTxIndexBlocks is the number of blocks a single section of the
+	// internal tx address index contains.
+	InternalTxIndexBlocks uint64 = 1024
+
+	// InternalTxIndexConfirms is the number of confirmation blocks before a
+	// section of the internal tx address index is indexed.
+	InternalTxIndexConfirms = 64
+
 	// CHTFrequency is the block frequency for creating CHTs
 	CHTFrequency = 32768
 